@@ -0,0 +1,214 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/AvengeMedia/DankMaterialShell/core/internal/log"
+	"github.com/AvengeMedia/DankMaterialShell/core/internal/server/bluez"
+	"github.com/AvengeMedia/DankMaterialShell/core/internal/server/models"
+	"github.com/spf13/cobra"
+)
+
+var bluetoothCmd = &cobra.Command{
+	Use:   "bluetooth",
+	Short: "Bluetooth file transfer",
+}
+
+var bluetoothSendCmd = &cobra.Command{
+	Use:   "send <device> <file>",
+	Short: "Send a file to a paired device over OBEX",
+	Long: `Send a file to a paired device using the Object Push profile.
+
+The device may be given as its address, alias, name or BlueZ object path.
+Progress is shown until the transfer finishes; Ctrl+C cancels it.
+
+Examples:
+  dms bluetooth send "Pixel 8" ~/Pictures/photo.jpg
+  dms bluetooth send AA:BB:CC:DD:EE:FF notes.pdf`,
+	Args: cobra.ExactArgs(2),
+	Run:  runBluetoothSend,
+}
+
+var bluetoothTransfersCmd = &cobra.Command{
+	Use:   "transfers",
+	Short: "List recent Bluetooth file transfers",
+	Args:  cobra.NoArgs,
+	Run:   runBluetoothTransfers,
+}
+
+var bluetoothCancelCmd = &cobra.Command{
+	Use:   "cancel <id>",
+	Short: "Cancel a Bluetooth file transfer",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		requestBluetooth("bluetooth.obex.cancel", map[string]any{"id": args[0]})
+		fmt.Println("Transfer cancelled")
+	},
+}
+
+var bluetoothReceiveDirCmd = &cobra.Command{
+	Use:   "receive-dir [dir]",
+	Short: "Show or set where received files are saved",
+	Long:  "Show or set where files received over Bluetooth are saved. Pass \"\" to go back to the Downloads folder.",
+	Args:  cobra.MaximumNArgs(1),
+	Run:   runBluetoothReceiveDir,
+}
+
+func init() {
+	bluetoothTransfersCmd.Flags().Bool("json", false, "Output in JSON format")
+	bluetoothCmd.AddCommand(bluetoothSendCmd, bluetoothTransfersCmd, bluetoothCancelCmd, bluetoothReceiveDirCmd)
+}
+
+func requestBluetooth(method string, params map[string]any) any {
+	resp, err := sendServerRequest(models.Request{
+		ID:     1,
+		Method: method,
+		Params: params,
+	})
+	if err != nil {
+		log.Fatalf("Failed: %v (is dms server running?)", err)
+	}
+	if resp.Error != "" {
+		log.Fatalf("Error: %s", resp.Error)
+	}
+	if resp.Result == nil {
+		return nil
+	}
+	return *resp.Result
+}
+
+func decodeResult[T any](result any) T {
+	var v T
+	data, err := json.Marshal(result)
+	if err != nil {
+		log.Fatalf("Invalid response format: %v", err)
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		log.Fatalf("Invalid response format: %v", err)
+	}
+	return v
+}
+
+func runBluetoothSend(cmd *cobra.Command, args []string) {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+
+	var currentID atomic.Value
+	go func() {
+		<-sigCh
+		if id, _ := currentID.Load().(string); id != "" {
+			tryServerRequest(models.Request{
+				Method: "bluetooth.obex.cancel",
+				Params: map[string]any{"id": id},
+			})
+		}
+		fmt.Fprintln(os.Stderr)
+		os.Exit(130)
+	}()
+
+	// The server resolves nothing against our working directory.
+	file, err := filepath.Abs(args[1])
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+
+	var final bluez.Transfer
+	err = streamServerRequest(models.Request{
+		ID:     1,
+		Method: "bluetooth.obex.send",
+		Params: map[string]any{"device": args[0], "file": file, "follow": true},
+	}, func(resp *models.Response[any]) bool {
+		if resp.Error != "" {
+			log.Fatalf("Error: %s", resp.Error)
+		}
+		if resp.Result == nil {
+			return true
+		}
+		t := decodeResult[bluez.Transfer](*resp.Result)
+		currentID.Store(t.ID)
+		fmt.Fprintf(os.Stderr, "\r%s → %s: %s", t.Name, t.DeviceName, formatTransferProgress(t))
+		if t.Finished() {
+			final = t
+			return false
+		}
+		return true
+	})
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		log.Fatalf("Failed: %v", err)
+	}
+
+	if final.Status != bluez.TransferComplete {
+		os.Exit(1)
+	}
+}
+
+func formatTransferProgress(t bluez.Transfer) string {
+	switch {
+	case t.Finished():
+		return t.Status
+	case t.Size > 0:
+		return fmt.Sprintf("%3d%% (%s / %s)", t.Transferred*100/t.Size, formatTransferSize(t.Transferred), formatTransferSize(t.Size))
+	}
+	return t.Status
+}
+
+func formatTransferSize(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGT"[exp])
+}
+
+func runBluetoothTransfers(cmd *cobra.Command, args []string) {
+	jsonFlag, _ := cmd.Flags().GetBool("json")
+	result := requestBluetooth("bluetooth.obex.getState", nil)
+	if jsonFlag {
+		data, _ := json.MarshalIndent(result, "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+
+	state := decodeResult[bluez.ObexState](result)
+	if !state.Available {
+		fmt.Println("Bluetooth file transfer unavailable (is obexd installed?)")
+		return
+	}
+	fmt.Printf("Receive directory: %s\n", state.ReceiveDir)
+	if len(state.Transfers) == 0 {
+		fmt.Println("No transfers")
+		return
+	}
+
+	fmt.Println()
+	for _, t := range state.Transfers {
+		arrow := "→"
+		if t.Direction == bluez.TransferIncoming {
+			arrow = "←"
+		}
+		fmt.Printf("%s %s %s  %s\n  %s\n", arrow, t.DeviceName, t.Name, formatTransferProgress(t), t.ID)
+	}
+}
+
+func runBluetoothReceiveDir(cmd *cobra.Command, args []string) {
+	if len(args) == 0 {
+		state := decodeResult[bluez.ObexState](requestBluetooth("bluetooth.obex.getState", nil))
+		fmt.Println(state.ReceiveDir)
+		return
+	}
+	state := decodeResult[bluez.ObexState](requestBluetooth("bluetooth.obex.setReceiveDir", map[string]any{"dir": args[0]}))
+	fmt.Printf("Received files will be saved to %s\n", state.ReceiveDir)
+}
@@ -38,6 +38,45 @@ var brightnessSetCmd = &cobra.Command{
 	Run: runBrightnessSet,
 }
 
+var brightnessDDCCmd = &cobra.Command{
+	Use:   "ddc",
+	Short: "Control DDC/CI monitor features",
+	Long:  "Read and write DDC/CI features such as contrast, input source, volume and power on external monitors",
+}
+
+var brightnessDDCCapsCmd = &cobra.Command{
+	Use:   "caps <device_id>",
+	Short: "Show a monitor's DDC/CI capabilities",
+	Args:  cobra.ExactArgs(1),
+	Run:   runBrightnessDDCCaps,
+}
+
+var brightnessDDCGetCmd = &cobra.Command{
+	Use:   "get <device_id> [feature]",
+	Short: "Read DDC/CI features",
+	Long:  "Read one DDC/CI feature, or every supported feature when none is given. Features: " + strings.Join(brightness.DDCFeatureNames(), ", ") + ", or a hex VCP code",
+	Args:  cobra.RangeArgs(1, 2),
+	Run:   runBrightnessDDCGet,
+}
+
+var brightnessDDCSetCmd = &cobra.Command{
+	Use:   "set <device_id> <feature> <value>",
+	Short: "Write a DDC/CI feature",
+	Long:  "Write a DDC/CI feature. Values may be numbers or names such as hdmi1, 6500k, muted or standby",
+	Args:  cobra.ExactArgs(3),
+	Run:   runBrightnessDDCSet,
+}
+
+var brightnessDDCInputCmd = &cobra.Command{
+	Use:   "input <device_id> <input>",
+	Short: "Switch monitor input source",
+	Long:  "Switch monitor input source (e.g. dp1, dp2, hdmi1, hdmi2, usbc, or a numeric code)",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		runBrightnessDDCSet(cmd, []string{args[0], "input", args[1]})
+	},
+}
+
 var brightnessGetCmd = &cobra.Command{
 	Use:   "get <device_id>",
 	Short: "Get brightness for a device",
@@ -116,7 +155,8 @@ Global Flags:
 {{.InheritedFlags.FlagUsages | trimTrailingWhitespaces}}{{end}}
 `)
 
-	brightnessCmd.AddCommand(brightnessListCmd, brightnessSetCmd, brightnessGetCmd)
+	brightnessDDCCmd.AddCommand(brightnessDDCCapsCmd, brightnessDDCGetCmd, brightnessDDCSetCmd, brightnessDDCInputCmd)
+	brightnessCmd.AddCommand(brightnessListCmd, brightnessSetCmd, brightnessGetCmd, brightnessDDCCmd)
 }
 
 func getAllBrightnessDevices(includeDDC bool) []brightness.Device {
@@ -309,3 +349,97 @@ func runBrightnessGet(cmd *cobra.Command, args []string) {
 
 	log.Fatalf("Device not found: %s", deviceID)
 }
+
+func openDDCBackend() *brightness.DDCBackend {
+	ddc, err := brightness.NewDDCBackend()
+	if err != nil {
+		log.Fatalf("Failed to initialize DDC backend: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	return ddc
+}
+
+func runBrightnessDDCCaps(cmd *cobra.Command, args []string) {
+	ddc := openDDCBackend()
+	defer ddc.Close()
+
+	caps, err := ddc.Capabilities(args[0])
+	if err != nil {
+		log.Fatalf("Failed to read capabilities: %v", err)
+	}
+
+	if caps.Model != "" {
+		fmt.Printf("Model: %s\n", caps.Model)
+	}
+	if caps.Type != "" {
+		fmt.Printf("Type:  %s\n", caps.Type)
+	}
+	if caps.MCCSVersion != "" {
+		fmt.Printf("MCCS:  %s\n", caps.MCCSVersion)
+	}
+	fmt.Println("VCP features:")
+	for _, vcp := range caps.VCP {
+		name := ""
+		if feature, ok := brightness.DDCFeatureByCode(byte(vcp.Code)); ok {
+			name = feature.Name
+		}
+		fmt.Printf("  0x%02X %-14s", vcp.Code, name)
+		for _, v := range vcp.Values {
+			fmt.Printf(" %02X", v)
+		}
+		fmt.Println()
+	}
+}
+
+func runBrightnessDDCGet(cmd *cobra.Command, args []string) {
+	ddc := openDDCBackend()
+	defer ddc.Close()
+
+	var values []brightness.DDCFeatureValue
+	switch len(args) {
+	case 1:
+		all, err := ddc.GetFeatures(args[0])
+		if err != nil {
+			log.Fatalf("Failed to read features: %v", err)
+		}
+		values = all
+	default:
+		feature, err := brightness.LookupDDCFeature(args[1])
+		if err != nil {
+			log.Fatalf("Invalid feature: %v", err)
+		}
+		value, err := ddc.GetFeature(args[0], feature)
+		if err != nil {
+			log.Fatalf("Failed to read %s: %v", feature.Name, err)
+		}
+		values = []brightness.DDCFeatureValue{value}
+	}
+
+	for _, v := range values {
+		switch v.Value {
+		case "":
+			fmt.Printf("%-14s %d/%d\n", v.Feature, v.Current, v.Max)
+		default:
+			fmt.Printf("%-14s %s (0x%02X)\n", v.Feature, v.Value, v.Current)
+		}
+	}
+}
+
+func runBrightnessDDCSet(cmd *cobra.Command, args []string) {
+	feature, err := brightness.LookupDDCFeature(args[1])
+	if err != nil {
+		log.Fatalf("Invalid feature: %v", err)
+	}
+	value, err := feature.ParseValue(args[2])
+	if err != nil {
+		log.Fatalf("Invalid value: %v", err)
+	}
+
+	ddc := openDDCBackend()
+	defer ddc.Close()
+
+	if err := ddc.SetFeature(args[0], feature, value); err != nil {
+		log.Fatalf("Failed to set %s: %v", feature.Name, err)
+	}
+	fmt.Printf("Set %s %s to %s\n", args[0], feature.Name, args[2])
+}
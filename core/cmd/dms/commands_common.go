@@ -794,5 +794,7 @@ func getCommonCommands() []*cobra.Command {
 		trashCmd,
 		systemCmd,
 		switchUserCmd,
+		powerCmd,
+		bluetoothCmd,
 	}...)
 }
@@ -1,8 +1,14 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+
+	"github.com/godbus/dbus/v5"
 
 	"github.com/AvengeMedia/DankMaterialShell/core/internal/notify"
 	"github.com/AvengeMedia/DankMaterialShell/core/internal/server/models"
@@ -10,10 +16,18 @@ import (
 )
 
 var (
-	notifyAppName string
-	notifyIcon    string
-	notifyFile    string
-	notifyTimeout int
+	notifyAppName  string
+	notifyIcon     string
+	notifyFile     string
+	notifyTimeout  int
+	notifyActions  []string
+	notifyWait     bool
+	notifyReplace  uint32
+	notifyUrgency  string
+	notifyCategory string
+	notifyHints    []string
+	notifyImage    string
+	notifyPrintID  bool
 )
 
 var notifyCmd = &cobra.Command{
@@ -22,11 +36,21 @@ var notifyCmd = &cobra.Command{
 	Long: `Send a desktop notification with optional actions.
 
 If --file is provided, the notification will have "Open" and "Open Folder" actions.
+Custom actions are given as id=Label. With --wait, dms blocks until the
+notification is closed and prints the id of the invoked action, if any.
+
+Hints use the notify-send TYPE:NAME:VALUE form, where TYPE is int, double,
+string, byte or boolean. A timeout of 0 never expires; -1 uses the server default.
 
 Examples:
   dms notify "Hello" "World"
   dms notify "File received" "photo.jpg" --file ~/Downloads/photo.jpg --icon smartphone
-  dms notify "Download complete" --file ~/Downloads/file.zip --app "My App"`,
+  dms notify "Download complete" --file ~/Downloads/file.zip --app "My App"
+  dms notify "Build failed" --action retry=Retry --action logs="Show Logs" --wait
+  id=$(dms notify "Copying" --hint int:value:0 --print-id)
+  dms notify "Copying" --hint int:value:50 --replace-id "$id"
+  dms notify "Disk full" --urgency critical --category device.error --image ~/alert.png
+  dms notify close "$id"`,
 	Args: cobra.MinimumNArgs(1),
 	Run:  runNotify,
 }
@@ -39,27 +63,85 @@ var genericNotifyActionCmd = &cobra.Command{
 	},
 }
 
+var notifyCloseCmd = &cobra.Command{
+	Use:   "close <id>",
+	Short: "Close a notification by id",
+	Args:  cobra.ExactArgs(1),
+	Run:   runNotifyClose,
+}
+
 func init() {
 	notifyCmd.Flags().StringVar(&notifyAppName, "app", "DMS", "Application name")
 	notifyCmd.Flags().StringVar(&notifyIcon, "icon", "", "Icon name or path")
 	notifyCmd.Flags().StringVar(&notifyFile, "file", "", "File path (enables Open/Open Folder actions)")
-	notifyCmd.Flags().IntVar(&notifyTimeout, "timeout", 5000, "Timeout in milliseconds")
+	notifyCmd.Flags().IntVar(&notifyTimeout, "timeout", 5000, "Timeout in milliseconds (0 = never, -1 = server default)")
+	notifyCmd.Flags().StringArrayVar(&notifyActions, "action", nil, "Add an action as id=Label (repeatable)")
+	notifyCmd.Flags().BoolVar(&notifyWait, "wait", false, "Wait for the notification to close and print the invoked action")
+	notifyCmd.Flags().Uint32Var(&notifyReplace, "replace-id", 0, "Replace the notification with this id")
+	notifyCmd.Flags().StringVarP(&notifyUrgency, "urgency", "u", "", "Urgency: low, normal or critical")
+	notifyCmd.Flags().StringVarP(&notifyCategory, "category", "c", "", "Notification category (e.g. im.received)")
+	notifyCmd.Flags().StringArrayVar(&notifyHints, "hint", nil, "Add a hint as TYPE:NAME:VALUE (repeatable)")
+	notifyCmd.Flags().StringVar(&notifyImage, "image", "", "Image file sent as image-data")
+	notifyCmd.Flags().BoolVarP(&notifyPrintID, "print-id", "p", false, "Print the notification id")
+
+	notifyCmd.AddCommand(notifyCloseCmd)
 }
 
-func runNotify(cmd *cobra.Command, args []string) {
-	summary := args[0]
-	body := ""
+func buildNotification(args []string) (notify.Notification, error) {
+	n := notify.Notification{
+		AppName:   notifyAppName,
+		Icon:      notifyIcon,
+		Summary:   args[0],
+		FilePath:  notifyFile,
+		Timeout:   int32(notifyTimeout),
+		ReplaceID: notifyReplace,
+		Category:  notifyCategory,
+		ImagePath: notifyImage,
+	}
 	if len(args) > 1 {
-		body = args[1]
+		n.Body = args[1]
 	}
 
-	n := notify.Notification{
-		AppName:  notifyAppName,
-		Icon:     notifyIcon,
-		Summary:  summary,
-		Body:     body,
-		FilePath: notifyFile,
-		Timeout:  int32(notifyTimeout),
+	if notifyUrgency != "" {
+		urgency, err := notify.ParseUrgency(notifyUrgency)
+		if err != nil {
+			return n, err
+		}
+		n.Urgency = &urgency
+	}
+
+	for _, raw := range notifyActions {
+		action, err := notify.ParseAction(raw)
+		if err != nil {
+			return n, err
+		}
+		n.Actions = append(n.Actions, action)
+	}
+
+	if len(notifyHints) > 0 {
+		n.Hints = make(map[string]dbus.Variant, len(notifyHints))
+	}
+	for _, raw := range notifyHints {
+		name, value, err := notify.ParseHint(raw)
+		if err != nil {
+			return n, err
+		}
+		n.Hints[name] = value
+	}
+
+	return n, nil
+}
+
+func runNotify(cmd *cobra.Command, args []string) {
+	n, err := buildNotification(args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if notifyWait {
+		runNotifyWait(n)
+		return
 	}
 
 	id, err := notify.Send(n)
@@ -67,12 +149,49 @@ func runNotify(cmd *cobra.Command, args []string) {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
+	if notifyPrintID {
+		fmt.Println(id)
+	}
 	if n.FilePath == "" {
 		return
 	}
 	watchNotificationAction(id, n.FilePath)
 }
 
+func runNotifyWait(n notify.Notification) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	result, err := notify.SendAndWait(ctx, n, func(id uint32) {
+		if notifyPrintID {
+			fmt.Println(id)
+		}
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			os.Exit(130)
+		}
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if result.Action != "" {
+		fmt.Println(result.Action)
+	}
+}
+
+func runNotifyClose(cmd *cobra.Command, args []string) {
+	id, err := strconv.ParseUint(args[0], 10, 32)
+	if err != nil || id == 0 {
+		fmt.Fprintf(os.Stderr, "Error: invalid notification id %q\n", args[0])
+		os.Exit(1)
+	}
+	if err := notify.Close(uint32(id)); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
 func watchNotificationAction(id uint32, path string) {
 	if id == 0 {
 		return
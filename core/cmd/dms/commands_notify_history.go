@@ -0,0 +1,198 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/AvengeMedia/DankMaterialShell/core/internal/log"
+	"github.com/AvengeMedia/DankMaterialShell/core/internal/server/models"
+	"github.com/AvengeMedia/DankMaterialShell/core/internal/server/notifyactions"
+	"github.com/spf13/cobra"
+)
+
+var notifyHistoryCmd = &cobra.Command{
+	Use:   "history [query]",
+	Short: "Show notification history",
+	Long: `Show notifications recorded by the server, newest first (requires server).
+
+With a query, only notifications whose app name, summary and body contain
+every word are shown.
+
+Examples:
+  dms notify history
+  dms notify history --app firefox --unread
+  dms notify history "download failed"
+  dms notify history read
+  dms notify history clear --app discord`,
+	Args: cobra.ArbitraryArgs,
+	Run:  runNotifyHistory,
+}
+
+var notifyHistoryDeleteCmd = &cobra.Command{
+	Use:   "delete <id>",
+	Short: "Delete a notification history entry",
+	Args:  cobra.ExactArgs(1),
+	Run:   runNotifyHistoryDelete,
+}
+
+var notifyHistoryClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Clear notification history",
+	Long:  "Clear all notification history, or only one app's with --app (requires server)",
+	Args:  cobra.NoArgs,
+	Run:   runNotifyHistoryClear,
+}
+
+var notifyHistoryReadCmd = &cobra.Command{
+	Use:   "read [id...]",
+	Short: "Mark notifications as read",
+	Long:  "Mark the given history entries as read, or all of them when no ID is given (requires server)",
+	Run:   runNotifyHistoryRead,
+}
+
+func init() {
+	notifyHistoryCmd.Flags().String("app", "", "Only show notifications from this app")
+	notifyHistoryCmd.Flags().Bool("unread", false, "Only show unread notifications")
+	notifyHistoryCmd.Flags().IntP("limit", "l", 50, "Max results")
+	notifyHistoryCmd.Flags().IntP("offset", "o", 0, "Result offset")
+	notifyHistoryCmd.Flags().Bool("json", false, "Output in JSON format")
+	notifyHistoryClearCmd.Flags().String("app", "", "Only clear notifications from this app")
+	notifyHistoryCmd.AddCommand(notifyHistoryDeleteCmd, notifyHistoryClearCmd, notifyHistoryReadCmd)
+	notifyCmd.AddCommand(notifyHistoryCmd)
+}
+
+func requestNotifyHistory(method string, params map[string]any) any {
+	resp, err := sendServerRequest(models.Request{
+		ID:     1,
+		Method: method,
+		Params: params,
+	})
+	if err != nil {
+		log.Fatalf("Failed: %v (is dms server running?)", err)
+	}
+	if resp.Error != "" {
+		log.Fatalf("Error: %s", resp.Error)
+	}
+	if resp.Result == nil {
+		return nil
+	}
+	return *resp.Result
+}
+
+func printNotifyHistoryMessage(result any) {
+	data, _ := json.Marshal(result)
+	var success models.SuccessResult
+	if err := json.Unmarshal(data, &success); err == nil && success.Message != "" {
+		fmt.Println(success.Message)
+	}
+}
+
+func runNotifyHistory(cmd *cobra.Command, args []string) {
+	app, _ := cmd.Flags().GetString("app")
+	unread, _ := cmd.Flags().GetBool("unread")
+	limit, _ := cmd.Flags().GetInt("limit")
+	offset, _ := cmd.Flags().GetInt("offset")
+	jsonFlag, _ := cmd.Flags().GetBool("json")
+
+	method := "notify.history.list"
+	params := map[string]any{
+		"limit":  limit,
+		"offset": offset,
+		"unread": unread,
+	}
+	if app != "" {
+		params["appName"] = app
+	}
+	if len(args) > 0 {
+		method = "notify.history.search"
+		params["query"] = strings.Join(args, " ")
+	}
+
+	result := requestNotifyHistory(method, params)
+	if jsonFlag {
+		data, _ := json.MarshalIndent(result, "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		log.Fatalf("Invalid response format: %v", err)
+	}
+	var history notifyactions.HistoryResult
+	if err := json.Unmarshal(data, &history); err != nil {
+		log.Fatalf("Invalid response format: %v", err)
+	}
+
+	if len(history.Entries) == 0 {
+		fmt.Println("No notifications")
+		return
+	}
+
+	fmt.Printf("Notifications: %d of %d (%d unread)\n\n", len(history.Entries), history.Total, history.Unread)
+	for _, entry := range history.Entries {
+		fmt.Println(formatHistoryEntry(entry))
+	}
+
+	if history.HasMore {
+		fmt.Printf("Use --offset %d to see more results\n", offset+limit)
+	}
+}
+
+func formatHistoryEntry(entry notifyactions.HistoryEntry) string {
+	var b strings.Builder
+
+	flags := []string{entry.Timestamp.Local().Format("2006-01-02 15:04")}
+	if !entry.Read {
+		flags = append(flags, "unread")
+	}
+	if entry.Urgency == notifyactions.UrgencyCritical {
+		flags = append(flags, "critical")
+	}
+	if entry.InvokedAction != "" {
+		flags = append(flags, "action: "+entry.InvokedAction)
+	}
+	fmt.Fprintf(&b, "ID: %d | %s | %s\n", entry.ID, entry.AppName, strings.Join(flags, " | "))
+	fmt.Fprintf(&b, "  %s\n", entry.Summary)
+	if body := strings.TrimSpace(entry.Body); body != "" {
+		fmt.Fprintf(&b, "  %s\n", strings.ReplaceAll(body, "\n", "\n  "))
+	}
+	return b.String()
+}
+
+func parseHistoryID(arg string) uint64 {
+	id, err := strconv.ParseUint(arg, 10, 64)
+	if err != nil || id == 0 {
+		log.Fatalf("Invalid ID: %s", arg)
+	}
+	return id
+}
+
+func runNotifyHistoryDelete(cmd *cobra.Command, args []string) {
+	id := parseHistoryID(args[0])
+	requestNotifyHistory("notify.history.delete", map[string]any{"id": id})
+	fmt.Printf("Deleted entry %d\n", id)
+}
+
+func runNotifyHistoryClear(cmd *cobra.Command, args []string) {
+	app, _ := cmd.Flags().GetString("app")
+	if app != "" {
+		printNotifyHistoryMessage(requestNotifyHistory("notify.history.clearApp", map[string]any{"appName": app}))
+		return
+	}
+	printNotifyHistoryMessage(requestNotifyHistory("notify.history.clear", nil))
+}
+
+func runNotifyHistoryRead(cmd *cobra.Command, args []string) {
+	var params map[string]any
+	if len(args) > 0 {
+		ids := make([]uint64, 0, len(args))
+		for _, arg := range args {
+			ids = append(ids, parseHistoryID(arg))
+		}
+		params = map[string]any{"ids": ids}
+	}
+	printNotifyHistoryMessage(requestNotifyHistory("notify.history.markRead", params))
+}
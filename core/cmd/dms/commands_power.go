@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/AvengeMedia/DankMaterialShell/core/internal/log"
+	"github.com/AvengeMedia/DankMaterialShell/core/internal/server/loginctl"
+	"github.com/AvengeMedia/DankMaterialShell/core/internal/server/models"
+	"github.com/spf13/cobra"
+)
+
+var powerCmd = &cobra.Command{
+	Use:   "power",
+	Short: "Session power actions",
+	Long:  "Schedule and manage session power actions (requires running server)",
+}
+
+var powerScheduleCmd = &cobra.Command{
+	Use:   "schedule [action when | cancel]",
+	Short: "Schedule a power action",
+	Long: `Schedule poweroff, reboot, suspend, hibernate, logout or lock.
+
+"when" is either a delay such as 45m or 1h30m, or a clock time such as 01:00
+(the next occurrence). Warning notifications are shown before the action
+runs. With no arguments, prints the pending schedule; "cancel" removes it.
+Schedules survive server restarts.
+
+Examples:
+  dms power schedule poweroff 45m
+  dms power schedule suspend 01:00
+  dms power schedule cancel`,
+	Args: cobra.RangeArgs(0, 2),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) != 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return []string{"poweroff", "reboot", "suspend", "hibernate", "logout", "lock", "cancel"}, cobra.ShellCompDirectiveNoFileComp
+	},
+	Run: runPowerSchedule,
+}
+
+func init() {
+	powerScheduleCmd.Flags().StringSlice("warn", nil, "Warn this long before the action (e.g. 10m,1m; default 10m,5m,1m)")
+	powerScheduleCmd.Flags().Bool("json", false, "Output in JSON format")
+	powerCmd.AddCommand(powerScheduleCmd)
+}
+
+func runPowerSchedule(cmd *cobra.Command, args []string) {
+	jsonFlag, _ := cmd.Flags().GetBool("json")
+
+	switch {
+	case len(args) == 0:
+		printPowerSchedule(requestPower("loginctl.schedule.get", nil), jsonFlag)
+	case len(args) == 1 && args[0] == "cancel":
+		requestPower("loginctl.schedule.cancel", nil)
+		fmt.Println("Schedule cancelled")
+	case len(args) == 2:
+		at, err := parseScheduleTime(args[1], time.Now())
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		params := map[string]any{
+			"action": args[0],
+			"at":     at.Format(time.RFC3339),
+		}
+		if warn, _ := cmd.Flags().GetStringSlice("warn"); len(warn) > 0 {
+			warnings := make([]int, 0, len(warn))
+			for _, w := range warn {
+				d, err := time.ParseDuration(w)
+				if err != nil {
+					log.Fatalf("Invalid --warn value %q: %v", w, err)
+				}
+				warnings = append(warnings, int(d.Seconds()))
+			}
+			params["warnings"] = warnings
+		}
+		printPowerSchedule(requestPower("loginctl.schedule.set", params), jsonFlag)
+	default:
+		log.Fatalf("Usage: dms power schedule <action> <when> | cancel")
+	}
+}
+
+// parseScheduleTime accepts a Go duration or a HH:MM clock time, which
+// resolves to its next occurrence.
+func parseScheduleTime(when string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(when); err == nil {
+		if d <= 0 {
+			return time.Time{}, fmt.Errorf("delay must be positive")
+		}
+		return now.Add(d), nil
+	}
+
+	clock, err := time.ParseInLocation("15:04", when, now.Location())
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q: use a delay like 45m or a clock time like 01:00", when)
+	}
+	at := time.Date(now.Year(), now.Month(), now.Day(), clock.Hour(), clock.Minute(), 0, 0, now.Location())
+	if !at.After(now) {
+		at = at.AddDate(0, 0, 1)
+	}
+	return at, nil
+}
+
+func requestPower(method string, params map[string]any) any {
+	resp, err := sendServerRequest(models.Request{
+		ID:     1,
+		Method: method,
+		Params: params,
+	})
+	if err != nil {
+		log.Fatalf("Failed: %v (is dms server running?)", err)
+	}
+	if resp.Error != "" {
+		log.Fatalf("Error: %s", resp.Error)
+	}
+	if resp.Result == nil {
+		return nil
+	}
+	return *resp.Result
+}
+
+func printPowerSchedule(result any, jsonOutput bool) {
+	if jsonOutput {
+		data, _ := json.MarshalIndent(result, "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+	if result == nil {
+		fmt.Println("Nothing scheduled")
+		return
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		log.Fatalf("Invalid response format: %v", err)
+	}
+	var schedule loginctl.PowerSchedule
+	if err := json.Unmarshal(data, &schedule); err != nil {
+		log.Fatalf("Invalid response format: %v", err)
+	}
+
+	remaining := time.Until(schedule.At).Round(time.Second)
+	fmt.Printf("%s at %s (in %s)\n", schedule.Action, schedule.At.Local().Format("Mon 15:04"), remaining)
+	if len(schedule.Warnings) > 0 {
+		warnings := make([]string, 0, len(schedule.Warnings))
+		for _, w := range schedule.Warnings {
+			warnings = append(warnings, (time.Duration(w) * time.Second).String())
+		}
+		fmt.Printf("Warnings: %s before\n", strings.Join(warnings, ", "))
+	}
+}
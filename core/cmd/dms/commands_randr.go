@@ -3,8 +3,11 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/AvengeMedia/DankMaterialShell/core/internal/log"
+	"github.com/AvengeMedia/DankMaterialShell/core/internal/server/models"
+	"github.com/AvengeMedia/DankMaterialShell/core/internal/server/wlroutput"
 	"github.com/spf13/cobra"
 )
 
@@ -15,8 +18,57 @@ var randrCmd = &cobra.Command{
 	Run:   runRandr,
 }
 
+var randrProfileCmd = &cobra.Command{
+	Use:   "profile",
+	Short: "Manage display profiles",
+	Long:  "Save, list, delete and apply display profiles. Saved profiles are applied automatically when the connected outputs match (requires running server).",
+}
+
+var randrProfileListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List saved display profiles",
+	Args:  cobra.NoArgs,
+	Run:   runRandrProfileList,
+}
+
+var randrProfileSaveCmd = &cobra.Command{
+	Use:   "save <name>",
+	Short: "Save the current output layout as a profile",
+	Args:  cobra.ExactArgs(1),
+	Run:   runRandrProfileSave,
+}
+
+var randrProfileDeleteCmd = &cobra.Command{
+	Use:               "delete <name>",
+	Short:             "Delete a display profile",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeRandrProfiles,
+	Run:               runRandrProfileDelete,
+}
+
+var randrProfileApplyCmd = &cobra.Command{
+	Use:               "apply <name>",
+	Short:             "Apply a display profile",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeRandrProfiles,
+	Run:               runRandrProfileApply,
+}
+
+var randrSaveCmd = &cobra.Command{
+	Use:   "save",
+	Short: "Persist the current output layout to the compositor config",
+	Long:  "Write the current output layout into the compositor's DMS outputs fragment (niri dms/outputs.kdl, Hyprland dms/outputs.lua or mango dms/outputs.conf) so it survives restarts. Blocks for other outputs and unrelated settings are preserved (requires running server).",
+	Args:  cobra.NoArgs,
+	Run:   runRandrSave,
+}
+
 func init() {
 	randrCmd.Flags().Bool("json", false, "Output in JSON format")
+	randrProfileListCmd.Flags().Bool("json", false, "Output in JSON format")
+	randrSaveCmd.Flags().String("compositor", "", "Target compositor: niri, hyprland or mango (default: detected)")
+
+	randrProfileCmd.AddCommand(randrProfileListCmd, randrProfileSaveCmd, randrProfileDeleteCmd, randrProfileApplyCmd)
+	randrCmd.AddCommand(randrProfileCmd, randrSaveCmd)
 }
 
 type randrJSON struct {
@@ -54,5 +106,150 @@ func runRandr(cmd *cobra.Command, args []string) {
 		if out.Refresh > 0 {
 			fmt.Printf("  Refresh:    %.2f Hz\n", float64(out.Refresh)/1000.0)
 		}
+		if out.EDID != nil {
+			fmt.Printf("  Panel:      %s %s (%dx%d mm)\n", out.EDID.Manufacturer, out.EDID.Name, out.EDID.PhysicalWidthMM, out.EDID.PhysicalHeightMM)
+			if out.EDID.VRR != nil {
+				fmt.Printf("  VRR range:  %d-%d Hz\n", out.EDID.VRR.MinHz, out.EDID.VRR.MaxHz)
+			}
+			if out.EDID.HDR != nil {
+				fmt.Printf("  HDR:        %s\n", strings.Join(out.EDID.HDR.EOTFs, ", "))
+			}
+			if out.SuggestedScale > 0 && out.SuggestedScale != out.Scale {
+				fmt.Printf("  Suggested:  scale %.4g\n", out.SuggestedScale)
+			}
+		}
+	}
+}
+
+func requestRandr(method string, params map[string]any) any {
+	resp, err := sendServerRequest(models.Request{
+		ID:     1,
+		Method: method,
+		Params: params,
+	})
+	if err != nil {
+		log.Fatalf("Failed: %v (is dms server running?)", err)
+	}
+	if resp.Error != "" {
+		log.Fatalf("Error: %s", resp.Error)
+	}
+	if resp.Result == nil {
+		return nil
+	}
+	return *resp.Result
+}
+
+func fetchRandrProfiles() []wlroutput.Profile {
+	result := requestRandr("wlroutput.profiles.list", nil)
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		log.Fatalf("Invalid response format: %v", err)
+	}
+
+	var profiles []wlroutput.Profile
+	if err := json.Unmarshal(data, &profiles); err != nil {
+		log.Fatalf("Invalid response format: %v", err)
+	}
+	return profiles
+}
+
+func completeRandrProfiles(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) != 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	resp, ok := tryServerRequest(models.Request{ID: 1, Method: "wlroutput.profiles.list"})
+	if !ok || resp.Error != "" || resp.Result == nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	list, _ := (*resp.Result).([]any)
+	names := make([]string, 0, len(list))
+	for _, item := range list {
+		if entry, ok := item.(map[string]any); ok {
+			if name, ok := entry["name"].(string); ok {
+				names = append(names, name)
+			}
+		}
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+func describeHeadMatch(m wlroutput.HeadMatch) string {
+	switch {
+	case m.Make != "" || m.Model != "" || m.Serial != "":
+		desc := fmt.Sprintf("%s %s", m.Make, m.Model)
+		if m.Serial != "" {
+			desc += " (" + m.Serial + ")"
+		}
+		if m.Name != "" {
+			desc += " on " + m.Name
+		}
+		return desc
+	default:
+		return m.Name
+	}
+}
+
+func runRandrProfileList(cmd *cobra.Command, args []string) {
+	profiles := fetchRandrProfiles()
+
+	if jsonFlag, _ := cmd.Flags().GetBool("json"); jsonFlag {
+		data, _ := json.MarshalIndent(profiles, "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+
+	if len(profiles) == 0 {
+		fmt.Println("No display profiles saved")
+		return
+	}
+
+	for i, profile := range profiles {
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Println(profile.Name)
+		for _, head := range profile.Heads {
+			if !head.Enabled {
+				fmt.Printf("  %s: disabled\n", describeHeadMatch(head.Match))
+				continue
+			}
+			mode := "auto"
+			if head.Mode != nil {
+				mode = fmt.Sprintf("%dx%d@%.2f", head.Mode.Width, head.Mode.Height, float64(head.Mode.Refresh)/1000.0)
+			}
+			fmt.Printf("  %s: %s at %d,%d scale %.4g transform %d\n",
+				describeHeadMatch(head.Match), mode, head.X, head.Y, head.Scale, head.Transform)
+		}
+	}
+}
+
+func runRandrProfileSave(cmd *cobra.Command, args []string) {
+	requestRandr("wlroutput.profiles.save", map[string]any{"name": args[0]})
+	fmt.Printf("Saved display profile %q\n", args[0])
+}
+
+func runRandrProfileDelete(cmd *cobra.Command, args []string) {
+	requestRandr("wlroutput.profiles.delete", map[string]any{"name": args[0]})
+	fmt.Printf("Deleted display profile %q\n", args[0])
+}
+
+func runRandrProfileApply(cmd *cobra.Command, args []string) {
+	requestRandr("wlroutput.profiles.apply", map[string]any{"name": args[0]})
+	fmt.Printf("Applied display profile %q\n", args[0])
+}
+
+func runRandrSave(cmd *cobra.Command, args []string) {
+	params := map[string]any{}
+	if compositor, _ := cmd.Flags().GetString("compositor"); compositor != "" {
+		params["compositor"] = compositor
+	}
+
+	result := requestRandr("wlroutput.persist", params)
+	data, _ := json.Marshal(result)
+	var success models.SuccessResult
+	if err := json.Unmarshal(data, &success); err != nil {
+		log.Fatalf("Invalid response format: %v", err)
 	}
+	fmt.Printf("Saved output layout to %s\n", success.Message)
 }
@@ -99,6 +99,26 @@ var setupWindowrulesCmd = &cobra.Command{
 	},
 }
 
+var setupPortalCmd = &cobra.Command{
+	Use:   "portal",
+	Short: "Register DMS as an xdg-desktop-portal backend",
+	Long: `Install the dms.portal file and prefer DMS in portals.conf so GTK4,
+libadwaita and Flatpak apps read the color scheme and accent color from DMS,
+and screenshot and color picker requests use DMS's overlays.
+Restart xdg-desktop-portal (or log out) afterwards.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		results, err := config.NewConfigDeployer(nil).DeployPortalConfig()
+		for _, result := range results {
+			if result.Deployed {
+				fmt.Printf("Deployed %s to %s\n", result.ConfigType, result.Path)
+			}
+		}
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+	},
+}
+
 type dmsConfigSpec struct {
 	niriFile     string
 	hyprFile     string
@@ -374,6 +394,14 @@ func runSetup() error {
 		}
 	}
 
+	if err == nil && wmSelected {
+		portalResults, portalErr := deployer.DeployPortalConfig()
+		results = append(results, portalResults...)
+		if portalErr != nil {
+			fmt.Printf("  Warning: failed to register the DMS portal backend: %v\n", portalErr)
+		}
+	}
+
 	close(logChan)
 
 	if err != nil {
@@ -4,20 +4,26 @@ import (
 	"fmt"
 
 	"github.com/AvengeMedia/DankMaterialShell/core/internal/proto/wlr_output_management"
+	"github.com/AvengeMedia/DankMaterialShell/core/internal/server/wlroutput"
 	wlclient "github.com/AvengeMedia/dankgo/wayland/client"
 )
 
 type randrOutput struct {
-	Name    string  `json:"name"`
-	Scale   float64 `json:"scale"`
-	Width   int32   `json:"width"`
-	Height  int32   `json:"height"`
-	Refresh int32   `json:"refresh"`
-	Enabled bool    `json:"enabled"`
+	Name           string          `json:"name"`
+	Scale          float64         `json:"scale"`
+	Width          int32           `json:"width"`
+	Height         int32           `json:"height"`
+	Refresh        int32           `json:"refresh"`
+	Enabled        bool            `json:"enabled"`
+	DRMConnector   string          `json:"drmConnector,omitempty"`
+	EDID           *wlroutput.EDID `json:"edid,omitempty"`
+	SuggestedScale float64         `json:"suggestedScale,omitempty"`
 }
 
 type randrHead struct {
 	name          string
+	model         string
+	serial        string
 	enabled       bool
 	scale         float64
 	currentModeID uint32
@@ -117,6 +123,14 @@ func (c *randrClient) handleHead(e wlr_output_management.ZwlrOutputManagerV1Head
 		head.name = e.Name
 	})
 
+	handle.SetModelHandler(func(e wlr_output_management.ZwlrOutputHeadV1ModelEvent) {
+		head.model = e.Model
+	})
+
+	handle.SetSerialNumberHandler(func(e wlr_output_management.ZwlrOutputHeadV1SerialNumberEvent) {
+		head.serial = e.SerialNumber
+	})
+
 	handle.SetEnabledHandler(func(e wlr_output_management.ZwlrOutputHeadV1EnabledEvent) {
 		head.enabled = e.Enabled != 0
 	})
@@ -165,6 +179,9 @@ func (c *randrClient) buildOutputs() []randrOutput {
 			out.Refresh = mode.refresh
 		}
 
+		out.DRMConnector, out.EDID = wlroutput.LookupEDID(head.name, head.model, head.serial)
+		out.SuggestedScale = out.EDID.SuggestedScale()
+
 		outputs = append(outputs, out)
 	}
 
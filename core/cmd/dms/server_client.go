@@ -54,6 +54,42 @@ func sendServerRequest(req models.Request) (*models.Response[any], error) {
 	return &resp, nil
 }
 
+// streamServerRequest sends req and passes each response to onResponse until
+// it returns false or the server closes the connection.
+func streamServerRequest(req models.Request, onResponse func(*models.Response[any]) bool) error {
+	socketPath := getServerSocketPath()
+
+	conn, err := net.Dial("unix", socketPath)
+	if err != nil {
+		return fmt.Errorf("failed to connect to server (is it running?): %w", err)
+	}
+	defer conn.Close()
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, bufio.MaxScanTokenSize), maxIPCMessageSize)
+	scanner.Scan() // discard initial capabilities message
+
+	reqData, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	if _, err := conn.Write(append(reqData, '\n')); err != nil {
+		return fmt.Errorf("failed to write request: %w", err)
+	}
+
+	for scanner.Scan() {
+		var resp models.Response[any]
+		if err := json.Unmarshal(scanner.Bytes(), &resp); err != nil {
+			return fmt.Errorf("failed to unmarshal response: %w", err)
+		}
+		if !onResponse(&resp) {
+			return nil
+		}
+	}
+	return scanner.Err()
+}
+
 // sendServerRequestFireAndForget sends a request without waiting for a response.
 // Useful for commands that trigger UI or async operations.
 func sendServerRequestFireAndForget(req models.Request) error {
@@ -0,0 +1,410 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	OutputsCompositorNiri     = "niri"
+	OutputsCompositorHyprland = "hyprland"
+	OutputsCompositorMango    = "mango"
+)
+
+// OutputLayout is a compositor-agnostic description of a single head.
+// Refresh is in mHz, transform uses the wl_output enum (0-7).
+type OutputLayout struct {
+	Name      string
+	Enabled   bool
+	Width     int32
+	Height    int32
+	Refresh   int32
+	X         int32
+	Y         int32
+	Scale     float64
+	Transform int32
+	VRR       bool
+}
+
+var niriTransforms = []string{"normal", "90", "180", "270", "flipped", "flipped-90", "flipped-180", "flipped-270"}
+
+var (
+	niriOutputBlockRegex = regexp.MustCompile(`(?m)^[ \t]*output\s+"([^"]+)"\s*\{[^{}]*(?:\{[^{}]*\}[^{}]*)*\}[ \t]*\n?`)
+	hyprMonitorLineRegex = regexp.MustCompile(`(?m)^[ \t]*hl\.monitor\(\{(.*)\}\)[ \t]*$`)
+	hyprLuaFieldRegex    = regexp.MustCompile(`(\w+)\s*=\s*("(?:[^"\\]|\\.)*"|[^,]+)`)
+	mangoMonitorRegex    = regexp.MustCompile(`(?m)^[ \t]*monitorrule\s*=(.*)$`)
+)
+
+// DetectOutputsCompositor returns the running compositor whose output
+// fragments DMS manages, or "" when none is detected.
+func DetectOutputsCompositor() string {
+	switch {
+	case os.Getenv("MANGO_INSTANCE_SIGNATURE") != "":
+		return OutputsCompositorMango
+	case os.Getenv("NIRI_SOCKET") != "":
+		return OutputsCompositorNiri
+	case os.Getenv("HYPRLAND_INSTANCE_SIGNATURE") != "":
+		return OutputsCompositorHyprland
+	}
+	return ""
+}
+
+func OutputsConfigPath(compositor string) (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	switch compositor {
+	case OutputsCompositorNiri:
+		return filepath.Join(configDir, "niri", "dms", "outputs.kdl"), nil
+	case OutputsCompositorHyprland:
+		return filepath.Join(configDir, "hypr", "dms", "outputs.lua"), nil
+	case OutputsCompositorMango:
+		return filepath.Join(configDir, "mango", "dms", "outputs.conf"), nil
+	default:
+		return "", fmt.Errorf("unsupported compositor: %s", compositor)
+	}
+}
+
+// WriteOutputsConfig merges layouts into the compositor's dms/outputs
+// fragment and returns the path written.
+func WriteOutputsConfig(compositor string, layouts []OutputLayout) (string, error) {
+	path, err := OutputsConfigPath(compositor)
+	if err != nil {
+		return "", err
+	}
+
+	existing, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	var merged string
+	switch compositor {
+	case OutputsCompositorNiri:
+		merged = MergeNiriOutputs(string(existing), layouts)
+	case OutputsCompositorHyprland:
+		merged = MergeHyprlandOutputs(string(existing), layouts)
+	case OutputsCompositorMango:
+		merged = MergeMangoOutputs(string(existing), layouts)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, []byte(merged), 0o644); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return path, nil
+}
+
+func formatOutputMode(l OutputLayout) string {
+	return fmt.Sprintf("%dx%d@%.3f", l.Width, l.Height, float64(l.Refresh)/1000)
+}
+
+func formatOutputScale(scale float64) string {
+	if scale <= 0 {
+		scale = 1
+	}
+	return strconv.FormatFloat(scale, 'f', -1, 64)
+}
+
+// MergeNiriOutputs rewrites the output blocks for the given heads in place.
+// Unmanaged directives inside a block (hot-corners, layout, backdrop-color,
+// ...) and blocks for other outputs are kept as-is.
+func MergeNiriOutputs(existing string, layouts []OutputLayout) string {
+	byName := make(map[string]OutputLayout, len(layouts))
+	for _, l := range layouts {
+		byName[l.Name] = l
+	}
+
+	written := make(map[string]bool, len(layouts))
+	merged := niriOutputBlockRegex.ReplaceAllStringFunc(existing, func(block string) string {
+		name := niriOutputBlockRegex.FindStringSubmatch(block)[1]
+		l, ok := byName[name]
+		switch {
+		case !ok:
+			return block
+		case written[name]:
+			return ""
+		}
+		written[name] = true
+		return renderNiriOutput(l, niriBlockBody(block))
+	})
+
+	var b strings.Builder
+	b.WriteString(merged)
+	for _, l := range layouts {
+		if written[l.Name] {
+			continue
+		}
+		if b.Len() > 0 && !strings.HasSuffix(b.String(), "\n\n") {
+			if !strings.HasSuffix(b.String(), "\n") {
+				b.WriteByte('\n')
+			}
+			b.WriteByte('\n')
+		}
+		b.WriteString(renderNiriOutput(l, nil))
+	}
+	return b.String()
+}
+
+func niriBlockBody(block string) []string {
+	open := strings.Index(block, "{")
+	end := strings.LastIndex(block, "}")
+	if open < 0 || end <= open {
+		return nil
+	}
+	return strings.Split(strings.Trim(block[open+1:end], "\n"), "\n")
+}
+
+func renderNiriOutput(l OutputLayout, body []string) string {
+	managed := map[string]bool{"off": true, "on": true, "mode": true, "scale": true, "transform": true, "position": true, "variable-refresh-rate": true}
+
+	var vrrLine string
+	var kept []string
+	depth := 0
+	for _, line := range body {
+		trimmed := strings.TrimSpace(line)
+		if depth == 0 && trimmed != "" {
+			key, _, _ := strings.Cut(trimmed, " ")
+			if managed[key] {
+				if key == "variable-refresh-rate" {
+					vrrLine = line
+				}
+				continue
+			}
+		}
+		depth += strings.Count(line, "{") - strings.Count(line, "}")
+		if trimmed != "" || len(kept) > 0 {
+			kept = append(kept, line)
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "output %s {\n", strconv.Quote(l.Name))
+	if !l.Enabled {
+		b.WriteString("    off\n")
+	}
+	if l.Width > 0 && l.Height > 0 {
+		fmt.Fprintf(&b, "    mode %s\n", strconv.Quote(formatOutputMode(l)))
+	}
+	fmt.Fprintf(&b, "    scale %s\n", formatOutputScale(l.Scale))
+	if l.Transform > 0 && int(l.Transform) < len(niriTransforms) {
+		fmt.Fprintf(&b, "    transform %s\n", strconv.Quote(niriTransforms[l.Transform]))
+	}
+	fmt.Fprintf(&b, "    position x=%d y=%d\n", l.X, l.Y)
+	if l.VRR {
+		if vrrLine != "" {
+			b.WriteString(vrrLine + "\n")
+		} else {
+			b.WriteString("    variable-refresh-rate\n")
+		}
+	}
+	for _, line := range kept {
+		b.WriteString(line + "\n")
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// MergeHyprlandOutputs replaces the hl.monitor() rules for the given heads,
+// keeping extra fields such as bitdepth or cm. New rules are inserted ahead
+// of the catch-all `output = ""` rule.
+func MergeHyprlandOutputs(existing string, layouts []OutputLayout) string {
+	byName := make(map[string]OutputLayout, len(layouts))
+	for _, l := range layouts {
+		byName[l.Name] = l
+	}
+
+	written := make(map[string]bool, len(layouts))
+	dropped := "\x00dms-dropped\x00"
+	merged := hyprMonitorLineRegex.ReplaceAllStringFunc(existing, func(line string) string {
+		fields := parseLuaFields(hyprMonitorLineRegex.FindStringSubmatch(line)[1])
+		name, err := strconv.Unquote(luaField(fields, "output"))
+		if err != nil {
+			return line
+		}
+		l, ok := byName[name]
+		switch {
+		case !ok:
+			return line
+		case written[name]:
+			return dropped
+		}
+		written[name] = true
+		return renderHyprlandMonitor(l, fields)
+	})
+	merged = strings.ReplaceAll(merged, dropped+"\n", "")
+	merged = strings.ReplaceAll(merged, dropped, "")
+
+	var added []string
+	for _, l := range layouts {
+		if !written[l.Name] {
+			added = append(added, renderHyprlandMonitor(l, nil))
+		}
+	}
+	if len(added) == 0 {
+		return merged
+	}
+
+	insert := strings.Join(added, "\n") + "\n"
+	for _, loc := range hyprMonitorLineRegex.FindAllStringSubmatchIndex(merged, -1) {
+		fields := parseLuaFields(merged[loc[2]:loc[3]])
+		if luaField(fields, "output") != `""` {
+			continue
+		}
+		return merged[:loc[0]] + insert + merged[loc[0]:]
+	}
+
+	if merged != "" && !strings.HasSuffix(merged, "\n") {
+		merged += "\n"
+	}
+	return merged + insert
+}
+
+type luaFieldPair struct {
+	key   string
+	value string
+}
+
+func parseLuaFields(s string) []luaFieldPair {
+	var fields []luaFieldPair
+	for _, m := range hyprLuaFieldRegex.FindAllStringSubmatch(s, -1) {
+		fields = append(fields, luaFieldPair{key: m[1], value: strings.TrimSpace(m[2])})
+	}
+	return fields
+}
+
+func luaField(fields []luaFieldPair, key string) string {
+	for _, f := range fields {
+		if f.key == key {
+			return f.value
+		}
+	}
+	return ""
+}
+
+func renderHyprlandMonitor(l OutputLayout, existing []luaFieldPair) string {
+	if !l.Enabled {
+		return fmt.Sprintf("hl.monitor({ output = %s, disabled = true })", strconv.Quote(l.Name))
+	}
+
+	mode := "preferred"
+	if l.Width > 0 && l.Height > 0 {
+		mode = formatOutputMode(l)
+	}
+	parts := []string{
+		fmt.Sprintf("output = %s", strconv.Quote(l.Name)),
+		fmt.Sprintf("mode = %s", strconv.Quote(mode)),
+		fmt.Sprintf("position = %s", strconv.Quote(fmt.Sprintf("%dx%d", l.X, l.Y))),
+		fmt.Sprintf("scale = %s", formatOutputScale(l.Scale)),
+	}
+	if l.Transform != 0 {
+		parts = append(parts, fmt.Sprintf("transform = %d", l.Transform))
+	}
+
+	vrr := luaField(existing, "vrr")
+	switch {
+	case !l.VRR:
+		vrr = "0"
+	case vrr == "" || vrr == "0":
+		vrr = "1"
+	}
+	parts = append(parts, "vrr = "+vrr)
+
+	managed := map[string]bool{"output": true, "mode": true, "position": true, "scale": true, "transform": true, "vrr": true, "disabled": true}
+	for _, f := range existing {
+		if !managed[f.key] {
+			parts = append(parts, f.key+" = "+f.value)
+		}
+	}
+	return "hl.monitor({ " + strings.Join(parts, ", ") + " })"
+}
+
+// MergeMangoOutputs replaces the monitorrule lines for the given heads,
+// keeping unrelated key:value pairs. Disabled heads have their rule removed
+// since mango has no per-rule disable.
+func MergeMangoOutputs(existing string, layouts []OutputLayout) string {
+	byName := make(map[string]OutputLayout, len(layouts))
+	for _, l := range layouts {
+		byName[l.Name] = l
+	}
+
+	written := make(map[string]bool, len(layouts))
+	dropped := "\x00dms-dropped\x00"
+	merged := mangoMonitorRegex.ReplaceAllStringFunc(existing, func(line string) string {
+		pairs := strings.Split(mangoMonitorRegex.FindStringSubmatch(line)[1], ",")
+		name := mangoRuleName(pairs)
+		l, ok := byName[name]
+		switch {
+		case !ok:
+			return line
+		case written[name], !l.Enabled:
+			written[name] = true
+			return dropped
+		}
+		written[name] = true
+		return renderMangoMonitorRule(l, pairs)
+	})
+	merged = strings.ReplaceAll(merged, dropped+"\n", "")
+	merged = strings.ReplaceAll(merged, dropped, "")
+
+	var b strings.Builder
+	b.WriteString(merged)
+	for _, l := range layouts {
+		if written[l.Name] || !l.Enabled {
+			continue
+		}
+		if b.Len() > 0 && !strings.HasSuffix(b.String(), "\n") {
+			b.WriteByte('\n')
+		}
+		b.WriteString(renderMangoMonitorRule(l, nil) + "\n")
+	}
+	return b.String()
+}
+
+func mangoRuleName(pairs []string) string {
+	for _, pair := range pairs {
+		key, value, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok || key != "name" {
+			continue
+		}
+		return strings.TrimSuffix(strings.TrimPrefix(value, "^"), "$")
+	}
+	return ""
+}
+
+func renderMangoMonitorRule(l OutputLayout, existing []string) string {
+	vrr := 0
+	if l.VRR {
+		vrr = 1
+	}
+	// Anchor the name: mango matches name: as an unanchored regex, so a
+	// bare "DP-1" would also match "eDP-1".
+	parts := []string{
+		"name:^" + l.Name + "$",
+		fmt.Sprintf("width:%d", l.Width),
+		fmt.Sprintf("height:%d", l.Height),
+		fmt.Sprintf("refresh:%d", (l.Refresh+500)/1000),
+		fmt.Sprintf("x:%d", l.X),
+		fmt.Sprintf("y:%d", l.Y),
+		"scale:" + formatOutputScale(l.Scale),
+		fmt.Sprintf("rr:%d", l.Transform),
+		fmt.Sprintf("vrr:%d", vrr),
+	}
+
+	managed := map[string]bool{"name": true, "width": true, "height": true, "refresh": true, "x": true, "y": true, "scale": true, "rr": true, "vrr": true}
+	for _, pair := range existing {
+		pair = strings.TrimSpace(pair)
+		key, _, _ := strings.Cut(pair, ":")
+		if pair != "" && !managed[key] {
+			parts = append(parts, pair)
+		}
+	}
+	return "monitorrule=" + strings.Join(parts, ",")
+}
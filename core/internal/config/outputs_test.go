@@ -0,0 +1,180 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testLayouts() []OutputLayout {
+	return []OutputLayout{
+		{Name: "eDP-1", Enabled: true, Width: 2256, Height: 1504, Refresh: 59999, Scale: 1.5},
+		{Name: "DP-3", Enabled: true, Width: 3840, Height: 2160, Refresh: 60000, X: 1504, Scale: 1, Transform: 1, VRR: true},
+	}
+}
+
+func TestMergeNiriOutputs_Empty(t *testing.T) {
+	got := MergeNiriOutputs("", testLayouts())
+
+	assert.Equal(t, `output "eDP-1" {
+    mode "2256x1504@59.999"
+    scale 1.5
+    position x=0 y=0
+}
+
+output "DP-3" {
+    mode "3840x2160@60.000"
+    scale 1
+    transform "90"
+    position x=1504 y=0
+    variable-refresh-rate
+}
+`, got)
+}
+
+func TestMergeNiriOutputs_PreservesUserContent(t *testing.T) {
+	existing := `// my outputs
+output "eDP-1" {
+    mode "1920x1080@60.000"
+    scale 2
+    focus-at-startup
+    hot-corners {
+        off
+    }
+}
+
+/-output "eDP-1" {
+    scale 3
+}
+
+output "HDMI-A-1" {
+    off
+}
+`
+	layouts := testLayouts()
+	layouts[0].Enabled = false
+	got := MergeNiriOutputs(existing, layouts)
+
+	assert.Equal(t, `// my outputs
+output "eDP-1" {
+    off
+    mode "2256x1504@59.999"
+    scale 1.5
+    position x=0 y=0
+    focus-at-startup
+    hot-corners {
+        off
+    }
+}
+
+/-output "eDP-1" {
+    scale 3
+}
+
+output "HDMI-A-1" {
+    off
+}
+
+output "DP-3" {
+    mode "3840x2160@60.000"
+    scale 1
+    transform "90"
+    position x=1504 y=0
+    variable-refresh-rate
+}
+`, got)
+}
+
+func TestMergeNiriOutputs_KeepsVRROnDemandAndDropsDuplicates(t *testing.T) {
+	existing := `output "DP-3" {
+    variable-refresh-rate on-demand=true
+}
+output "DP-3" {
+    scale 2
+}
+`
+	got := MergeNiriOutputs(existing, testLayouts()[1:])
+
+	assert.Equal(t, `output "DP-3" {
+    mode "3840x2160@60.000"
+    scale 1
+    transform "90"
+    position x=1504 y=0
+    variable-refresh-rate on-demand=true
+}
+`, got)
+}
+
+func TestMergeHyprlandOutputs(t *testing.T) {
+	existing := `-- Per-output monitor rules
+hl.monitor({ output = "DP-3", mode = "preferred", position = "auto", scale = "auto", bitdepth = 10, cm = "hdr", vrr = 2 })
+hl.monitor({ output = "HDMI-A-1", disabled = true })
+
+hl.monitor({ output = "", mode = "preferred", position = "auto", scale = "auto" })
+`
+	got := MergeHyprlandOutputs(existing, testLayouts())
+
+	assert.Equal(t, `-- Per-output monitor rules
+hl.monitor({ output = "DP-3", mode = "3840x2160@60.000", position = "1504x0", scale = 1, transform = 1, vrr = 2, bitdepth = 10, cm = "hdr" })
+hl.monitor({ output = "HDMI-A-1", disabled = true })
+
+hl.monitor({ output = "eDP-1", mode = "2256x1504@59.999", position = "0x0", scale = 1.5, vrr = 0 })
+hl.monitor({ output = "", mode = "preferred", position = "auto", scale = "auto" })
+`, got)
+}
+
+func TestMergeHyprlandOutputs_DisabledAndAppend(t *testing.T) {
+	layouts := []OutputLayout{{Name: "HDMI-A-1"}}
+	got := MergeHyprlandOutputs("-- header", layouts)
+	assert.Equal(t, "-- header\nhl.monitor({ output = \"HDMI-A-1\", disabled = true })\n", got)
+}
+
+func TestMergeMangoOutputs(t *testing.T) {
+	existing := `# Auto-generated by DMS - do not edit manually
+
+monitorrule=name:^eDP-1$,width:1920,height:1080,refresh:60,x:0,y:0,scale:1,rr:0,vrr:0,mfact:0.6
+monitorrule=name:^DP-1$,width:1920,height:1080,refresh:60,x:0,y:0,scale:1,rr:0,vrr:0
+monitorrule=name:^HDMI-A-1$,width:1920,height:1080,refresh:60,x:0,y:0,scale:1,rr:0,vrr:0
+`
+	layouts := append(testLayouts(), OutputLayout{Name: "HDMI-A-1"})
+	got := MergeMangoOutputs(existing, layouts)
+
+	assert.Equal(t, `# Auto-generated by DMS - do not edit manually
+
+monitorrule=name:^eDP-1$,width:2256,height:1504,refresh:60,x:0,y:0,scale:1.5,rr:0,vrr:0,mfact:0.6
+monitorrule=name:^DP-1$,width:1920,height:1080,refresh:60,x:0,y:0,scale:1,rr:0,vrr:0
+monitorrule=name:^DP-3$,width:3840,height:2160,refresh:60,x:1504,y:0,scale:1,rr:1,vrr:1
+`, got)
+}
+
+func TestWriteOutputsConfig(t *testing.T) {
+	td := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", td)
+
+	path, err := WriteOutputsConfig(OutputsCompositorNiri, testLayouts()[:1])
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(td, "niri", "dms", "outputs.kdl"), path)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `output "eDP-1" {`)
+
+	_, err = WriteOutputsConfig("sway", testLayouts())
+	assert.Error(t, err)
+}
+
+func TestDetectOutputsCompositor(t *testing.T) {
+	t.Setenv("MANGO_INSTANCE_SIGNATURE", "")
+	t.Setenv("NIRI_SOCKET", "")
+	t.Setenv("HYPRLAND_INSTANCE_SIGNATURE", "")
+	assert.Equal(t, "", DetectOutputsCompositor())
+
+	t.Setenv("HYPRLAND_INSTANCE_SIGNATURE", "abc")
+	assert.Equal(t, OutputsCompositorHyprland, DetectOutputsCompositor())
+
+	t.Setenv("NIRI_SOCKET", "/run/niri.sock")
+	assert.Equal(t, OutputsCompositorNiri, DetectOutputsCompositor())
+}
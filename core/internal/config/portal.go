@@ -0,0 +1,174 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/AvengeMedia/DankMaterialShell/core/internal/utils"
+)
+
+// PortalBackendName is the backend name xdg-desktop-portal knows the DMS
+// server by; the server owns org.freedesktop.impl.portal.desktop.dms.
+const PortalBackendName = "dms"
+
+// PortalInterfaces are the impl.portal interfaces the DMS server serves.
+var PortalInterfaces = []string{
+	"org.freedesktop.impl.portal.Settings",
+	"org.freedesktop.impl.portal.Screenshot",
+}
+
+var systemPortalConfDirs = []string{"/etc/xdg-desktop-portal", "/usr/share/xdg-desktop-portal"}
+
+func portalFileContent() string {
+	return fmt.Sprintf("[portal]\nDBusName=org.freedesktop.impl.portal.desktop.%s\nInterfaces=%s;\n",
+		PortalBackendName, strings.Join(PortalInterfaces, ";"))
+}
+
+// portalsConfName picks the file xdg-desktop-portal reads first: the
+// per-desktop one when XDG_CURRENT_DESKTOP is set, since a system
+// <desktop>-portals.conf outranks a user portals.conf.
+func portalsConfName(currentDesktop string) string {
+	desktop, _, _ := strings.Cut(currentDesktop, ":")
+	desktop = strings.ToLower(strings.TrimSpace(desktop))
+	if desktop == "" {
+		return "portals.conf"
+	}
+	return desktop + "-portals.conf"
+}
+
+// preferPortalBackend puts backend first for each interface in the
+// [preferred] section, keeping the previous choice (or the default) as the
+// fallback.
+func preferPortalBackend(content, backend string, interfaces []string) string {
+	lines := strings.Split(strings.TrimRight(content, "\n"), "\n")
+	if content == "" {
+		lines = nil
+	}
+
+	start, end := -1, len(lines)
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if !strings.HasPrefix(trimmed, "[") {
+			continue
+		}
+		if start >= 0 {
+			end = i
+			break
+		}
+		if trimmed == "[preferred]" {
+			start = i
+		}
+	}
+	if start < 0 {
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, "[preferred]")
+		start, end = len(lines)-1, len(lines)
+	}
+
+	values := map[string]int{}
+	defaultValue := ""
+	for i := start + 1; i < end; i++ {
+		key, value, ok := strings.Cut(lines[i], "=")
+		if !ok {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		values[key] = i
+		if key == "default" {
+			defaultValue = strings.TrimSpace(value)
+		}
+	}
+
+	var added []string
+	for _, iface := range interfaces {
+		previous := defaultValue
+		idx, exists := values[iface]
+		if exists {
+			_, previous, _ = strings.Cut(lines[idx], "=")
+		}
+		backends := []string{backend}
+		for _, b := range strings.Split(previous, ";") {
+			if b = strings.TrimSpace(b); b != "" && !slices.Contains(backends, b) {
+				backends = append(backends, b)
+			}
+		}
+		line := iface + "=" + strings.Join(backends, ";")
+		if exists {
+			lines[idx] = line
+		} else {
+			added = append(added, line)
+		}
+	}
+
+	insertAt := end
+	for insertAt > start+1 && strings.TrimSpace(lines[insertAt-1]) == "" {
+		insertAt--
+	}
+	lines = slices.Insert(lines, insertAt, added...)
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// DeployPortalConfig registers the DMS server as an xdg-desktop-portal
+// backend and prefers it for the interfaces it implements.
+func (cd *ConfigDeployer) DeployPortalConfig() ([]DeploymentResult, error) {
+	var results []DeploymentResult
+
+	portalResult := DeploymentResult{
+		ConfigType: "XDG Portal Backend",
+		Path:       filepath.Join(utils.XDGDataHome(), "xdg-desktop-portal", "portals", PortalBackendName+".portal"),
+	}
+	if err := os.MkdirAll(filepath.Dir(portalResult.Path), 0o755); err != nil {
+		portalResult.Error = fmt.Errorf("failed to create portals directory: %w", err)
+		return []DeploymentResult{portalResult}, portalResult.Error
+	}
+	if err := os.WriteFile(portalResult.Path, []byte(portalFileContent()), 0o644); err != nil {
+		portalResult.Error = fmt.Errorf("failed to write portal file: %w", err)
+		return []DeploymentResult{portalResult}, portalResult.Error
+	}
+	portalResult.Deployed = true
+	cd.log("Registered DMS xdg-desktop-portal backend")
+	results = append(results, portalResult)
+
+	name := portalsConfName(os.Getenv("XDG_CURRENT_DESKTOP"))
+	confResult := DeploymentResult{
+		ConfigType: "XDG Portal Preferences",
+		Path:       filepath.Join(utils.XDGConfigHome(), "xdg-desktop-portal", name),
+	}
+
+	existing, err := os.ReadFile(confResult.Path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		// A user file replaces the system one entirely, so start from it.
+		for _, dir := range systemPortalConfDirs {
+			if data, err := os.ReadFile(filepath.Join(dir, name)); err == nil {
+				cd.log(fmt.Sprintf("Starting from %s", filepath.Join(dir, name)))
+				existing = data
+				break
+			}
+		}
+	case err != nil:
+		confResult.Error = fmt.Errorf("failed to read %s: %w", name, err)
+		return append(results, confResult), confResult.Error
+	}
+
+	if err := os.MkdirAll(filepath.Dir(confResult.Path), 0o755); err != nil {
+		confResult.Error = fmt.Errorf("failed to create xdg-desktop-portal config directory: %w", err)
+		return append(results, confResult), confResult.Error
+	}
+	content := preferPortalBackend(string(existing), PortalBackendName, PortalInterfaces)
+	if err := os.WriteFile(confResult.Path, []byte(content), 0o644); err != nil {
+		confResult.Error = fmt.Errorf("failed to write %s: %w", name, err)
+		return append(results, confResult), confResult.Error
+	}
+	confResult.Deployed = true
+	cd.log(fmt.Sprintf("Preferred DMS portal backend in %s", name))
+	results = append(results, confResult)
+
+	return results, nil
+}
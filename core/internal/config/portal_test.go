@@ -0,0 +1,81 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPortalsConfName(t *testing.T) {
+	assert.Equal(t, "portals.conf", portalsConfName(""))
+	assert.Equal(t, "niri-portals.conf", portalsConfName("niri"))
+	assert.Equal(t, "hyprland-portals.conf", portalsConfName("Hyprland:wlroots"))
+}
+
+func TestPreferPortalBackend(t *testing.T) {
+	settings := []string{"org.freedesktop.impl.portal.Settings"}
+
+	t.Run("empty file", func(t *testing.T) {
+		assert.Equal(t, "[preferred]\norg.freedesktop.impl.portal.Settings=dms\n",
+			preferPortalBackend("", "dms", settings))
+	})
+
+	t.Run("falls back to the default", func(t *testing.T) {
+		in := "[preferred]\ndefault=gnome;gtk;\norg.freedesktop.impl.portal.Access=gtk\n\n[other]\nkey=value\n"
+		want := "[preferred]\ndefault=gnome;gtk;\norg.freedesktop.impl.portal.Access=gtk\norg.freedesktop.impl.portal.Settings=dms;gnome;gtk\n\n[other]\nkey=value\n"
+		assert.Equal(t, want, preferPortalBackend(in, "dms", settings))
+	})
+
+	t.Run("keeps an explicit choice as fallback and is idempotent", func(t *testing.T) {
+		in := "[preferred]\ndefault=gtk\norg.freedesktop.impl.portal.Settings=darkman;gtk\n"
+		out := preferPortalBackend(in, "dms", settings)
+		assert.Equal(t, "[preferred]\ndefault=gtk\norg.freedesktop.impl.portal.Settings=dms;darkman;gtk\n", out)
+		assert.Equal(t, out, preferPortalBackend(out, "dms", settings))
+	})
+
+	t.Run("adds a preferred section", func(t *testing.T) {
+		assert.Equal(t, "[other]\nkey=value\n\n[preferred]\norg.freedesktop.impl.portal.Settings=dms\n",
+			preferPortalBackend("[other]\nkey=value\n", "dms", settings))
+	})
+}
+
+func TestDeployPortalConfig(t *testing.T) {
+	dataHome := t.TempDir()
+	configHome := t.TempDir()
+	systemDir := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", dataHome)
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+	t.Setenv("XDG_CURRENT_DESKTOP", "niri")
+
+	old := systemPortalConfDirs
+	systemPortalConfDirs = []string{systemDir}
+	t.Cleanup(func() { systemPortalConfDirs = old })
+	require.NoError(t, os.WriteFile(filepath.Join(systemDir, "niri-portals.conf"),
+		[]byte("[preferred]\ndefault=gnome;gtk;\n"), 0o644))
+
+	cd := NewConfigDeployer(nil)
+	results, err := cd.DeployPortalConfig()
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+
+	portal, err := os.ReadFile(filepath.Join(dataHome, "xdg-desktop-portal", "portals", "dms.portal"))
+	require.NoError(t, err)
+	assert.Contains(t, string(portal), "DBusName=org.freedesktop.impl.portal.desktop.dms\n")
+	assert.Contains(t, string(portal), "Interfaces=org.freedesktop.impl.portal.Settings;org.freedesktop.impl.portal.Screenshot;\n")
+
+	confPath := filepath.Join(configHome, "xdg-desktop-portal", "niri-portals.conf")
+	assert.Equal(t, confPath, results[1].Path)
+	conf, err := os.ReadFile(confPath)
+	require.NoError(t, err)
+	assert.Equal(t, "[preferred]\ndefault=gnome;gtk;\norg.freedesktop.impl.portal.Settings=dms;gnome;gtk\norg.freedesktop.impl.portal.Screenshot=dms;gnome;gtk\n", string(conf),
+		"the system file is carried over since the user file replaces it")
+
+	_, err = cd.DeployPortalConfig()
+	require.NoError(t, err)
+	again, err := os.ReadFile(confPath)
+	require.NoError(t, err)
+	assert.Equal(t, string(conf), string(again))
+}
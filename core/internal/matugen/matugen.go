@@ -268,6 +268,8 @@ func Run(opts Options) error {
 		syncColorScheme(opts.Mode)
 	}
 
+	notifyThemeApplied(&opts)
+
 	if !changed {
 		log.Info("No color changes detected, skipping refresh")
 		return ErrNoChanges
@@ -1076,6 +1078,40 @@ func expectColorSchemeEcho(scheme string) {
 	}
 }
 
+// AppliedTheme describes a finished build: its mode and the primary color
+// for that mode.
+type AppliedTheme struct {
+	Mode    ColorMode
+	Primary string
+}
+
+var themeAppliedHook func(AppliedTheme)
+
+// SetThemeAppliedHook registers a callback run after every successful build,
+// including ones that left the colors unchanged.
+func SetThemeAppliedHook(hook func(AppliedTheme)) {
+	themeAppliedHook = hook
+}
+
+func notifyThemeApplied(opts *Options) {
+	if themeAppliedHook == nil {
+		return
+	}
+	theme := AppliedTheme{Mode: opts.Mode}
+	if data, err := os.ReadFile(opts.ColorsOutput()); err == nil {
+		var colors ColorsOutput
+		if err := json.Unmarshal(data, &colors); err == nil {
+			switch opts.Mode {
+			case ColorModeLight:
+				theme.Primary = colors.Colors.Light["primary"]
+			default:
+				theme.Primary = colors.Colors.Dark["primary"]
+			}
+		}
+	}
+	themeAppliedHook(theme)
+}
+
 // The color-scheme round trip is the only mechanism that makes running GTK4
 // apps reload ~/.config/gtk-4.0 CSS (a gtk-theme flip does not). But apps
 // following the portal color-scheme (Chromium) can drop the restore signal
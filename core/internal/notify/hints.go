@@ -0,0 +1,131 @@
+package notify
+
+import (
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/godbus/dbus/v5"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// maxImageSize bounds image-data so large files do not blow past the D-Bus
+// message limit; servers show them far smaller anyway.
+const maxImageSize = 256
+
+// imageData is the (iiibiiay) image-data hint.
+type imageData struct {
+	Width         int32
+	Height        int32
+	RowStride     int32
+	HasAlpha      bool
+	BitsPerSample int32
+	Channels      int32
+	Data          []byte
+}
+
+func ParseUrgency(s string) (Urgency, error) {
+	switch strings.ToLower(s) {
+	case "low":
+		return UrgencyLow, nil
+	case "normal":
+		return UrgencyNormal, nil
+	case "critical":
+		return UrgencyCritical, nil
+	}
+	return 0, fmt.Errorf("invalid urgency %q: use low, normal or critical", s)
+}
+
+// ParseAction parses "id=Label". A bare label doubles as its id.
+func ParseAction(s string) (Action, error) {
+	id, label, ok := strings.Cut(s, "=")
+	if !ok {
+		label = id
+	}
+	if id == "" || label == "" {
+		return Action{}, fmt.Errorf("invalid action %q: use id=Label", s)
+	}
+	return Action{ID: id, Label: label}, nil
+}
+
+// ParseHint parses a notify-send style TYPE:NAME:VALUE hint, where TYPE is
+// int, double, string, byte or boolean.
+func ParseHint(s string) (string, dbus.Variant, error) {
+	parts := strings.SplitN(s, ":", 3)
+	if len(parts) != 3 || parts[1] == "" {
+		return "", dbus.Variant{}, fmt.Errorf("invalid hint %q: use TYPE:NAME:VALUE", s)
+	}
+	kind, name, value := parts[0], parts[1], parts[2]
+
+	var v any
+	var err error
+	switch kind {
+	case "int":
+		var i int64
+		i, err = strconv.ParseInt(value, 10, 32)
+		v = int32(i)
+	case "double":
+		v, err = strconv.ParseFloat(value, 64)
+	case "string":
+		v = value
+	case "byte":
+		var b uint64
+		b, err = strconv.ParseUint(value, 10, 8)
+		v = byte(b)
+	case "boolean":
+		v, err = strconv.ParseBool(value)
+	default:
+		return "", dbus.Variant{}, fmt.Errorf("invalid hint type %q: use int, double, string, byte or boolean", kind)
+	}
+	if err != nil {
+		return "", dbus.Variant{}, fmt.Errorf("invalid %s value for hint %s: %q", kind, name, value)
+	}
+	return name, dbus.MakeVariant(v), nil
+}
+
+func loadImageData(path string) (imageData, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return imageData{}, fmt.Errorf("failed to open image: %w", err)
+	}
+	defer f.Close()
+
+	src, _, err := image.Decode(f)
+	if err != nil {
+		return imageData{}, fmt.Errorf("failed to decode image %s: %w", path, err)
+	}
+	return encodeImageData(src), nil
+}
+
+// encodeImageData converts img to non-premultiplied RGBA, scaled down to fit
+// maxImageSize.
+func encodeImageData(img image.Image) imageData {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w > maxImageSize || h > maxImageSize {
+		if w >= h {
+			w, h = maxImageSize, max(1, h*maxImageSize/w)
+		} else {
+			w, h = max(1, w*maxImageSize/h), maxImageSize
+		}
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	draw.ApproxBiLinear.Scale(dst, dst.Bounds(), img, b, draw.Src, nil)
+
+	return imageData{
+		Width:         int32(w),
+		Height:        int32(h),
+		RowStride:     int32(dst.Stride),
+		HasAlpha:      true,
+		BitsPerSample: 8,
+		Channels:      4,
+		Data:          dst.Pix,
+	}
+}
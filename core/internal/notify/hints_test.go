@@ -0,0 +1,78 @@
+package notify
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseHint(t *testing.T) {
+	name, v, err := ParseHint("int:value:42")
+	require.NoError(t, err)
+	assert.Equal(t, "value", name)
+	assert.Equal(t, int32(42), v.Value())
+
+	_, v, err = ParseHint("string:x-dunst-stack-tag:build:main")
+	require.NoError(t, err)
+	assert.Equal(t, "build:main", v.Value(), "values may contain colons")
+
+	_, v, err = ParseHint("byte:urgency:2")
+	require.NoError(t, err)
+	assert.Equal(t, byte(2), v.Value())
+
+	_, v, err = ParseHint("boolean:transient:true")
+	require.NoError(t, err)
+	assert.Equal(t, true, v.Value())
+
+	for _, bad := range []string{"value:42", "int:value:x", "float:value:1", "byte:urgency:300", "int::1"} {
+		_, _, err := ParseHint(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestParseAction(t *testing.T) {
+	a, err := ParseAction("retry=Retry build")
+	require.NoError(t, err)
+	assert.Equal(t, Action{ID: "retry", Label: "Retry build"}, a)
+
+	a, err = ParseAction("Open")
+	require.NoError(t, err)
+	assert.Equal(t, Action{ID: "Open", Label: "Open"}, a)
+
+	_, err = ParseAction("=Label")
+	assert.Error(t, err)
+}
+
+func TestNotificationHints(t *testing.T) {
+	critical := UrgencyCritical
+	n := Notification{
+		FilePath: "/tmp/a.png",
+		Urgency:  &critical,
+		Category: "transfer.complete",
+		Actions:  []Action{{ID: "retry", Label: "Retry"}},
+		Hints:    map[string]dbus.Variant{"urgency": dbus.MakeVariant(byte(0))},
+	}
+
+	hints, err := n.hints()
+	require.NoError(t, err)
+	assert.Equal(t, "file:///tmp/a.png", hints["image_path"].Value())
+	assert.Equal(t, "transfer.complete", hints["category"].Value())
+	assert.Equal(t, byte(0), hints["urgency"].Value(), "explicit hints win")
+	assert.Equal(t, []string{"open", "Open", "folder", "Open Folder", "retry", "Retry"}, n.actions())
+}
+
+func TestEncodeImageData(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 512, 128))
+	img.Set(0, 0, color.RGBA{R: 255, A: 255})
+
+	data := encodeImageData(img)
+	assert.Equal(t, int32(256), data.Width)
+	assert.Equal(t, int32(64), data.Height)
+	assert.Equal(t, int32(256*4), data.RowStride)
+	assert.Len(t, data.Data, 256*64*4)
+	assert.Equal(t, "(iiibiiay)", dbus.MakeVariant(data).Signature().String())
+}
@@ -1,6 +1,7 @@
 package notify
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
@@ -18,49 +19,66 @@ const (
 	notifyPath      = "/org/freedesktop/Notifications"
 	notifyInterface = "org.freedesktop.Notifications"
 
-	maxSummaryLen = 29
-	maxBodyLen    = 80
-
 	listenerMaxLifetime = time.Hour
 )
 
-type Notification struct {
-	AppName  string
-	Icon     string
-	Summary  string
-	Body     string
-	FilePath string
-	Timeout  int32
-}
+type Urgency byte
 
-func Send(n Notification) (uint32, error) {
-	conn, err := dbus.SessionBus()
-	if err != nil {
-		return 0, fmt.Errorf("dbus session failed: %w", err)
-	}
+const (
+	UrgencyLow      Urgency = 0
+	UrgencyNormal   Urgency = 1
+	UrgencyCritical Urgency = 2
+)
 
-	if n.AppName == "" {
-		n.AppName = "DMS"
-	}
-	if n.Timeout == 0 {
-		n.Timeout = 5000
-	}
+type Action struct {
+	ID    string
+	Label string
+}
 
-	if len(n.Summary) > maxSummaryLen {
-		n.Summary = n.Summary[:maxSummaryLen-3] + "..."
-	}
-	if len(n.Body) > maxBodyLen {
-		n.Body = n.Body[:maxBodyLen-3] + "..."
-	}
+// Notification is one Notify call. Timeout is in ms; 0 never expires and -1
+// leaves it to the server. A FilePath adds "Open" and "Open Folder" actions
+// ahead of Actions.
+type Notification struct {
+	AppName   string
+	Icon      string
+	Summary   string
+	Body      string
+	FilePath  string
+	Timeout   int32
+	ReplaceID uint32
+	Urgency   *Urgency
+	Category  string
+	Actions   []Action
+	// Hints are sent as given and override the hints derived from the
+	// fields above.
+	Hints map[string]dbus.Variant
+	// ImagePath is a file decoded and sent as image-data.
+	ImagePath string
+}
+
+// Result is how a notification sent with SendAndWait ended: with an action
+// or closed with a NotificationClosed reason.
+type Result struct {
+	ID     uint32
+	Action string
+	Reason uint32
+}
 
+func (n *Notification) actions() []string {
 	var actions []string
 	if n.FilePath != "" {
-		actions = []string{
+		actions = append(actions,
 			"open", "Open",
 			"folder", "Open Folder",
-		}
+		)
+	}
+	for _, a := range n.Actions {
+		actions = append(actions, a.ID, a.Label)
 	}
+	return actions
+}
 
+func (n *Notification) hints() (map[string]dbus.Variant, error) {
 	hints := map[string]dbus.Variant{}
 	if n.FilePath != "" {
 		imgPath := n.FilePath
@@ -69,17 +87,53 @@ func Send(n Notification) (uint32, error) {
 		}
 		hints["image_path"] = dbus.MakeVariant(imgPath)
 	}
+	if n.Urgency != nil {
+		hints["urgency"] = dbus.MakeVariant(byte(*n.Urgency))
+	}
+	if n.Category != "" {
+		hints["category"] = dbus.MakeVariant(n.Category)
+	}
+	if n.ImagePath != "" {
+		data, err := loadImageData(n.ImagePath)
+		if err != nil {
+			return nil, err
+		}
+		hints["image-data"] = dbus.MakeVariant(data)
+	}
+	for k, v := range n.Hints {
+		hints[k] = v
+	}
+	return hints, nil
+}
+
+func Send(n Notification) (uint32, error) {
+	conn, err := dbus.SessionBus()
+	if err != nil {
+		return 0, fmt.Errorf("dbus session failed: %w", err)
+	}
+	return send(conn, n)
+}
+
+func send(conn *dbus.Conn, n Notification) (uint32, error) {
+	if n.AppName == "" {
+		n.AppName = "DMS"
+	}
+
+	hints, err := n.hints()
+	if err != nil {
+		return 0, err
+	}
 
 	obj := conn.Object(notifyDest, notifyPath)
 	call := obj.Call(
 		notifyInterface+".Notify",
 		0,
 		n.AppName,
-		uint32(0),
+		n.ReplaceID,
 		n.Icon,
 		n.Summary,
 		n.Body,
-		actions,
+		n.actions(),
 		hints,
 		n.Timeout,
 	)
@@ -96,6 +150,82 @@ func Send(n Notification) (uint32, error) {
 	return notificationID, nil
 }
 
+// SendAndWait sends n and blocks until one of its actions is invoked, it is
+// closed, or ctx ends. onSent, if set, gets the ID as soon as it is known.
+// The Open/Open Folder file actions are carried out before it returns.
+func SendAndWait(ctx context.Context, n Notification, onSent func(id uint32)) (Result, error) {
+	conn, err := dbus.SessionBus()
+	if err != nil {
+		return Result{}, fmt.Errorf("dbus session failed: %w", err)
+	}
+
+	// Listen before sending so a quick close cannot be missed.
+	matchOpts := []dbus.MatchOption{
+		dbus.WithMatchObjectPath(notifyPath),
+		dbus.WithMatchInterface(notifyInterface),
+	}
+	if err := conn.AddMatchSignal(matchOpts...); err != nil {
+		return Result{}, fmt.Errorf("failed to watch notification signals: %w", err)
+	}
+	defer conn.RemoveMatchSignal(matchOpts...)
+	signals := make(chan *dbus.Signal, 10)
+	conn.Signal(signals)
+	defer conn.RemoveSignal(signals)
+
+	id, err := send(conn, n)
+	if err != nil {
+		return Result{}, err
+	}
+	if onSent != nil {
+		onSent(id)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return Result{ID: id}, ctx.Err()
+		case sig := <-signals:
+			if result, done := waitResult(sig, id); done {
+				if result.Action != "" && n.FilePath != "" {
+					handleAction(result.Action, n.FilePath)
+				}
+				return result, nil
+			}
+		}
+	}
+}
+
+func waitResult(sig *dbus.Signal, notificationID uint32) (Result, bool) {
+	if sig == nil || len(sig.Body) < 2 {
+		return Result{}, false
+	}
+	id, ok := sig.Body[0].(uint32)
+	if !ok || id != notificationID {
+		return Result{}, false
+	}
+	switch sig.Name {
+	case notifyInterface + ".ActionInvoked":
+		action, ok := sig.Body[1].(string)
+		return Result{ID: id, Action: action}, ok
+	case notifyInterface + ".NotificationClosed":
+		reason, ok := sig.Body[1].(uint32)
+		return Result{ID: id, Reason: reason}, ok
+	}
+	return Result{}, false
+}
+
+// Close asks the server to close notification id.
+func Close(id uint32) error {
+	conn, err := dbus.SessionBus()
+	if err != nil {
+		return fmt.Errorf("dbus session failed: %w", err)
+	}
+	if err := conn.Object(notifyDest, notifyPath).Call(notifyInterface+".CloseNotification", 0, id).Err; err != nil {
+		return fmt.Errorf("close call failed: %w", err)
+	}
+	return nil
+}
+
 func SpawnActionListener(notificationID uint32, filePath string) {
 	exe, err := os.Executable()
 	if err != nil {
@@ -0,0 +1,293 @@
+package bluez
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/AvengeMedia/DankMaterialShell/core/internal/log"
+	"github.com/AvengeMedia/dankgo/dbusutil"
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	TransportAuto  = "auto"
+	TransportBREDR = "bredr"
+	TransportLE    = "le"
+)
+
+// DiscoveryFilter mirrors the subset of Adapter1.SetDiscoveryFilter that is
+// useful from the shell. BlueZ scopes the filter to our D-Bus connection, so
+// it is reapplied before every discovery.
+type DiscoveryFilter struct {
+	Transport string   `json:"transport,omitempty"`
+	RSSI      *int16   `json:"rssi,omitempty"`
+	UUIDs     []string `json:"uuids,omitempty"`
+	Pattern   string   `json:"pattern,omitempty"`
+}
+
+func (f *DiscoveryFilter) validate() error {
+	switch f.Transport {
+	case "", TransportAuto, TransportBREDR, TransportLE:
+	default:
+		return fmt.Errorf("invalid transport %q: use auto, bredr or le", f.Transport)
+	}
+	if f.RSSI != nil && (*f.RSSI < -127 || *f.RSSI > 20) {
+		return fmt.Errorf("invalid rssi threshold %d", *f.RSSI)
+	}
+	return nil
+}
+
+func (f *DiscoveryFilter) empty() bool {
+	return f == nil || (f.Transport == "" && f.RSSI == nil && len(f.UUIDs) == 0 && f.Pattern == "")
+}
+
+// toDBus builds the SetDiscoveryFilter argument. A nil filter yields an
+// empty dict, which clears any filter set earlier.
+func (f *DiscoveryFilter) toDBus() map[string]dbus.Variant {
+	args := map[string]dbus.Variant{}
+	if f == nil {
+		return args
+	}
+	if f.Transport != "" {
+		args["Transport"] = dbus.MakeVariant(f.Transport)
+	}
+	if f.RSSI != nil {
+		args["RSSI"] = dbus.MakeVariant(*f.RSSI)
+	}
+	if len(f.UUIDs) > 0 {
+		args["UUIDs"] = dbus.MakeVariant(f.UUIDs)
+	}
+	if f.Pattern != "" {
+		args["Pattern"] = dbus.MakeVariant(f.Pattern)
+	}
+	return args
+}
+
+// applyAdapterProps copies the Adapter1 properties present in props onto a and
+// reports whether any of them were known.
+func applyAdapterProps(a *AdapterInfo, props map[string]dbus.Variant) bool {
+	known := false
+	set := func(key string, apply func(dbus.Variant)) {
+		if v, ok := props[key]; ok {
+			apply(v)
+			known = true
+		}
+	}
+
+	set("Alias", func(v dbus.Variant) { a.Name = dbusutil.AsOr(v, a.Name) })
+	set("Name", func(v dbus.Variant) { a.SystemName = dbusutil.AsOr(v, a.SystemName) })
+	set("Address", func(v dbus.Variant) { a.Address = dbusutil.AsOr(v, a.Address) })
+	set("Powered", func(v dbus.Variant) { a.Powered = dbusutil.AsOr(v, a.Powered) })
+	set("Discovering", func(v dbus.Variant) { a.Discovering = dbusutil.AsOr(v, a.Discovering) })
+	set("Discoverable", func(v dbus.Variant) { a.Discoverable = dbusutil.AsOr(v, a.Discoverable) })
+	set("DiscoverableTimeout", func(v dbus.Variant) { a.DiscoverableTimeout = dbusutil.AsOr(v, a.DiscoverableTimeout) })
+	set("Pairable", func(v dbus.Variant) { a.Pairable = dbusutil.AsOr(v, a.Pairable) })
+	set("PairableTimeout", func(v dbus.Variant) { a.PairableTimeout = dbusutil.AsOr(v, a.PairableTimeout) })
+	return known
+}
+
+// defaultAdapterLocked picks the preferred adapter by address when present,
+// else the first one. stateMutex must be held.
+func (m *Manager) defaultAdapterLocked(preferred string) dbus.ObjectPath {
+	if preferred != "" {
+		for _, a := range m.state.Adapters {
+			if strings.EqualFold(a.Address, preferred) && slices.Contains(m.adapterPaths, dbus.ObjectPath(a.Path)) {
+				return dbus.ObjectPath(a.Path)
+			}
+		}
+	}
+	if len(m.adapterPaths) == 0 {
+		return ""
+	}
+	return m.adapterPaths[0]
+}
+
+func (m *Manager) preferredAdapter() string {
+	m.configMutex.RLock()
+	defer m.configMutex.RUnlock()
+	return m.config.DefaultAdapter
+}
+
+func (m *Manager) discoveryFilter() *DiscoveryFilter {
+	m.configMutex.RLock()
+	defer m.configMutex.RUnlock()
+	return m.config.DiscoveryFilter
+}
+
+// updateConfig applies fn to a copy of the config and keeps it only once it
+// has been saved.
+func (m *Manager) updateConfig(fn func(*Config)) error {
+	m.configMutex.Lock()
+	defer m.configMutex.Unlock()
+
+	cfg := m.config.clone()
+	fn(&cfg)
+	if err := saveConfig(cfg); err != nil {
+		return fmt.Errorf("failed to save bluetooth config: %w", err)
+	}
+	m.config = cfg
+	return nil
+}
+
+// refreshDefaultAdapter re-derives the default flags after the adapter list
+// or the preference changed.
+func (m *Manager) refreshDefaultAdapter() {
+	preferred := m.preferredAdapter()
+
+	m.stateMutex.Lock()
+	def := m.defaultAdapterLocked(preferred)
+	m.state.DefaultAdapter = string(def)
+	m.state.Powered = false
+	m.state.Discovering = false
+	for i := range m.state.Adapters {
+		a := &m.state.Adapters[i]
+		a.Default = a.Path == string(def)
+		if a.Default {
+			m.state.Powered = a.Powered
+			m.state.Discovering = a.Discovering
+		}
+	}
+	m.stateMutex.Unlock()
+}
+
+func (m *Manager) adapterAddress(path dbus.ObjectPath) string {
+	m.stateMutex.RLock()
+	defer m.stateMutex.RUnlock()
+	for _, a := range m.state.Adapters {
+		if a.Path == string(path) {
+			return a.Address
+		}
+	}
+	return ""
+}
+
+// rememberPowered records the powered state of an adapter, however it was
+// changed, so restorePowered never undoes an rfkill key or bluetoothctl.
+func (m *Manager) rememberPowered(address string, powered bool) {
+	address = strings.ToUpper(address)
+	if address == "" {
+		return
+	}
+	m.configMutex.RLock()
+	known, ok := m.config.AdapterPowered[address]
+	m.configMutex.RUnlock()
+	if ok && known == powered {
+		return
+	}
+	if err := m.updateConfig(func(cfg *Config) {
+		if cfg.AdapterPowered == nil {
+			cfg.AdapterPowered = map[string]bool{}
+		}
+		cfg.AdapterPowered[address] = powered
+	}); err != nil {
+		log.Warnf("[BluezManager] %v", err)
+	}
+}
+
+// restorePowered powers adapters back to their last recorded state. It
+// never lifts an rfkill block; a blocked adapter stays off.
+func (m *Manager) restorePowered() {
+	m.configMutex.RLock()
+	remembered := m.config.AdapterPowered
+	m.configMutex.RUnlock()
+	if len(remembered) == 0 {
+		return
+	}
+
+	for _, a := range m.GetState().Adapters {
+		want, ok := remembered[strings.ToUpper(a.Address)]
+		if !ok || want == a.Powered {
+			continue
+		}
+		log.Infof("[BluezManager] restoring %s powered=%v", a.Address, want)
+		if err := m.setAdapterPowered(dbus.ObjectPath(a.Path), want); err != nil {
+			log.Warnf("[BluezManager] restore powered on %s failed: %v", a.Address, err)
+		}
+	}
+}
+
+func (m *Manager) setAdapterPowered(path dbus.ObjectPath, powered bool) error {
+	obj := m.dbusConn.Object(bluezService, path)
+	return obj.Call(propertiesIface+".Set", 0, adapter1Iface, "Powered", dbus.MakeVariant(powered)).Err
+}
+
+func (m *Manager) setAdapterProperty(adapterPath, name string, value any) error {
+	path, err := m.resolveAdapter(adapterPath)
+	if err != nil {
+		return err
+	}
+	obj := m.dbusConn.Object(bluezService, path)
+	return obj.Call(propertiesIface+".Set", 0, adapter1Iface, name, dbus.MakeVariant(value)).Err
+}
+
+// SetAlias renames the adapter; an empty alias reverts to the system name.
+func (m *Manager) SetAlias(adapterPath, alias string) error {
+	return m.setAdapterProperty(adapterPath, "Alias", alias)
+}
+
+// SetDiscoverable toggles discoverability. timeout is in seconds, 0 meaning
+// no limit; nil leaves the adapter's current timeout alone.
+func (m *Manager) SetDiscoverable(adapterPath string, discoverable bool, timeout *uint32) error {
+	if timeout != nil {
+		if err := m.setAdapterProperty(adapterPath, "DiscoverableTimeout", *timeout); err != nil {
+			return err
+		}
+	}
+	return m.setAdapterProperty(adapterPath, "Discoverable", discoverable)
+}
+
+// SetPairable works like SetDiscoverable for the Pairable property.
+func (m *Manager) SetPairable(adapterPath string, pairable bool, timeout *uint32) error {
+	if timeout != nil {
+		if err := m.setAdapterProperty(adapterPath, "PairableTimeout", *timeout); err != nil {
+			return err
+		}
+	}
+	return m.setAdapterProperty(adapterPath, "Pairable", pairable)
+}
+
+// SetDefaultAdapter prefers the adapter with the given path or address for
+// calls that omit one. The preference is stored by address, which unlike
+// the hciN path stays the same across reboots and replugging.
+func (m *Manager) SetDefaultAdapter(ref string) error {
+	address := ""
+	if ref != "" {
+		for _, a := range m.GetState().Adapters {
+			if a.Path == ref || strings.EqualFold(a.Address, ref) {
+				address = strings.ToUpper(a.Address)
+				break
+			}
+		}
+		if address == "" {
+			return fmt.Errorf("unknown adapter: %s", ref)
+		}
+	}
+
+	if err := m.updateConfig(func(cfg *Config) { cfg.DefaultAdapter = address }); err != nil {
+		return err
+	}
+	m.refreshDefaultAdapter()
+	m.notifySubscribers()
+	return nil
+}
+
+// SetDiscoveryFilter stores filter and applies it to the next discovery. A
+// nil or empty filter clears it.
+func (m *Manager) SetDiscoveryFilter(filter *DiscoveryFilter) error {
+	if filter.empty() {
+		filter = nil
+	} else if err := filter.validate(); err != nil {
+		return err
+	}
+
+	if err := m.updateConfig(func(cfg *Config) { cfg.DiscoveryFilter = filter }); err != nil {
+		return err
+	}
+
+	m.stateMutex.Lock()
+	m.state.DiscoveryFilter = filter
+	m.stateMutex.Unlock()
+	m.notifySubscribers()
+	return nil
+}
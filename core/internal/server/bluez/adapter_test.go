@@ -0,0 +1,129 @@
+package bluez
+
+import (
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+)
+
+func TestApplyAdapterProps(t *testing.T) {
+	a := AdapterInfo{Path: "/org/bluez/hci0", Powered: true}
+	known := applyAdapterProps(&a, map[string]dbus.Variant{
+		"Alias":               dbus.MakeVariant("Desk"),
+		"Name":                dbus.MakeVariant("archbox"),
+		"Discoverable":        dbus.MakeVariant(true),
+		"DiscoverableTimeout": dbus.MakeVariant(uint32(180)),
+	})
+	if !known {
+		t.Fatal("expected known properties to be reported")
+	}
+	if a.Name != "Desk" || a.SystemName != "archbox" || !a.Discoverable || a.DiscoverableTimeout != 180 || !a.Powered {
+		t.Errorf("unexpected adapter: %+v", a)
+	}
+
+	if applyAdapterProps(&a, map[string]dbus.Variant{"UUIDs": dbus.MakeVariant([]string{})}) {
+		t.Error("expected unrelated properties to be ignored")
+	}
+}
+
+func TestDefaultAdapter(t *testing.T) {
+	m := &Manager{
+		state: &BluetoothState{Adapters: []AdapterInfo{
+			{Path: "/org/bluez/hci0", Address: "00:11:22:33:44:55", Powered: false},
+			{Path: "/org/bluez/hci1", Address: "AA:BB:CC:DD:EE:FF", Powered: true},
+		}},
+		adapterPaths: []dbus.ObjectPath{"/org/bluez/hci0", "/org/bluez/hci1"},
+	}
+
+	if got := m.defaultAdapterLocked(""); got != "/org/bluez/hci0" {
+		t.Errorf("expected first adapter without a preference, got %s", got)
+	}
+	if got := m.defaultAdapterLocked("aa:bb:cc:dd:ee:ff"); got != "/org/bluez/hci1" {
+		t.Errorf("expected preferred adapter, got %s", got)
+	}
+	if got := m.defaultAdapterLocked("12:34:56:78:9A:BC"); got != "/org/bluez/hci0" {
+		t.Errorf("expected fallback for an unplugged preference, got %s", got)
+	}
+
+	m.config.DefaultAdapter = "AA:BB:CC:DD:EE:FF"
+	m.refreshDefaultAdapter()
+	if m.state.DefaultAdapter != "/org/bluez/hci1" || !m.state.Powered || m.state.Adapters[0].Default || !m.state.Adapters[1].Default {
+		t.Errorf("unexpected state: %+v", m.state)
+	}
+
+	path, err := m.resolveAdapter("")
+	if err != nil || path != "/org/bluez/hci1" {
+		t.Errorf("expected calls without an adapter to use the preferred one, got %s, %v", path, err)
+	}
+}
+
+func TestSetDefaultAdapterPersists(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	m := &Manager{
+		state: &BluetoothState{Adapters: []AdapterInfo{
+			{Path: "/org/bluez/hci0", Address: "00:11:22:33:44:55"},
+			{Path: "/org/bluez/hci1", Address: "aa:bb:cc:dd:ee:ff"},
+		}},
+		adapterPaths: []dbus.ObjectPath{"/org/bluez/hci0", "/org/bluez/hci1"},
+		dirty:        make(chan struct{}, 1),
+	}
+
+	if err := m.SetDefaultAdapter("/org/bluez/hci1"); err != nil {
+		t.Fatal(err)
+	}
+	if got := loadConfig().DefaultAdapter; got != "AA:BB:CC:DD:EE:FF" {
+		t.Errorf("expected the address to be stored, got %q", got)
+	}
+	if err := m.SetDefaultAdapter("/org/bluez/hci9"); err == nil {
+		t.Error("expected unknown adapter to be rejected")
+	}
+}
+
+func TestDiscoveryFilter(t *testing.T) {
+	if args := (*DiscoveryFilter)(nil).toDBus(); len(args) != 0 {
+		t.Errorf("expected nil filter to clear, got %v", args)
+	}
+
+	rssi := int16(-70)
+	f := &DiscoveryFilter{Transport: TransportLE, RSSI: &rssi, UUIDs: []string{"0000180f-0000-1000-8000-00805f9b34fb"}}
+	if err := f.validate(); err != nil {
+		t.Fatal(err)
+	}
+	args := f.toDBus()
+	if args["Transport"].Value() != "le" || args["RSSI"].Value() != int16(-70) || args["RSSI"].Signature().String() != "n" {
+		t.Errorf("unexpected filter args: %v", args)
+	}
+	if _, ok := args["Pattern"]; ok {
+		t.Error("expected unset fields to be omitted")
+	}
+
+	if err := (&DiscoveryFilter{Transport: "usb"}).validate(); err == nil {
+		t.Error("expected invalid transport to be rejected")
+	}
+	if !(&DiscoveryFilter{}).empty() {
+		t.Error("expected zero filter to be empty")
+	}
+}
+
+func TestAdapterPoweredFollowsExternalChanges(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	m := &Manager{
+		state: &BluetoothState{Adapters: []AdapterInfo{
+			{Path: "/org/bluez/hci0", Address: "aa:bb:cc:dd:ee:ff", Powered: true},
+		}},
+		adapterPaths: []dbus.ObjectPath{"/org/bluez/hci0"},
+		config:       Config{AdapterPowered: map[string]bool{"AA:BB:CC:DD:EE:FF": true}},
+		dirty:        make(chan struct{}, 1),
+	}
+
+	m.handleAdapterPropertiesChanged("/org/bluez/hci0", map[string]dbus.Variant{"Powered": dbus.MakeVariant(false)})
+	if powered, ok := loadConfig().AdapterPowered["AA:BB:CC:DD:EE:FF"]; !ok || powered {
+		t.Errorf("expected power off from outside DMS to be recorded, got %v", loadConfig().AdapterPowered)
+	}
+
+	// With the off state recorded, restoring must leave the adapter alone
+	// rather than powering it back on.
+	m.restorePowered()
+}
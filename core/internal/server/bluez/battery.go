@@ -0,0 +1,156 @@
+package bluez
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/AvengeMedia/dankgo/dbusutil"
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	BatteryLevelNormal   = ""
+	BatteryLevelLow      = "low"
+	BatteryLevelCritical = "critical"
+
+	batteryLowPercent      = 20
+	batteryCriticalPercent = 10
+	// batteryHysteresis keeps a reading that hovers around a threshold from
+	// raising the same alert over and over.
+	batteryHysteresis = 5
+)
+
+// Battery is one org.bluez.Battery1 object. Most devices expose a single one
+// on the device path; with the experimental battery provider API, plugins
+// such as those for earbuds and their case may add more beneath it.
+type Battery struct {
+	Path       string `json:"path"`
+	Percentage uint8  `json:"percentage"`
+	Source     string `json:"source,omitempty"`
+}
+
+type BatteryEvent struct {
+	Type       string `json:"type"`
+	DevicePath string `json:"devicePath"`
+	DeviceName string `json:"deviceName"`
+	DeviceAddr string `json:"deviceAddr"`
+	Icon       string `json:"icon"`
+	Percentage uint8  `json:"percentage"`
+}
+
+// batteryOwner returns the device path a Battery1 object belongs to, i.e.
+// the first /org/bluez/hciN/dev_XX components of path.
+func batteryOwner(path dbus.ObjectPath) string {
+	parts := strings.SplitN(string(path), "/", 6)
+	if len(parts) < 5 || !strings.HasPrefix(parts[4], "dev_") {
+		return ""
+	}
+	return strings.Join(parts[:5], "/")
+}
+
+func collectBatteries(objects map[dbus.ObjectPath]map[string]map[string]dbus.Variant) map[string][]Battery {
+	batteries := map[string][]Battery{}
+	for path, interfaces := range objects {
+		props, ok := interfaces[battery1Iface]
+		if !ok {
+			continue
+		}
+		owner := batteryOwner(path)
+		if owner == "" {
+			continue
+		}
+		batteries[owner] = append(batteries[owner], Battery{
+			Path:       string(path),
+			Percentage: dbusutil.GetOr(props, "Percentage", uint8(0)),
+			Source:     dbusutil.GetOr(props, "Source", ""),
+		})
+	}
+	for _, list := range batteries {
+		slices.SortFunc(list, func(a, b Battery) int { return strings.Compare(a.Path, b.Path) })
+	}
+	return batteries
+}
+
+// devicePercentage prefers the battery on the device itself and otherwise
+// reports the lowest of the provided ones.
+func devicePercentage(devicePath string, batteries []Battery) *uint8 {
+	var lowest *uint8
+	for i := range batteries {
+		if batteries[i].Path == devicePath {
+			return &batteries[i].Percentage
+		}
+		if lowest == nil || batteries[i].Percentage < *lowest {
+			lowest = &batteries[i].Percentage
+		}
+	}
+	return lowest
+}
+
+func batteryThreshold(level string) uint8 {
+	switch level {
+	case BatteryLevelCritical:
+		return batteryCriticalPercent
+	case BatteryLevelLow:
+		return batteryLowPercent
+	}
+	return 100
+}
+
+// batteryLevel classifies percent given the level last reported for the
+// device. A level is only left once the reading recovers past its threshold
+// plus batteryHysteresis.
+func batteryLevel(percent uint8, prev string) string {
+	switch {
+	case percent <= batteryCriticalPercent:
+		return BatteryLevelCritical
+	case prev == BatteryLevelCritical && percent < batteryCriticalPercent+batteryHysteresis:
+		return BatteryLevelCritical
+	case percent <= batteryLowPercent:
+		return BatteryLevelLow
+	case prev != BatteryLevelNormal && percent < batteryLowPercent+batteryHysteresis:
+		return BatteryLevelLow
+	}
+	return BatteryLevelNormal
+}
+
+// batteryEvents updates levels for connected devices and returns an event for
+// each that dropped into a worse level. Devices that are gone or disconnected
+// are forgotten so a reconnect can alert again.
+func batteryEvents(devices []Device, levels map[string]string) []BatteryEvent {
+	var events []BatteryEvent
+	seen := make(map[string]bool, len(devices))
+
+	for _, dev := range devices {
+		if !dev.Connected || dev.Battery == nil {
+			continue
+		}
+		seen[dev.Path] = true
+
+		prev := levels[dev.Path]
+		level := batteryLevel(*dev.Battery, prev)
+		levels[dev.Path] = level
+
+		if level == BatteryLevelNormal || batteryThreshold(level) >= batteryThreshold(prev) {
+			continue
+		}
+		name := dev.Alias
+		if name == "" {
+			name = dev.Name
+		}
+		events = append(events, BatteryEvent{
+			Type:       level,
+			DevicePath: dev.Path,
+			DeviceName: name,
+			DeviceAddr: dev.Address,
+			Icon:       dev.Icon,
+			Percentage: *dev.Battery,
+		})
+	}
+
+	for path := range levels {
+		if !seen[path] {
+			delete(levels, path)
+		}
+	}
+	return events
+}
@@ -0,0 +1,108 @@
+package bluez
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+)
+
+func TestBatteryLevel(t *testing.T) {
+	cases := []struct {
+		percent uint8
+		prev    string
+		want    string
+	}{
+		{50, BatteryLevelNormal, BatteryLevelNormal},
+		{20, BatteryLevelNormal, BatteryLevelLow},
+		{22, BatteryLevelLow, BatteryLevelLow},
+		{25, BatteryLevelLow, BatteryLevelNormal},
+		{10, BatteryLevelLow, BatteryLevelCritical},
+		{13, BatteryLevelCritical, BatteryLevelCritical},
+		{16, BatteryLevelCritical, BatteryLevelLow},
+	}
+
+	for _, tc := range cases {
+		if got := batteryLevel(tc.percent, tc.prev); got != tc.want {
+			t.Errorf("batteryLevel(%d, %q) = %q, want %q", tc.percent, tc.prev, got, tc.want)
+		}
+	}
+}
+
+func TestBatteryEvents(t *testing.T) {
+	pct := func(v uint8) *uint8 { return &v }
+	levels := map[string]string{}
+	dev := Device{Path: "/org/bluez/hci0/dev_AA", Alias: "Headset", Connected: true, Battery: pct(18)}
+
+	events := batteryEvents([]Device{dev}, levels)
+	if len(events) != 1 || events[0].Type != BatteryLevelLow || events[0].DeviceName != "Headset" {
+		t.Fatalf("expected one low event, got %+v", events)
+	}
+
+	dev.Battery = pct(17)
+	if events := batteryEvents([]Device{dev}, levels); len(events) != 0 {
+		t.Errorf("expected no repeat event, got %+v", events)
+	}
+
+	dev.Battery = pct(9)
+	events = batteryEvents([]Device{dev}, levels)
+	if len(events) != 1 || events[0].Type != BatteryLevelCritical {
+		t.Fatalf("expected one critical event, got %+v", events)
+	}
+
+	dev.Connected = false
+	batteryEvents([]Device{dev}, levels)
+	if _, ok := levels[dev.Path]; ok {
+		t.Error("expected disconnected device to be forgotten")
+	}
+}
+
+func TestCollectBatteries(t *testing.T) {
+	battery := func(pct uint8) map[string]map[string]dbus.Variant {
+		return map[string]map[string]dbus.Variant{
+			battery1Iface: {"Percentage": dbus.MakeVariant(pct)},
+		}
+	}
+	objects := map[dbus.ObjectPath]map[string]map[string]dbus.Variant{
+		"/org/bluez/hci0/dev_AA":           battery(80),
+		"/org/bluez/hci0/dev_BB/battery_1": battery(60),
+		"/org/bluez/hci0/dev_BB/battery_0": battery(40),
+		"/org/bluez/hci0":                  battery(10),
+	}
+
+	batteries := collectBatteries(objects)
+	if len(batteries) != 2 {
+		t.Fatalf("expected batteries for 2 devices, got %d", len(batteries))
+	}
+	if got := devicePercentage("/org/bluez/hci0/dev_AA", batteries["/org/bluez/hci0/dev_AA"]); got == nil || *got != 80 {
+		t.Errorf("expected 80%%, got %v", got)
+	}
+
+	bb := batteries["/org/bluez/hci0/dev_BB"]
+	if len(bb) != 2 || bb[0].Path != "/org/bluez/hci0/dev_BB/battery_0" {
+		t.Fatalf("expected 2 sorted provider batteries, got %+v", bb)
+	}
+	if got := devicePercentage("/org/bluez/hci0/dev_BB", bb); got == nil || *got != 40 {
+		t.Errorf("expected lowest provider battery, got %v", got)
+	}
+}
+
+func TestDeviceInfoParsing(t *testing.T) {
+	m := parseModalias("usb:v046DpB023d0011")
+	if m == nil || m.Source != "usb" || m.Vendor != 0x046D || m.Product != 0xB023 || m.Version != 0x0011 {
+		t.Errorf("unexpected modalias: %+v", m)
+	}
+	if parseModalias("bluetooth:vXYZ") != nil {
+		t.Error("expected invalid modalias to be rejected")
+	}
+
+	profiles := profilesFromUUIDs([]string{
+		"0000110B-0000-1000-8000-00805F9B34FB",
+		"0000111e-0000-1000-8000-00805f9b34fb",
+		"00001800-0000-1000-8000-00805f9b34fb",
+		"6e400001-b5a3-f393-e0a9-e50e24dcca9e",
+	})
+	if !slices.Equal(profiles, []string{"A2DP Sink", "HFP"}) {
+		t.Errorf("unexpected profiles: %v", profiles)
+	}
+}
@@ -0,0 +1,83 @@
+package bluez
+
+import (
+	"encoding/json"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/AvengeMedia/DankMaterialShell/core/internal/log"
+	"github.com/AvengeMedia/DankMaterialShell/core/internal/utils"
+)
+
+// Config holds the Bluetooth preferences DMS keeps itself, as opposed to
+// the ones BlueZ already persists per adapter and device.
+type Config struct {
+	ObexReceiveDir  string           `json:"obexReceiveDir,omitempty"`
+	DefaultAdapter  string           `json:"defaultAdapter,omitempty"`
+	DiscoveryFilter *DiscoveryFilter `json:"discoveryFilter,omitempty"`
+	// AdapterPowered is the last powered state seen for each adapter, keyed
+	// by upper-case adapter address.
+	AdapterPowered map[string]bool `json:"adapterPowered,omitempty"`
+	Policy         Policy          `json:"policy"`
+}
+
+func (c Config) clone() Config {
+	c.AdapterPowered = maps.Clone(c.AdapterPowered)
+	c.Policy.Devices = slices.Clone(c.Policy.Devices)
+	c.Policy.Groups = slices.Clone(c.Policy.Groups)
+	return c
+}
+
+func configPath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "DankMaterialShell", "bluetooth.json"), nil
+}
+
+func loadConfig() Config {
+	var cfg Config
+	path, err := configPath()
+	if err != nil {
+		return cfg
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		log.Warnf("Invalid bluetooth config %s: %v", path, err)
+		return Config{}
+	}
+	return cfg
+}
+
+func saveConfig(cfg Config) error {
+	path, err := configPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// obexReceiveDir falls back to the XDG download directory, then ~/Downloads.
+func (c Config) obexReceiveDir() string {
+	if c.ObexReceiveDir != "" {
+		return c.ObexReceiveDir
+	}
+	if dir := utils.XDGDownloadDir(); dir != "" {
+		return dir
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, "Downloads")
+}
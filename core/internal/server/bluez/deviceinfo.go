@@ -0,0 +1,87 @@
+package bluez
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Modalias is the parsed form of Device1.Modalias, e.g.
+// "usb:v046Dp0B34d0001" or "bluetooth:v004Cp200Ed0100".
+type Modalias struct {
+	Source  string `json:"source"`
+	Vendor  uint16 `json:"vendor"`
+	Product uint16 `json:"product"`
+	Version uint16 `json:"version"`
+}
+
+func parseModalias(s string) *Modalias {
+	source, rest, ok := strings.Cut(s, ":")
+	if !ok || source == "" {
+		return nil
+	}
+
+	m := &Modalias{Source: source}
+	fields := []struct {
+		key byte
+		dst *uint16
+	}{{'v', &m.Vendor}, {'p', &m.Product}, {'d', &m.Version}}
+
+	for _, f := range fields {
+		if len(rest) < 5 || rest[0] != f.key {
+			return nil
+		}
+		v, err := strconv.ParseUint(rest[1:5], 16, 16)
+		if err != nil {
+			return nil
+		}
+		*f.dst = uint16(v)
+		rest = rest[5:]
+	}
+	return m
+}
+
+// profileNames maps the 16-bit assigned numbers of common service classes to
+// short names. Anything else is left out of Device.Profiles but stays in UUIDs.
+var profileNames = map[string]string{
+	"1101": "SPP",
+	"1103": "DUN",
+	"1105": "OPP",
+	"1106": "FTP",
+	"1108": "HSP",
+	"110a": "A2DP Source",
+	"110b": "A2DP Sink",
+	"110c": "AVRCP Target",
+	"110e": "AVRCP",
+	"1112": "HSP AG",
+	"1115": "PANU",
+	"1116": "NAP",
+	"111e": "HFP",
+	"111f": "HFP AG",
+	"1124": "HID",
+	"112f": "PBAP",
+	"1132": "MAP",
+	"1200": "PnP",
+	"180f": "Battery",
+	"1812": "HID over GATT",
+	"184e": "ASCS",
+	"1850": "PACS",
+	"1853": "CAS",
+}
+
+const baseUUIDSuffix = "-0000-1000-8000-00805f9b34fb"
+
+func profilesFromUUIDs(uuids []string) []string {
+	profiles := []string{}
+	for _, uuid := range uuids {
+		uuid = strings.ToLower(uuid)
+		if len(uuid) != 36 || !strings.HasPrefix(uuid, "0000") || !strings.HasSuffix(uuid, baseUUIDSuffix) {
+			continue
+		}
+		name, ok := profileNames[uuid[4:8]]
+		if !ok {
+			continue
+		}
+		profiles = append(profiles, name)
+	}
+	return profiles
+}
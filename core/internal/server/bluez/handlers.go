@@ -1,7 +1,10 @@
 package bluez
 
 import (
+	"encoding/json"
 	"fmt"
+	"math"
+	"path/filepath"
 
 	"github.com/AvengeMedia/DankMaterialShell/core/internal/server/models"
 	"github.com/AvengeMedia/dankgo/ipc/params"
@@ -22,6 +25,14 @@ func HandleRequest(conn *models.Conn, req models.Request, manager *Manager) {
 		handleStopDiscovery(conn, req, manager)
 	case "bluetooth.setPowered":
 		handleSetPowered(conn, req, manager)
+	case "bluetooth.setAlias":
+		handleSetAlias(conn, req, manager)
+	case "bluetooth.setDiscoverable", "bluetooth.setPairable":
+		handleSetVisibility(conn, req, manager)
+	case "bluetooth.setDefaultAdapter":
+		handleSetDefaultAdapter(conn, req, manager)
+	case "bluetooth.setDiscoveryFilter":
+		handleSetDiscoveryFilter(conn, req, manager)
 	case "bluetooth.pair":
 		handlePairDevice(conn, req, manager)
 	case "bluetooth.connect":
@@ -40,6 +51,27 @@ func HandleRequest(conn *models.Conn, req models.Request, manager *Manager) {
 		handlePairingSubmit(conn, req, manager)
 	case "bluetooth.pairing.cancel":
 		handlePairingCancel(conn, req, manager)
+	case "bluetooth.obex.getState":
+		models.Respond(conn, req.ID, manager.GetObexState())
+	case "bluetooth.obex.send":
+		handleObexSend(conn, req, manager)
+	case "bluetooth.obex.cancel":
+		handleObexCancel(conn, req, manager)
+	case "bluetooth.obex.setReceiveDir":
+		handleObexSetReceiveDir(conn, req, manager)
+	case "bluetooth.policy.getState":
+		models.Respond(conn, req.ID, manager.GetPolicyState())
+	case "bluetooth.policy.set":
+		handlePolicySet(conn, req, manager)
+	case "bluetooth.policy.setDevice":
+		handlePolicySetDevice(conn, req, manager)
+	case "bluetooth.policy.setGroups":
+		handlePolicySetGroups(conn, req, manager)
+	case "bluetooth.policy.reconnect":
+		manager.Reconnect()
+		models.Respond(conn, req.ID, manager.GetPolicyState())
+	case "bluetooth.obex.subscribe":
+		handleObexSubscribe(conn, req, manager)
 	default:
 		models.RespondError(conn, req.ID, fmt.Sprintf("unknown method: %s", req.Method))
 	}
@@ -80,6 +112,94 @@ func handleSetPowered(conn *models.Conn, req models.Request, manager *Manager) {
 	models.Respond(conn, req.ID, models.SuccessResult{Success: true, Message: "powered state updated"})
 }
 
+func handleSetAlias(conn *models.Conn, req models.Request, manager *Manager) {
+	alias, err := params.String(req.Params, "alias")
+	if err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+
+	if err := manager.SetAlias(params.StringOpt(req.Params, "adapter", ""), alias); err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+
+	models.Respond(conn, req.ID, models.SuccessResult{Success: true, Message: "alias updated"})
+}
+
+// handleSetVisibility serves setDiscoverable and setPairable, which share
+// their shape: a toggle plus an optional timeout in seconds (0 = no limit).
+func handleSetVisibility(conn *models.Conn, req models.Request, manager *Manager) {
+	key, set, message := "discoverable", manager.SetDiscoverable, "discoverable state updated"
+	if req.Method == "bluetooth.setPairable" {
+		key, set, message = "pairable", manager.SetPairable, "pairable state updated"
+	}
+
+	enabled, err := params.Bool(req.Params, key)
+	if err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+
+	var timeout *uint32
+	if v, ok := models.Get[float64](req, "timeout"); ok {
+		if v < 0 || v > math.MaxUint32 {
+			models.RespondError(conn, req.ID, "invalid 'timeout' parameter")
+			return
+		}
+		t := uint32(v)
+		timeout = &t
+	}
+
+	if err := set(params.StringOpt(req.Params, "adapter", ""), enabled, timeout); err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+
+	models.Respond(conn, req.ID, models.SuccessResult{Success: true, Message: message})
+}
+
+func handleSetDefaultAdapter(conn *models.Conn, req models.Request, manager *Manager) {
+	if err := manager.SetDefaultAdapter(params.StringOpt(req.Params, "adapter", "")); err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+
+	models.Respond(conn, req.ID, manager.GetState())
+}
+
+func handleSetDiscoveryFilter(conn *models.Conn, req models.Request, manager *Manager) {
+	filter := &DiscoveryFilter{
+		Transport: params.StringOpt(req.Params, "transport", ""),
+		Pattern:   params.StringOpt(req.Params, "pattern", ""),
+	}
+	if rssi, ok := models.Get[float64](req, "rssi"); ok {
+		if rssi < math.MinInt16 || rssi > math.MaxInt16 {
+			models.RespondError(conn, req.ID, "invalid 'rssi' parameter")
+			return
+		}
+		v := int16(rssi)
+		filter.RSSI = &v
+	}
+	if uuids, ok := models.Get[[]any](req, "uuids"); ok {
+		for _, u := range uuids {
+			s, ok := u.(string)
+			if !ok {
+				models.RespondError(conn, req.ID, "invalid 'uuids' parameter")
+				return
+			}
+			filter.UUIDs = append(filter.UUIDs, s)
+		}
+	}
+
+	if err := manager.SetDiscoveryFilter(filter); err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+
+	models.Respond(conn, req.ID, manager.GetState())
+}
+
 func handlePairDevice(conn *models.Conn, req models.Request, manager *Manager) {
 	devicePath, err := params.String(req.Params, "device")
 	if err != nil {
@@ -233,3 +353,174 @@ func handleSubscribe(conn *models.Conn, req models.Request, manager *Manager) {
 		}
 	}
 }
+
+// handleObexSend replies with the queued transfer. With follow set it keeps
+// streaming that transfer's updates until it finishes.
+func handleObexSend(conn *models.Conn, req models.Request, manager *Manager) {
+	device, err := params.String(req.Params, "device")
+	if err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+	file, err := params.String(req.Params, "file")
+	if err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+	if !filepath.IsAbs(file) {
+		models.RespondError(conn, req.ID, "file must be an absolute path")
+		return
+	}
+	follow := params.BoolOpt(req.Params, "follow", false)
+
+	var updates chan Transfer
+	if follow && manager.Obex() != nil {
+		clientID := fmt.Sprintf("obex-send-%p-%d", conn, req.ID)
+		updates = manager.Obex().Subscribe(clientID)
+		defer manager.Obex().Unsubscribe(clientID)
+	}
+
+	transfer, err := manager.SendFile(device, file)
+	if err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+	if err := conn.WriteResponse(models.Response[Transfer]{ID: req.ID, Result: &transfer}); err != nil {
+		return
+	}
+	if updates == nil {
+		return
+	}
+
+	for update := range updates {
+		if update.ID != transfer.ID {
+			continue
+		}
+		if err := conn.WriteResponse(models.Response[Transfer]{ID: req.ID, Result: &update}); err != nil {
+			return
+		}
+		if update.Finished() {
+			return
+		}
+	}
+}
+
+func handleObexCancel(conn *models.Conn, req models.Request, manager *Manager) {
+	id, err := params.String(req.Params, "id")
+	if err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+
+	if err := manager.CancelTransfer(id); err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+
+	models.Respond(conn, req.ID, models.SuccessResult{Success: true, Message: "transfer cancelled"})
+}
+
+func handleObexSetReceiveDir(conn *models.Conn, req models.Request, manager *Manager) {
+	if err := manager.SetObexReceiveDir(params.StringOpt(req.Params, "dir", "")); err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+
+	models.Respond(conn, req.ID, manager.GetObexState())
+}
+
+func handleObexSubscribe(conn *models.Conn, req models.Request, manager *Manager) {
+	obex := manager.Obex()
+	if obex == nil {
+		models.RespondError(conn, req.ID, errObexUnavailable.Error())
+		return
+	}
+
+	clientID := fmt.Sprintf("obex-%p", conn)
+	updates := obex.Subscribe(clientID)
+	defer obex.Unsubscribe(clientID)
+
+	initialState := manager.GetObexState()
+	if err := conn.WriteResponse(models.Response[ObexState]{
+		ID:     req.ID,
+		Result: &initialState,
+	}); err != nil {
+		return
+	}
+
+	for range updates {
+		state := manager.GetObexState()
+		if err := conn.WriteResponse(models.Response[ObexState]{
+			ID:     req.ID,
+			Result: &state,
+		}); err != nil {
+			return
+		}
+	}
+}
+
+// decodeParam re-decodes a JSON-shaped parameter into v.
+func decodeParam(req models.Request, key string, v any) error {
+	raw, ok := models.Get[any](req, key)
+	if !ok {
+		return fmt.Errorf("missing or invalid '%s' parameter", key)
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return fmt.Errorf("missing or invalid '%s' parameter", key)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("invalid '%s' parameter: %v", key, err)
+	}
+	return nil
+}
+
+func handlePolicySet(conn *models.Conn, req models.Request, manager *Manager) {
+	var policy Policy
+	if err := decodeParam(req, "policy", &policy); err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+
+	if err := manager.SetPolicy(policy); err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+
+	models.Respond(conn, req.ID, manager.GetPolicyState())
+}
+
+func handlePolicySetDevice(conn *models.Conn, req models.Request, manager *Manager) {
+	device, err := params.String(req.Params, "device")
+	if err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+
+	policy, err := manager.SetDevicePolicy(device, func(d *DevicePolicy) {
+		d.AutoConnect = params.BoolOpt(req.Params, "autoConnect", d.AutoConnect)
+		d.DisconnectOnLock = params.BoolOpt(req.Params, "disconnectOnLock", d.DisconnectOnLock)
+		d.DisconnectOnSuspend = params.BoolOpt(req.Params, "disconnectOnSuspend", d.DisconnectOnSuspend)
+	})
+	if err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+
+	models.Respond(conn, req.ID, policy)
+}
+
+func handlePolicySetGroups(conn *models.Conn, req models.Request, manager *Manager) {
+	var groups []PriorityGroup
+	if err := decodeParam(req, "groups", &groups); err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+
+	if err := manager.SetPriorityGroups(groups); err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+
+	models.Respond(conn, req.ID, manager.GetPolicyState())
+}
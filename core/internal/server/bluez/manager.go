@@ -14,6 +14,7 @@ import (
 
 const (
 	adapter1Iface   = "org.bluez.Adapter1"
+	battery1Iface   = "org.bluez.Battery1"
 	objectMgrIface  = "org.freedesktop.DBus.ObjectManager"
 	propertiesIface = "org.freedesktop.DBus.Properties"
 )
@@ -39,7 +40,11 @@ func NewManager() (*Manager, error) {
 			PairedDevices:    []Device{},
 			ConnectedDevices: []Device{},
 		},
-		stateMutex: sync.RWMutex{},
+		stateMutex:     sync.RWMutex{},
+		config:         loadConfig(),
+		connectedSince: make(map[string]time.Time),
+		batteryLevels:  make(map[string]string),
+		policyPending:  make(map[string]bool),
 
 		stopChan:   make(chan struct{}),
 		dbusConn:   conn,
@@ -48,6 +53,8 @@ func NewManager() (*Manager, error) {
 		eventQueue: make(chan func(), 32),
 	}
 
+	m.state.DiscoveryFilter = m.config.DiscoveryFilter
+
 	broker := NewSubscriptionBroker(m.broadcastPairingPrompt)
 	m.promptBroker = broker
 
@@ -64,11 +71,18 @@ func NewManager() (*Manager, error) {
 	if err := m.initialize(); err != nil {
 		return nil, err
 	}
+	m.restorePowered()
 
 	if err := m.startAgent(); err != nil {
 		return nil, fmt.Errorf("agent start failed: %w", err)
 	}
 
+	if obex, err := newObex(m); err != nil {
+		log.Warnf("[BluezManager] OBEX file transfer unavailable: %v", err)
+	} else {
+		m.obex = obex
+	}
+
 	if err := m.startSignalPump(); err != nil {
 		m.Close()
 		return nil, err
@@ -108,6 +122,8 @@ func (m *Manager) adapterPathsSnapshot() []dbus.ObjectPath {
 }
 
 func (m *Manager) resolveAdapter(adapterPath string) (dbus.ObjectPath, error) {
+	preferred := m.preferredAdapter()
+
 	m.stateMutex.RLock()
 	defer m.stateMutex.RUnlock()
 
@@ -115,7 +131,7 @@ func (m *Manager) resolveAdapter(adapterPath string) (dbus.ObjectPath, error) {
 		return "", ErrNoAdapter
 	}
 	if adapterPath == "" {
-		return m.adapterPaths[0], nil
+		return m.defaultAdapterLocked(preferred), nil
 	}
 	if !slices.Contains(m.adapterPaths, dbus.ObjectPath(adapterPath)) {
 		return "", fmt.Errorf("unknown adapter: %s", adapterPath)
@@ -142,6 +158,7 @@ func (m *Manager) refreshAdapters() {
 	if err := m.updateAdapterState(); err != nil {
 		log.Warnf("[BluezManager] adapter state refresh failed: %v", err)
 	}
+	m.restorePowered()
 	m.notifySubscribers()
 }
 
@@ -162,33 +179,21 @@ func (m *Manager) updateAdapterState() error {
 	adapters := make([]AdapterInfo, 0, len(paths))
 
 	for _, path := range paths {
+		var props map[string]dbus.Variant
 		obj := m.dbusConn.Object(bluezService, path)
-
-		poweredVar, err := obj.GetProperty(adapter1Iface + ".Powered")
-		if err != nil {
+		if err := obj.Call(propertiesIface+".GetAll", 0, adapter1Iface).Store(&props); err != nil {
 			return err
 		}
-		discoveringVar, err := obj.GetProperty(adapter1Iface + ".Discovering")
-		if err != nil {
-			return err
-		}
-		aliasVar, _ := obj.GetProperty(adapter1Iface + ".Alias")
-		addressVar, _ := obj.GetProperty(adapter1Iface + ".Address")
 
-		adapters = append(adapters, AdapterInfo{
-			Path:        string(path),
-			Name:        dbusutil.AsOr(aliasVar, ""),
-			Address:     dbusutil.AsOr(addressVar, ""),
-			Powered:     dbusutil.AsOr(poweredVar, false),
-			Discovering: dbusutil.AsOr(discoveringVar, false),
-		})
+		adapter := AdapterInfo{Path: string(path)}
+		applyAdapterProps(&adapter, props)
+		adapters = append(adapters, adapter)
 	}
 
 	m.stateMutex.Lock()
 	m.state.Adapters = adapters
-	m.state.Powered = len(adapters) > 0 && adapters[0].Powered
-	m.state.Discovering = len(adapters) > 0 && adapters[0].Discovering
 	m.stateMutex.Unlock()
+	m.refreshDefaultAdapter()
 
 	return nil
 }
@@ -202,10 +207,13 @@ func (m *Manager) updateDevices() error {
 	}
 
 	adapters := m.adapterPathsSnapshot()
+	batteries := collectBatteries(objects)
 	devices := []Device{}
 	paired := []Device{}
 	connected := []Device{}
+	now := time.Now()
 
+	m.stateMutex.Lock()
 	for path, interfaces := range objects {
 		devProps, ok := interfaces[device1Iface]
 		if !ok {
@@ -217,6 +225,16 @@ func (m *Manager) updateDevices() error {
 		}
 
 		dev := m.deviceFromProps(string(path), devProps)
+		dev.Batteries = batteries[dev.Path]
+		dev.Battery = devicePercentage(dev.Path, dev.Batteries)
+		if dev.Connected {
+			since, ok := m.connectedSince[dev.Path]
+			if !ok {
+				since = now
+				m.connectedSince[dev.Path] = since
+			}
+			dev.ConnectedSince = &since
+		}
 		devices = append(devices, dev)
 
 		if dev.Paired {
@@ -227,12 +245,23 @@ func (m *Manager) updateDevices() error {
 		}
 	}
 
-	m.stateMutex.Lock()
+	for path := range m.connectedSince {
+		if !slices.ContainsFunc(connected, func(d Device) bool { return d.Path == path }) {
+			delete(m.connectedSince, path)
+		}
+	}
+	events := batteryEvents(devices, m.batteryLevels)
+
 	m.state.Devices = devices
 	m.state.PairedDevices = paired
 	m.state.ConnectedDevices = connected
 	m.stateMutex.Unlock()
 
+	for _, event := range events {
+		log.Infof("[BluezManager] %s battery on %s: %d%%", event.Type, event.DeviceName, event.Percentage)
+		m.broadcastBatteryEvent(event)
+	}
+
 	return nil
 }
 
@@ -246,7 +275,8 @@ func deviceOnAnyAdapter(path dbus.ObjectPath, adapters []dbus.ObjectPath) bool {
 }
 
 func (m *Manager) deviceFromProps(path string, props map[string]dbus.Variant) Device {
-	return Device{
+	uuids := dbusutil.GetOr(props, "UUIDs", []string{})
+	dev := Device{
 		Path:          path,
 		Address:       dbusutil.GetOr(props, "Address", ""),
 		Name:          dbusutil.GetOr(props, "Name", ""),
@@ -259,7 +289,16 @@ func (m *Manager) deviceFromProps(path string, props map[string]dbus.Variant) De
 		Icon:          dbusutil.GetOr(props, "Icon", ""),
 		RSSI:          dbusutil.GetOr(props, "RSSI", int16(0)),
 		LegacyPairing: dbusutil.GetOr(props, "LegacyPairing", false),
+
+		ServicesResolved: dbusutil.GetOr(props, "ServicesResolved", false),
+		UUIDs:            uuids,
+		Profiles:         profilesFromUUIDs(uuids),
+		Modalias:         parseModalias(dbusutil.GetOr(props, "Modalias", "")),
 	}
+	if txPower, ok := dbusutil.Get[int16](props, "TxPower"); ok {
+		dev.TxPower = &txPower
+	}
+	return dev
 }
 
 func (m *Manager) startAgent() error {
@@ -327,6 +366,10 @@ func (m *Manager) handleSignal(sig *dbus.Signal) {
 			m.handleAdapterPropertiesChanged(sig.Path, changed)
 		case device1Iface:
 			m.handleDevicePropertiesChanged(sig.Path, changed)
+		case battery1Iface:
+			if _, ok := changed["Percentage"]; ok {
+				m.notifySubscribers()
+			}
 		}
 
 	case objectMgrIface + ".InterfacesAdded":
@@ -365,47 +408,45 @@ func signalTouchesAdapter(sig *dbus.Signal) bool {
 }
 
 func (m *Manager) handleAdapterPropertiesChanged(path dbus.ObjectPath, changed map[string]dbus.Variant) {
-	powered, hasPowered := dbusutil.Get[bool](changed, "Powered")
-	discovering, hasDiscovering := dbusutil.Get[bool](changed, "Discovering")
-	if !hasPowered && !hasDiscovering {
-		return
-	}
-
 	m.stateMutex.Lock()
 	dirty := false
+	poweredOn, poweredOff := false, false
+	var address string
 	for i := range m.state.Adapters {
 		if m.state.Adapters[i].Path != string(path) {
 			continue
 		}
-		if hasPowered {
-			m.state.Adapters[i].Powered = powered
-		}
-		if hasDiscovering {
-			m.state.Adapters[i].Discovering = discovering
-		}
-		dirty = true
-	}
-	if len(m.adapterPaths) > 0 && m.adapterPaths[0] == path {
-		if hasPowered {
-			m.state.Powered = powered
-		}
-		if hasDiscovering {
-			m.state.Discovering = discovering
-		}
-		dirty = true
+		wasPowered := m.state.Adapters[i].Powered
+		dirty = applyAdapterProps(&m.state.Adapters[i], changed)
+		poweredOn = !wasPowered && m.state.Adapters[i].Powered
+		poweredOff = wasPowered && !m.state.Adapters[i].Powered
+		address = m.state.Adapters[i].Address
 	}
 	m.stateMutex.Unlock()
 
 	if !dirty {
 		return
 	}
+	if poweredOn || poweredOff {
+		m.rememberPowered(address, poweredOn)
+	}
+	m.refreshDefaultAdapter()
 	m.notifySubscribers()
+
+	switch {
+	case poweredOn:
+		m.startPolicyRun(TriggerPower, powerOnSettleDelay, nil)
+	case poweredOff:
+		m.cancelPolicyRun()
+	}
 }
 
 func (m *Manager) handleDevicePropertiesChanged(path dbus.ObjectPath, changed map[string]dbus.Variant) {
 	paired, hasPaired := dbusutil.Get[bool](changed, "Paired")
 	_, hasConnected := changed["Connected"]
 	_, hasTrusted := changed["Trusted"]
+	_, hasResolved := changed["ServicesResolved"]
+	_, hasUUIDs := changed["UUIDs"]
 
 	if hasPaired {
 		devicePath := string(path)
@@ -432,7 +473,7 @@ func (m *Manager) handleDevicePropertiesChanged(path dbus.ObjectPath, changed ma
 		}
 	}
 
-	if hasPaired || hasConnected || hasTrusted {
+	if hasPaired || hasConnected || hasTrusted || hasResolved || hasUUIDs {
 		select {
 		case m.eventQueue <- func() {
 			time.Sleep(100 * time.Millisecond)
@@ -548,6 +589,28 @@ func (m *Manager) UnsubscribePairing(id string) {
 	}
 }
 
+func (m *Manager) SubscribeBattery(id string) chan BatteryEvent {
+	ch := make(chan BatteryEvent, 16)
+	m.batterySubscribers.Store(id, ch)
+	return ch
+}
+
+func (m *Manager) UnsubscribeBattery(id string) {
+	if ch, ok := m.batterySubscribers.LoadAndDelete(id); ok {
+		close(ch)
+	}
+}
+
+func (m *Manager) broadcastBatteryEvent(event BatteryEvent) {
+	m.batterySubscribers.Range(func(key string, ch chan BatteryEvent) bool {
+		select {
+		case ch <- event:
+		default:
+		}
+		return true
+	})
+}
+
 func (m *Manager) broadcastPairingPrompt(prompt PairingPrompt) {
 	m.pairingSubscribers.Range(func(key string, ch chan PairingPrompt) bool {
 		select {
@@ -586,6 +649,9 @@ func (m *Manager) StartDiscovery(adapterPath string) error {
 		return err
 	}
 	obj := m.dbusConn.Object(bluezService, path)
+	if err := obj.Call(adapter1Iface+".SetDiscoveryFilter", 0, m.discoveryFilter().toDBus()).Err; err != nil {
+		log.Warnf("[BluezManager] discovery filter not applied: %v", err)
+	}
 	return obj.Call(adapter1Iface+".StartDiscovery", 0).Err
 }
 
@@ -608,8 +674,11 @@ func (m *Manager) SetPowered(adapterPath string, powered bool) error {
 			log.Debugf("[BluezManager] rfkill unblock failed: %v", err)
 		}
 	}
-	obj := m.dbusConn.Object(bluezService, path)
-	return obj.Call(propertiesIface+".Set", 0, adapter1Iface, "Powered", dbus.MakeVariant(powered)).Err
+	if err := m.setAdapterPowered(path, powered); err != nil {
+		return err
+	}
+	m.rememberPowered(m.adapterAddress(path), powered)
+	return nil
 }
 
 func (m *Manager) PairDevice(devicePath string) error {
@@ -630,7 +699,10 @@ func (m *Manager) ConnectDevice(devicePath string) error {
 	return obj.Call(device1Iface+".Connect", 0).Err
 }
 
+// DisconnectDevice also stops a pending auto-connect so it can't undo an
+// explicit disconnect.
 func (m *Manager) DisconnectDevice(devicePath string) error {
+	m.cancelPolicyRun()
 	obj := m.dbusConn.Object(bluezService, dbus.ObjectPath(devicePath))
 	return obj.Call(device1Iface+".Disconnect", 0).Err
 }
@@ -653,6 +725,7 @@ func (m *Manager) Close() {
 	close(m.stopChan)
 	m.notifierWg.Wait()
 	m.eventWg.Wait()
+	m.policyWg.Wait()
 
 	m.sigWG.Wait()
 
@@ -668,6 +741,10 @@ func (m *Manager) Close() {
 		m.agent.Close()
 	}
 
+	if m.obex != nil {
+		m.obex.Close()
+	}
+
 	m.subscribers.Range(func(key string, ch chan BluetoothState) bool {
 		close(ch)
 		m.subscribers.Delete(key)
@@ -679,6 +756,12 @@ func (m *Manager) Close() {
 		m.pairingSubscribers.Delete(key)
 		return true
 	})
+
+	m.batterySubscribers.Range(func(key string, ch chan BatteryEvent) bool {
+		close(ch)
+		m.batterySubscribers.Delete(key)
+		return true
+	})
 }
 
 func stateChanged(old, new *BluetoothState) bool {
@@ -688,6 +771,9 @@ func stateChanged(old, new *BluetoothState) bool {
 	if old.Discovering != new.Discovering {
 		return true
 	}
+	if old.DefaultAdapter != new.DefaultAdapter || old.DiscoveryFilter != new.DiscoveryFilter {
+		return true
+	}
 	if !slices.Equal(old.Adapters, new.Adapters) {
 		return true
 	}
@@ -701,15 +787,22 @@ func stateChanged(old, new *BluetoothState) bool {
 		return true
 	}
 	for i := range old.Devices {
-		if old.Devices[i].Path != new.Devices[i].Path {
-			return true
-		}
-		if old.Devices[i].Paired != new.Devices[i].Paired {
-			return true
-		}
-		if old.Devices[i].Connected != new.Devices[i].Connected {
+		if deviceChanged(&old.Devices[i], &new.Devices[i]) {
 			return true
 		}
 	}
 	return false
 }
+
+func deviceChanged(old, new *Device) bool {
+	if old.Path != new.Path || old.Paired != new.Paired || old.Connected != new.Connected {
+		return true
+	}
+	if old.ServicesResolved != new.ServicesResolved || !slices.Equal(old.UUIDs, new.UUIDs) {
+		return true
+	}
+	if !slices.Equal(old.Batteries, new.Batteries) {
+		return true
+	}
+	return false
+}
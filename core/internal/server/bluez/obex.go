@@ -0,0 +1,470 @@
+package bluez
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AvengeMedia/DankMaterialShell/core/internal/log"
+	"github.com/AvengeMedia/DankMaterialShell/core/internal/utils"
+	"github.com/AvengeMedia/dankgo/dbusutil"
+	"github.com/AvengeMedia/dankgo/syncmap"
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	obexService       = "org.bluez.obex"
+	obexPath          = "/org/bluez/obex"
+	obexClientIface   = "org.bluez.obex.Client1"
+	obexSessionIface  = "org.bluez.obex.Session1"
+	obexPushIface     = "org.bluez.obex.ObjectPush1"
+	obexTransferIface = "org.bluez.obex.Transfer1"
+
+	// obexConnectTimeout covers CreateSession, which returns only once the
+	// remote side has accepted the OBEX connection.
+	obexConnectTimeout   = 60 * time.Second
+	maxFinishedTransfers = 20
+)
+
+const (
+	TransferOutgoing = "outgoing"
+	TransferIncoming = "incoming"
+
+	TransferQueued    = "queued"
+	TransferActive    = "active"
+	TransferSuspended = "suspended"
+	TransferComplete  = "complete"
+	TransferError     = "error"
+	TransferCancelled = "cancelled"
+)
+
+type Transfer struct {
+	ID          string     `json:"id"`
+	Direction   string     `json:"direction"`
+	DevicePath  string     `json:"devicePath"`
+	DeviceName  string     `json:"deviceName"`
+	DeviceAddr  string     `json:"deviceAddr"`
+	Name        string     `json:"name"`
+	Filename    string     `json:"filename"`
+	Size        uint64     `json:"size"`
+	Transferred uint64     `json:"transferred"`
+	Status      string     `json:"status"`
+	StartedAt   time.Time  `json:"startedAt"`
+	FinishedAt  *time.Time `json:"finishedAt,omitempty"`
+
+	session   dbus.ObjectPath
+	cancelled bool
+}
+
+func (t *Transfer) Finished() bool {
+	switch t.Status {
+	case TransferComplete, TransferError, TransferCancelled:
+		return true
+	}
+	return false
+}
+
+type ObexState struct {
+	Available  bool       `json:"available"`
+	ReceiveDir string     `json:"receiveDir"`
+	Transfers  []Transfer `json:"transfers"`
+}
+
+// Obex drives obexd, which lives on the session bus rather than the system
+// bus the rest of the package talks to.
+type Obex struct {
+	conn        *dbus.Conn
+	manager     *Manager
+	agent       *obexAgent
+	mu          sync.Mutex
+	transfers   []*Transfer
+	subscribers syncmap.Map[string, chan Transfer]
+	signals     chan *dbus.Signal
+	stopChan    chan struct{}
+	wg          sync.WaitGroup
+	closeOnce   sync.Once
+}
+
+var obexMatchRule = []dbus.MatchOption{
+	dbus.WithMatchSender(obexService),
+	dbus.WithMatchInterface(propertiesIface),
+	dbus.WithMatchMember("PropertiesChanged"),
+	dbus.WithMatchArg(0, obexTransferIface),
+}
+
+func newObex(m *Manager) (*Obex, error) {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return nil, fmt.Errorf("session bus connection failed: %w", err)
+	}
+
+	o := &Obex{
+		conn:     conn,
+		manager:  m,
+		signals:  make(chan *dbus.Signal, 256),
+		stopChan: make(chan struct{}),
+	}
+
+	if err := conn.AddMatchSignal(obexMatchRule...); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.Signal(o.signals)
+
+	agent, err := newObexAgent(o)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	o.agent = agent
+
+	o.wg.Go(o.signalLoop)
+
+	log.Infof("[BluezObex] agent registered at %s", obexAgentPath)
+	return o, nil
+}
+
+func (o *Obex) signalLoop() {
+	for {
+		select {
+		case <-o.stopChan:
+			return
+		case sig, ok := <-o.signals:
+			if !ok {
+				return
+			}
+			if sig == nil || sig.Name != propertiesIface+".PropertiesChanged" || len(sig.Body) < 2 {
+				continue
+			}
+			changed, ok := sig.Body[1].(map[string]dbus.Variant)
+			if !ok {
+				continue
+			}
+			o.applyChanges(sig.Path, changed)
+		}
+	}
+}
+
+func (o *Obex) applyChanges(path dbus.ObjectPath, changed map[string]dbus.Variant) {
+	o.mu.Lock()
+	idx := slices.IndexFunc(o.transfers, func(t *Transfer) bool { return t.ID == string(path) })
+	if idx < 0 || o.transfers[idx].Finished() {
+		o.mu.Unlock()
+		return
+	}
+
+	t := o.transfers[idx]
+	if v, ok := dbusutil.Get[uint64](changed, "Transferred"); ok {
+		t.Transferred = v
+	}
+	if v, ok := dbusutil.Get[uint64](changed, "Size"); ok {
+		t.Size = v
+	}
+	if v, ok := dbusutil.Get[string](changed, "Filename"); ok && v != "" {
+		t.Filename = v
+	}
+	if v, ok := dbusutil.Get[string](changed, "Status"); ok {
+		t.Status = v
+		if v == TransferError && t.cancelled {
+			t.Status = TransferCancelled
+		}
+	}
+
+	finished := t.Finished()
+	if finished {
+		now := time.Now()
+		t.FinishedAt = &now
+		if t.Status == TransferComplete {
+			t.Transferred = t.Size
+		}
+		o.pruneLocked()
+	}
+	snapshot := *t
+	o.mu.Unlock()
+
+	if finished {
+		log.Infof("[BluezObex] %s transfer of %s (%s): %s", snapshot.Direction, snapshot.Name, snapshot.DeviceName, snapshot.Status)
+		if snapshot.session != "" {
+			o.removeSession(snapshot.session)
+		}
+	}
+	o.broadcast(snapshot)
+}
+
+// pruneLocked drops the oldest finished transfers past maxFinishedTransfers.
+func (o *Obex) pruneLocked() {
+	finished := 0
+	for i := len(o.transfers) - 1; i >= 0; i-- {
+		if !o.transfers[i].Finished() {
+			continue
+		}
+		finished++
+		if finished > maxFinishedTransfers {
+			o.transfers = slices.Delete(o.transfers, i, i+1)
+		}
+	}
+}
+
+func (o *Obex) removeSession(session dbus.ObjectPath) {
+	client := o.conn.Object(obexService, obexPath)
+	if err := client.Call(obexClientIface+".RemoveSession", 0, session).Err; err != nil {
+		log.Debugf("[BluezObex] RemoveSession %s: %v", session, err)
+	}
+}
+
+func (o *Obex) track(t *Transfer) {
+	o.transfers = append(o.transfers, t)
+	o.broadcast(*t)
+}
+
+// SendFile pushes path to dev over the Object Push profile. path must be
+// absolute; the server's working directory means nothing to the caller. It
+// returns once obexd has queued the transfer; progress follows through
+// Subscribe.
+func (o *Obex) SendFile(dev Device, adapterAddr, path string) (Transfer, error) {
+	if !filepath.IsAbs(path) {
+		return Transfer{}, fmt.Errorf("not an absolute path: %s", path)
+	}
+	abs := filepath.Clean(path)
+	info, err := os.Stat(abs)
+	if err != nil {
+		return Transfer{}, err
+	}
+	if !info.Mode().IsRegular() {
+		return Transfer{}, fmt.Errorf("not a regular file: %s", abs)
+	}
+
+	args := map[string]dbus.Variant{"Target": dbus.MakeVariant("opp")}
+	if adapterAddr != "" {
+		args["Source"] = dbus.MakeVariant(adapterAddr)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), obexConnectTimeout)
+	defer cancel()
+
+	var session dbus.ObjectPath
+	client := o.conn.Object(obexService, obexPath)
+	if err := client.CallWithContext(ctx, obexClientIface+".CreateSession", 0, dev.Address, args).Store(&session); err != nil {
+		return Transfer{}, fmt.Errorf("obex connection failed: %w", err)
+	}
+
+	// Hold the lock across SendFile so signals for the new transfer wait
+	// until it is tracked.
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	var transferPath dbus.ObjectPath
+	var props map[string]dbus.Variant
+	if err := o.conn.Object(obexService, session).Call(obexPushIface+".SendFile", 0, abs).Store(&transferPath, &props); err != nil {
+		go o.removeSession(session)
+		return Transfer{}, fmt.Errorf("send failed: %w", err)
+	}
+
+	t := &Transfer{
+		ID:         string(transferPath),
+		Direction:  TransferOutgoing,
+		DevicePath: dev.Path,
+		DeviceName: deviceDisplayName(dev),
+		DeviceAddr: dev.Address,
+		Name:       dbusutil.GetOr(props, "Name", filepath.Base(abs)),
+		Filename:   abs,
+		Size:       dbusutil.GetOr(props, "Size", uint64(info.Size())),
+		Status:     dbusutil.GetOr(props, "Status", TransferQueued),
+		StartedAt:  time.Now(),
+		session:    session,
+	}
+	o.track(t)
+
+	log.Infof("[BluezObex] sending %s to %s", t.Name, t.DeviceName)
+	return *t, nil
+}
+
+func (o *Obex) Cancel(id string) error {
+	o.mu.Lock()
+	idx := slices.IndexFunc(o.transfers, func(t *Transfer) bool { return t.ID == id })
+	if idx < 0 {
+		o.mu.Unlock()
+		return fmt.Errorf("unknown transfer: %s", id)
+	}
+	t := o.transfers[idx]
+	if t.Finished() {
+		o.mu.Unlock()
+		return fmt.Errorf("transfer already %s", t.Status)
+	}
+	t.cancelled = true
+	o.mu.Unlock()
+
+	if err := o.conn.Object(obexService, dbus.ObjectPath(id)).Call(obexTransferIface+".Cancel", 0).Err; err != nil {
+		o.mu.Lock()
+		t.cancelled = false
+		o.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+func (o *Obex) Transfers() []Transfer {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	transfers := make([]Transfer, 0, len(o.transfers))
+	for _, t := range o.transfers {
+		transfers = append(transfers, *t)
+	}
+	return transfers
+}
+
+func (o *Obex) Subscribe(id string) chan Transfer {
+	ch := make(chan Transfer, 64)
+	o.subscribers.Store(id, ch)
+	return ch
+}
+
+func (o *Obex) Unsubscribe(id string) {
+	if ch, ok := o.subscribers.LoadAndDelete(id); ok {
+		close(ch)
+	}
+}
+
+func (o *Obex) broadcast(t Transfer) {
+	o.subscribers.Range(func(key string, ch chan Transfer) bool {
+		select {
+		case ch <- t:
+		default:
+		}
+		return true
+	})
+}
+
+func (o *Obex) Close() {
+	o.closeOnce.Do(func() {
+		close(o.stopChan)
+		o.wg.Wait()
+
+		o.agent.Close()
+		o.conn.RemoveSignal(o.signals)
+		_ = o.conn.RemoveMatchSignal(obexMatchRule...)
+
+		o.mu.Lock()
+		for _, t := range o.transfers {
+			if t.session != "" && !t.Finished() {
+				o.removeSession(t.session)
+			}
+		}
+		o.mu.Unlock()
+
+		o.subscribers.Range(func(key string, ch chan Transfer) bool {
+			close(ch)
+			o.subscribers.Delete(key)
+			return true
+		})
+		o.conn.Close()
+	})
+}
+
+func deviceDisplayName(dev Device) string {
+	switch {
+	case dev.Alias != "":
+		return dev.Alias
+	case dev.Name != "":
+		return dev.Name
+	}
+	return dev.Address
+}
+
+var errObexUnavailable = errors.New("bluetooth file transfer unavailable (is obexd installed?)")
+
+func (m *Manager) Obex() *Obex {
+	return m.obex
+}
+
+func (m *Manager) obexReceiveDir() string {
+	m.configMutex.RLock()
+	defer m.configMutex.RUnlock()
+	return m.config.obexReceiveDir()
+}
+
+func (m *Manager) GetObexState() ObexState {
+	state := ObexState{
+		Available:  m.obex != nil,
+		ReceiveDir: m.obexReceiveDir(),
+		Transfers:  []Transfer{},
+	}
+	if m.obex != nil {
+		state.Transfers = m.obex.Transfers()
+	}
+	return state
+}
+
+func (m *Manager) SetObexReceiveDir(dir string) error {
+	if dir != "" {
+		expanded, err := utils.ExpandPath(dir)
+		if err != nil {
+			return err
+		}
+		if !filepath.IsAbs(expanded) {
+			return fmt.Errorf("receive directory must be absolute: %s", dir)
+		}
+		dir = filepath.Clean(expanded)
+	}
+
+	return m.updateConfig(func(cfg *Config) { cfg.ObexReceiveDir = dir })
+}
+
+// FindDevice looks a device up by object path, address, alias or name.
+func (m *Manager) FindDevice(ref string) (Device, bool) {
+	if ref == "" {
+		return Device{}, false
+	}
+
+	m.stateMutex.RLock()
+	defer m.stateMutex.RUnlock()
+
+	for _, match := range []func(Device) bool{
+		func(d Device) bool { return d.Path == ref },
+		func(d Device) bool { return strings.EqualFold(d.Address, ref) },
+		func(d Device) bool { return d.Alias == ref },
+		func(d Device) bool { return d.Name == ref },
+	} {
+		if idx := slices.IndexFunc(m.state.Devices, match); idx >= 0 {
+			return m.state.Devices[idx], true
+		}
+	}
+	return Device{}, false
+}
+
+func (m *Manager) adapterAddressFor(devicePath string) string {
+	m.stateMutex.RLock()
+	defer m.stateMutex.RUnlock()
+
+	for _, adapter := range m.state.Adapters {
+		if strings.HasPrefix(devicePath, adapter.Path+"/") {
+			return adapter.Address
+		}
+	}
+	return ""
+}
+
+func (m *Manager) SendFile(deviceRef, path string) (Transfer, error) {
+	if m.obex == nil {
+		return Transfer{}, errObexUnavailable
+	}
+	dev, ok := m.FindDevice(deviceRef)
+	if !ok {
+		return Transfer{}, fmt.Errorf("unknown device: %s", deviceRef)
+	}
+	return m.obex.SendFile(dev, m.adapterAddressFor(dev.Path), path)
+}
+
+func (m *Manager) CancelTransfer(id string) error {
+	if m.obex == nil {
+		return errObexUnavailable
+	}
+	return m.obex.Cancel(id)
+}
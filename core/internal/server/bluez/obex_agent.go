@@ -0,0 +1,234 @@
+package bluez
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AvengeMedia/DankMaterialShell/core/internal/errdefs"
+	"github.com/AvengeMedia/DankMaterialShell/core/internal/log"
+	"github.com/AvengeMedia/dankgo/dbusutil"
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	obexAgentManagerIface = "org.bluez.obex.AgentManager1"
+	obexAgentIface        = "org.bluez.obex.Agent1"
+	obexAgentPath         = "/com/danklinux/bluez/obex_agent"
+	obexPromptTimeout     = 60 * time.Second
+)
+
+const obexIntrospectXML = `
+<node>
+	<interface name="org.bluez.obex.Agent1">
+		<method name="Release"/>
+		<method name="AuthorizePush">
+			<arg direction="in" type="o" name="transfer"/>
+			<arg direction="out" type="s" name="filename"/>
+		</method>
+		<method name="Cancel"/>
+	</interface>
+	<interface name="org.freedesktop.DBus.Introspectable">
+		<method name="Introspect">
+			<arg direction="out" type="s" name="data"/>
+		</method>
+	</interface>
+</node>`
+
+// obexAgent answers obexd's push authorization requests. It is a separate
+// type from Obex so only the Agent1 methods end up exported on the bus.
+type obexAgent struct {
+	obex *Obex
+
+	mu          sync.Mutex
+	promptToken string
+}
+
+func newObexAgent(o *Obex) (*obexAgent, error) {
+	a := &obexAgent{obex: o}
+
+	if err := o.conn.Export(a, dbus.ObjectPath(obexAgentPath), obexAgentIface); err != nil {
+		return nil, fmt.Errorf("obex agent export failed: %w", err)
+	}
+	if err := o.conn.Export(a, dbus.ObjectPath(obexAgentPath), "org.freedesktop.DBus.Introspectable"); err != nil {
+		a.unexport()
+		return nil, fmt.Errorf("introspection export failed: %w", err)
+	}
+
+	mgr := o.conn.Object(obexService, dbus.ObjectPath(obexPath))
+	if err := mgr.Call(obexAgentManagerIface+".RegisterAgent", 0, dbus.ObjectPath(obexAgentPath)).Err; err != nil {
+		a.unexport()
+		return nil, fmt.Errorf("obex agent registration failed: %w", err)
+	}
+
+	return a, nil
+}
+
+func (a *obexAgent) Close() {
+	mgr := a.obex.conn.Object(obexService, dbus.ObjectPath(obexPath))
+	mgr.Call(obexAgentManagerIface+".UnregisterAgent", 0, dbus.ObjectPath(obexAgentPath))
+	a.unexport()
+}
+
+func (a *obexAgent) unexport() {
+	_ = a.obex.conn.Export(nil, dbus.ObjectPath(obexAgentPath), obexAgentIface)
+	_ = a.obex.conn.Export(nil, dbus.ObjectPath(obexAgentPath), "org.freedesktop.DBus.Introspectable")
+}
+
+func (a *obexAgent) Release() *dbus.Error {
+	log.Infof("[BluezObex] agent released")
+	return nil
+}
+
+func (a *obexAgent) Introspect() (string, *dbus.Error) {
+	return obexIntrospectXML, nil
+}
+
+// Cancel is called when the remote side gives up before the user answered.
+func (a *obexAgent) Cancel() *dbus.Error {
+	a.mu.Lock()
+	token := a.promptToken
+	a.mu.Unlock()
+
+	log.Infof("[BluezObex] push request cancelled by remote")
+	if token != "" && a.obex.manager.promptBroker != nil {
+		_ = a.obex.manager.promptBroker.Resolve(token, PromptReply{Cancel: true})
+	}
+	return nil
+}
+
+func (a *obexAgent) AuthorizePush(transfer dbus.ObjectPath) (string, *dbus.Error) {
+	o := a.obex
+	var props map[string]dbus.Variant
+	if err := o.conn.Object(obexService, transfer).Call(propertiesIface+".GetAll", 0, obexTransferIface).Store(&props); err != nil {
+		log.Warnf("[BluezObex] AuthorizePush: transfer properties: %v", err)
+		return "", dbus.MakeFailedError(err)
+	}
+
+	name := dbusutil.GetOr(props, "Name", "")
+	size := dbusutil.GetOr(props, "Size", uint64(0))
+	addr := a.sessionDestination(dbusutil.GetOr(props, "Session", dbus.ObjectPath("")))
+
+	dev, _ := o.manager.FindDevice(addr)
+	if dev.Address == "" {
+		dev.Address = addr
+	}
+	log.Infof("[BluezObex] AuthorizePush: %s (%d bytes) from %s", name, size, dev.Address)
+
+	if err := a.prompt(dev, name, size); err != nil {
+		log.Infof("[BluezObex] push of %s rejected: %v", name, err)
+		if errors.Is(err, errdefs.ErrSecretPromptCancelled) || errors.Is(err, errdefs.ErrSecretPromptTimeout) {
+			return "", dbus.NewError("org.bluez.obex.Error.Rejected", nil)
+		}
+		return "", dbus.MakeFailedError(err)
+	}
+
+	dir := o.manager.obexReceiveDir()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		log.Warnf("[BluezObex] receive dir %s: %v", dir, err)
+		return "", dbus.MakeFailedError(err)
+	}
+	dest := uniquePath(dir, sanitizeFileName(name))
+
+	o.mu.Lock()
+	o.track(&Transfer{
+		ID:         string(transfer),
+		Direction:  TransferIncoming,
+		DevicePath: dev.Path,
+		DeviceName: deviceDisplayName(dev),
+		DeviceAddr: dev.Address,
+		Name:       name,
+		Filename:   dest,
+		Size:       size,
+		Status:     TransferQueued,
+		StartedAt:  time.Now(),
+	})
+	o.mu.Unlock()
+
+	return dest, nil
+}
+
+func (a *obexAgent) sessionDestination(session dbus.ObjectPath) string {
+	if session == "" {
+		return ""
+	}
+	v, err := a.obex.conn.Object(obexService, session).GetProperty(obexSessionIface + ".Destination")
+	if err != nil {
+		return ""
+	}
+	return dbusutil.AsOr(v, "")
+}
+
+func (a *obexAgent) prompt(dev Device, name string, size uint64) error {
+	broker := a.obex.manager.promptBroker
+	if broker == nil {
+		return fmt.Errorf("broker not initialized")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), obexPromptTimeout)
+	defer cancel()
+
+	token, err := broker.Ask(ctx, PromptRequest{
+		DevicePath:  dev.Path,
+		DeviceName:  deviceDisplayName(dev),
+		DeviceAddr:  dev.Address,
+		RequestType: "authorize-push",
+		Fields:      []string{"decision"},
+		Hints:       []string{},
+		FileName:    name,
+		FileSize:    size,
+	})
+	if err != nil {
+		return fmt.Errorf("prompt creation failed: %w", err)
+	}
+
+	a.mu.Lock()
+	a.promptToken = token
+	a.mu.Unlock()
+	defer func() {
+		a.mu.Lock()
+		a.promptToken = ""
+		a.mu.Unlock()
+	}()
+
+	reply, err := broker.Wait(ctx, token)
+	if err != nil {
+		return err
+	}
+	if !reply.Accept || (reply.Secrets["decision"] != "yes" && reply.Secrets["decision"] != "accept") {
+		return errdefs.ErrSecretPromptCancelled
+	}
+	return nil
+}
+
+// sanitizeFileName keeps only the base name a remote device sent, so a push
+// cannot write outside the receive directory.
+func sanitizeFileName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	if name == "." || name == "/" || name == ".." || name == "" {
+		return "bluetooth-file"
+	}
+	return name
+}
+
+// uniquePath appends " (n)" before the extension until name is unused in dir.
+func uniquePath(dir, name string) string {
+	path := filepath.Join(dir, name)
+	if _, err := os.Lstat(path); os.IsNotExist(err) {
+		return path
+	}
+
+	ext := filepath.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+	for i := 1; ; i++ {
+		path = filepath.Join(dir, fmt.Sprintf("%s (%d)%s", stem, i, ext))
+		if _, err := os.Lstat(path); os.IsNotExist(err) {
+			return path
+		}
+	}
+}
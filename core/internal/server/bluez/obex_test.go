@@ -0,0 +1,114 @@
+package bluez
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+)
+
+func TestSanitizeFileName(t *testing.T) {
+	cases := map[string]string{
+		"photo.jpg":              "photo.jpg",
+		"../../.bashrc":          ".bashrc",
+		"/etc/passwd":            "passwd",
+		`..\..\windows\evil.exe`: "evil.exe",
+		"..":                     "bluetooth-file",
+		"":                       "bluetooth-file",
+	}
+	for in, want := range cases {
+		if got := sanitizeFileName(in); got != want {
+			t.Errorf("sanitizeFileName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestUniquePath(t *testing.T) {
+	dir := t.TempDir()
+	if got := uniquePath(dir, "photo.jpg"); got != filepath.Join(dir, "photo.jpg") {
+		t.Errorf("expected unused name to be kept, got %s", got)
+	}
+
+	for _, name := range []string{"photo.jpg", "photo (1).jpg"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := uniquePath(dir, "photo.jpg"); got != filepath.Join(dir, "photo (2).jpg") {
+		t.Errorf("expected photo (2).jpg, got %s", got)
+	}
+}
+
+func TestObexApplyChanges(t *testing.T) {
+	o := &Obex{}
+	updates := o.Subscribe("test")
+	o.track(&Transfer{ID: "/org/bluez/obex/server/session1/transfer1", Direction: TransferIncoming, Size: 100, Status: TransferQueued})
+	<-updates
+
+	o.applyChanges("/org/bluez/obex/server/session1/transfer1", map[string]dbus.Variant{
+		"Status":      dbus.MakeVariant(TransferActive),
+		"Transferred": dbus.MakeVariant(uint64(40)),
+	})
+	update := <-updates
+	if update.Status != TransferActive || update.Transferred != 40 {
+		t.Fatalf("unexpected update: %+v", update)
+	}
+
+	o.applyChanges("/org/bluez/obex/other", map[string]dbus.Variant{"Status": dbus.MakeVariant(TransferComplete)})
+	select {
+	case u := <-updates:
+		t.Fatalf("expected unknown transfers to be ignored, got %+v", u)
+	default:
+	}
+
+	o.transfers[0].cancelled = true
+	o.applyChanges("/org/bluez/obex/server/session1/transfer1", map[string]dbus.Variant{"Status": dbus.MakeVariant(TransferError)})
+	update = <-updates
+	if update.Status != TransferCancelled || update.FinishedAt == nil {
+		t.Fatalf("expected a cancelled, finished transfer, got %+v", update)
+	}
+}
+
+func TestObexPrune(t *testing.T) {
+	o := &Obex{}
+	o.track(&Transfer{ID: "active", Status: TransferActive})
+	for i := 0; i < maxFinishedTransfers+5; i++ {
+		o.track(&Transfer{ID: string(rune('a' + i)), Status: TransferComplete})
+	}
+	o.pruneLocked()
+
+	transfers := o.Transfers()
+	if len(transfers) != maxFinishedTransfers+1 {
+		t.Fatalf("expected %d transfers, got %d", maxFinishedTransfers+1, len(transfers))
+	}
+	if transfers[0].ID != "active" || transfers[1].ID != string(rune('a'+5)) {
+		t.Errorf("expected the active and newest finished transfers to be kept, got %s, %s", transfers[0].ID, transfers[1].ID)
+	}
+}
+
+func TestObexReceiveDirConfig(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("XDG_DOWNLOAD_DIR", "/tmp/dl")
+
+	m := &Manager{config: loadConfig()}
+	if got := m.obexReceiveDir(); got != "/tmp/dl" {
+		t.Errorf("expected XDG download dir, got %s", got)
+	}
+
+	if err := m.SetObexReceiveDir("relative"); err == nil {
+		t.Error("expected relative directory to be rejected")
+	}
+	if err := m.SetObexReceiveDir("/srv/inbox/"); err != nil {
+		t.Fatal(err)
+	}
+	if got := loadConfig().ObexReceiveDir; got != "/srv/inbox" {
+		t.Errorf("expected persisted receive dir, got %q", got)
+	}
+}
+
+func TestObexSendFileRejectsRelativePath(t *testing.T) {
+	if _, err := (&Obex{}).SendFile(Device{}, "", "notes.pdf"); err == nil {
+		t.Error("expected a relative path to be rejected")
+	}
+}
@@ -0,0 +1,550 @@
+package bluez
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/AvengeMedia/DankMaterialShell/core/internal/log"
+	"github.com/AvengeMedia/DankMaterialShell/core/internal/server/loginctl"
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	defaultRetryAttempts     = 5
+	defaultRetryInitialDelay = 2
+	defaultRetryMaxDelay     = 60
+
+	// powerOnSettleDelay and resumeSettleDelay give the controller time to
+	// come up before the first connect attempt.
+	powerOnSettleDelay = time.Second
+	resumeSettleDelay  = 3 * time.Second
+	// policyDisconnectTimeout bounds each disconnect, so a stuck device
+	// can't hold the suspend inhibitor for logind's whole delay.
+	policyDisconnectTimeout = 2 * time.Second
+)
+
+const (
+	TriggerPower  = "power"
+	TriggerResume = "resume"
+	TriggerUnlock = "unlock"
+	TriggerManual = "manual"
+)
+
+type DevicePolicy struct {
+	Address             string `json:"address"`
+	AutoConnect         bool   `json:"autoConnect"`
+	DisconnectOnLock    bool   `json:"disconnectOnLock"`
+	DisconnectOnSuspend bool   `json:"disconnectOnSuspend"`
+}
+
+// PriorityGroup is an ordered list of device addresses of which only the
+// first reachable one is connected, e.g. the good headset before the spare.
+type PriorityGroup struct {
+	ID      string   `json:"id"`
+	Name    string   `json:"name,omitempty"`
+	Devices []string `json:"devices"`
+}
+
+// RetryPolicy delays are in seconds; each failed round doubles the delay up
+// to MaxDelay.
+type RetryPolicy struct {
+	Attempts     int `json:"attempts"`
+	InitialDelay int `json:"initialDelay"`
+	MaxDelay     int `json:"maxDelay"`
+}
+
+type Policy struct {
+	Devices []DevicePolicy  `json:"devices"`
+	Groups  []PriorityGroup `json:"groups"`
+	Retry   RetryPolicy     `json:"retry"`
+}
+
+type PolicyState struct {
+	Policy       Policy   `json:"policy"`
+	Reconnecting bool     `json:"reconnecting"`
+	Trigger      string   `json:"trigger,omitempty"`
+	Pending      []string `json:"pending"`
+}
+
+func normalizeAddress(addr string) string {
+	return strings.ToUpper(strings.TrimSpace(addr))
+}
+
+// normalize validates p and fills in defaults, upper-casing addresses so
+// lookups don't depend on how the shell wrote them.
+func (p Policy) normalize() (Policy, error) {
+	out := Policy{Devices: []DevicePolicy{}, Groups: []PriorityGroup{}, Retry: p.Retry}
+
+	seen := map[string]bool{}
+	for _, d := range p.Devices {
+		d.Address = normalizeAddress(d.Address)
+		if d.Address == "" {
+			return out, fmt.Errorf("device policy: address required")
+		}
+		if seen[d.Address] {
+			return out, fmt.Errorf("duplicate device policy: %s", d.Address)
+		}
+		seen[d.Address] = true
+		if d.AutoConnect || d.DisconnectOnLock || d.DisconnectOnSuspend {
+			out.Devices = append(out.Devices, d)
+		}
+	}
+
+	ids := map[string]bool{}
+	for i, g := range p.Groups {
+		if g.ID == "" {
+			g.ID = fmt.Sprintf("group-%d", i+1)
+		}
+		if ids[g.ID] {
+			return out, fmt.Errorf("duplicate priority group: %s", g.ID)
+		}
+		ids[g.ID] = true
+		if len(g.Devices) == 0 {
+			return out, fmt.Errorf("priority group %s: no devices", g.ID)
+		}
+		devices := make([]string, 0, len(g.Devices))
+		for _, addr := range g.Devices {
+			addr = normalizeAddress(addr)
+			if addr == "" || slices.Contains(devices, addr) {
+				return out, fmt.Errorf("priority group %s: invalid or repeated device %q", g.ID, addr)
+			}
+			devices = append(devices, addr)
+		}
+		g.Devices = devices
+		out.Groups = append(out.Groups, g)
+	}
+
+	if out.Retry.Attempts < 0 || out.Retry.InitialDelay < 0 || out.Retry.MaxDelay < 0 {
+		return out, fmt.Errorf("retry values must not be negative")
+	}
+	out.Retry = out.Retry.withDefaults()
+	return out, nil
+}
+
+// withDefaults fills in unset values, also covering hand-edited configs
+// that never went through normalize.
+func (r RetryPolicy) withDefaults() RetryPolicy {
+	if r.Attempts <= 0 {
+		r.Attempts = defaultRetryAttempts
+	}
+	if r.InitialDelay <= 0 {
+		r.InitialDelay = defaultRetryInitialDelay
+	}
+	if r.MaxDelay <= 0 {
+		r.MaxDelay = defaultRetryMaxDelay
+	}
+	r.MaxDelay = max(r.MaxDelay, r.InitialDelay)
+	return r
+}
+
+func (p Policy) device(addr string) DevicePolicy {
+	addr = normalizeAddress(addr)
+	for _, d := range p.Devices {
+		if d.Address == addr {
+			return d
+		}
+	}
+	return DevicePolicy{Address: addr}
+}
+
+// backoff is the wait after the given failed round, counting from zero.
+func (r RetryPolicy) backoff(round int) time.Duration {
+	delay := time.Duration(r.InitialDelay) * time.Second
+	maxDelay := time.Duration(r.MaxDelay) * time.Second
+	for range round {
+		delay *= 2
+		if delay >= maxDelay {
+			return maxDelay
+		}
+	}
+	return delay
+}
+
+// connectPlan lists what to connect: one ordered candidate list per priority
+// group, then one single-device list per remaining auto-connect device. Only
+// paired devices are candidates, and targets with a member already connected
+// are skipped. only, when set, restricts both group candidates and single
+// devices to those addresses.
+func connectPlan(p Policy, devices []Device, only map[string]bool) [][]Device {
+	byAddr := make(map[string]Device, len(devices))
+	for _, d := range devices {
+		if d.Paired {
+			byAddr[normalizeAddress(d.Address)] = d
+		}
+	}
+
+	var plan [][]Device
+	grouped := map[string]bool{}
+	for _, g := range p.Groups {
+		var candidates []Device
+		connected := false
+		for _, addr := range g.Devices {
+			grouped[addr] = true
+			dev, ok := byAddr[addr]
+			if !ok {
+				continue
+			}
+			connected = connected || dev.Connected
+			if only != nil && !only[addr] {
+				continue
+			}
+			candidates = append(candidates, dev)
+		}
+		if !connected && len(candidates) > 0 {
+			plan = append(plan, candidates)
+		}
+	}
+
+	for _, d := range p.Devices {
+		if grouped[d.Address] {
+			continue
+		}
+		if only != nil && !only[d.Address] {
+			continue
+		}
+		if only == nil && !d.AutoConnect {
+			continue
+		}
+		dev, ok := byAddr[d.Address]
+		if !ok || dev.Connected {
+			continue
+		}
+		plan = append(plan, []Device{dev})
+	}
+	return plan
+}
+
+func (m *Manager) policy() Policy {
+	m.configMutex.RLock()
+	p := m.config.Policy
+	m.configMutex.RUnlock()
+
+	if normalized, err := p.normalize(); err == nil {
+		return normalized
+	}
+	if p.Devices == nil {
+		p.Devices = []DevicePolicy{}
+	}
+	if p.Groups == nil {
+		p.Groups = []PriorityGroup{}
+	}
+	p.Retry = p.Retry.withDefaults()
+	return p
+}
+
+func (m *Manager) GetPolicyState() PolicyState {
+	m.policyMutex.Lock()
+	defer m.policyMutex.Unlock()
+
+	pending := make([]string, 0, len(m.policyPending))
+	for addr := range m.policyPending {
+		pending = append(pending, addr)
+	}
+	slices.Sort(pending)
+
+	return PolicyState{
+		Policy:       m.policy(),
+		Reconnecting: m.policyCancel != nil,
+		Trigger:      m.policyTrigger,
+		Pending:      pending,
+	}
+}
+
+func (m *Manager) SetPolicy(p Policy) error {
+	normalized, err := p.normalize()
+	if err != nil {
+		return err
+	}
+	if err := m.updateConfig(func(cfg *Config) { cfg.Policy = normalized }); err != nil {
+		return err
+	}
+	m.syncSuspendInhibitor()
+	return nil
+}
+
+// SetDevicePolicy updates the flags of one device, resolving ref through the
+// known devices so names and object paths work as well as addresses.
+func (m *Manager) SetDevicePolicy(ref string, update func(*DevicePolicy)) (DevicePolicy, error) {
+	addr := normalizeAddress(ref)
+	if dev, ok := m.FindDevice(ref); ok {
+		addr = normalizeAddress(dev.Address)
+	}
+	if addr == "" {
+		return DevicePolicy{}, fmt.Errorf("missing device")
+	}
+
+	p := m.policy()
+	d := p.device(addr)
+	update(&d)
+
+	devices := slices.DeleteFunc(slices.Clone(p.Devices), func(e DevicePolicy) bool { return e.Address == addr })
+	p.Devices = append(devices, d)
+	if err := m.SetPolicy(p); err != nil {
+		return DevicePolicy{}, err
+	}
+	return d, nil
+}
+
+func (m *Manager) SetPriorityGroups(groups []PriorityGroup) error {
+	p := m.policy()
+	p.Groups = groups
+	return m.SetPolicy(p)
+}
+
+// Reconnect runs the auto-connect plan now, replacing any run in progress.
+func (m *Manager) Reconnect() {
+	m.startPolicyRun(TriggerManual, 0, nil)
+}
+
+// startPolicyRun cancels the current run and starts a new one after delay.
+func (m *Manager) startPolicyRun(trigger string, delay time.Duration, only map[string]bool) {
+	m.policyMutex.Lock()
+	if m.policyCancel != nil {
+		m.policyCancel()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m.policyCancel = cancel
+	m.policyTrigger = trigger
+	m.policyRun++
+	run := m.policyRun
+	m.policyMutex.Unlock()
+
+	m.policyWg.Go(func() {
+		defer cancel()
+		defer m.finishPolicyRun(run)
+
+		go func() {
+			select {
+			case <-m.stopChan:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+
+		if !sleepCtx(ctx, delay) {
+			return
+		}
+		m.runPolicy(ctx, trigger, only)
+	})
+}
+
+func (m *Manager) finishPolicyRun(run uint64) {
+	m.policyMutex.Lock()
+	defer m.policyMutex.Unlock()
+	if m.policyRun != run {
+		return
+	}
+	m.policyCancel = nil
+	m.policyTrigger = ""
+	clear(m.policyPending)
+}
+
+func (m *Manager) runPolicy(ctx context.Context, trigger string, only map[string]bool) {
+	p := m.policy()
+	plan := connectPlan(p, m.GetState().Devices, only)
+	if len(plan) == 0 {
+		return
+	}
+	log.Infof("[BluezPolicy] %s: connecting %d target(s)", trigger, len(plan))
+
+	done := make(chan struct{}, len(plan))
+	for _, candidates := range plan {
+		m.setPending(candidates, true)
+		go func() {
+			defer func() { done <- struct{}{} }()
+			defer m.setPending(candidates, false)
+			m.connectFirstAvailable(ctx, candidates, p.Retry)
+		}()
+	}
+	for range plan {
+		<-done
+	}
+}
+
+func (m *Manager) setPending(devices []Device, pending bool) {
+	m.policyMutex.Lock()
+	defer m.policyMutex.Unlock()
+	for _, d := range devices {
+		if pending {
+			m.policyPending[normalizeAddress(d.Address)] = true
+		} else {
+			delete(m.policyPending, normalizeAddress(d.Address))
+		}
+	}
+}
+
+// connectFirstAvailable tries candidates in order each round and stops at the
+// first success, when one gets connected some other way, or when retries run
+// out.
+func (m *Manager) connectFirstAvailable(ctx context.Context, candidates []Device, retry RetryPolicy) {
+	for round := range retry.Attempts {
+		for _, dev := range candidates {
+			if m.isConnected(dev.Path) {
+				return
+			}
+		}
+		for _, dev := range candidates {
+			if ctx.Err() != nil {
+				return
+			}
+			err := m.connectDeviceContext(ctx, dev.Path)
+			if err == nil {
+				log.Infof("[BluezPolicy] connected %s", deviceDisplayName(dev))
+				return
+			}
+			log.Debugf("[BluezPolicy] connect %s (round %d) failed: %v", deviceDisplayName(dev), round+1, err)
+		}
+		if round+1 < retry.Attempts && !sleepCtx(ctx, retry.backoff(round)) {
+			return
+		}
+	}
+	log.Infof("[BluezPolicy] giving up on %s", deviceDisplayName(candidates[0]))
+}
+
+func (m *Manager) isConnected(path string) bool {
+	m.stateMutex.RLock()
+	defer m.stateMutex.RUnlock()
+	return slices.ContainsFunc(m.state.ConnectedDevices, func(d Device) bool { return d.Path == path })
+}
+
+func (m *Manager) connectDeviceContext(ctx context.Context, devicePath string) error {
+	obj := m.dbusConn.Object(bluezService, dbus.ObjectPath(devicePath))
+	return obj.CallWithContext(ctx, device1Iface+".Connect", 0).Err
+}
+
+// disconnectWhere disconnects connected devices whose policy matches and
+// returns their addresses.
+func (m *Manager) disconnectWhere(reason string, match func(DevicePolicy) bool) []string {
+	p := m.policy()
+	var disconnected []string
+
+	for _, dev := range m.GetState().ConnectedDevices {
+		if !match(p.device(dev.Address)) {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), policyDisconnectTimeout)
+		err := m.dbusConn.Object(bluezService, dbus.ObjectPath(dev.Path)).CallWithContext(ctx, device1Iface+".Disconnect", 0).Err
+		cancel()
+		if err != nil {
+			log.Warnf("[BluezPolicy] disconnect %s on %s failed: %v", deviceDisplayName(dev), reason, err)
+			continue
+		}
+		log.Infof("[BluezPolicy] disconnected %s on %s", deviceDisplayName(dev), reason)
+		disconnected = append(disconnected, normalizeAddress(dev.Address))
+	}
+	return disconnected
+}
+
+// syncSuspendInhibitor holds a logind delay lock, so the suspend
+// disconnects finish before the system sleeps, only while some device
+// disconnects on suspend and no suspend is under way. Nobody else should
+// wait on Bluetooth at suspend.
+func (m *Manager) syncSuspendInhibitor() {
+	m.policyMutex.Lock()
+	defer m.policyMutex.Unlock()
+
+	want := m.inhibitSleep != nil && !m.sleeping &&
+		slices.ContainsFunc(m.policy().Devices, func(d DevicePolicy) bool { return d.DisconnectOnSuspend })
+	switch {
+	case want && m.suspendInhibitor == nil:
+		f, err := m.inhibitSleep("Disconnect Bluetooth devices before suspend")
+		if err != nil {
+			log.Warnf("[BluezPolicy] failed to take sleep inhibitor: %v", err)
+			return
+		}
+		m.suspendInhibitor = f
+	case !want && m.suspendInhibitor != nil:
+		m.suspendInhibitor.Close()
+		m.suspendInhibitor = nil
+	}
+}
+
+// setSleepState records the logind sleep source and whether a suspend is
+// under way; a nil inhibit stops taking sleep inhibitors altogether.
+func (m *Manager) setSleepState(inhibit func(string) (*os.File, error), sleeping bool) {
+	m.policyMutex.Lock()
+	m.inhibitSleep = inhibit
+	m.sleeping = sleeping
+	m.policyMutex.Unlock()
+	m.syncSuspendInhibitor()
+}
+
+func (m *Manager) cancelPolicyRun() {
+	m.policyMutex.Lock()
+	defer m.policyMutex.Unlock()
+	if m.policyCancel != nil {
+		m.policyCancel()
+	}
+}
+
+// WatchLoginctl applies the lock and suspend policies and reconnects after
+// resume and unlock. While a device disconnects on suspend it holds a sleep
+// delay inhibitor between suspends so logind waits for the disconnects.
+func (m *Manager) WatchLoginctl(lm *loginctl.Manager) {
+	ch := lm.Subscribe("bluez-policy")
+	initial := lm.GetState()
+	m.setSleepState(lm.InhibitSleep, initial.PreparingForSleep)
+
+	m.policyWg.Go(func() {
+		defer lm.Unsubscribe("bluez-policy")
+		defer m.setSleepState(nil, false)
+
+		locked := initial.Locked
+		sleeping := initial.PreparingForSleep
+		var lockDisconnected []string
+		for {
+			select {
+			case <-m.stopChan:
+				return
+			case state, ok := <-ch:
+				if !ok {
+					return
+				}
+
+				if state.PreparingForSleep != sleeping {
+					sleeping = state.PreparingForSleep
+					if sleeping {
+						m.cancelPolicyRun()
+						m.disconnectWhere("suspend", func(d DevicePolicy) bool { return d.DisconnectOnSuspend })
+						m.setSleepState(lm.InhibitSleep, true)
+					} else {
+						m.setSleepState(lm.InhibitSleep, false)
+						m.startPolicyRun(TriggerResume, resumeSettleDelay, nil)
+					}
+				}
+
+				if state.Locked != locked {
+					locked = state.Locked
+					if locked {
+						lockDisconnected = m.disconnectWhere("lock", func(d DevicePolicy) bool { return d.DisconnectOnLock })
+					} else if len(lockDisconnected) > 0 {
+						only := map[string]bool{}
+						for _, addr := range lockDisconnected {
+							only[addr] = true
+						}
+						lockDisconnected = nil
+						m.startPolicyRun(TriggerUnlock, 0, only)
+					}
+				}
+			}
+		}
+	})
+}
+
+func sleepCtx(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
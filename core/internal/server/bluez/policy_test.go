@@ -0,0 +1,158 @@
+package bluez
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestPolicyNormalize(t *testing.T) {
+	p, err := Policy{
+		Devices: []DevicePolicy{
+			{Address: " aa:bb:cc:dd:ee:01 ", AutoConnect: true},
+			{Address: "AA:BB:CC:DD:EE:02"},
+		},
+		Groups: []PriorityGroup{{Devices: []string{"aa:bb:cc:dd:ee:03", "AA:BB:CC:DD:EE:04"}}},
+	}.normalize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Devices) != 1 || p.Devices[0].Address != "AA:BB:CC:DD:EE:01" {
+		t.Errorf("expected one upper-cased device with flags, got %+v", p.Devices)
+	}
+	if p.Groups[0].ID != "group-1" || p.Groups[0].Devices[0] != "AA:BB:CC:DD:EE:03" {
+		t.Errorf("unexpected group: %+v", p.Groups[0])
+	}
+	if p.Retry.Attempts != defaultRetryAttempts || p.Retry.InitialDelay != defaultRetryInitialDelay {
+		t.Errorf("expected retry defaults, got %+v", p.Retry)
+	}
+
+	invalid := []Policy{
+		{Devices: []DevicePolicy{{Address: "AA:BB:CC:DD:EE:01"}, {Address: "aa:bb:cc:dd:ee:01"}}},
+		{Groups: []PriorityGroup{{ID: "x", Devices: []string{"A", "a"}}}},
+		{Groups: []PriorityGroup{{ID: "x"}}},
+		{Retry: RetryPolicy{Attempts: -1}},
+	}
+	for _, p := range invalid {
+		if _, err := p.normalize(); err == nil {
+			t.Errorf("expected %+v to be rejected", p)
+		}
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	r := RetryPolicy{InitialDelay: 2, MaxDelay: 10}
+	want := []time.Duration{2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second}
+	for round, w := range want {
+		if got := r.backoff(round); got != w {
+			t.Errorf("backoff(%d) = %s, want %s", round, got, w)
+		}
+	}
+}
+
+func TestConnectPlan(t *testing.T) {
+	devices := []Device{
+		{Path: "/a", Address: "AA:00:00:00:00:01", Paired: true},
+		{Path: "/b", Address: "aa:00:00:00:00:02", Paired: true},
+		{Path: "/c", Address: "AA:00:00:00:00:03", Paired: true, Connected: true},
+		{Path: "/d", Address: "AA:00:00:00:00:04", Paired: true},
+		{Path: "/e", Address: "AA:00:00:00:00:05"},
+		{Path: "/f", Address: "AA:00:00:00:00:06", Paired: true},
+	}
+	p := Policy{
+		Devices: []DevicePolicy{
+			{Address: "AA:00:00:00:00:01", AutoConnect: true},
+			{Address: "AA:00:00:00:00:05", AutoConnect: true},
+			{Address: "AA:00:00:00:00:06", DisconnectOnLock: true},
+		},
+		Groups: []PriorityGroup{
+			{ID: "headsets", Devices: []string{"AA:00:00:00:00:09", "AA:00:00:00:00:02", "AA:00:00:00:00:01"}},
+			{ID: "speakers", Devices: []string{"AA:00:00:00:00:03", "AA:00:00:00:00:04"}},
+		},
+	}
+
+	plan := connectPlan(p, devices, nil)
+	if len(plan) != 1 {
+		t.Fatalf("expected only the headset group, got %+v", plan)
+	}
+	if len(plan[0]) != 2 || plan[0][0].Path != "/b" || plan[0][1].Path != "/a" {
+		t.Errorf("expected headsets in priority order, got %+v", plan[0])
+	}
+
+	plan = connectPlan(p, devices, map[string]bool{"AA:00:00:00:00:06": true})
+	if len(plan) != 1 || plan[0][0].Path != "/f" {
+		t.Errorf("expected only the restricted device, got %+v", plan)
+	}
+
+	plan = connectPlan(p, devices, map[string]bool{"AA:00:00:00:00:01": true})
+	if len(plan) != 1 || len(plan[0]) != 1 || plan[0][0].Path != "/a" {
+		t.Errorf("expected the group restricted to the disconnected member, got %+v", plan)
+	}
+}
+
+func TestSetDevicePolicyPersists(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	m := &Manager{
+		state: &BluetoothState{Devices: []Device{{Path: "/org/bluez/hci0/dev_AA", Address: "aa:bb:cc:dd:ee:ff", Alias: "Headphones"}}},
+	}
+
+	d, err := m.SetDevicePolicy("Headphones", func(d *DevicePolicy) { d.DisconnectOnSuspend = true })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Address != "AA:BB:CC:DD:EE:FF" || !d.DisconnectOnSuspend {
+		t.Errorf("unexpected device policy: %+v", d)
+	}
+	if got := loadConfig().Policy.device("aa:bb:cc:dd:ee:ff"); !got.DisconnectOnSuspend {
+		t.Errorf("expected policy to be persisted, got %+v", got)
+	}
+
+	if _, err := m.SetDevicePolicy("AA:BB:CC:DD:EE:FF", func(d *DevicePolicy) { d.DisconnectOnSuspend = false }); err != nil {
+		t.Fatal(err)
+	}
+	if devices := m.GetPolicyState().Policy.Devices; len(devices) != 0 {
+		t.Errorf("expected a policy without flags to be dropped, got %+v", devices)
+	}
+}
+
+func TestSuspendInhibitorFollowsPolicy(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	taken := 0
+	inhibit := func(string) (*os.File, error) {
+		taken++
+		return os.Open(os.DevNull)
+	}
+	m := &Manager{
+		state: &BluetoothState{Devices: []Device{{Path: "/org/bluez/hci0/dev_AA", Address: "AA:BB:CC:DD:EE:FF", Alias: "Headphones"}}},
+	}
+
+	m.setSleepState(inhibit, false)
+	if taken != 0 || m.suspendInhibitor != nil {
+		t.Fatalf("no device disconnects on suspend, yet an inhibitor was taken")
+	}
+
+	if _, err := m.SetDevicePolicy("Headphones", func(d *DevicePolicy) { d.DisconnectOnSuspend = true }); err != nil {
+		t.Fatal(err)
+	}
+	if taken != 1 || m.suspendInhibitor == nil {
+		t.Fatalf("expected the inhibitor once a device disconnects on suspend, taken=%d", taken)
+	}
+
+	m.setSleepState(inhibit, true)
+	if m.suspendInhibitor != nil {
+		t.Errorf("expected the inhibitor released while suspending")
+	}
+	m.setSleepState(inhibit, false)
+	if taken != 2 || m.suspendInhibitor == nil {
+		t.Errorf("expected the inhibitor re-taken after resume, taken=%d", taken)
+	}
+
+	if _, err := m.SetDevicePolicy("Headphones", func(d *DevicePolicy) { d.DisconnectOnSuspend = false }); err != nil {
+		t.Fatal(err)
+	}
+	if m.suspendInhibitor != nil {
+		t.Errorf("expected the inhibitor released with the last suspend policy")
+	}
+}
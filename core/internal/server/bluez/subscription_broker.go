@@ -40,6 +40,8 @@ func (b *SubscriptionBroker) Ask(ctx context.Context, req PromptRequest) (string
 			Fields:      req.Fields,
 			Hints:       req.Hints,
 			Passkey:     req.Passkey,
+			FileName:    req.FileName,
+			FileSize:    req.FileSize,
 		}
 		b.broadcastPrompt(prompt)
 	}
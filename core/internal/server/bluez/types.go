@@ -1,19 +1,24 @@
 package bluez
 
 import (
+	"context"
+	"os"
 	"sync"
+	"time"
 
 	"github.com/AvengeMedia/dankgo/syncmap"
 	"github.com/godbus/dbus/v5"
 )
 
 type BluetoothState struct {
-	Powered          bool          `json:"powered"`
-	Discovering      bool          `json:"discovering"`
-	Adapters         []AdapterInfo `json:"adapters"`
-	Devices          []Device      `json:"devices"`
-	PairedDevices    []Device      `json:"pairedDevices"`
-	ConnectedDevices []Device      `json:"connectedDevices"`
+	Powered          bool             `json:"powered"`
+	Discovering      bool             `json:"discovering"`
+	DefaultAdapter   string           `json:"defaultAdapter"`
+	DiscoveryFilter  *DiscoveryFilter `json:"discoveryFilter,omitempty"`
+	Adapters         []AdapterInfo    `json:"adapters"`
+	Devices          []Device         `json:"devices"`
+	PairedDevices    []Device         `json:"pairedDevices"`
+	ConnectedDevices []Device         `json:"connectedDevices"`
 }
 
 type AdapterInfo struct {
@@ -22,6 +27,13 @@ type AdapterInfo struct {
 	Address     string `json:"address"`
 	Powered     bool   `json:"powered"`
 	Discovering bool   `json:"discovering"`
+
+	SystemName          string `json:"systemName"`
+	Discoverable        bool   `json:"discoverable"`
+	DiscoverableTimeout uint32 `json:"discoverableTimeout"`
+	Pairable            bool   `json:"pairable"`
+	PairableTimeout     uint32 `json:"pairableTimeout"`
+	Default             bool   `json:"default"`
 }
 
 type Device struct {
@@ -37,6 +49,17 @@ type Device struct {
 	Icon          string `json:"icon"`
 	RSSI          int16  `json:"rssi"`
 	LegacyPairing bool   `json:"legacyPairing"`
+
+	ServicesResolved bool      `json:"servicesResolved"`
+	UUIDs            []string  `json:"uuids"`
+	Profiles         []string  `json:"profiles"`
+	Modalias         *Modalias `json:"modalias,omitempty"`
+	TxPower          *int16    `json:"txPower,omitempty"`
+	Battery          *uint8    `json:"battery,omitempty"`
+	Batteries        []Battery `json:"batteries,omitempty"`
+	// ConnectedSince is when DMS first saw the device connected; BlueZ
+	// does not track it, so links older than the server start at its start.
+	ConnectedSince *time.Time `json:"connectedSince,omitempty"`
 }
 
 type PromptRequest struct {
@@ -47,6 +70,8 @@ type PromptRequest struct {
 	Fields      []string `json:"fields"`
 	Hints       []string `json:"hints"`
 	Passkey     *uint32  `json:"passkey,omitempty"`
+	FileName    string   `json:"fileName,omitempty"`
+	FileSize    uint64   `json:"fileSize,omitempty"`
 }
 
 type PromptReply struct {
@@ -64,6 +89,8 @@ type PairingPrompt struct {
 	Fields      []string `json:"fields"`
 	Hints       []string `json:"hints"`
 	Passkey     *uint32  `json:"passkey,omitempty"`
+	FileName    string   `json:"fileName,omitempty"`
+	FileSize    uint64   `json:"fileSize,omitempty"`
 }
 
 type Manager struct {
@@ -77,6 +104,9 @@ type Manager struct {
 	agent              *BluezAgent
 	promptBroker       PromptBroker
 	pairingSubscribers syncmap.Map[string, chan PairingPrompt]
+	batterySubscribers syncmap.Map[string, chan BatteryEvent]
+	connectedSince     map[string]time.Time
+	batteryLevels      map[string]string
 	dirty              chan struct{}
 	notifierWg         sync.WaitGroup
 	lastNotifiedState  *BluetoothState
@@ -84,4 +114,16 @@ type Manager struct {
 	pendingPairings    syncmap.Map[string, bool]
 	eventQueue         chan func()
 	eventWg            sync.WaitGroup
+	obex               *Obex
+	config             Config
+	configMutex        sync.RWMutex
+	policyMutex        sync.Mutex
+	policyCancel       context.CancelFunc
+	policyTrigger      string
+	policyRun          uint64
+	policyPending      map[string]bool
+	policyWg           sync.WaitGroup
+	suspendInhibitor   *os.File
+	inhibitSleep       func(why string) (*os.File, error)
+	sleeping           bool
 }
@@ -0,0 +1,271 @@
+package brightness
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AvengeMedia/DankMaterialShell/core/internal/log"
+	"github.com/AvengeMedia/dankgo/dbusutil"
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	sensorProxyDest  = "net.hadess.SensorProxy"
+	sensorProxyPath  = "/net/hadess/SensorProxy"
+	sensorProxyIface = "net.hadess.SensorProxy"
+
+	iioPollInterval = time.Second
+)
+
+var iioSysfsRoot = "/sys/bus/iio/devices"
+
+// LightSensor delivers ambient illuminance readings in lux.
+type LightSensor interface {
+	Name() string
+	Readings() <-chan float64
+	Close()
+}
+
+// NewLightSensor prefers iio-sensor-proxy, which arbitrates access between
+// clients, and falls back to polling the iio sysfs nodes directly.
+func NewLightSensor() (LightSensor, error) {
+	sensor, err := newSensorProxyLight()
+	if err == nil {
+		return sensor, nil
+	}
+	log.Debugf("iio-sensor-proxy light sensor unavailable: %v", err)
+
+	return newIIOLightSensor(iioSysfsRoot)
+}
+
+type sensorProxyLight struct {
+	conn     *dbus.Conn
+	obj      dbus.BusObject
+	signals  chan *dbus.Signal
+	readings chan float64
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+	once     sync.Once
+}
+
+func newSensorProxyLight() (*sensorProxyLight, error) {
+	conn, err := dbus.ConnectSystemBus()
+	if err != nil {
+		return nil, fmt.Errorf("connect to system bus: %w", err)
+	}
+
+	obj := conn.Object(sensorProxyDest, sensorProxyPath)
+	hasLight, err := obj.GetProperty(sensorProxyIface + ".HasAmbientLight")
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("query sensor proxy: %w", err)
+	}
+	if !dbusutil.AsOr(hasLight, false) {
+		conn.Close()
+		return nil, fmt.Errorf("no ambient light sensor")
+	}
+
+	if err := obj.Call(sensorProxyIface+".ClaimLight", 0).Err; err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("claim light sensor: %w", err)
+	}
+
+	s := &sensorProxyLight{
+		conn:     conn,
+		obj:      obj,
+		signals:  make(chan *dbus.Signal, 16),
+		readings: make(chan float64, 1),
+		stopChan: make(chan struct{}),
+	}
+
+	if err := conn.AddMatchSignal(
+		dbus.WithMatchObjectPath(sensorProxyPath),
+		dbus.WithMatchInterface("org.freedesktop.DBus.Properties"),
+		dbus.WithMatchMember("PropertiesChanged"),
+	); err != nil {
+		s.Close()
+		return nil, fmt.Errorf("watch sensor proxy: %w", err)
+	}
+	conn.Signal(s.signals)
+
+	if level, err := obj.GetProperty(sensorProxyIface + ".LightLevel"); err == nil {
+		s.push(dbusutil.AsOr(level, 0.0))
+	}
+
+	s.wg.Add(1)
+	go s.run()
+	return s, nil
+}
+
+func (s *sensorProxyLight) Name() string { return "iio-sensor-proxy" }
+
+func (s *sensorProxyLight) Readings() <-chan float64 { return s.readings }
+
+func (s *sensorProxyLight) run() {
+	defer s.wg.Done()
+	for {
+		select {
+		case <-s.stopChan:
+			return
+		case sig, ok := <-s.signals:
+			if !ok {
+				return
+			}
+			if sig == nil || len(sig.Body) < 2 {
+				continue
+			}
+			if iface, _ := sig.Body[0].(string); iface != sensorProxyIface {
+				continue
+			}
+			changed, ok := sig.Body[1].(map[string]dbus.Variant)
+			if !ok {
+				continue
+			}
+			if level, ok := dbusutil.Get[float64](changed, "LightLevel"); ok {
+				s.push(level)
+			}
+		}
+	}
+}
+
+// push keeps only the latest reading; stale lux values are useless.
+func (s *sensorProxyLight) push(lux float64) {
+	select {
+	case <-s.readings:
+	default:
+	}
+	s.readings <- lux
+}
+
+func (s *sensorProxyLight) Close() {
+	s.once.Do(func() {
+		close(s.stopChan)
+		s.conn.RemoveSignal(s.signals)
+		s.wg.Wait()
+		if err := s.obj.Call(sensorProxyIface+".ReleaseLight", 0).Err; err != nil {
+			log.Debugf("Failed to release light sensor: %v", err)
+		}
+		s.conn.Close()
+	})
+}
+
+type iioLightSensor struct {
+	device   string
+	input    string
+	raw      string
+	scale    float64
+	offset   float64
+	readings chan float64
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+	once     sync.Once
+}
+
+func newIIOLightSensor(root string) (*iioLightSensor, error) {
+	devices, err := filepath.Glob(filepath.Join(root, "iio:device*"))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, dev := range devices {
+		s := &iioLightSensor{
+			device:   filepath.Base(dev),
+			scale:    1,
+			readings: make(chan float64, 1),
+			stopChan: make(chan struct{}),
+		}
+
+		switch {
+		case fileExists(filepath.Join(dev, "in_illuminance_input")):
+			s.input = filepath.Join(dev, "in_illuminance_input")
+		case fileExists(filepath.Join(dev, "in_illuminance_raw")):
+			s.raw = filepath.Join(dev, "in_illuminance_raw")
+			if v, err := readFloatFile(filepath.Join(dev, "in_illuminance_scale")); err == nil {
+				s.scale = v
+			}
+			if v, err := readFloatFile(filepath.Join(dev, "in_illuminance_offset")); err == nil {
+				s.offset = v
+			}
+		default:
+			continue
+		}
+
+		if _, err := s.read(); err != nil {
+			log.Debugf("Skipping iio device %s: %v", s.device, err)
+			continue
+		}
+
+		s.wg.Add(1)
+		go s.run()
+		return s, nil
+	}
+
+	return nil, fmt.Errorf("no iio illuminance sensor found")
+}
+
+func (s *iioLightSensor) Name() string { return s.device }
+
+func (s *iioLightSensor) Readings() <-chan float64 { return s.readings }
+
+func (s *iioLightSensor) read() (float64, error) {
+	if s.input != "" {
+		return readFloatFile(s.input)
+	}
+	raw, err := readFloatFile(s.raw)
+	if err != nil {
+		return 0, err
+	}
+	return (raw + s.offset) * s.scale, nil
+}
+
+func (s *iioLightSensor) run() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(iioPollInterval)
+	defer ticker.Stop()
+
+	for {
+		lux, err := s.read()
+		switch {
+		case err != nil:
+			log.Debugf("Failed to read %s: %v", s.Name(), err)
+		default:
+			select {
+			case <-s.readings:
+			default:
+			}
+			s.readings <- lux
+		}
+
+		select {
+		case <-s.stopChan:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *iioLightSensor) Close() {
+	s.once.Do(func() {
+		close(s.stopChan)
+		s.wg.Wait()
+	})
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func readFloatFile(path string) (float64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseFloat(strings.TrimSpace(string(data)), 64)
+}
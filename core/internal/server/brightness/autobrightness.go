@@ -0,0 +1,573 @@
+package brightness
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/AvengeMedia/DankMaterialShell/core/internal/log"
+)
+
+const (
+	// autoSmoothing is the EMA weight of a new reading in log-lux space.
+	autoSmoothing = 0.3
+	// autoLuxHysteresis is the log10 distance (~40%) the ambient light has
+	// to move before a new target is chosen.
+	autoLuxHysteresis     = 0.15
+	autoPercentHysteresis = 3
+	autoMaxStep           = 8
+	autoManualBackoff     = 30 * time.Second
+	autoRampInterval      = 250 * time.Millisecond
+	// autoLearnRadius merges learned points closer than this in log-lux.
+	autoLearnRadius = 0.25
+)
+
+type CurvePoint struct {
+	Lux     float64 `json:"lux"`
+	Percent int     `json:"percent"`
+}
+
+var defaultCurve = []CurvePoint{
+	{Lux: 0, Percent: 5},
+	{Lux: 10, Percent: 20},
+	{Lux: 100, Percent: 40},
+	{Lux: 1000, Percent: 70},
+	{Lux: 10000, Percent: 100},
+}
+
+type AutoBrightnessState struct {
+	Enabled     bool         `json:"enabled"`
+	Paused      bool         `json:"paused"`
+	PausedUntil *time.Time   `json:"pausedUntil,omitempty"`
+	Device      string       `json:"device,omitempty"`
+	Sensor      string       `json:"sensor,omitempty"`
+	Lux         float64      `json:"lux"`
+	Target      int          `json:"target"`
+	Curve       []CurvePoint `json:"curve,omitempty"`
+}
+
+type autoConfig struct {
+	Enabled bool                    `json:"enabled"`
+	Device  string                  `json:"device,omitempty"`
+	Curves  map[string][]CurvePoint `json:"curves,omitempty"`
+}
+
+type autoBrightness struct {
+	mu sync.Mutex
+
+	config      autoConfig
+	sensor      LightSensor
+	sensorStop  chan struct{}
+	ctrl        autoController
+	paused      bool
+	pausedUntil time.Time
+	backoff     time.Time
+
+	newSensor func() (LightSensor, error)
+	now       func() time.Time
+}
+
+func luxToLog(lux float64) float64 {
+	return math.Log10(max(lux, 0) + 1)
+}
+
+func logToLux(v float64) float64 {
+	return math.Pow(10, v) - 1
+}
+
+// curvePercent interpolates the curve linearly in log-lux space, which
+// roughly matches perceived brightness.
+func curvePercent(points []CurvePoint, lux float64) int {
+	if len(points) == 0 {
+		points = defaultCurve
+	}
+	x := luxToLog(lux)
+	if x <= luxToLog(points[0].Lux) {
+		return points[0].Percent
+	}
+	for i := 1; i < len(points); i++ {
+		x0, x1 := luxToLog(points[i-1].Lux), luxToLog(points[i].Lux)
+		if x > x1 {
+			continue
+		}
+		if x1 == x0 {
+			return points[i].Percent
+		}
+		t := (x - x0) / (x1 - x0)
+		p := float64(points[i-1].Percent) + t*float64(points[i].Percent-points[i-1].Percent)
+		return int(math.Round(p))
+	}
+	return points[len(points)-1].Percent
+}
+
+// learnPoint records a manual adjustment. Nearby points are replaced and the
+// rest of the curve is clamped so brightness never decreases as lux rises.
+func learnPoint(points []CurvePoint, lux float64, percent int) []CurvePoint {
+	if len(points) == 0 {
+		points = defaultCurve
+	}
+	percent = max(min(percent, 100), 0)
+	x := luxToLog(lux)
+
+	learned := make([]CurvePoint, 0, len(points)+1)
+	for _, p := range points {
+		px := luxToLog(p.Lux)
+		if math.Abs(px-x) < autoLearnRadius {
+			continue
+		}
+		switch {
+		case px < x:
+			p.Percent = min(p.Percent, percent)
+		default:
+			p.Percent = max(p.Percent, percent)
+		}
+		learned = append(learned, p)
+	}
+	learned = append(learned, CurvePoint{Lux: math.Round(lux*10) / 10, Percent: percent})
+	sort.Slice(learned, func(i, j int) bool { return learned[i].Lux < learned[j].Lux })
+	return learned
+}
+
+// autoController turns noisy lux readings into brightness steps. Readings
+// are smoothed with an EMA, a new target is only chosen once the light moved
+// past autoLuxHysteresis, and the output ramps by at most autoMaxStep.
+type autoController struct {
+	smoothed   float64
+	hasReading bool
+
+	anchor   float64
+	anchored bool
+
+	current    int
+	hasCurrent bool
+	rampTarget int
+	ramping    bool
+	target     int
+}
+
+func (c *autoController) observe(lux float64) float64 {
+	v := luxToLog(lux)
+	switch {
+	case !c.hasReading:
+		c.smoothed = v
+		c.hasReading = true
+	default:
+		c.smoothed += autoSmoothing * (v - c.smoothed)
+	}
+	return logToLux(c.smoothed)
+}
+
+func (c *autoController) lux() float64 {
+	return logToLux(c.smoothed)
+}
+
+func (c *autoController) next(curve []CurvePoint) (int, bool) {
+	if !c.hasReading || !c.hasCurrent {
+		return 0, false
+	}
+	c.target = curvePercent(curve, c.lux())
+
+	switch {
+	case c.anchored && math.Abs(c.smoothed-c.anchor) < autoLuxHysteresis:
+		if !c.ramping || c.current == c.rampTarget {
+			c.ramping = false
+			return c.current, false
+		}
+	default:
+		delta := c.target - c.current
+		if delta < 0 {
+			delta = -delta
+		}
+		if delta < autoPercentHysteresis {
+			return c.current, false
+		}
+		c.anchor, c.anchored = c.smoothed, true
+		c.rampTarget, c.ramping = c.target, true
+	}
+
+	step := max(min(c.rampTarget-c.current, autoMaxStep), -autoMaxStep)
+	c.current += step
+	if c.current == c.rampTarget {
+		c.ramping = false
+	}
+	return c.current, true
+}
+
+// manual re-anchors at the current light level so the user's choice sticks.
+func (c *autoController) manual(percent int) {
+	c.current, c.hasCurrent = percent, true
+	c.ramping = false
+	if c.hasReading {
+		c.anchor, c.anchored = c.smoothed, true
+	}
+}
+
+// clone deep-copies the curves so the copy can be saved after auto.mu is
+// released while learning keeps editing the original.
+func (c autoConfig) clone() autoConfig {
+	curves := make(map[string][]CurvePoint, len(c.Curves))
+	for id, curve := range c.Curves {
+		curves[id] = slices.Clone(curve)
+	}
+	c.Curves = curves
+	return c
+}
+
+func autoConfigPath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "DankMaterialShell", "auto-brightness.json"), nil
+}
+
+func loadAutoConfig() autoConfig {
+	cfg := autoConfig{Curves: map[string][]CurvePoint{}}
+
+	path, err := autoConfigPath()
+	if err != nil {
+		return cfg
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		log.Warnf("Invalid auto-brightness config %s: %v", path, err)
+		return autoConfig{Curves: map[string][]CurvePoint{}}
+	}
+	if cfg.Curves == nil {
+		cfg.Curves = map[string][]CurvePoint{}
+	}
+	return cfg
+}
+
+func saveAutoConfig(cfg autoConfig) error {
+	path, err := autoConfigPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+func (a *autoBrightness) clock() time.Time {
+	if a.now != nil {
+		return a.now()
+	}
+	return time.Now()
+}
+
+func (m *Manager) initAutoBrightness() {
+	m.auto.mu.Lock()
+	enabled := m.auto.config.Enabled
+	m.auto.mu.Unlock()
+
+	if !enabled {
+		return
+	}
+	if err := m.startAutoBrightness(); err != nil {
+		log.Warnf("Auto-brightness enabled but unavailable: %v", err)
+	}
+}
+
+// autoDevice returns the configured device or the first backlight.
+func (m *Manager) autoDevice() (Device, bool) {
+	m.auto.mu.Lock()
+	want := m.auto.config.Device
+	m.auto.mu.Unlock()
+
+	state := m.GetState()
+	for _, dev := range state.Devices {
+		if want != "" && dev.ID == want {
+			return dev, true
+		}
+		if want == "" && dev.Class == ClassBacklight {
+			return dev, true
+		}
+	}
+	return Device{}, false
+}
+
+// startAutoBrightness opens the sensor with auto.mu held so concurrent enables
+// can't both subscribe.
+func (m *Manager) startAutoBrightness() error {
+	m.auto.mu.Lock()
+	if m.auto.sensor != nil {
+		m.auto.mu.Unlock()
+		return nil
+	}
+
+	newSensor := m.auto.newSensor
+	if newSensor == nil {
+		newSensor = NewLightSensor
+	}
+	sensor, err := newSensor()
+	if err != nil {
+		m.auto.mu.Unlock()
+		return err
+	}
+
+	stop := make(chan struct{})
+	m.auto.sensor = sensor
+	m.auto.sensorStop = stop
+	m.auto.ctrl = autoController{}
+	m.auto.mu.Unlock()
+
+	log.Infof("Auto-brightness using %s", sensor.Name())
+	go m.runAutoBrightness(sensor, stop)
+	m.publishAutoState()
+	return nil
+}
+
+func (m *Manager) stopAutoBrightness() {
+	m.auto.mu.Lock()
+	sensor, stop := m.auto.sensor, m.auto.sensorStop
+	m.auto.sensor, m.auto.sensorStop = nil, nil
+	m.auto.mu.Unlock()
+
+	if sensor == nil {
+		return
+	}
+	close(stop)
+	sensor.Close()
+}
+
+func (m *Manager) runAutoBrightness(sensor LightSensor, stop chan struct{}) {
+	// Sensors only report when the light changes, so an unfinished ramp is
+	// driven by its own timer rather than by the next reading.
+	var ramp <-chan time.Time
+	for {
+		select {
+		case <-stop:
+			return
+		case <-m.stopChan:
+			return
+		case lux, ok := <-sensor.Readings():
+			if !ok {
+				return
+			}
+			ramp = rampTimer(m.handleLux(lux))
+		case <-ramp:
+			ramp = rampTimer(m.stepAutoBrightness())
+		}
+	}
+}
+
+func rampTimer(ramping bool) <-chan time.Time {
+	if !ramping {
+		return nil
+	}
+	return time.After(autoRampInterval)
+}
+
+// handleLux feeds a reading to the controller and reports whether a ramp is
+// still in progress.
+func (m *Manager) handleLux(lux float64) bool {
+	m.auto.mu.Lock()
+	m.auto.ctrl.observe(lux)
+	m.auto.mu.Unlock()
+	return m.stepAutoBrightness()
+}
+
+func (m *Manager) stepAutoBrightness() bool {
+	dev, ok := m.autoDevice()
+
+	m.auto.mu.Lock()
+	if !ok {
+		m.auto.mu.Unlock()
+		return false
+	}
+	if !m.auto.ctrl.hasCurrent {
+		m.auto.ctrl.current, m.auto.ctrl.hasCurrent = dev.CurrentPercent, true
+	}
+
+	now := m.auto.clock()
+	if m.auto.paused || now.Before(m.auto.pausedUntil) || now.Before(m.auto.backoff) {
+		m.auto.mu.Unlock()
+		return false
+	}
+
+	percent, apply := m.auto.ctrl.next(m.auto.config.Curves[dev.ID])
+	ramping := m.auto.ctrl.ramping
+	lux := m.auto.ctrl.lux()
+	m.auto.mu.Unlock()
+
+	if !apply {
+		return false
+	}
+	log.Debugf("Auto-brightness: %.0f lux -> %s %d%%", lux, dev.ID, percent)
+	if err := m.setBrightness(dev.ID, percent, m.exponential, 1.2); err != nil {
+		log.Debugf("Auto-brightness failed to set %s: %v", dev.ID, err)
+		return false
+	}
+	return ramping
+}
+
+// noteManualBrightness backs off after a user adjustment and teaches the
+// device's curve the chosen brightness for the current light level.
+func (m *Manager) noteManualBrightness(deviceID string, percent int) {
+	m.auto.mu.Lock()
+	if m.auto.sensor == nil {
+		m.auto.mu.Unlock()
+		return
+	}
+	m.auto.mu.Unlock()
+
+	dev, ok := m.autoDevice()
+	if !ok || dev.ID != deviceID {
+		return
+	}
+
+	m.auto.mu.Lock()
+	m.auto.backoff = m.auto.clock().Add(autoManualBackoff)
+	m.auto.ctrl.manual(percent)
+	var cfg autoConfig
+	learned := m.auto.ctrl.hasReading
+	if learned {
+		lux := m.auto.ctrl.lux()
+		if m.auto.config.Curves == nil {
+			m.auto.config.Curves = map[string][]CurvePoint{}
+		}
+		m.auto.config.Curves[deviceID] = learnPoint(m.auto.config.Curves[deviceID], lux, percent)
+		cfg = m.auto.config.clone()
+	}
+	m.auto.mu.Unlock()
+
+	if learned {
+		if err := saveAutoConfig(cfg); err != nil {
+			log.Warnf("Failed to save auto-brightness curve: %v", err)
+		}
+	}
+}
+
+func (m *Manager) GetAutoBrightnessState() AutoBrightnessState {
+	dev, hasDev := m.autoDevice()
+
+	m.auto.mu.Lock()
+	defer m.auto.mu.Unlock()
+
+	state := AutoBrightnessState{
+		Enabled: m.auto.config.Enabled,
+		Paused:  m.auto.paused,
+		Device:  m.auto.config.Device,
+		Lux:     math.Round(m.auto.ctrl.lux()*10) / 10,
+		Target:  m.auto.ctrl.target,
+	}
+	if m.auto.sensor != nil {
+		state.Sensor = m.auto.sensor.Name()
+	}
+	if until := m.auto.pausedUntil; m.auto.clock().Before(until) {
+		state.Paused = true
+		state.PausedUntil = &until
+	}
+	if hasDev {
+		state.Device = dev.ID
+		state.Curve = slices.Clone(m.auto.config.Curves[dev.ID])
+		if state.Curve == nil {
+			state.Curve = slices.Clone(defaultCurve)
+		}
+	}
+	return state
+}
+
+func (m *Manager) publishAutoState() {
+	auto := m.GetAutoBrightnessState()
+	auto.Curve = nil
+
+	m.stateMutex.Lock()
+	m.state.AutoBrightness = &auto
+	m.stateMutex.Unlock()
+	m.NotifySubscribers()
+}
+
+func (m *Manager) EnableAutoBrightness(deviceID string) error {
+	if deviceID != "" {
+		found := slices.ContainsFunc(m.GetState().Devices, func(d Device) bool { return d.ID == deviceID })
+		if !found {
+			return fmt.Errorf("device not found: %s", deviceID)
+		}
+	}
+
+	m.auto.mu.Lock()
+	m.auto.config.Device = deviceID
+	m.auto.mu.Unlock()
+
+	if err := m.startAutoBrightness(); err != nil {
+		return fmt.Errorf("no ambient light sensor: %w", err)
+	}
+
+	m.auto.mu.Lock()
+	m.auto.config.Enabled = true
+	m.auto.paused = false
+	m.auto.pausedUntil = time.Time{}
+	cfg := m.auto.config.clone()
+	m.auto.mu.Unlock()
+
+	m.publishAutoState()
+	return saveAutoConfig(cfg)
+}
+
+func (m *Manager) DisableAutoBrightness() error {
+	m.stopAutoBrightness()
+
+	m.auto.mu.Lock()
+	m.auto.config.Enabled = false
+	cfg := m.auto.config.clone()
+	m.auto.mu.Unlock()
+
+	m.publishAutoState()
+	return saveAutoConfig(cfg)
+}
+
+// PauseAutoBrightness suspends adjustments for d, or until resumed when d is 0.
+func (m *Manager) PauseAutoBrightness(d time.Duration) {
+	m.auto.mu.Lock()
+	switch {
+	case d > 0:
+		m.auto.pausedUntil = m.auto.clock().Add(d)
+	default:
+		m.auto.paused = true
+	}
+	m.auto.mu.Unlock()
+	m.publishAutoState()
+}
+
+func (m *Manager) ResumeAutoBrightness() {
+	m.auto.mu.Lock()
+	m.auto.paused = false
+	m.auto.pausedUntil = time.Time{}
+	m.auto.backoff = time.Time{}
+	m.auto.ctrl.anchored = false
+	m.auto.mu.Unlock()
+	m.publishAutoState()
+}
+
+func (m *Manager) ResetAutoBrightnessCurve(deviceID string) error {
+	if deviceID == "" {
+		dev, ok := m.autoDevice()
+		if !ok {
+			return fmt.Errorf("no auto-brightness device")
+		}
+		deviceID = dev.ID
+	}
+
+	m.auto.mu.Lock()
+	delete(m.auto.config.Curves, deviceID)
+	cfg := m.auto.config.clone()
+	m.auto.mu.Unlock()
+
+	return saveAutoConfig(cfg)
+}
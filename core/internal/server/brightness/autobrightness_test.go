@@ -0,0 +1,280 @@
+package brightness
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeLightSensor struct {
+	readings chan float64
+	closed   bool
+}
+
+func newFakeLightSensor() *fakeLightSensor {
+	return &fakeLightSensor{readings: make(chan float64, 16)}
+}
+
+func (s *fakeLightSensor) Name() string             { return "fake" }
+func (s *fakeLightSensor) Readings() <-chan float64 { return s.readings }
+func (s *fakeLightSensor) Close()                   { s.closed = true }
+
+type fakeSet struct {
+	id          string
+	percent     int
+	exponential bool
+}
+
+type fakeBackend struct {
+	mu    sync.Mutex
+	calls []fakeSet
+}
+
+func (b *fakeBackend) Rescan() error                 { return nil }
+func (b *fakeBackend) GetDevices() ([]Device, error) { return nil, nil }
+func (b *fakeBackend) SetBrightnessWithExponent(id string, percent int, exponential bool, exponent float64) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.calls = append(b.calls, fakeSet{id: id, percent: percent, exponential: exponential})
+	return nil
+}
+
+func (b *fakeBackend) values() []int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	values := make([]int, 0, len(b.calls))
+	for _, call := range b.calls {
+		values = append(values, call.percent)
+	}
+	return values
+}
+
+func setupAutoManager(t *testing.T) (*Manager, *fakeBackend, *fakeLightSensor, *time.Time) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	backend := &fakeBackend{}
+	sensor := newFakeLightSensor()
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	m := &Manager{
+		nativeBackend: backend,
+		nativeReady:   true,
+		stopChan:      make(chan struct{}),
+	}
+	m.state = State{Devices: []Device{
+		{Class: ClassBacklight, ID: "backlight:intel_backlight", Name: "intel_backlight", CurrentPercent: 50},
+		{Class: ClassLED, ID: "leds:kbd_backlight", Name: "kbd_backlight"},
+	}}
+	m.auto.config = autoConfig{Curves: map[string][]CurvePoint{}}
+	m.auto.newSensor = func() (LightSensor, error) { return sensor, nil }
+	m.auto.now = func() time.Time { return now }
+
+	t.Cleanup(func() {
+		m.stopAutoBrightness()
+	})
+	return m, backend, sensor, &now
+}
+
+func TestCurvePercent(t *testing.T) {
+	assert.Equal(t, 5, curvePercent(nil, 0))
+	assert.Equal(t, 40, curvePercent(defaultCurve, 100))
+	assert.Equal(t, 100, curvePercent(defaultCurve, 50000))
+
+	mid := curvePercent(defaultCurve, 300)
+	assert.Greater(t, mid, 40)
+	assert.Less(t, mid, 70)
+}
+
+func TestLearnPoint_ReplacesNearbyAndStaysMonotonic(t *testing.T) {
+	curve := learnPoint(defaultCurve, 120, 25)
+
+	assert.Equal(t, 25, curvePercent(curve, 120))
+	for _, p := range curve {
+		assert.NotEqual(t, 100.0, p.Lux, "point within the learn radius should be replaced")
+	}
+	for i := 1; i < len(curve); i++ {
+		assert.Less(t, curve[i-1].Lux, curve[i].Lux)
+		assert.LessOrEqual(t, curve[i-1].Percent, curve[i].Percent)
+	}
+
+	curve = learnPoint(curve, 5, 60)
+	assert.Equal(t, 60, curvePercent(curve, 120), "brighter choice in dim light lifts the rest of the curve")
+}
+
+func TestAutoController_SmoothingAndHysteresis(t *testing.T) {
+	var c autoController
+	c.current, c.hasCurrent = 40, true
+
+	c.observe(100)
+	_, apply := c.next(defaultCurve)
+	assert.False(t, apply, "target within the percent hysteresis")
+
+	c.observe(130)
+	_, apply = c.next(defaultCurve)
+	assert.False(t, apply, "small lux wobble must not move brightness")
+
+	smoothed := c.observe(10000)
+	assert.Less(t, smoothed, 10000.0, "single spike is smoothed")
+
+	var steps []int
+	for range 20 {
+		c.observe(10000)
+		if p, ok := c.next(defaultCurve); ok {
+			steps = append(steps, p)
+		}
+	}
+	require.NotEmpty(t, steps)
+	for i := 1; i < len(steps); i++ {
+		assert.LessOrEqual(t, steps[i]-steps[i-1], autoMaxStep)
+	}
+	assert.InDelta(t, 100, c.current, autoPercentHysteresis)
+}
+
+func TestAutoBrightness_AppliesFromSensor(t *testing.T) {
+	m, backend, _, _ := setupAutoManager(t)
+	require.NoError(t, m.EnableAutoBrightness(""))
+
+	state := m.GetAutoBrightnessState()
+	assert.True(t, state.Enabled)
+	assert.Equal(t, "fake", state.Sensor)
+	assert.Equal(t, "backlight:intel_backlight", state.Device)
+	require.NotNil(t, m.GetState().AutoBrightness)
+
+	m.handleLux(10000)
+	assert.Equal(t, []int{58}, backend.values(), "ramps toward the target by at most autoMaxStep")
+
+	_, err := os.Stat(filepath.Join(os.Getenv("XDG_CONFIG_HOME"), "DankMaterialShell", "auto-brightness.json"))
+	assert.NoError(t, err)
+}
+
+func TestAutoBrightness_RampFinishesWithoutNewReadings(t *testing.T) {
+	m, backend, _, _ := setupAutoManager(t)
+	require.NoError(t, m.EnableAutoBrightness(""))
+
+	require.True(t, m.handleLux(10000))
+	for range 10 {
+		if !m.stepAutoBrightness() {
+			break
+		}
+	}
+	values := backend.values()
+	assert.Equal(t, curvePercent(defaultCurve, 10000), values[len(values)-1], "steady light still reaches the target")
+	assert.False(t, m.stepAutoBrightness())
+}
+
+func TestAutoBrightness_ManualAdjustBacksOffAndLearns(t *testing.T) {
+	m, backend, _, now := setupAutoManager(t)
+	require.NoError(t, m.EnableAutoBrightness(""))
+
+	m.handleLux(200)
+	require.NoError(t, m.SetBrightness("backlight:intel_backlight", 20))
+
+	applied := len(backend.values())
+	m.handleLux(5000)
+	assert.Len(t, backend.values(), applied, "auto must back off after a manual change")
+
+	state := m.GetAutoBrightnessState()
+	assert.Equal(t, 20, curvePercent(state.Curve, 200), "manual choice is learned")
+
+	*now = now.Add(autoManualBackoff + time.Second)
+	m.handleLux(5000)
+	assert.Greater(t, len(backend.values()), applied)
+}
+
+func TestAutoBrightness_ConcurrentLearnAndReset(t *testing.T) {
+	m, _, _, _ := setupAutoManager(t)
+	require.NoError(t, m.EnableAutoBrightness(""))
+	m.handleLux(200)
+
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Go(func() { m.noteManualBrightness("backlight:intel_backlight", 20+i) })
+		wg.Go(func() { assert.NoError(t, m.ResetAutoBrightnessCurve("backlight:intel_backlight")) })
+	}
+	wg.Wait()
+}
+
+func TestAutoBrightness_ConcurrentEnableOpensOneSensor(t *testing.T) {
+	m, _, sensor, _ := setupAutoManager(t)
+
+	var mu sync.Mutex
+	opened := 0
+	m.auto.newSensor = func() (LightSensor, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		opened++
+		return sensor, nil
+	}
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() { assert.NoError(t, m.EnableAutoBrightness("")) })
+	}
+	wg.Wait()
+	assert.Equal(t, 1, opened)
+}
+
+func TestAutoBrightness_PauseResume(t *testing.T) {
+	m, backend, _, now := setupAutoManager(t)
+	require.NoError(t, m.EnableAutoBrightness(""))
+
+	m.PauseAutoBrightness(time.Minute)
+	assert.True(t, m.GetAutoBrightnessState().Paused)
+	m.handleLux(10000)
+	assert.Empty(t, backend.values())
+
+	*now = now.Add(2 * time.Minute)
+	assert.False(t, m.GetAutoBrightnessState().Paused)
+
+	m.PauseAutoBrightness(0)
+	m.handleLux(10000)
+	assert.Empty(t, backend.values())
+
+	m.ResumeAutoBrightness()
+	m.handleLux(10000)
+	assert.NotEmpty(t, backend.values())
+}
+
+func TestAutoBrightness_EnableErrors(t *testing.T) {
+	m, _, sensor, _ := setupAutoManager(t)
+
+	assert.Error(t, m.EnableAutoBrightness("backlight:missing"))
+
+	m.auto.newSensor = func() (LightSensor, error) { return nil, os.ErrNotExist }
+	assert.Error(t, m.EnableAutoBrightness(""))
+	assert.False(t, m.GetAutoBrightnessState().Enabled)
+
+	m.auto.newSensor = func() (LightSensor, error) { return sensor, nil }
+	require.NoError(t, m.EnableAutoBrightness("leds:kbd_backlight"))
+	require.NoError(t, m.DisableAutoBrightness())
+	assert.True(t, sensor.closed)
+	assert.False(t, m.GetAutoBrightnessState().Enabled)
+}
+
+func TestIIOLightSensor_Sysfs(t *testing.T) {
+	root := t.TempDir()
+	dev := filepath.Join(root, "iio:device0")
+	require.NoError(t, os.MkdirAll(dev, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dev, "in_illuminance_raw"), []byte("200\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dev, "in_illuminance_scale"), []byte("0.5\n"), 0o644))
+
+	_, err := newIIOLightSensor(t.TempDir())
+	assert.Error(t, err)
+
+	s, err := newIIOLightSensor(root)
+	require.NoError(t, err)
+	defer s.Close()
+
+	assert.Equal(t, "iio:device0", s.Name())
+	select {
+	case lux := <-s.Readings():
+		assert.Equal(t, 100.0, lux)
+	case <-time.After(time.Second):
+		t.Fatal("no reading from iio sensor")
+	}
+}
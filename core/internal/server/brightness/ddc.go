@@ -269,6 +269,9 @@ func (b *DDCBackend) setBrightnessImmediateWithExponent(id string, value int) er
 		return fmt.Errorf("device not found: %s", id)
 	}
 
+	dev.ioMutex.Lock()
+	defer dev.ioMutex.Unlock()
+
 	busPath := fmt.Sprintf("/dev/i2c-%d", dev.bus)
 
 	if _, err := os.Stat(busPath); os.IsNotExist(err) {
@@ -0,0 +1,541 @@
+package brightness
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/AvengeMedia/DankMaterialShell/core/internal/log"
+)
+
+const (
+	VCP_CONTRAST     = 0x12
+	VCP_COLOR_PRESET = 0x14
+	VCP_RED_GAIN     = 0x16
+	VCP_GREEN_GAIN   = 0x18
+	VCP_BLUE_GAIN    = 0x1A
+	VCP_INPUT_SOURCE = 0x60
+	VCP_VOLUME       = 0x62
+	VCP_MUTE         = 0x8D
+	VCP_POWER_MODE   = 0xD6
+
+	DDCCI_CAPS_REQUEST = 0xF3
+	DDCCI_CAPS_REPLY   = 0xE3
+
+	ddcCapsMaxFragments = 64
+)
+
+type ddcNamedValue struct {
+	name  string
+	value int
+}
+
+// DDCFeature describes a VCP code DMS knows how to drive. For non-continuous
+// features the first name for a value is canonical; later ones are aliases.
+type DDCFeature struct {
+	Name   string
+	Code   byte
+	values []ddcNamedValue
+}
+
+var ddcInputSources = []ddcNamedValue{
+	{"vga1", 0x01}, {"vga2", 0x02},
+	{"dvi1", 0x03}, {"dvi2", 0x04},
+	{"composite1", 0x05}, {"composite2", 0x06},
+	{"svideo1", 0x07}, {"svideo2", 0x08},
+	{"tuner1", 0x09}, {"tuner2", 0x0A}, {"tuner3", 0x0B},
+	{"component1", 0x0C}, {"component2", 0x0D}, {"component3", 0x0E},
+	{"dp1", 0x0F}, {"dp2", 0x10},
+	{"hdmi1", 0x11}, {"hdmi2", 0x12},
+	{"usbc", 0x1B},
+	{"displayport1", 0x0F}, {"displayport2", 0x10}, {"dp", 0x0F}, {"hdmi", 0x11},
+}
+
+var ddcColorPresets = []ddcNamedValue{
+	{"srgb", 0x01}, {"native", 0x02},
+	{"4000k", 0x03}, {"5000k", 0x04}, {"6500k", 0x05}, {"7500k", 0x06},
+	{"8200k", 0x07}, {"9300k", 0x08}, {"10000k", 0x09}, {"11500k", 0x0A},
+	{"user1", 0x0B}, {"user2", 0x0C}, {"user3", 0x0D},
+}
+
+var ddcMuteValues = []ddcNamedValue{
+	{"muted", 0x01}, {"unmuted", 0x02},
+	{"on", 0x01}, {"true", 0x01}, {"yes", 0x01},
+	{"off", 0x02}, {"false", 0x02}, {"no", 0x02},
+}
+
+var ddcPowerModes = []ddcNamedValue{
+	{"on", 0x01}, {"standby", 0x02}, {"suspend", 0x03}, {"off", 0x04}, {"hard-off", 0x05},
+}
+
+var ddcFeatures = []DDCFeature{
+	{Name: "brightness", Code: VCP_BRIGHTNESS},
+	{Name: "contrast", Code: VCP_CONTRAST},
+	{Name: "input", Code: VCP_INPUT_SOURCE, values: ddcInputSources},
+	{Name: "volume", Code: VCP_VOLUME},
+	{Name: "mute", Code: VCP_MUTE, values: ddcMuteValues},
+	{Name: "color-preset", Code: VCP_COLOR_PRESET, values: ddcColorPresets},
+	{Name: "red-gain", Code: VCP_RED_GAIN},
+	{Name: "green-gain", Code: VCP_GREEN_GAIN},
+	{Name: "blue-gain", Code: VCP_BLUE_GAIN},
+	{Name: "power", Code: VCP_POWER_MODE, values: ddcPowerModes},
+}
+
+func DDCFeatureNames() []string {
+	names := make([]string, 0, len(ddcFeatures))
+	for _, f := range ddcFeatures {
+		names = append(names, f.Name)
+	}
+	return names
+}
+
+// LookupDDCFeature accepts a feature name or a raw VCP code ("0x60", "60").
+func LookupDDCFeature(name string) (DDCFeature, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, f := range ddcFeatures {
+		if f.Name == name {
+			return f, nil
+		}
+	}
+
+	code, err := strconv.ParseUint(strings.TrimPrefix(name, "0x"), 16, 8)
+	if err != nil {
+		return DDCFeature{}, fmt.Errorf("unknown DDC feature: %s", name)
+	}
+	if f, ok := DDCFeatureByCode(byte(code)); ok {
+		return f, nil
+	}
+	return DDCFeature{Name: fmt.Sprintf("0x%02x", code), Code: byte(code)}, nil
+}
+
+func DDCFeatureByCode(code byte) (DDCFeature, bool) {
+	for _, f := range ddcFeatures {
+		if f.Code == code {
+			return f, true
+		}
+	}
+	return DDCFeature{}, false
+}
+
+func (f DDCFeature) continuous() bool {
+	return len(f.values) == 0
+}
+
+// ParseValue resolves a symbolic name ("hdmi1", "6500k", "standby") or a
+// number (decimal or 0x-prefixed hex).
+func (f DDCFeature) ParseValue(s string) (int, error) {
+	key := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(s), "-", ""))
+	for _, nv := range f.values {
+		if strings.ReplaceAll(nv.name, "-", "") == key {
+			return nv.value, nil
+		}
+	}
+
+	v, err := strconv.ParseInt(strings.TrimSpace(s), 0, 32)
+	if err != nil || v < 0 || v > 0xFFFF {
+		return 0, fmt.Errorf("invalid value %q for %s", s, f.Name)
+	}
+	return int(v), nil
+}
+
+func (f DDCFeature) valueName(v int) string {
+	for _, nv := range f.values {
+		if nv.value == v {
+			return nv.name
+		}
+	}
+	return ""
+}
+
+type DDCFeatureValue struct {
+	Feature string `json:"feature"`
+	Code    int    `json:"code"`
+	Current int    `json:"current"`
+	Max     int    `json:"max"`
+	Value   string `json:"value,omitempty"`
+}
+
+type DDCVCPCapability struct {
+	Code   int   `json:"code"`
+	Values []int `json:"values,omitempty"`
+}
+
+type DDCCapabilities struct {
+	Raw         string             `json:"raw"`
+	Protocol    string             `json:"protocol,omitempty"`
+	Type        string             `json:"type,omitempty"`
+	Model       string             `json:"model,omitempty"`
+	MCCSVersion string             `json:"mccsVersion,omitempty"`
+	Commands    []int              `json:"commands,omitempty"`
+	VCP         []DDCVCPCapability `json:"vcp"`
+}
+
+func (c *DDCCapabilities) Supports(code byte) bool {
+	if c == nil {
+		return true
+	}
+	return slices.ContainsFunc(c.VCP, func(v DDCVCPCapability) bool { return v.Code == int(code) })
+}
+
+// ParseDDCCapabilities parses an MCCS capability string such as
+// "(prot(monitor)type(lcd)model(U2720Q)vcp(10 12 60(0F 11 1B))mccs_ver(2.1))".
+func ParseDDCCapabilities(raw string) (*DDCCapabilities, error) {
+	s := strings.TrimSpace(strings.TrimRight(raw, "\x00"))
+	if strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") {
+		s = s[1 : len(s)-1]
+	}
+
+	caps := &DDCCapabilities{Raw: raw, VCP: []DDCVCPCapability{}}
+	i := 0
+	for i < len(s) {
+		open := strings.IndexByte(s[i:], '(')
+		if open < 0 {
+			break
+		}
+		key := strings.ToLower(strings.TrimSpace(s[i : i+open]))
+		body, next, err := capsGroup(s, i+open)
+		if err != nil {
+			return nil, err
+		}
+		i = next
+
+		switch key {
+		case "prot":
+			caps.Protocol = body
+		case "type":
+			caps.Type = body
+		case "model":
+			caps.Model = body
+		case "mccs_ver":
+			caps.MCCSVersion = body
+		case "cmds":
+			for _, tok := range strings.Fields(body) {
+				if v, err := strconv.ParseUint(tok, 16, 8); err == nil {
+					caps.Commands = append(caps.Commands, int(v))
+				}
+			}
+		case "vcp":
+			vcp, err := parseCapsVCP(body)
+			if err != nil {
+				return nil, err
+			}
+			caps.VCP = vcp
+		}
+	}
+
+	if caps.Protocol == "" && len(caps.VCP) == 0 {
+		return nil, fmt.Errorf("invalid capability string")
+	}
+	return caps, nil
+}
+
+// capsGroup returns the contents of the balanced group opening at s[open].
+func capsGroup(s string, open int) (string, int, error) {
+	depth := 0
+	for j := open; j < len(s); j++ {
+		switch s[j] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return s[open+1 : j], j + 1, nil
+			}
+		}
+	}
+	return "", 0, fmt.Errorf("unbalanced capability string")
+}
+
+func parseCapsVCP(body string) ([]DDCVCPCapability, error) {
+	var vcp []DDCVCPCapability
+	i := 0
+	for i < len(body) {
+		switch c := body[i]; {
+		case c == ' ':
+			i++
+		case c == '(':
+			values, next, err := capsGroup(body, i)
+			if err != nil {
+				return nil, err
+			}
+			i = next
+			if len(vcp) == 0 {
+				continue
+			}
+			for _, tok := range strings.Fields(values) {
+				if v, err := strconv.ParseUint(tok, 16, 16); err == nil {
+					vcp[len(vcp)-1].Values = append(vcp[len(vcp)-1].Values, int(v))
+				}
+			}
+		default:
+			end := i
+			for end < len(body) && body[end] != ' ' && body[end] != '(' {
+				end++
+			}
+			code, err := strconv.ParseUint(body[i:end], 16, 8)
+			if err != nil {
+				return nil, fmt.Errorf("invalid vcp code %q", body[i:end])
+			}
+			vcp = append(vcp, DDCVCPCapability{Code: int(code)})
+			i = end
+		}
+	}
+	return vcp, nil
+}
+
+// ddcIO is the raw i2c transport; tests substitute a fake monitor.
+type ddcIO interface {
+	Write(p []byte) (int, error)
+	Read(p []byte) (int, error)
+}
+
+type fdIO int
+
+func (f fdIO) Write(p []byte) (int, error) { return syscall.Write(int(f), p) }
+func (f fdIO) Read(p []byte) (int, error)  { return syscall.Read(int(f), p) }
+
+// readCapabilities issues Capabilities Request (0xF3) fragments until the
+// monitor replies with an empty fragment.
+func readCapabilities(rw ddcIO, delay time.Duration) (string, error) {
+	var caps []byte
+	for range ddcCapsMaxFragments {
+		offset := len(caps)
+		req := []byte{DDC_SOURCE_ADDR, 0x83, DDCCI_CAPS_REQUEST, byte(offset >> 8), byte(offset & 0xFF)}
+		req = append(req, ddcciChecksum(req))
+		if n, err := rw.Write(req); err != nil || n != len(req) {
+			return "", fmt.Errorf("write capabilities request: wrote %d/%d: %w", n, len(req), err)
+		}
+
+		time.Sleep(delay)
+
+		resp := make([]byte, 40)
+		n, err := rw.Read(resp)
+		if err != nil {
+			return "", fmt.Errorf("read capabilities reply: %w", err)
+		}
+		if n < 6 {
+			return "", fmt.Errorf("short capabilities reply")
+		}
+		length := int(resp[1] & 0x7F)
+		if resp[0] != 0x6E || resp[2] != DDCCI_CAPS_REPLY || length < 3 || 2+length >= n {
+			return "", fmt.Errorf("invalid capabilities reply")
+		}
+		if got := int(resp[3])<<8 | int(resp[4]); got != offset {
+			return "", fmt.Errorf("capabilities offset mismatch: wanted %d, got %d", offset, got)
+		}
+
+		fragment := resp[5 : 2+length]
+		if len(fragment) == 0 {
+			return string(caps), nil
+		}
+		caps = append(caps, fragment...)
+	}
+	return "", fmt.Errorf("capabilities string too long")
+}
+
+// withDevice opens the device's bus and serializes access to it.
+func (b *DDCBackend) withDevice(id string, fn func(dev *ddcDevice, fd int) error) error {
+	dev, ok := b.devices.Load(id)
+	if !ok {
+		if err := b.scanI2CDevicesInternal(true); err != nil {
+			log.Debugf("rescan failed for %s: %v", id, err)
+		}
+		dev, ok = b.devices.Load(id)
+	}
+	if !ok {
+		return fmt.Errorf("device not found: %s", id)
+	}
+
+	dev.ioMutex.Lock()
+	defer dev.ioMutex.Unlock()
+
+	busPath := fmt.Sprintf("/dev/i2c-%d", dev.bus)
+	fd, err := syscall.Open(busPath, syscall.O_RDWR, 0)
+	if err != nil {
+		if os.IsNotExist(err) {
+			b.devices.Delete(id)
+		}
+		return fmt.Errorf("open i2c device: %w", err)
+	}
+	defer syscall.Close(fd)
+
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), I2C_SLAVE, uintptr(dev.addr)); errno != 0 {
+		return fmt.Errorf("set i2c slave addr: %w", errno)
+	}
+
+	return fn(dev, fd)
+}
+
+func (b *DDCBackend) Capabilities(id string) (*DDCCapabilities, error) {
+	var caps *DDCCapabilities
+	err := b.withDevice(id, func(dev *ddcDevice, fd int) error {
+		if dev.caps != nil {
+			caps = dev.caps
+			return nil
+		}
+		raw, err := readCapabilities(fdIO(fd), 50*time.Millisecond)
+		if err != nil {
+			return err
+		}
+		parsed, err := ParseDDCCapabilities(raw)
+		if err != nil {
+			return err
+		}
+		dev.caps = parsed
+		caps = parsed
+		return nil
+	})
+	return caps, err
+}
+
+func (b *DDCBackend) GetFeature(id string, feature DDCFeature) (DDCFeatureValue, error) {
+	var value DDCFeatureValue
+	err := b.withDevice(id, func(dev *ddcDevice, fd int) error {
+		cap, err := b.getVCPFeature(fd, feature.Code)
+		if err != nil {
+			return err
+		}
+		value = featureValue(feature, cap)
+		return nil
+	})
+	return value, err
+}
+
+func featureValue(feature DDCFeature, cap *ddcCapability) DDCFeatureValue {
+	current := cap.current
+	if !feature.continuous() {
+		// Non-continuous replies carry vendor bits in the high byte (SH).
+		current &= 0xFF
+	}
+	return DDCFeatureValue{
+		Feature: feature.Name,
+		Code:    int(feature.Code),
+		Current: current,
+		Max:     cap.max,
+		Value:   feature.valueName(current),
+	}
+}
+
+// GetFeatures reads every known feature the monitor advertises. Features
+// that fail to read are skipped rather than failing the whole request.
+func (b *DDCBackend) GetFeatures(id string) ([]DDCFeatureValue, error) {
+	caps, err := b.Capabilities(id)
+	if err != nil {
+		log.Debugf("DDC capabilities unavailable for %s: %v", id, err)
+		caps = nil
+	}
+
+	values := []DDCFeatureValue{}
+	err = b.withDevice(id, func(dev *ddcDevice, fd int) error {
+		for _, feature := range ddcFeatures {
+			if !caps.Supports(feature.Code) {
+				continue
+			}
+			cap, err := b.getVCPFeature(fd, feature.Code)
+			if err != nil {
+				log.Debugf("DDC %s: failed to read %s: %v", id, feature.Name, err)
+				continue
+			}
+			values = append(values, featureValue(feature, cap))
+		}
+		return nil
+	})
+	return values, err
+}
+
+func (b *DDCBackend) SetFeature(id string, feature DDCFeature, value int) error {
+	if value < 0 || value > 0xFFFF {
+		return fmt.Errorf("value out of range: %d", value)
+	}
+
+	return b.withDevice(id, func(dev *ddcDevice, fd int) error {
+		if dev.caps != nil && !dev.caps.Supports(feature.Code) {
+			return fmt.Errorf("%s is not supported by %s", feature.Name, dev.name)
+		}
+		if err := b.setVCPFeature(fd, feature.Code, value); err != nil {
+			return err
+		}
+		if feature.Code == VCP_BRIGHTNESS {
+			dev.lastBrightness = value
+		}
+		log.Debugf("DDC %s: set %s to %d", id, feature.Name, value)
+		return nil
+	})
+}
+
+func (m *Manager) ddc(id string) (*DDCBackend, error) {
+	if !m.ddcReady || m.ddcBackend == nil {
+		return nil, fmt.Errorf("DDC backend not available")
+	}
+	if !strings.HasPrefix(id, string(ClassDDC)+":") {
+		return nil, fmt.Errorf("not a DDC device: %s", id)
+	}
+	return m.ddcBackend, nil
+}
+
+func (m *Manager) GetDDCCapabilities(id string) (*DDCCapabilities, error) {
+	b, err := m.ddc(id)
+	if err != nil {
+		return nil, err
+	}
+	return b.Capabilities(id)
+}
+
+func (m *Manager) GetDDCFeatures(id string) ([]DDCFeatureValue, error) {
+	b, err := m.ddc(id)
+	if err != nil {
+		return nil, err
+	}
+	return b.GetFeatures(id)
+}
+
+func (m *Manager) GetDDCFeature(id, name string) (DDCFeatureValue, error) {
+	b, err := m.ddc(id)
+	if err != nil {
+		return DDCFeatureValue{}, err
+	}
+	feature, err := LookupDDCFeature(name)
+	if err != nil {
+		return DDCFeatureValue{}, err
+	}
+	return b.GetFeature(id, feature)
+}
+
+// SetDDCFeature writes a VCP feature and reads it back. Brightness writes
+// bypass the debounce, so the published state is refreshed here.
+func (m *Manager) SetDDCFeature(id, name, value string) (DDCFeatureValue, error) {
+	b, err := m.ddc(id)
+	if err != nil {
+		return DDCFeatureValue{}, err
+	}
+	feature, err := LookupDDCFeature(name)
+	if err != nil {
+		return DDCFeatureValue{}, err
+	}
+	raw, err := feature.ParseValue(value)
+	if err != nil {
+		return DDCFeatureValue{}, err
+	}
+	if err := b.SetFeature(id, feature, raw); err != nil {
+		return DDCFeatureValue{}, err
+	}
+
+	if feature.Code == VCP_BRIGHTNESS {
+		m.updateState()
+		m.debouncedBroadcast(id)
+	}
+
+	result := DDCFeatureValue{Feature: feature.Name, Code: int(feature.Code), Current: raw, Value: feature.valueName(raw)}
+	if feature.Code == VCP_POWER_MODE {
+		// Monitors entering standby often stop answering; don't read back.
+		return result, nil
+	}
+	if readBack, err := b.GetFeature(id, feature); err == nil {
+		return readBack, nil
+	}
+	return result, nil
+}
@@ -0,0 +1,131 @@
+package brightness
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testCapsString = "(prot(monitor)type(LCD)model(U2720Q)cmds(01 02 03 07 0C E3 F3)vcp(02 04 10 12 14(01 05 08 0B) 60(0F 11 1B) 62 8D(01 02) D6(01 04 05))mccs_ver(2.1))"
+
+// fakeCapsMonitor answers Capabilities Requests in 32-byte fragments.
+type fakeCapsMonitor struct {
+	caps     string
+	offset   int
+	requests int
+}
+
+func (f *fakeCapsMonitor) Write(p []byte) (int, error) {
+	f.requests++
+	f.offset = int(p[3])<<8 | int(p[4])
+	return len(p), nil
+}
+
+func (f *fakeCapsMonitor) Read(p []byte) (int, error) {
+	end := min(f.offset+32, len(f.caps))
+	fragment := []byte(f.caps[min(f.offset, end):end])
+
+	resp := []byte{0x6E, byte(3+len(fragment)) | 0x80, DDCCI_CAPS_REPLY, byte(f.offset >> 8), byte(f.offset)}
+	resp = append(resp, fragment...)
+	resp = append(resp, 0x00)
+	return copy(p, resp), nil
+}
+
+func TestReadCapabilities_AssemblesFragments(t *testing.T) {
+	mon := &fakeCapsMonitor{caps: testCapsString}
+
+	raw, err := readCapabilities(mon, 0)
+	require.NoError(t, err)
+	assert.Equal(t, testCapsString, raw)
+	assert.Equal(t, len(testCapsString)/32+2, mon.requests)
+}
+
+func TestReadCapabilities_RejectsBadReply(t *testing.T) {
+	mon := &badCapsMonitor{}
+	_, err := readCapabilities(mon, 0)
+	assert.Error(t, err)
+}
+
+type badCapsMonitor struct{}
+
+func (badCapsMonitor) Write(p []byte) (int, error) { return len(p), nil }
+func (badCapsMonitor) Read(p []byte) (int, error) {
+	return copy(p, []byte{0x6E, 0x80, 0xBE}), nil
+}
+
+func TestParseDDCCapabilities(t *testing.T) {
+	caps, err := ParseDDCCapabilities(testCapsString)
+	require.NoError(t, err)
+
+	assert.Equal(t, "monitor", caps.Protocol)
+	assert.Equal(t, "LCD", caps.Type)
+	assert.Equal(t, "U2720Q", caps.Model)
+	assert.Equal(t, "2.1", caps.MCCSVersion)
+	assert.Equal(t, []int{0x01, 0x02, 0x03, 0x07, 0x0C, 0xE3, 0xF3}, caps.Commands)
+
+	require.Len(t, caps.VCP, 9)
+	assert.Equal(t, DDCVCPCapability{Code: 0x60, Values: []int{0x0F, 0x11, 0x1B}}, caps.VCP[5])
+	assert.Equal(t, DDCVCPCapability{Code: 0x62}, caps.VCP[6])
+
+	assert.True(t, caps.Supports(VCP_INPUT_SOURCE))
+	assert.False(t, caps.Supports(VCP_RED_GAIN))
+
+	_, err = ParseDDCCapabilities("(prot(monitor)vcp(10 12")
+	assert.Error(t, err)
+}
+
+func TestLookupDDCFeature(t *testing.T) {
+	f, err := LookupDDCFeature("Input")
+	require.NoError(t, err)
+	assert.Equal(t, byte(VCP_INPUT_SOURCE), f.Code)
+
+	f, err = LookupDDCFeature("0x12")
+	require.NoError(t, err)
+	assert.Equal(t, "contrast", f.Name)
+
+	f, err = LookupDDCFeature("e0")
+	require.NoError(t, err)
+	assert.Equal(t, "0xe0", f.Name)
+
+	_, err = LookupDDCFeature("sharpness")
+	assert.Error(t, err)
+}
+
+func TestDDCFeature_ParseValue(t *testing.T) {
+	input, _ := LookupDDCFeature("input")
+	for in, want := range map[string]int{"hdmi1": 0x11, "HDMI-2": 0x12, "dp1": 0x0F, "usbc": 0x1B, "0x0f": 0x0F, "17": 17} {
+		got, err := input.ParseValue(in)
+		require.NoError(t, err, in)
+		assert.Equal(t, want, got, in)
+	}
+	_, err := input.ParseValue("scart")
+	assert.Error(t, err)
+
+	mute, _ := LookupDDCFeature("mute")
+	v, err := mute.ParseValue("off")
+	require.NoError(t, err)
+	assert.Equal(t, 2, v)
+	assert.Equal(t, "unmuted", mute.valueName(v))
+
+	power, _ := LookupDDCFeature("power")
+	v, err = power.ParseValue("standby")
+	require.NoError(t, err)
+	assert.Equal(t, 2, v)
+
+	contrast, _ := LookupDDCFeature("contrast")
+	_, err = contrast.ParseValue("-1")
+	assert.Error(t, err)
+}
+
+func TestFeatureValue_MasksNonContinuous(t *testing.T) {
+	input, _ := LookupDDCFeature("input")
+	v := featureValue(input, &ddcCapability{vcp: VCP_INPUT_SOURCE, max: 0x1B, current: 0x0111})
+	assert.Equal(t, 0x11, v.Current)
+	assert.Equal(t, "hdmi1", v.Value)
+
+	volume, _ := LookupDDCFeature("volume")
+	v = featureValue(volume, &ddcCapability{vcp: VCP_VOLUME, max: 100, current: 0x0111})
+	assert.Equal(t, 0x0111, v.Current)
+	assert.Empty(t, v.Value)
+}
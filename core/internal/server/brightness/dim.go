@@ -0,0 +1,145 @@
+package brightness
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/AvengeMedia/DankMaterialShell/core/internal/log"
+)
+
+const dimFadeSteps = 15
+
+// backlightDim remembers panel levels while they are dimmed for idle.
+type backlightDim struct {
+	mu         sync.Mutex
+	saved      map[string]int
+	pausedAuto bool
+	cancel     chan struct{}
+}
+
+func (m *Manager) backlights() []Device {
+	m.stateMutex.RLock()
+	defer m.stateMutex.RUnlock()
+	var devices []Device
+	for _, dev := range m.state.Devices {
+		if dev.Class == ClassBacklight {
+			devices = append(devices, dev)
+		}
+	}
+	return devices
+}
+
+// DimBacklights fades every backlight to percent of its level before the
+// first dim. Auto-brightness is paused until UndimBacklights.
+func (m *Manager) DimBacklights(percent int, fade time.Duration) error {
+	if percent < 0 || percent > 100 {
+		return fmt.Errorf("dim percent out of range: %d", percent)
+	}
+
+	devices := m.backlights()
+	if len(devices) == 0 {
+		return fmt.Errorf("no backlight devices")
+	}
+
+	m.dim.mu.Lock()
+	if m.dim.saved == nil {
+		m.dim.saved = make(map[string]int, len(devices))
+		for _, dev := range devices {
+			m.dim.saved[dev.ID] = dev.CurrentPercent
+		}
+
+		m.auto.mu.Lock()
+		m.dim.pausedAuto = m.auto.config.Enabled && !m.auto.paused
+		m.auto.mu.Unlock()
+	}
+
+	targets := make(map[string]int, len(devices))
+	for _, dev := range devices {
+		saved, ok := m.dim.saved[dev.ID]
+		if !ok {
+			continue
+		}
+		target := saved * percent / 100
+		if saved > 0 && target == 0 {
+			target = 1
+		}
+		targets[dev.ID] = target
+	}
+	m.startFadeLocked(devices, targets, fade)
+	pause := m.dim.pausedAuto
+	m.dim.mu.Unlock()
+
+	if pause {
+		m.PauseAutoBrightness(0)
+	}
+	return nil
+}
+
+// UndimBacklights fades back to the levels saved by DimBacklights.
+func (m *Manager) UndimBacklights(fade time.Duration) error {
+	m.dim.mu.Lock()
+	if m.dim.saved == nil {
+		m.dim.mu.Unlock()
+		return nil
+	}
+	targets := m.dim.saved
+	resume := m.dim.pausedAuto
+	m.dim.saved = nil
+	m.dim.pausedAuto = false
+	m.startFadeLocked(m.backlights(), targets, fade)
+	m.dim.mu.Unlock()
+
+	if resume {
+		m.ResumeAutoBrightness()
+	}
+	return nil
+}
+
+func (m *Manager) BacklightsDimmed() bool {
+	m.dim.mu.Lock()
+	defer m.dim.mu.Unlock()
+	return m.dim.saved != nil
+}
+
+// startFadeLocked replaces any running fade. Caller holds dim.mu.
+func (m *Manager) startFadeLocked(devices []Device, targets map[string]int, fade time.Duration) {
+	if m.dim.cancel != nil {
+		close(m.dim.cancel)
+	}
+	cancel := make(chan struct{})
+	m.dim.cancel = cancel
+
+	from := make(map[string]int, len(devices))
+	for _, dev := range devices {
+		if _, ok := targets[dev.ID]; ok {
+			from[dev.ID] = dev.CurrentPercent
+		}
+	}
+
+	steps := dimFadeSteps
+	if fade <= 0 {
+		steps = 1
+	}
+	interval := fade / time.Duration(steps)
+
+	go func() {
+		for step := 1; step <= steps; step++ {
+			if step > 1 {
+				select {
+				case <-cancel:
+					return
+				case <-m.stopChan:
+					return
+				case <-time.After(interval):
+				}
+			}
+			for id, start := range from {
+				percent := start + (targets[id]-start)*step/steps
+				if err := m.setBrightness(id, percent, false, 1); err != nil {
+					log.Debugf("Backlight dim: failed to set %s to %d%%: %v", id, percent, err)
+				}
+			}
+		}
+	}()
+}
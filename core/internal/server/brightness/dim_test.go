@@ -0,0 +1,53 @@
+package brightness
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func backlightPercent(m *Manager) int {
+	return m.backlights()[0].CurrentPercent
+}
+
+func TestDimBacklights_FadeAndRestore(t *testing.T) {
+	m, backend, _ := setupKbdManager(t)
+	m.auto.config.Enabled = true
+
+	require.NoError(t, m.DimBacklights(30, 0))
+	require.Eventually(t, func() bool { return backlightPercent(m) == 15 }, time.Second, 5*time.Millisecond)
+	assert.True(t, m.BacklightsDimmed())
+	assert.True(t, m.GetAutoBrightnessState().Paused, "auto-brightness must not fight the dim")
+
+	require.NoError(t, m.DimBacklights(10, 0))
+	require.Eventually(t, func() bool { return backlightPercent(m) == 5 }, time.Second, 5*time.Millisecond,
+		"a deeper dim is relative to the original level")
+
+	require.NoError(t, m.UndimBacklights(60*time.Millisecond))
+	assert.False(t, m.BacklightsDimmed())
+	require.Eventually(t, func() bool { return backlightPercent(m) == 50 }, time.Second, 5*time.Millisecond)
+	assert.False(t, m.GetAutoBrightnessState().Paused)
+
+	values := backend.values()
+	require.Greater(t, len(values), 3, "undim fades in steps")
+	fade := values[2:]
+	assert.IsIncreasing(t, fade)
+	assert.Equal(t, 50, fade[len(fade)-1])
+
+	require.NoError(t, m.UndimBacklights(0))
+	assert.Len(t, backend.values(), len(values), "undim without a dim is a no-op")
+}
+
+func TestDimBacklights_LeavesManualPause(t *testing.T) {
+	m, _, _ := setupKbdManager(t)
+	m.auto.config.Enabled = true
+	m.PauseAutoBrightness(0)
+
+	require.NoError(t, m.DimBacklights(30, 0))
+	require.NoError(t, m.UndimBacklights(0))
+	assert.True(t, m.GetAutoBrightnessState().Paused)
+
+	assert.Error(t, m.DimBacklights(120, 0))
+}
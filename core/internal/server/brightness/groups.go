@@ -0,0 +1,329 @@
+package brightness
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/AvengeMedia/DankMaterialShell/core/internal/log"
+)
+
+const groupIDPrefix = "group:"
+
+// LinkPoint maps a group percentage to a member percentage.
+type LinkPoint struct {
+	In  int `json:"in"`
+	Out int `json:"out"`
+}
+
+// LinkMember follows the group through either an explicit point curve or
+// out = offset + scale*in. A zero scale is treated as 1.
+type LinkMember struct {
+	Device string      `json:"device"`
+	Offset int         `json:"offset,omitempty"`
+	Scale  float64     `json:"scale,omitempty"`
+	Points []LinkPoint `json:"points,omitempty"`
+}
+
+type LinkGroup struct {
+	Name    string       `json:"name"`
+	Members []LinkMember `json:"members"`
+}
+
+type GroupState struct {
+	ID             string   `json:"id"`
+	Name           string   `json:"name"`
+	CurrentPercent int      `json:"currentPercent"`
+	Members        []string `json:"members"`
+}
+
+type groupFile struct {
+	Groups []LinkGroup `json:"groups"`
+}
+
+func GroupID(name string) string {
+	return groupIDPrefix + name
+}
+
+func isGroupID(id string) bool {
+	return strings.HasPrefix(id, groupIDPrefix)
+}
+
+func groupsPath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "DankMaterialShell", "brightness-groups.json"), nil
+}
+
+func loadGroups() []LinkGroup {
+	path, err := groupsPath()
+	if err != nil {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var file groupFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		log.Warnf("Invalid brightness groups file %s: %v", path, err)
+		return nil
+	}
+	return file.Groups
+}
+
+func saveGroups(groups []LinkGroup) error {
+	path, err := groupsPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(groupFile{Groups: groups}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+func (lm LinkMember) validate() error {
+	if lm.Device == "" {
+		return fmt.Errorf("member device is required")
+	}
+	if isGroupID(lm.Device) {
+		return fmt.Errorf("groups cannot be nested: %s", lm.Device)
+	}
+	if lm.Scale < 0 {
+		return fmt.Errorf("scale must not be negative for %s", lm.Device)
+	}
+	for i := 1; i < len(lm.Points); i++ {
+		if lm.Points[i].In <= lm.Points[i-1].In {
+			return fmt.Errorf("points for %s must have increasing input", lm.Device)
+		}
+	}
+	return nil
+}
+
+// apply maps a group percentage onto this member.
+func (lm LinkMember) apply(in int) int {
+	if len(lm.Points) > 0 {
+		pts := lm.Points
+		switch {
+		case in <= pts[0].In:
+			return clampPercent(pts[0].Out)
+		case in >= pts[len(pts)-1].In:
+			return clampPercent(pts[len(pts)-1].Out)
+		}
+		for i := 1; i < len(pts); i++ {
+			if in > pts[i].In {
+				continue
+			}
+			t := float64(in-pts[i-1].In) / float64(pts[i].In-pts[i-1].In)
+			return clampPercent(int(math.Round(float64(pts[i-1].Out) + t*float64(pts[i].Out-pts[i-1].Out))))
+		}
+	}
+
+	scale := lm.Scale
+	if scale == 0 {
+		scale = 1
+	}
+	return clampPercent(int(math.Round(float64(lm.Offset) + scale*float64(in))))
+}
+
+// invert finds the group percentage that best explains a member's current
+// brightness; used to seed the group level before it was ever set.
+func (lm LinkMember) invert(out int) int {
+	best, bestDelta := 0, math.MaxInt
+	for in := 0; in <= 100; in++ {
+		delta := lm.apply(in) - out
+		if delta < 0 {
+			delta = -delta
+		}
+		if delta < bestDelta {
+			best, bestDelta = in, delta
+		}
+	}
+	return best
+}
+
+func clampPercent(p int) int {
+	return max(min(p, 100), 0)
+}
+
+func (m *Manager) ListGroups() []LinkGroup {
+	m.groupsMutex.Lock()
+	defer m.groupsMutex.Unlock()
+	return slices.Clone(m.groups)
+}
+
+func (m *Manager) SetGroup(group LinkGroup) error {
+	if group.Name == "" || strings.ContainsAny(group.Name, ": ") {
+		return fmt.Errorf("invalid group name: %q", group.Name)
+	}
+	if len(group.Members) == 0 {
+		return fmt.Errorf("group %s has no members", group.Name)
+	}
+	seen := make(map[string]bool, len(group.Members))
+	for _, member := range group.Members {
+		if err := member.validate(); err != nil {
+			return err
+		}
+		if seen[member.Device] {
+			return fmt.Errorf("duplicate member: %s", member.Device)
+		}
+		seen[member.Device] = true
+	}
+
+	m.groupsMutex.Lock()
+	groups := slices.Clone(m.groups)
+	idx := slices.IndexFunc(groups, func(g LinkGroup) bool { return g.Name == group.Name })
+	switch idx {
+	case -1:
+		groups = append(groups, group)
+	default:
+		groups[idx] = group
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Name < groups[j].Name })
+	if err := saveGroups(groups); err != nil {
+		m.groupsMutex.Unlock()
+		return fmt.Errorf("failed to save groups: %w", err)
+	}
+	m.groups = groups
+	delete(m.groupPercent, group.Name)
+	m.groupsMutex.Unlock()
+
+	m.refreshGroups()
+	return nil
+}
+
+func (m *Manager) DeleteGroup(name string) error {
+	m.groupsMutex.Lock()
+	idx := slices.IndexFunc(m.groups, func(g LinkGroup) bool { return g.Name == name })
+	if idx == -1 {
+		m.groupsMutex.Unlock()
+		return fmt.Errorf("group not found: %s", name)
+	}
+	groups := slices.Delete(slices.Clone(m.groups), idx, idx+1)
+	if err := saveGroups(groups); err != nil {
+		m.groupsMutex.Unlock()
+		return fmt.Errorf("failed to save groups: %w", err)
+	}
+	m.groups = groups
+	delete(m.groupPercent, name)
+	m.groupsMutex.Unlock()
+
+	m.refreshGroups()
+	return nil
+}
+
+func (m *Manager) findGroup(id string) (LinkGroup, bool) {
+	name := strings.TrimPrefix(id, groupIDPrefix)
+
+	m.groupsMutex.Lock()
+	defer m.groupsMutex.Unlock()
+
+	idx := slices.IndexFunc(m.groups, func(g LinkGroup) bool { return g.Name == name })
+	if idx == -1 {
+		return LinkGroup{}, false
+	}
+	return m.groups[idx], true
+}
+
+// groupStates derives each group's level from its last set value, or from
+// the first present member when the group hasn't been driven yet.
+func (m *Manager) groupStates(devices []Device) []GroupState {
+	m.groupsMutex.Lock()
+	defer m.groupsMutex.Unlock()
+
+	if len(m.groups) == 0 {
+		return nil
+	}
+
+	byID := make(map[string]Device, len(devices))
+	for _, dev := range devices {
+		byID[dev.ID] = dev
+	}
+
+	states := make([]GroupState, 0, len(m.groups))
+	for _, group := range m.groups {
+		gs := GroupState{ID: GroupID(group.Name), Name: group.Name, Members: []string{}}
+		percent, known := m.groupPercent[group.Name]
+		for _, member := range group.Members {
+			dev, ok := byID[member.Device]
+			if !ok {
+				continue
+			}
+			gs.Members = append(gs.Members, member.Device)
+			if !known {
+				percent, known = member.invert(dev.CurrentPercent), true
+			}
+		}
+		gs.CurrentPercent = percent
+		states = append(states, gs)
+	}
+	return states
+}
+
+func (m *Manager) refreshGroups() {
+	m.stateMutex.Lock()
+	m.state.Groups = m.groupStates(m.state.Devices)
+	m.stateMutex.Unlock()
+	m.NotifySubscribers()
+}
+
+// setGroupBrightness drives every present member. DDC members go through
+// the backend's per-device debounce, so a drag only issues the final write.
+func (m *Manager) setGroupBrightness(id string, percent int, exponential bool, exponent float64) error {
+	group, ok := m.findGroup(id)
+	if !ok {
+		return fmt.Errorf("device not found: %s", id)
+	}
+	percent = clampPercent(percent)
+
+	m.groupsMutex.Lock()
+	if m.groupPercent == nil {
+		m.groupPercent = make(map[string]int)
+	}
+	m.groupPercent[group.Name] = percent
+	m.groupsMutex.Unlock()
+
+	present := make(map[string]bool)
+	for _, dev := range m.GetState().Devices {
+		present[dev.ID] = true
+	}
+
+	var errs []string
+	applied := 0
+	for _, member := range group.Members {
+		if !present[member.Device] {
+			log.Debugf("Group %s: member %s not present", group.Name, member.Device)
+			continue
+		}
+		value := member.apply(percent)
+		if err := m.setBrightness(member.Device, value, exponential, exponent); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", member.Device, err))
+			continue
+		}
+		m.noteManualBrightness(member.Device, value)
+		m.noteKbdManual(member.Device, value)
+		applied++
+	}
+
+	m.refreshGroups()
+
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to set group %s: %s", group.Name, strings.Join(errs, "; "))
+	}
+	if applied == 0 {
+		return fmt.Errorf("no members of group %s are present", group.Name)
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package brightness
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupGroupManager(t *testing.T) (*Manager, *fakeBackend) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	backend := &fakeBackend{}
+	m := &Manager{
+		nativeBackend: backend,
+		nativeReady:   true,
+		stopChan:      make(chan struct{}),
+		groupPercent:  make(map[string]int),
+	}
+	m.state = State{Devices: []Device{
+		{Class: ClassBacklight, ID: "backlight:intel_backlight", CurrentPercent: 60},
+		{Class: ClassLED, ID: "leds:dell::monitor1", CurrentPercent: 30},
+		{Class: ClassLED, ID: "leds:dell::monitor2", CurrentPercent: 30},
+	}}
+	return m, backend
+}
+
+func TestLinkMember_Apply(t *testing.T) {
+	assert.Equal(t, 50, LinkMember{}.apply(50))
+	assert.Equal(t, 30, LinkMember{Offset: -20}.apply(50))
+	assert.Equal(t, 0, LinkMember{Offset: -20}.apply(10))
+	assert.Equal(t, 100, LinkMember{Scale: 1.5}.apply(80))
+
+	curve := LinkMember{Points: []LinkPoint{{In: 0, Out: 10}, {In: 50, Out: 20}, {In: 100, Out: 80}}}
+	assert.Equal(t, 10, curve.apply(0))
+	assert.Equal(t, 15, curve.apply(25))
+	assert.Equal(t, 50, curve.apply(75))
+	assert.Equal(t, 80, curve.apply(100))
+
+	assert.Equal(t, 75, curve.invert(50))
+	assert.Equal(t, 40, LinkMember{Offset: -20}.invert(20))
+}
+
+func TestSetGroup_Validation(t *testing.T) {
+	m, _ := setupGroupManager(t)
+
+	assert.Error(t, m.SetGroup(LinkGroup{Name: "", Members: []LinkMember{{Device: "a"}}}))
+	assert.Error(t, m.SetGroup(LinkGroup{Name: "desk"}))
+	assert.Error(t, m.SetGroup(LinkGroup{Name: "desk", Members: []LinkMember{{Device: "group:other"}}}))
+	assert.Error(t, m.SetGroup(LinkGroup{Name: "desk", Members: []LinkMember{{Device: "a"}, {Device: "a"}}}))
+	assert.Error(t, m.SetGroup(LinkGroup{Name: "desk", Members: []LinkMember{
+		{Device: "a", Points: []LinkPoint{{In: 50, Out: 1}, {In: 10, Out: 2}}},
+	}}))
+	assert.Empty(t, m.ListGroups())
+}
+
+func TestGroup_SetMovesAllMembers(t *testing.T) {
+	m, backend := setupGroupManager(t)
+
+	require.NoError(t, m.SetGroup(LinkGroup{Name: "desk", Members: []LinkMember{
+		{Device: "backlight:intel_backlight"},
+		{Device: "leds:dell::monitor1", Offset: -10},
+		{Device: "leds:dell::monitor2", Scale: 0.5},
+		{Device: "leds:unplugged"},
+	}}))
+
+	state := m.GetState()
+	require.Len(t, state.Groups, 1)
+	assert.Equal(t, "group:desk", state.Groups[0].ID)
+	assert.Equal(t, 60, state.Groups[0].CurrentPercent, "seeded from the first present member")
+	assert.Len(t, state.Groups[0].Members, 3)
+
+	require.NoError(t, m.SetBrightnessWithExponent("group:desk", 40, true, 1.2))
+	assert.Equal(t, []fakeSet{
+		{id: "backlight:intel_backlight", percent: 40, exponential: true},
+		{id: "leds:dell::monitor1", percent: 30, exponential: true},
+		{id: "leds:dell::monitor2", percent: 20, exponential: true},
+	}, backend.calls)
+	assert.Equal(t, 40, m.GetState().Groups[0].CurrentPercent)
+
+	require.NoError(t, m.IncrementBrightness("group:desk", 10))
+	assert.Equal(t, 50, m.GetState().Groups[0].CurrentPercent)
+	assert.Equal(t, 40, backend.calls[len(backend.calls)-2].percent)
+}
+
+func TestGroup_PersistAndDelete(t *testing.T) {
+	m, _ := setupGroupManager(t)
+	require.NoError(t, m.SetGroup(LinkGroup{Name: "desk", Members: []LinkMember{{Device: "leds:dell::monitor1"}}}))
+
+	assert.Equal(t, m.ListGroups(), loadGroups())
+
+	require.NoError(t, m.DeleteGroup("desk"))
+	assert.Empty(t, loadGroups())
+	assert.Empty(t, m.GetState().Groups)
+	assert.Error(t, m.DeleteGroup("desk"))
+	assert.Error(t, m.SetBrightness("group:desk", 10))
+}
+
+func TestGroup_SetBacksOffAutoBrightness(t *testing.T) {
+	m, backend, _, _ := setupAutoManager(t)
+	require.NoError(t, m.SetGroup(LinkGroup{Name: "desk", Members: []LinkMember{
+		{Device: "backlight:intel_backlight"},
+		{Device: "leds:kbd_backlight", Offset: -10},
+	}}))
+	require.NoError(t, m.EnableAutoBrightness(""))
+
+	m.handleLux(200)
+	require.NoError(t, m.SetBrightness("group:desk", 20))
+
+	applied := len(backend.values())
+	m.handleLux(5000)
+	assert.Len(t, backend.values(), applied, "a group change is a manual change for its members")
+	assert.Equal(t, 20, curvePercent(m.GetAutoBrightnessState().Curve, 200))
+}
@@ -1,7 +1,9 @@
 package brightness
 
 import (
+	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/AvengeMedia/DankMaterialShell/core/internal/server/models"
 	"github.com/AvengeMedia/dankgo/ipc/params"
@@ -21,6 +23,39 @@ func HandleRequest(conn *models.Conn, req models.Request, m *Manager) {
 		handleRescan(conn, req, m)
 	case "brightness.subscribe":
 		handleSubscribe(conn, req, m)
+	case "brightness.auto.getState":
+		models.Respond(conn, req.ID, m.GetAutoBrightnessState())
+	case "brightness.auto.enable":
+		handleAutoEnable(conn, req, m)
+	case "brightness.auto.disable":
+		handleAutoDisable(conn, req, m)
+	case "brightness.auto.pause":
+		handleAutoPause(conn, req, m)
+	case "brightness.auto.resume":
+		m.ResumeAutoBrightness()
+		models.Respond(conn, req.ID, m.GetAutoBrightnessState())
+	case "brightness.auto.resetCurve":
+		handleAutoResetCurve(conn, req, m)
+	case "brightness.groups.list":
+		models.Respond(conn, req.ID, m.ListGroups())
+	case "brightness.groups.set":
+		handleSetGroup(conn, req, m)
+	case "brightness.groups.delete":
+		handleDeleteGroup(conn, req, m)
+	case "brightness.kbd.getState":
+		models.Respond(conn, req.ID, m.GetKbdBacklightState())
+	case "brightness.kbd.configure":
+		handleKbdConfigure(conn, req, m)
+	case "brightness.ddc.capabilities":
+		handleDDCCapabilities(conn, req, m)
+	case "brightness.ddc.getState":
+		handleDDCGetState(conn, req, m)
+	case "brightness.ddc.get":
+		handleDDCGet(conn, req, m)
+	case "brightness.ddc.set":
+		handleDDCSet(conn, req, m)
+	case "brightness.ddc.setInput":
+		handleDDCSetInput(conn, req, m)
 	default:
 		models.RespondError(conn, req.ID, "unknown method: "+req.Method)
 	}
@@ -97,6 +132,215 @@ func handleRescan(conn *models.Conn, req models.Request, m *Manager) {
 	models.Respond(conn, req.ID, m.GetState())
 }
 
+func handleAutoEnable(conn *models.Conn, req models.Request, m *Manager) {
+	device := params.StringOpt(req.Params, "device", "")
+
+	if err := m.EnableAutoBrightness(device); err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+
+	models.Respond(conn, req.ID, m.GetAutoBrightnessState())
+}
+
+func handleAutoDisable(conn *models.Conn, req models.Request, m *Manager) {
+	if err := m.DisableAutoBrightness(); err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+
+	models.Respond(conn, req.ID, m.GetAutoBrightnessState())
+}
+
+func handleAutoPause(conn *models.Conn, req models.Request, m *Manager) {
+	seconds := params.IntOpt(req.Params, "duration", 0)
+	if seconds < 0 {
+		models.RespondError(conn, req.ID, "duration must not be negative")
+		return
+	}
+
+	m.PauseAutoBrightness(time.Duration(seconds) * time.Second)
+	models.Respond(conn, req.ID, m.GetAutoBrightnessState())
+}
+
+func handleAutoResetCurve(conn *models.Conn, req models.Request, m *Manager) {
+	device := params.StringOpt(req.Params, "device", "")
+
+	if err := m.ResetAutoBrightnessCurve(device); err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+
+	models.Respond(conn, req.ID, m.GetAutoBrightnessState())
+}
+
+func handleSetGroup(conn *models.Conn, req models.Request, m *Manager) {
+	name, err := params.StringNonEmpty(req.Params, "name")
+	if err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+
+	raw, ok := params.Any(req.Params, "members")
+	if !ok {
+		models.RespondError(conn, req.ID, "missing 'members' parameter")
+		return
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		models.RespondError(conn, req.ID, "invalid 'members' parameter format")
+		return
+	}
+	var members []LinkMember
+	if err := json.Unmarshal(data, &members); err != nil {
+		models.RespondError(conn, req.ID, fmt.Sprintf("invalid members: %v", err))
+		return
+	}
+
+	if err := m.SetGroup(LinkGroup{Name: name, Members: members}); err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+
+	models.Respond(conn, req.ID, m.GetState())
+}
+
+func handleDeleteGroup(conn *models.Conn, req models.Request, m *Manager) {
+	name, err := params.StringNonEmpty(req.Params, "name")
+	if err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+
+	if err := m.DeleteGroup(name); err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+
+	models.Respond(conn, req.ID, m.GetState())
+}
+
+func handleKbdConfigure(conn *models.Conn, req models.Request, m *Manager) {
+	cfg := m.GetKbdBacklightState().KbdBacklightConfig
+	cfg.Enabled = params.BoolOpt(req.Params, "enabled", cfg.Enabled)
+	cfg.Device = params.StringOpt(req.Params, "device", cfg.Device)
+	cfg.Timeout = params.IntOpt(req.Params, "timeout", cfg.Timeout)
+	cfg.BatteryMaxPercent = params.IntOpt(req.Params, "batteryMaxPercent", cfg.BatteryMaxPercent)
+	cfg.OffOnLid = params.BoolOpt(req.Params, "offOnLid", cfg.OffOnLid)
+	cfg.OffOnLock = params.BoolOpt(req.Params, "offOnLock", cfg.OffOnLock)
+
+	if err := m.ConfigureKbdBacklight(cfg); err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+
+	models.Respond(conn, req.ID, m.GetKbdBacklightState())
+}
+
+func handleDDCCapabilities(conn *models.Conn, req models.Request, m *Manager) {
+	device, err := params.String(req.Params, "device")
+	if err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+
+	caps, err := m.GetDDCCapabilities(device)
+	if err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+
+	models.Respond(conn, req.ID, caps)
+}
+
+func handleDDCGetState(conn *models.Conn, req models.Request, m *Manager) {
+	device, err := params.String(req.Params, "device")
+	if err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+
+	features, err := m.GetDDCFeatures(device)
+	if err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+
+	models.Respond(conn, req.ID, features)
+}
+
+func handleDDCGet(conn *models.Conn, req models.Request, m *Manager) {
+	device, err := params.String(req.Params, "device")
+	if err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+
+	feature, err := params.String(req.Params, "feature")
+	if err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+
+	value, err := m.GetDDCFeature(device, feature)
+	if err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+
+	models.Respond(conn, req.ID, value)
+}
+
+func handleDDCSet(conn *models.Conn, req models.Request, m *Manager) {
+	device, err := params.String(req.Params, "device")
+	if err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+
+	feature, err := params.String(req.Params, "feature")
+	if err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+
+	raw, ok := params.Any(req.Params, "value")
+	if !ok {
+		models.RespondError(conn, req.ID, "missing or invalid 'value' parameter")
+		return
+	}
+
+	value, err := m.SetDDCFeature(device, feature, fmt.Sprint(raw))
+	if err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+
+	models.Respond(conn, req.ID, value)
+}
+
+func handleDDCSetInput(conn *models.Conn, req models.Request, m *Manager) {
+	device, err := params.String(req.Params, "device")
+	if err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+
+	input, err := params.String(req.Params, "input")
+	if err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+
+	value, err := m.SetDDCFeature(device, "input", input)
+	if err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+
+	models.Respond(conn, req.ID, value)
+}
+
 func handleSubscribe(conn *models.Conn, req models.Request, m *Manager) {
 	clientID := fmt.Sprintf("brightness-%d", req.ID)
 
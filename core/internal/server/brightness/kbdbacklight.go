@@ -0,0 +1,591 @@
+package brightness
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AvengeMedia/DankMaterialShell/core/internal/log"
+	"github.com/AvengeMedia/DankMaterialShell/core/internal/server/evdev"
+	"github.com/AvengeMedia/DankMaterialShell/core/internal/server/loginctl"
+)
+
+const (
+	kbdDefaultTimeout = 30
+	kbdPollInterval   = 5 * time.Second
+)
+
+var (
+	powerSupplyRoot = "/sys/class/power_supply"
+	acpiLidGlob     = "/proc/acpi/button/lid/*/state"
+)
+
+// KbdBacklightConfig is persisted to kbd-backlight.json. Timeout is in
+// seconds; 0 disables the idle timeout. BatteryMaxPercent caps the restored
+// level while on battery; 0 disables dimming.
+type KbdBacklightConfig struct {
+	Enabled           bool   `json:"enabled"`
+	Device            string `json:"device,omitempty"`
+	Timeout           int    `json:"timeout"`
+	BatteryMaxPercent int    `json:"batteryMaxPercent"`
+	OffOnLid          bool   `json:"offOnLid"`
+	OffOnLock         bool   `json:"offOnLock"`
+}
+
+type KbdBacklightState struct {
+	KbdBacklightConfig
+	Device       string `json:"device"`
+	Lit          bool   `json:"lit"`
+	Reason       string `json:"reason,omitempty"`
+	SavedPercent int    `json:"savedPercent"`
+	OnBattery    bool   `json:"onBattery"`
+	LidClosed    bool   `json:"lidClosed"`
+	Locked       bool   `json:"locked"`
+}
+
+func defaultKbdConfig() KbdBacklightConfig {
+	return KbdBacklightConfig{
+		Timeout:   kbdDefaultTimeout,
+		OffOnLid:  true,
+		OffOnLock: true,
+	}
+}
+
+type kbdBacklight struct {
+	mu       sync.Mutex
+	config   KbdBacklightConfig
+	saved    int
+	hasSaved bool
+	// inputIdle is set by the input timeout and sessionIdle by the logind
+	// idle hint; either turns the backlight off.
+	inputIdle   bool
+	sessionIdle bool
+	locked      bool
+	lidClosed   bool
+	onBattery   bool
+	lidSwitch   bool
+	applied     int
+	timer       *time.Timer
+	timerGen    uint64
+	pollStop    chan struct{}
+	// wantPointers asks the input source for pointer and touch activity,
+	// which is only needed while an input timeout can fire.
+	wantPointers func(bool)
+}
+
+func kbdConfigPath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "DankMaterialShell", "kbd-backlight.json"), nil
+}
+
+func loadKbdConfig() KbdBacklightConfig {
+	cfg := defaultKbdConfig()
+	path, err := kbdConfigPath()
+	if err != nil {
+		return cfg
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		log.Warnf("Invalid keyboard backlight config %s: %v", path, err)
+		return defaultKbdConfig()
+	}
+	return cfg
+}
+
+func saveKbdConfig(cfg KbdBacklightConfig) error {
+	path, err := kbdConfigPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+func isKbdBacklight(dev Device) bool {
+	return dev.Class == ClassLED && strings.Contains(dev.Name, "kbd_backlight")
+}
+
+// OnBattery reports whether the machine is running from battery.
+func OnBattery() bool {
+	return onBattery(powerSupplyRoot)
+}
+
+// onBattery reports whether no mains/USB supply is online. Machines without
+// any such supply (desktops) are never considered on battery.
+func onBattery(root string) bool {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return false
+	}
+	sawMains := false
+	for _, entry := range entries {
+		dir := filepath.Join(root, entry.Name())
+		data, err := os.ReadFile(filepath.Join(dir, "type"))
+		if err != nil {
+			continue
+		}
+		switch strings.TrimSpace(string(data)) {
+		case "Mains", "USB":
+		default:
+			continue
+		}
+		sawMains = true
+		if online, err := os.ReadFile(filepath.Join(dir, "online")); err == nil && strings.TrimSpace(string(online)) == "1" {
+			return false
+		}
+	}
+	return sawMains
+}
+
+func lidClosed(glob string) bool {
+	matches, _ := filepath.Glob(glob)
+	for _, path := range matches {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		if strings.Contains(string(data), "closed") {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Manager) kbdDevice() (Device, bool) {
+	m.kbd.mu.Lock()
+	id := m.kbd.config.Device
+	m.kbd.mu.Unlock()
+
+	devices := m.GetState().Devices
+	if id != "" {
+		idx := slices.IndexFunc(devices, func(d Device) bool { return d.ID == id })
+		if idx == -1 {
+			return Device{}, false
+		}
+		return devices[idx], true
+	}
+	idx := slices.IndexFunc(devices, isKbdBacklight)
+	if idx == -1 {
+		return Device{}, false
+	}
+	return devices[idx], true
+}
+
+func (m *Manager) initKbdBacklight() {
+	m.kbd.mu.Lock()
+	enabled := m.kbd.config.Enabled
+	m.kbd.mu.Unlock()
+
+	if !enabled {
+		return
+	}
+	m.startKbdBacklight()
+	m.publishKbdState()
+}
+
+func (m *Manager) startKbdBacklight() {
+	m.kbd.mu.Lock()
+	if !m.kbd.config.Enabled || m.kbd.pollStop != nil {
+		m.kbd.mu.Unlock()
+		return
+	}
+	stop := make(chan struct{})
+	m.kbd.pollStop = stop
+	m.kbd.onBattery = onBattery(powerSupplyRoot)
+	if !m.kbd.lidSwitch {
+		m.kbd.lidClosed = lidClosed(acpiLidGlob)
+	}
+	m.kbd.mu.Unlock()
+
+	go m.pollKbdPower(stop)
+	m.NoteInputActivity()
+}
+
+func (m *Manager) stopKbdBacklight() {
+	m.kbd.mu.Lock()
+	if m.kbd.pollStop != nil {
+		close(m.kbd.pollStop)
+		m.kbd.pollStop = nil
+	}
+	if m.kbd.timer != nil {
+		m.kbd.timer.Stop()
+		m.kbd.timer = nil
+	}
+	m.kbd.mu.Unlock()
+}
+
+func (m *Manager) pollKbdPower(stop chan struct{}) {
+	ticker := time.NewTicker(kbdPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-m.stopChan:
+			return
+		case <-ticker.C:
+		}
+
+		battery := onBattery(powerSupplyRoot)
+		lid := lidClosed(acpiLidGlob)
+
+		m.kbd.mu.Lock()
+		if m.kbd.lidSwitch {
+			lid = m.kbd.lidClosed
+		}
+		changed := battery != m.kbd.onBattery || lid != m.kbd.lidClosed
+		m.kbd.onBattery = battery
+		m.kbd.lidClosed = lid
+		m.kbd.mu.Unlock()
+
+		if changed {
+			m.applyKbdPolicy()
+		}
+	}
+}
+
+// kbdReason returns why the backlight should be off, or "" when it should
+// be lit. Caller holds kbd.mu.
+func (m *Manager) kbdReason() string {
+	switch {
+	case m.kbd.lidClosed && m.kbd.config.OffOnLid:
+		return "lid"
+	case m.kbd.locked && m.kbd.config.OffOnLock:
+		return "locked"
+	case m.kbd.inputIdle || m.kbd.sessionIdle:
+		return "idle"
+	}
+	return ""
+}
+
+// applyKbdPolicy drives the keyboard backlight to the level implied by the
+// current idle/lock/lid/power state. The lit level is remembered on the way
+// down and restored on the way back up.
+func (m *Manager) applyKbdPolicy() {
+	dev, ok := m.kbdDevice()
+	if !ok {
+		return
+	}
+
+	m.kbd.mu.Lock()
+	if !m.kbd.config.Enabled {
+		m.kbd.mu.Unlock()
+		return
+	}
+
+	target := -1
+	switch m.kbdReason() {
+	case "":
+		if !m.kbd.hasSaved {
+			m.kbd.saved, m.kbd.hasSaved = dev.CurrentPercent, true
+		}
+		target = m.kbd.saved
+		if m.kbd.onBattery && m.kbd.config.BatteryMaxPercent > 0 {
+			target = min(target, m.kbd.config.BatteryMaxPercent)
+		}
+	default:
+		// Keep the uncapped level unless the key was changed behind our back
+		// (e.g. a firmware Fn shortcut).
+		if !m.kbd.hasSaved || (dev.CurrentPercent > 0 && dev.CurrentPercent != m.kbd.applied) {
+			m.kbd.saved, m.kbd.hasSaved = dev.CurrentPercent, true
+		}
+		target = 0
+	}
+	if target >= 0 {
+		m.kbd.applied = target
+	}
+	m.kbd.mu.Unlock()
+
+	if target >= 0 && target != dev.CurrentPercent {
+		if err := m.setBrightness(dev.ID, target, false, 1); err != nil {
+			log.Debugf("Keyboard backlight: failed to set %s to %d%%: %v", dev.ID, target, err)
+		}
+	}
+	m.publishKbdState()
+}
+
+// NoteInputActivity restores an idled-out keyboard backlight and restarts
+// the idle timeout. Called for every evdev key or pointer event.
+func (m *Manager) NoteInputActivity() {
+	m.kbd.mu.Lock()
+	if !m.kbd.config.Enabled {
+		m.kbd.mu.Unlock()
+		return
+	}
+	needApply := m.kbd.inputIdle || m.kbd.sessionIdle || !m.kbd.hasSaved
+	m.kbd.inputIdle, m.kbd.sessionIdle = false, false
+	m.resetKbdTimerLocked()
+	m.kbd.mu.Unlock()
+
+	if needApply {
+		m.applyKbdPolicy()
+	}
+}
+
+func (m *Manager) resetKbdTimerLocked() {
+	if m.kbd.timer != nil {
+		m.kbd.timer.Stop()
+		m.kbd.timer = nil
+	}
+	if m.kbd.config.Timeout <= 0 {
+		return
+	}
+	m.kbd.timerGen++
+	gen := m.kbd.timerGen
+	m.kbd.timer = time.AfterFunc(time.Duration(m.kbd.config.Timeout)*time.Second, func() { m.kbdTimerFired(gen) })
+}
+
+// kbdTimerFired ignores a timer that was replaced while it fired.
+func (m *Manager) kbdTimerFired(gen uint64) {
+	m.kbd.mu.Lock()
+	current := gen == m.kbd.timerGen
+	if current {
+		m.kbd.timer = nil
+	}
+	m.kbd.mu.Unlock()
+	if current {
+		m.kbdIdleTimeout()
+	}
+}
+
+func (m *Manager) kbdIdleTimeout() {
+	m.kbd.mu.Lock()
+	if !m.kbd.config.Enabled || m.kbd.inputIdle {
+		m.kbd.mu.Unlock()
+		return
+	}
+	m.kbd.inputIdle = true
+	m.kbd.mu.Unlock()
+	m.applyKbdPolicy()
+}
+
+// SetSessionIdle feeds the session idle hint; idle turns the backlight off
+// just like the input timeout does.
+func (m *Manager) SetSessionIdle(idle bool) {
+	m.kbd.mu.Lock()
+	if !m.kbd.config.Enabled || m.kbd.sessionIdle == idle {
+		m.kbd.mu.Unlock()
+		return
+	}
+	m.kbd.sessionIdle = idle
+	if !idle {
+		m.resetKbdTimerLocked()
+	}
+	m.kbd.mu.Unlock()
+	m.applyKbdPolicy()
+}
+
+// SetLidClosed feeds the evdev lid switch, which then takes precedence
+// over polling the ACPI lid state.
+func (m *Manager) SetLidClosed(closed bool) {
+	m.kbd.mu.Lock()
+	m.kbd.lidSwitch = true
+	if m.kbd.lidClosed == closed {
+		m.kbd.mu.Unlock()
+		return
+	}
+	m.kbd.lidClosed = closed
+	enabled := m.kbd.config.Enabled
+	m.kbd.mu.Unlock()
+	if enabled {
+		m.applyKbdPolicy()
+	}
+}
+
+func (m *Manager) SetSessionLocked(locked bool) {
+	m.kbd.mu.Lock()
+	if m.kbd.locked == locked {
+		m.kbd.mu.Unlock()
+		return
+	}
+	m.kbd.locked = locked
+	enabled := m.kbd.config.Enabled
+	m.kbd.mu.Unlock()
+	if enabled {
+		m.applyKbdPolicy()
+	}
+}
+
+// noteKbdManual records a user-chosen keyboard level so later restores use it.
+func (m *Manager) noteKbdManual(deviceID string, percent int) {
+	dev, ok := m.kbdDevice()
+	if !ok || dev.ID != deviceID {
+		return
+	}
+	m.kbd.mu.Lock()
+	if m.kbd.config.Enabled && m.kbdReason() == "" {
+		m.kbd.saved, m.kbd.hasSaved = percent, true
+		m.kbd.applied = percent
+	}
+	m.kbd.mu.Unlock()
+}
+
+func (m *Manager) GetKbdBacklightState() KbdBacklightState {
+	dev, ok := m.kbdDevice()
+
+	m.kbd.mu.Lock()
+	defer m.kbd.mu.Unlock()
+
+	state := KbdBacklightState{
+		KbdBacklightConfig: m.kbd.config,
+		SavedPercent:       m.kbd.saved,
+		OnBattery:          m.kbd.onBattery,
+		LidClosed:          m.kbd.lidClosed,
+		Locked:             m.kbd.locked,
+		Reason:             m.kbdReason(),
+	}
+	if ok {
+		state.Device = dev.ID
+		state.Lit = dev.CurrentPercent > 0
+	}
+	return state
+}
+
+func (m *Manager) publishKbdState() {
+	kbd := m.GetKbdBacklightState()
+
+	m.stateMutex.Lock()
+	m.state.KbdBacklight = &kbd
+	m.stateMutex.Unlock()
+	m.NotifySubscribers()
+}
+
+func (m *Manager) ConfigureKbdBacklight(cfg KbdBacklightConfig) error {
+	if cfg.Timeout < 0 {
+		return fmt.Errorf("timeout must not be negative")
+	}
+	if cfg.BatteryMaxPercent < 0 || cfg.BatteryMaxPercent > 100 {
+		return fmt.Errorf("batteryMaxPercent out of range: %d", cfg.BatteryMaxPercent)
+	}
+	if cfg.Device != "" {
+		idx := slices.IndexFunc(m.GetState().Devices, func(d Device) bool { return d.ID == cfg.Device })
+		if idx == -1 {
+			return fmt.Errorf("device not found: %s", cfg.Device)
+		}
+	}
+	if err := saveKbdConfig(cfg); err != nil {
+		return fmt.Errorf("failed to save keyboard backlight config: %w", err)
+	}
+
+	oldDev, hadDev := m.kbdDevice()
+	m.stopKbdBacklight()
+
+	m.kbd.mu.Lock()
+	old := m.kbd.config
+	restore := old.Enabled && m.kbdReason() != "" && m.kbd.hasSaved && (!cfg.Enabled || cfg.Device != old.Device)
+	saved := m.kbd.saved
+	m.kbd.config = cfg
+	m.kbd.inputIdle, m.kbd.sessionIdle = false, false
+	if cfg.Device != old.Device {
+		m.kbd.hasSaved = false
+	}
+	m.kbd.mu.Unlock()
+
+	if restore && hadDev {
+		if err := m.setBrightness(oldDev.ID, saved, false, 1); err != nil {
+			log.Debugf("Keyboard backlight: failed to restore %s: %v", oldDev.ID, err)
+		}
+	}
+
+	m.startKbdBacklight()
+	m.syncKbdPointers()
+	m.publishKbdState()
+	return nil
+}
+
+func (m *Manager) syncKbdPointers() {
+	m.kbd.mu.Lock()
+	want := m.kbd.wantPointers
+	on := m.kbd.config.Enabled && m.kbd.config.Timeout > 0
+	m.kbd.mu.Unlock()
+
+	if want != nil {
+		want(on)
+	}
+}
+
+// WatchEvdev feeds keyboard activity and the lid switch into the keyboard
+// backlight policy, and pointer and touch activity while the input timeout
+// is enabled.
+func (m *Manager) WatchEvdev(em *evdev.Manager) {
+	activity := em.SubscribeActivity("brightness-kbd")
+	states := em.Subscribe("brightness-kbd")
+	m.kbd.mu.Lock()
+	m.kbd.wantPointers = func(want bool) { em.WantPointerActivity("brightness-kbd", want) }
+	m.kbd.mu.Unlock()
+	m.syncKbdPointers()
+	if closed, ok := em.Switch(evdev.SwitchLid); ok {
+		m.SetLidClosed(closed)
+	}
+
+	go func() {
+		defer em.UnsubscribeActivity("brightness-kbd")
+		defer em.Unsubscribe("brightness-kbd")
+		for {
+			select {
+			case <-m.stopChan:
+				return
+			case _, ok := <-activity:
+				if !ok {
+					return
+				}
+				m.NoteInputActivity()
+			case state, ok := <-states:
+				if !ok {
+					return
+				}
+				if closed, ok := state.Switches[evdev.SwitchLid]; ok {
+					m.SetLidClosed(closed)
+				}
+			}
+		}
+	}()
+}
+
+// WatchLoginctl feeds session lock and idle hints into the keyboard
+// backlight policy.
+func (m *Manager) WatchLoginctl(lm *loginctl.Manager) {
+	ch := lm.Subscribe("brightness-kbd")
+	initial := lm.GetState()
+	m.SetSessionLocked(initial.Locked)
+
+	go func() {
+		defer lm.Unsubscribe("brightness-kbd")
+		// Only forward idle hint edges so unrelated session updates don't
+		// cancel an input timeout.
+		lastIdle := initial.IdleHint
+		for {
+			select {
+			case <-m.stopChan:
+				return
+			case state, ok := <-ch:
+				if !ok {
+					return
+				}
+				m.SetSessionLocked(state.Locked)
+				if state.IdleHint != lastIdle {
+					lastIdle = state.IdleHint
+					m.SetSessionIdle(state.IdleHint)
+				}
+			}
+		}
+	}()
+}
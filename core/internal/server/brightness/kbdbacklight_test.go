@@ -0,0 +1,196 @@
+package brightness
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeSysfs(t *testing.T, path, value string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(value+"\n"), 0o644))
+}
+
+func setupKbdManager(t *testing.T) (*Manager, *fakeBackend, string) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	root := t.TempDir()
+	oldPower, oldLid := powerSupplyRoot, acpiLidGlob
+	powerSupplyRoot = filepath.Join(root, "power_supply")
+	acpiLidGlob = filepath.Join(root, "lid", "*", "state")
+	t.Cleanup(func() { powerSupplyRoot, acpiLidGlob = oldPower, oldLid })
+
+	writeSysfs(t, filepath.Join(powerSupplyRoot, "AC", "type"), "Mains")
+	writeSysfs(t, filepath.Join(powerSupplyRoot, "AC", "online"), "1")
+	writeSysfs(t, filepath.Join(root, "lid", "LID0", "state"), "state:      open")
+
+	backend := &fakeBackend{}
+	m := &Manager{
+		nativeBackend: backend,
+		nativeReady:   true,
+		stopChan:      make(chan struct{}),
+	}
+	m.state = State{Devices: []Device{
+		{Class: ClassBacklight, ID: "backlight:intel_backlight", Name: "intel_backlight", CurrentPercent: 50},
+		{Class: ClassLED, ID: "leds:tpacpi::kbd_backlight", Name: "tpacpi::kbd_backlight", CurrentPercent: 100},
+	}}
+	m.kbd.config = defaultKbdConfig()
+
+	t.Cleanup(m.stopKbdBacklight)
+	return m, backend, root
+}
+
+func kbdPercent(m *Manager) int {
+	dev, _ := m.kbdDevice()
+	return dev.CurrentPercent
+}
+
+func TestKbdBacklight_IdleAndRestore(t *testing.T) {
+	m, backend, _ := setupKbdManager(t)
+	require.NoError(t, m.ConfigureKbdBacklight(KbdBacklightConfig{Enabled: true, Timeout: 60, OffOnLid: true, OffOnLock: true}))
+
+	state := m.GetKbdBacklightState()
+	assert.Equal(t, "leds:tpacpi::kbd_backlight", state.Device)
+	assert.True(t, state.Lit)
+	assert.Empty(t, backend.values(), "enabling must not touch a lit backlight")
+
+	m.kbdIdleTimeout()
+	assert.Equal(t, 0, kbdPercent(m))
+	assert.Equal(t, "idle", m.GetKbdBacklightState().Reason)
+
+	m.NoteInputActivity()
+	assert.Equal(t, 100, kbdPercent(m))
+	assert.Equal(t, []int{0, 100}, backend.values())
+
+	m.NoteInputActivity()
+	assert.Len(t, backend.values(), 2, "activity while lit is a no-op")
+
+	_, err := os.Stat(filepath.Join(os.Getenv("XDG_CONFIG_HOME"), "DankMaterialShell", "kbd-backlight.json"))
+	assert.NoError(t, err)
+}
+
+func TestKbdBacklight_InputTimeoutIndependentOfSessionIdle(t *testing.T) {
+	m, _, _ := setupKbdManager(t)
+	require.NoError(t, m.ConfigureKbdBacklight(KbdBacklightConfig{Enabled: true, Timeout: 60}))
+
+	m.kbdIdleTimeout()
+	m.SetSessionIdle(true)
+	m.SetSessionIdle(false)
+	assert.Equal(t, 0, kbdPercent(m), "the session waking doesn't undo the input timeout")
+
+	m.NoteInputActivity()
+	assert.Equal(t, 100, kbdPercent(m))
+
+	m.kbd.mu.Lock()
+	stale := m.kbd.timerGen
+	m.resetKbdTimerLocked()
+	m.kbd.mu.Unlock()
+	m.kbdTimerFired(stale)
+	assert.Equal(t, 100, kbdPercent(m), "a replaced timer must not idle the backlight")
+}
+
+func TestKbdBacklight_RestoresManualLevel(t *testing.T) {
+	m, _, _ := setupKbdManager(t)
+	require.NoError(t, m.ConfigureKbdBacklight(KbdBacklightConfig{Enabled: true, Timeout: 60}))
+
+	require.NoError(t, m.SetBrightness("leds:tpacpi::kbd_backlight", 40))
+	m.SetSessionIdle(true)
+	assert.Equal(t, 0, kbdPercent(m))
+
+	m.SetSessionIdle(false)
+	assert.Equal(t, 40, kbdPercent(m))
+}
+
+func TestKbdBacklight_LockAndLid(t *testing.T) {
+	m, _, root := setupKbdManager(t)
+	require.NoError(t, m.ConfigureKbdBacklight(KbdBacklightConfig{Enabled: true, OffOnLid: true, OffOnLock: true}))
+
+	m.SetSessionLocked(true)
+	assert.Equal(t, 0, kbdPercent(m))
+	m.NoteInputActivity()
+	assert.Equal(t, 0, kbdPercent(m), "key presses on the lock screen keep it off")
+
+	m.SetSessionLocked(false)
+	assert.Equal(t, 100, kbdPercent(m))
+
+	writeSysfs(t, filepath.Join(root, "lid", "LID0", "state"), "state:      closed")
+	assert.True(t, lidClosed(acpiLidGlob))
+	m.kbd.mu.Lock()
+	m.kbd.lidClosed = true
+	m.kbd.mu.Unlock()
+	m.applyKbdPolicy()
+	assert.Equal(t, 0, kbdPercent(m))
+	assert.Equal(t, "lid", m.GetKbdBacklightState().Reason)
+}
+
+func TestKbdBacklight_BatteryCap(t *testing.T) {
+	m, _, _ := setupKbdManager(t)
+	writeSysfs(t, filepath.Join(powerSupplyRoot, "AC", "online"), "0")
+	writeSysfs(t, filepath.Join(powerSupplyRoot, "BAT0", "type"), "Battery")
+
+	require.NoError(t, m.ConfigureKbdBacklight(KbdBacklightConfig{Enabled: true, BatteryMaxPercent: 30}))
+	assert.True(t, m.GetKbdBacklightState().OnBattery)
+	assert.Equal(t, 30, kbdPercent(m))
+
+	m.SetSessionIdle(true)
+	assert.Equal(t, 100, m.GetKbdBacklightState().SavedPercent, "the uncapped level is kept")
+}
+
+func TestKbdBacklight_DisableRestores(t *testing.T) {
+	m, _, _ := setupKbdManager(t)
+	require.NoError(t, m.ConfigureKbdBacklight(KbdBacklightConfig{Enabled: true}))
+
+	m.SetSessionIdle(true)
+	require.Equal(t, 0, kbdPercent(m))
+
+	require.NoError(t, m.ConfigureKbdBacklight(KbdBacklightConfig{Enabled: false}))
+	assert.Equal(t, 100, kbdPercent(m))
+
+	m.SetSessionIdle(true)
+	assert.Equal(t, 100, kbdPercent(m), "disabled policy leaves the backlight alone")
+
+	assert.Error(t, m.ConfigureKbdBacklight(KbdBacklightConfig{Enabled: true, Device: "leds:missing"}))
+	assert.Error(t, m.ConfigureKbdBacklight(KbdBacklightConfig{Enabled: true, Timeout: -1}))
+}
+
+func TestKbdBacklight_PointersOnlyWithTimeout(t *testing.T) {
+	m, _, _ := setupKbdManager(t)
+	var wants []bool
+	m.kbd.wantPointers = func(want bool) { wants = append(wants, want) }
+
+	require.NoError(t, m.ConfigureKbdBacklight(KbdBacklightConfig{Enabled: true}))
+	require.NoError(t, m.ConfigureKbdBacklight(KbdBacklightConfig{Enabled: true, Timeout: 60}))
+	require.NoError(t, m.ConfigureKbdBacklight(KbdBacklightConfig{Enabled: false, Timeout: 60}))
+	assert.Equal(t, []bool{false, true, false}, wants)
+}
+
+func TestOnBattery(t *testing.T) {
+	root := t.TempDir()
+	assert.False(t, onBattery(root), "no mains supply means a desktop")
+
+	writeSysfs(t, filepath.Join(root, "BAT0", "type"), "Battery")
+	writeSysfs(t, filepath.Join(root, "ADP1", "type"), "Mains")
+	writeSysfs(t, filepath.Join(root, "ADP1", "online"), "0")
+	assert.True(t, onBattery(root))
+
+	writeSysfs(t, filepath.Join(root, "ucsi-source-psy-1", "type"), "USB")
+	writeSysfs(t, filepath.Join(root, "ucsi-source-psy-1", "online"), "1")
+	assert.False(t, onBattery(root))
+}
+
+func TestKbdBacklight_LidSwitchOverridesACPI(t *testing.T) {
+	m, _, _ := setupKbdManager(t)
+	m.SetLidClosed(true)
+	require.NoError(t, m.ConfigureKbdBacklight(KbdBacklightConfig{Enabled: true, OffOnLid: true}))
+
+	state := m.GetKbdBacklightState()
+	assert.True(t, state.LidClosed, "ACPI reports open but the evdev switch wins")
+	assert.Equal(t, 0, kbdPercent(m))
+
+	m.SetLidClosed(false)
+	assert.Equal(t, 100, kbdPercent(m))
+}
@@ -18,10 +18,16 @@ func NewManagerWithOptions(exponential bool) (*Manager, error) {
 		stopChan:    make(chan struct{}),
 		exponential: exponential,
 	}
+	m.auto.config = loadAutoConfig()
+	m.kbd.config = loadKbdConfig()
+	m.groups = loadGroups()
+	m.groupPercent = make(map[string]int)
 
 	go m.initLogind()
 	go m.initNative()
 	go m.initDDC()
+	go m.initAutoBrightness()
+	go m.initKbdBacklight()
 
 	return m, nil
 }
@@ -111,6 +117,16 @@ func stateChanged(old, new State) bool {
 		}
 	}
 
+	if len(old.Groups) != len(new.Groups) {
+		return true
+	}
+	for i := range new.Groups {
+		if old.Groups[i].ID != new.Groups[i].ID || old.Groups[i].CurrentPercent != new.Groups[i].CurrentPercent ||
+			len(old.Groups[i].Members) != len(new.Groups[i].Members) {
+			return true
+		}
+	}
+
 	return false
 }
 
@@ -141,7 +157,12 @@ func (m *Manager) updateState() {
 
 	m.stateMutex.Lock()
 	oldState := m.state
-	newState := State{Devices: allDevices}
+	newState := State{
+		Devices:        allDevices,
+		Groups:         m.groupStates(allDevices),
+		AutoBrightness: oldState.AutoBrightness,
+		KbdBacklight:   oldState.KbdBacklight,
+	}
 
 	if !stateChanged(oldState, newState) {
 		m.stateMutex.Unlock()
@@ -163,6 +184,18 @@ func (m *Manager) SetBrightnessWithMode(deviceID string, percent int, exponentia
 }
 
 func (m *Manager) SetBrightnessWithExponent(deviceID string, percent int, exponential bool, exponent float64) error {
+	if isGroupID(deviceID) {
+		return m.setGroupBrightness(deviceID, percent, exponential, exponent)
+	}
+	if err := m.setBrightness(deviceID, percent, exponential, exponent); err != nil {
+		return err
+	}
+	m.noteManualBrightness(deviceID, percent)
+	m.noteKbdManual(deviceID, percent)
+	return nil
+}
+
+func (m *Manager) setBrightness(deviceID string, percent int, exponential bool, exponent float64) error {
 	if percent < 0 {
 		return fmt.Errorf("percent out of range: %d", percent)
 	}
@@ -195,7 +228,9 @@ func (m *Manager) SetBrightnessWithExponent(deviceID string, percent int, expone
 	newDevices := make([]Device, len(currentState.Devices))
 	copy(newDevices, currentState.Devices)
 	newDevices[deviceIndex].CurrentPercent = percent
-	m.state = State{Devices: newDevices}
+	newState := currentState
+	newState.Devices = newDevices
+	m.state = newState
 	m.stateMutex.Unlock()
 
 	var err error
@@ -251,6 +286,13 @@ func (m *Manager) IncrementBrightnessWithExponent(deviceID string, step int, exp
 			break
 		}
 	}
+	for _, group := range currentState.Groups {
+		if group.ID == deviceID {
+			currentPercent = group.CurrentPercent
+			found = true
+			break
+		}
+	}
 
 	if !found {
 		return fmt.Errorf("device not found: %s", deviceID)
@@ -26,7 +26,10 @@ type Device struct {
 }
 
 type State struct {
-	Devices []Device `json:"devices"`
+	Devices        []Device             `json:"devices"`
+	Groups         []GroupState         `json:"groups,omitempty"`
+	AutoBrightness *AutoBrightnessState `json:"autoBrightness,omitempty"`
+	KbdBacklight   *KbdBacklightState   `json:"kbdBacklight,omitempty"`
 }
 
 type DeviceUpdate struct {
@@ -56,6 +59,14 @@ type Manager struct {
 
 	exponential bool
 
+	auto autoBrightness
+	kbd  kbdBacklight
+	dim  backlightDim
+
+	groupsMutex  sync.Mutex
+	groups       []LinkGroup
+	groupPercent map[string]int
+
 	stateMutex sync.RWMutex
 	state      State
 
@@ -110,6 +121,8 @@ type ddcDevice struct {
 	name           string
 	max            int
 	lastBrightness int
+	ioMutex        sync.Mutex
+	caps           *DDCCapabilities
 }
 
 type ddcCapability struct {
@@ -169,6 +182,8 @@ func (m *Manager) GetState() State {
 
 func (m *Manager) Close() {
 	close(m.stopChan)
+	m.stopAutoBrightness()
+	m.stopKbdBacklight()
 
 	m.subscribers.Range(func(key string, ch chan State) bool {
 		close(ch)
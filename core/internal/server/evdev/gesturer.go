@@ -0,0 +1,364 @@
+package evdev
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/AvengeMedia/DankMaterialShell/core/internal/log"
+	"github.com/AvengeMedia/dankgo/syncmap"
+	evdev "github.com/holoplot/go-evdev"
+)
+
+// gestureSource is a touchpad read alongside the compositor; it is never
+// grabbed.
+type gestureSource interface {
+	Path() string
+	ReadOne() (*evdev.InputEvent, error)
+	Close() error
+	AbsInfos() (map[evdev.EvCode]evdev.AbsInfo, error)
+}
+
+var openGestureSource = func(path string) (gestureSource, error) {
+	return evdev.Open(path)
+}
+
+// runGestureAction is swapped out in tests.
+var runGestureAction = func(b GestureBinding) error {
+	var cmd *exec.Cmd
+	if len(b.IPC) > 0 {
+		exe, err := os.Executable()
+		if err != nil {
+			return err
+		}
+		cmd = exec.Command(exe, append([]string{"ipc", "call"}, b.IPC...)...)
+	} else {
+		cmd = exec.Command("sh", "-c", b.Command)
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go cmd.Wait()
+	return nil
+}
+
+type GestureState struct {
+	Config  GestureConfig `json:"config"`
+	Devices []string      `json:"devices"`
+	Error   string        `json:"error,omitempty"`
+}
+
+type gestureSession struct {
+	path   string
+	source gestureSource
+	emit   func(Gesture)
+
+	mu    sync.Mutex
+	rec   *gestureRecognizer
+	timer *time.Timer
+	done  chan struct{}
+}
+
+func (s *gestureSession) handle(event *evdev.InputEvent) {
+	s.mu.Lock()
+	g := s.rec.Feed(event)
+	if event.Type == evdev.EV_SYN {
+		s.scheduleLocked(eventTime(event))
+	}
+	s.mu.Unlock()
+
+	if g != nil {
+		g.Device = s.path
+		s.emit(*g)
+	}
+}
+
+func (s *gestureSession) scheduleLocked(now time.Time) {
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	deadline, ok := s.rec.Deadline()
+	if !ok {
+		return
+	}
+	s.timer = time.AfterFunc(deadline.Sub(now), s.tick)
+}
+
+func (s *gestureSession) tick() {
+	s.mu.Lock()
+	deadline, ok := s.rec.Deadline()
+	g := s.rec.Tick(deadline)
+	s.mu.Unlock()
+
+	if ok && g != nil {
+		g.Device = s.path
+		s.emit(*g)
+	}
+}
+
+func (s *gestureSession) run() {
+	defer close(s.done)
+	for {
+		event, err := s.source.ReadOne()
+		if err != nil {
+			if isClosedError(err) {
+				return
+			}
+			log.Debugf("evdev gestures: %s: %v", s.path, err)
+			time.Sleep(100 * time.Millisecond)
+			continue
+		}
+		if event != nil {
+			s.handle(event)
+		}
+	}
+}
+
+func (s *gestureSession) stop() {
+	s.mu.Lock()
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+	s.mu.Unlock()
+	s.source.Close()
+	<-s.done
+}
+
+// gesturer recognizes gestures on every touchpad in the inventory and
+// dispatches them to bindings and subscribers.
+type gesturer struct {
+	mu        sync.Mutex
+	config    GestureConfig
+	err       string
+	sessions  map[string]*gestureSession
+	inventory func() []InputDevice
+
+	subscribers syncmap.Map[string, chan Gesture]
+}
+
+func newGesturer(inventory func() []InputDevice) *gesturer {
+	return &gesturer{
+		config:    defaultGestureConfig(),
+		sessions:  make(map[string]*gestureSession),
+		inventory: inventory,
+	}
+}
+
+func (g *gesturer) start() {
+	cfg, err := loadGestureConfig()
+	if err == nil {
+		err = cfg.validate()
+	}
+	if err != nil {
+		log.Warnf("evdev gestures: %v", err)
+		g.mu.Lock()
+		g.err = err.Error()
+		g.mu.Unlock()
+		return
+	}
+	g.apply(cfg)
+}
+
+func (g *gesturer) apply(cfg GestureConfig) {
+	g.mu.Lock()
+	g.config = cfg
+	g.err = ""
+	sessions := g.detachAllLocked()
+	g.mu.Unlock()
+
+	for _, s := range sessions {
+		s.stop()
+	}
+	if !cfg.Enabled {
+		return
+	}
+	for _, info := range g.inventory() {
+		g.deviceAdded(info)
+	}
+}
+
+func (g *gesturer) detachAllLocked() []*gestureSession {
+	sessions := make([]*gestureSession, 0, len(g.sessions))
+	for path, s := range g.sessions {
+		sessions = append(sessions, s)
+		delete(g.sessions, path)
+	}
+	return sessions
+}
+
+func (g *gesturer) deviceAdded(info InputDevice) {
+	if !info.is(KindTouchpad) {
+		return
+	}
+
+	g.mu.Lock()
+	_, attached := g.sessions[info.Path]
+	cfg := g.config
+	g.mu.Unlock()
+	if !cfg.Enabled || attached {
+		return
+	}
+
+	source, err := openGestureSource(info.Path)
+	if err != nil {
+		log.Warnf("evdev gestures: open %s: %v", info.Path, err)
+		return
+	}
+	absInfo, err := source.AbsInfos()
+	if err != nil {
+		log.Warnf("evdev gestures: %s: %v", info.Path, err)
+		source.Close()
+		return
+	}
+	x, okX := absInfo[evdev.ABS_MT_POSITION_X]
+	y, okY := absInfo[evdev.ABS_MT_POSITION_Y]
+	if !okX || !okY {
+		log.Debugf("evdev gestures: %s is not multitouch", info.Path)
+		source.Close()
+		return
+	}
+	armNonBlocking(source)
+
+	session := &gestureSession{
+		path:   info.Path,
+		source: source,
+		emit:   g.dispatch,
+		rec:    newGestureRecognizer(cfg, x, y),
+		done:   make(chan struct{}),
+	}
+
+	g.mu.Lock()
+	if _, attached := g.sessions[info.Path]; attached || !g.config.Enabled {
+		g.mu.Unlock()
+		source.Close()
+		return
+	}
+	g.sessions[info.Path] = session
+	g.mu.Unlock()
+
+	log.Infof("evdev gestures: watching %s (%s)", info.Name, info.Path)
+	go session.run()
+}
+
+func (g *gesturer) deviceRemoved(path string) {
+	g.mu.Lock()
+	session, ok := g.sessions[path]
+	delete(g.sessions, path)
+	g.mu.Unlock()
+	if ok {
+		session.stop()
+	}
+}
+
+// dispatch runs the first matching binding and publishes the gesture.
+func (g *gesturer) dispatch(gesture Gesture) {
+	g.mu.Lock()
+	bindings := g.config.Bindings
+	g.mu.Unlock()
+
+	for _, b := range bindings {
+		if !b.matches(gesture) {
+			continue
+		}
+		gesture.Action = b.describe()
+		if err := runGestureAction(b); err != nil {
+			log.Warnf("evdev gestures: %s %d-finger %s: %v", gesture.Type, gesture.Fingers, gesture.Direction, err)
+		}
+		break
+	}
+
+	log.Debugf("Gesture: %s %d-finger %s on %s", gesture.Type, gesture.Fingers, gesture.Direction, gesture.Device)
+	g.subscribers.Range(func(key string, ch chan Gesture) bool {
+		select {
+		case ch <- gesture:
+		default:
+		}
+		return true
+	})
+}
+
+func (g *gesturer) state() GestureState {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	state := GestureState{Config: g.config, Error: g.err, Devices: make([]string, 0, len(g.sessions))}
+	for path := range g.sessions {
+		state.Devices = append(state.Devices, path)
+	}
+	sort.Strings(state.Devices)
+	return state
+}
+
+func (g *gesturer) stop() {
+	g.mu.Lock()
+	sessions := g.detachAllLocked()
+	g.mu.Unlock()
+	for _, s := range sessions {
+		s.stop()
+	}
+	g.subscribers.Range(func(key string, ch chan Gesture) bool {
+		close(ch)
+		g.subscribers.Delete(key)
+		return true
+	})
+}
+
+var errGesturesUnavailable = errors.New("gesture recognizer not available")
+
+func (m *Manager) GetGestureState() (GestureState, error) {
+	if m.gestures == nil {
+		return GestureState{}, errGesturesUnavailable
+	}
+	return m.gestures.state(), nil
+}
+
+// SetGestureConfig validates, persists and applies a gesture config.
+func (m *Manager) SetGestureConfig(cfg GestureConfig) error {
+	if m.gestures == nil {
+		return errGesturesUnavailable
+	}
+	if cfg.Bindings == nil {
+		cfg.Bindings = []GestureBinding{}
+	}
+	if err := cfg.validate(); err != nil {
+		return err
+	}
+	if err := saveGestureConfig(cfg); err != nil {
+		return fmt.Errorf("save gesture config: %w", err)
+	}
+	m.gestures.apply(cfg)
+	return nil
+}
+
+func (m *Manager) SetGesturesEnabled(enabled bool) error {
+	state, err := m.GetGestureState()
+	if err != nil {
+		return err
+	}
+	cfg := state.Config
+	cfg.Enabled = enabled
+	return m.SetGestureConfig(cfg)
+}
+
+func (m *Manager) SubscribeGestures(id string) chan Gesture {
+	ch := make(chan Gesture, 16)
+	if m.gestures == nil {
+		close(ch)
+		return ch
+	}
+	m.gestures.subscribers.Store(id, ch)
+	return ch
+}
+
+func (m *Manager) UnsubscribeGestures(id string) {
+	if m.gestures == nil {
+		return
+	}
+	if val, ok := m.gestures.subscribers.LoadAndDelete(id); ok {
+		close(val)
+	}
+}
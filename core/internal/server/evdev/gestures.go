@@ -0,0 +1,389 @@
+package evdev
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	evdev "github.com/holoplot/go-evdev"
+)
+
+const (
+	GestureSwipe = "swipe"
+	GesturePinch = "pinch"
+	GestureHold  = "hold"
+)
+
+const (
+	DirectionUp    = "up"
+	DirectionDown  = "down"
+	DirectionLeft  = "left"
+	DirectionRight = "right"
+	DirectionIn    = "in"
+	DirectionOut   = "out"
+)
+
+const gestureMaxSlots = 10
+
+var toolFingerCounts = map[evdev.EvCode]int{
+	evdev.BTN_TOOL_FINGER:    1,
+	evdev.BTN_TOOL_DOUBLETAP: 2,
+	evdev.BTN_TOOL_TRIPLETAP: 3,
+	evdev.BTN_TOOL_QUADTAP:   4,
+	evdev.BTN_TOOL_QUINTTAP:  5,
+}
+
+// Gesture is published to subscribers for every recognized gesture,
+// whether or not a binding handled it.
+type Gesture struct {
+	Type      string `json:"type"`
+	Fingers   int    `json:"fingers"`
+	Direction string `json:"direction,omitempty"`
+	Device    string `json:"device"`
+	Action    string `json:"action,omitempty"`
+}
+
+// GestureConfig is persisted to gestures.json. Thresholds are fractions
+// of the touchpad size so they behave the same on every pad.
+type GestureConfig struct {
+	Enabled    bool `json:"enabled"`
+	MinFingers int  `json:"minFingers"`
+	// SwipeThreshold is the centroid travel that counts as a swipe.
+	SwipeThreshold float64 `json:"swipeThreshold"`
+	// PinchThreshold is the relative change in finger spread that counts
+	// as a pinch.
+	PinchThreshold float64 `json:"pinchThreshold"`
+	// HoldTime is how long, in ms, fingers must rest to count as a hold.
+	HoldTime int              `json:"holdTime"`
+	Bindings []GestureBinding `json:"bindings"`
+}
+
+// GestureBinding runs either a shell IPC call (target, function, args...)
+// or a shell command. Zero Fingers or empty Direction match any.
+type GestureBinding struct {
+	Type      string   `json:"type"`
+	Fingers   int      `json:"fingers,omitempty"`
+	Direction string   `json:"direction,omitempty"`
+	IPC       []string `json:"ipc,omitempty"`
+	Command   string   `json:"command,omitempty"`
+}
+
+func defaultGestureConfig() GestureConfig {
+	return GestureConfig{
+		MinFingers:     3,
+		SwipeThreshold: 0.15,
+		PinchThreshold: 0.3,
+		HoldTime:       600,
+		Bindings:       []GestureBinding{},
+	}
+}
+
+func gestureConfigPath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "DankMaterialShell", "gestures.json"), nil
+}
+
+func loadGestureConfig() (GestureConfig, error) {
+	cfg := defaultGestureConfig()
+	path, err := gestureConfigPath()
+	if err != nil {
+		return cfg, err
+	}
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return cfg, nil
+	case err != nil:
+		return cfg, err
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return defaultGestureConfig(), fmt.Errorf("invalid gesture config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+func saveGestureConfig(cfg GestureConfig) error {
+	path, err := gestureConfigPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+var gestureDirections = map[string][]string{
+	GestureSwipe: {DirectionUp, DirectionDown, DirectionLeft, DirectionRight},
+	GesturePinch: {DirectionIn, DirectionOut},
+	GestureHold:  {},
+}
+
+func (cfg GestureConfig) validate() error {
+	switch {
+	case cfg.MinFingers < 2 || cfg.MinFingers > 5:
+		return fmt.Errorf("minFingers must be between 2 and 5")
+	case cfg.SwipeThreshold <= 0 || cfg.SwipeThreshold >= 1:
+		return fmt.Errorf("swipeThreshold must be between 0 and 1")
+	case cfg.PinchThreshold <= 0 || cfg.PinchThreshold >= 1:
+		return fmt.Errorf("pinchThreshold must be between 0 and 1")
+	case cfg.HoldTime <= 0:
+		return fmt.Errorf("holdTime must be positive")
+	}
+
+	for i, b := range cfg.Bindings {
+		directions, ok := gestureDirections[b.Type]
+		if !ok {
+			return fmt.Errorf("binding %d: unknown gesture type: %s", i, b.Type)
+		}
+		if b.Direction != "" && !slices.Contains(directions, b.Direction) {
+			return fmt.Errorf("binding %d: invalid direction %q for %s", i, b.Direction, b.Type)
+		}
+		if b.Fingers != 0 && (b.Fingers < cfg.MinFingers || b.Fingers > 5) {
+			return fmt.Errorf("binding %d: fingers must be between %d and 5", i, cfg.MinFingers)
+		}
+		switch {
+		case len(b.IPC) > 0 && b.Command != "":
+			return fmt.Errorf("binding %d: set either ipc or command, not both", i)
+		case len(b.IPC) == 1:
+			return fmt.Errorf("binding %d: ipc needs a target and a function", i)
+		case len(b.IPC) == 0 && b.Command == "":
+			return fmt.Errorf("binding %d: missing ipc or command", i)
+		}
+	}
+	return nil
+}
+
+func (b GestureBinding) matches(g Gesture) bool {
+	return b.Type == g.Type &&
+		(b.Fingers == 0 || b.Fingers == g.Fingers) &&
+		(b.Direction == "" || b.Direction == g.Direction)
+}
+
+func (b GestureBinding) describe() string {
+	if len(b.IPC) > 0 {
+		return fmt.Sprintf("ipc %v", b.IPC)
+	}
+	return b.Command
+}
+
+type touchSlot struct {
+	active bool
+	x, y   float64
+}
+
+// gestureRecognizer consumes one touchpad's raw multitouch (protocol B)
+// stream. All timing comes from event timestamps, so recorded streams
+// replay deterministically.
+type gestureRecognizer struct {
+	cfg           GestureConfig
+	width, height float64
+	originX       float64
+	originY       float64
+
+	slots       [gestureMaxSlots]touchSlot
+	slot        int
+	toolFingers int
+
+	fingers     int
+	tracking    bool
+	done        bool
+	holdBroken  bool
+	startTime   time.Time
+	startX      float64
+	startY      float64
+	startSpread float64
+}
+
+func newGestureRecognizer(cfg GestureConfig, x, y evdev.AbsInfo) *gestureRecognizer {
+	width := float64(x.Maximum - x.Minimum)
+	height := float64(y.Maximum - y.Minimum)
+	if width <= 0 {
+		width = 1
+	}
+	if height <= 0 {
+		height = 1
+	}
+	return &gestureRecognizer{
+		cfg:     cfg,
+		width:   width,
+		height:  height,
+		originX: float64(x.Minimum),
+		originY: float64(y.Minimum),
+	}
+}
+
+func eventTime(ev *evdev.InputEvent) time.Time {
+	return time.Unix(int64(ev.Time.Sec), int64(ev.Time.Usec)*int64(time.Microsecond))
+}
+
+// Feed returns a gesture when one completes on a SYN_REPORT frame.
+func (r *gestureRecognizer) Feed(ev *evdev.InputEvent) *Gesture {
+	switch ev.Type {
+	case evdev.EV_ABS:
+		switch ev.Code {
+		case evdev.ABS_MT_SLOT:
+			if ev.Value >= 0 && ev.Value < gestureMaxSlots {
+				r.slot = int(ev.Value)
+			}
+		case evdev.ABS_MT_TRACKING_ID:
+			r.slots[r.slot].active = ev.Value >= 0
+		case evdev.ABS_MT_POSITION_X:
+			r.slots[r.slot].x = (float64(ev.Value) - r.originX) / r.width
+		case evdev.ABS_MT_POSITION_Y:
+			r.slots[r.slot].y = (float64(ev.Value) - r.originY) / r.height
+		}
+	case evdev.EV_KEY:
+		if n, ok := toolFingerCounts[ev.Code]; ok {
+			switch {
+			case ev.Value != 0:
+				r.toolFingers = n
+			case r.toolFingers == n:
+				r.toolFingers = 0
+			}
+		}
+	case evdev.EV_SYN:
+		if ev.Code == evdev.SYN_REPORT {
+			return r.frame(eventTime(ev))
+		}
+	}
+	return nil
+}
+
+// count prefers BTN_TOOL_*TAP, since pads that track only two slots still
+// report the real finger count there.
+func (r *gestureRecognizer) count() int {
+	active := 0
+	for _, s := range r.slots {
+		if s.active {
+			active++
+		}
+	}
+	return max(active, r.toolFingers)
+}
+
+// centroid returns the mean position and mean distance from it of the
+// tracked contacts, in pad-relative units.
+func (r *gestureRecognizer) centroid() (x, y, spread float64) {
+	n := 0
+	for _, s := range r.slots {
+		if s.active {
+			x += s.x
+			y += s.y
+			n++
+		}
+	}
+	if n == 0 {
+		return 0, 0, 0
+	}
+	x /= float64(n)
+	y /= float64(n)
+	for _, s := range r.slots {
+		if s.active {
+			spread += math.Hypot(s.x-x, s.y-y)
+		}
+	}
+	return x, y, spread / float64(n)
+}
+
+func (r *gestureRecognizer) frame(now time.Time) *Gesture {
+	n := r.count()
+	switch {
+	case n == 0:
+		r.fingers, r.tracking, r.done = 0, false, false
+		return nil
+	case r.done:
+		return nil
+	case n < r.fingers:
+		// Lifting fingers before anything was recognized cancels.
+		r.done = r.tracking
+		r.fingers = n
+		return nil
+	case n > r.fingers:
+		r.fingers = n
+		r.tracking = n >= r.cfg.MinFingers
+		if r.tracking {
+			r.startTime = now
+			r.startX, r.startY, r.startSpread = r.centroid()
+			r.holdBroken = false
+		}
+		return nil
+	case !r.tracking:
+		return nil
+	}
+
+	cx, cy, spread := r.centroid()
+	dx, dy := cx-r.startX, cy-r.startY
+	moved := math.Hypot(dx, dy)
+
+	var g *Gesture
+	if r.startSpread > 0 {
+		scale := spread/r.startSpread - 1
+		if math.Abs(scale) >= r.cfg.PinchThreshold && moved < r.cfg.SwipeThreshold {
+			g = &Gesture{Type: GesturePinch, Direction: DirectionOut}
+			if scale < 0 {
+				g.Direction = DirectionIn
+			}
+		}
+		if math.Abs(scale) >= r.cfg.PinchThreshold/4 {
+			r.holdBroken = true
+		}
+	}
+
+	if g == nil && max(math.Abs(dx), math.Abs(dy)) >= r.cfg.SwipeThreshold {
+		g = &Gesture{Type: GestureSwipe}
+		switch {
+		case math.Abs(dx) > math.Abs(dy) && dx > 0:
+			g.Direction = DirectionRight
+		case math.Abs(dx) > math.Abs(dy):
+			g.Direction = DirectionLeft
+		case dy > 0:
+			g.Direction = DirectionDown
+		default:
+			g.Direction = DirectionUp
+		}
+	}
+
+	if moved >= r.cfg.SwipeThreshold/4 {
+		r.holdBroken = true
+	}
+	if g == nil {
+		return r.Tick(now)
+	}
+	return r.finish(g)
+}
+
+func (r *gestureRecognizer) finish(g *Gesture) *Gesture {
+	r.done = true
+	g.Fingers = r.fingers
+	return g
+}
+
+// Deadline reports when resting fingers become a hold, for pads that stop
+// reporting while contacts are still.
+func (r *gestureRecognizer) Deadline() (time.Time, bool) {
+	if !r.tracking || r.done || r.holdBroken {
+		return time.Time{}, false
+	}
+	return r.startTime.Add(time.Duration(r.cfg.HoldTime) * time.Millisecond), true
+}
+
+func (r *gestureRecognizer) Tick(now time.Time) *Gesture {
+	deadline, ok := r.Deadline()
+	if !ok || now.Before(deadline) {
+		return nil
+	}
+	return r.finish(&Gesture{Type: GestureHold})
+}
@@ -0,0 +1,293 @@
+package evdev
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	evdev "github.com/holoplot/go-evdev"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recording struct {
+	abs    map[evdev.EvCode]evdev.AbsInfo
+	events []evdev.InputEvent
+}
+
+// loadRecording parses the subset of the evemu-record format the
+// recognizer cares about: "A:" axis lines and "E:" event lines.
+func loadRecording(t *testing.T, name string) recording {
+	t.Helper()
+	f, err := os.Open(filepath.Join("testdata", name))
+	require.NoError(t, err)
+	defer f.Close()
+
+	rec := recording{abs: make(map[evdev.EvCode]evdev.AbsInfo)}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "A:":
+			require.Len(t, fields, 7, scanner.Text())
+			code, err := strconv.ParseUint(fields[1], 16, 16)
+			require.NoError(t, err)
+			minimum, _ := strconv.Atoi(fields[2])
+			maximum, _ := strconv.Atoi(fields[3])
+			rec.abs[evdev.EvCode(code)] = evdev.AbsInfo{Minimum: int32(minimum), Maximum: int32(maximum)}
+		case "E:":
+			require.Len(t, fields, 5, scanner.Text())
+			sec, usec, _ := strings.Cut(fields[1], ".")
+			s, err := strconv.ParseInt(sec, 10, 64)
+			require.NoError(t, err)
+			us, err := strconv.ParseInt(usec, 10, 64)
+			require.NoError(t, err)
+			typ, err := strconv.ParseUint(fields[2], 16, 16)
+			require.NoError(t, err)
+			code, err := strconv.ParseUint(fields[3], 16, 16)
+			require.NoError(t, err)
+			value, err := strconv.ParseInt(fields[4], 10, 32)
+			require.NoError(t, err)
+			rec.events = append(rec.events, evdev.InputEvent{
+				Time:  syscall.Timeval{Sec: s, Usec: us},
+				Type:  evdev.EvType(typ),
+				Code:  evdev.EvCode(code),
+				Value: int32(value),
+			})
+		}
+	}
+	require.NoError(t, scanner.Err())
+	return rec
+}
+
+func (rec recording) replay(cfg GestureConfig) []Gesture {
+	r := newGestureRecognizer(cfg, rec.abs[evdev.ABS_MT_POSITION_X], rec.abs[evdev.ABS_MT_POSITION_Y])
+	var gestures []Gesture
+	for i := range rec.events {
+		if g := r.Feed(&rec.events[i]); g != nil {
+			gestures = append(gestures, *g)
+		}
+	}
+	return gestures
+}
+
+func TestGestureRecognizer_Recordings(t *testing.T) {
+	tests := []struct {
+		file string
+		want []Gesture
+	}{
+		{"swipe3_left.evemu", []Gesture{{Type: GestureSwipe, Fingers: 3, Direction: DirectionLeft}}},
+		{"swipe4_down.evemu", []Gesture{{Type: GestureSwipe, Fingers: 4, Direction: DirectionDown}}},
+		{"pinch3_in.evemu", []Gesture{{Type: GesturePinch, Fingers: 3, Direction: DirectionIn}}},
+		{"swipe3_twoslot_up.evemu", []Gesture{{Type: GestureSwipe, Fingers: 3, Direction: DirectionUp}}},
+		{"hold4.evemu", []Gesture{{Type: GestureHold, Fingers: 4}}},
+		{"cancel3_lift.evemu", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			assert.Equal(t, tt.want, loadRecording(t, tt.file).replay(defaultGestureConfig()))
+		})
+	}
+}
+
+func TestGestureRecognizer_Thresholds(t *testing.T) {
+	cfg := defaultGestureConfig()
+	cfg.MinFingers = 4
+	assert.Empty(t, loadRecording(t, "swipe3_left.evemu").replay(cfg), "below minFingers")
+
+	cfg = defaultGestureConfig()
+	cfg.SwipeThreshold = 0.5
+	assert.Empty(t, loadRecording(t, "swipe3_left.evemu").replay(cfg), "travel below the swipe threshold")
+
+	cfg = defaultGestureConfig()
+	cfg.PinchThreshold = 0.9
+	assert.Empty(t, loadRecording(t, "pinch3_in.evemu").replay(cfg), "spread change below the pinch threshold")
+}
+
+func TestGestureRecognizer_HoldDeadline(t *testing.T) {
+	rec := loadRecording(t, "hold4.evemu")
+	r := newGestureRecognizer(defaultGestureConfig(), rec.abs[evdev.ABS_MT_POSITION_X], rec.abs[evdev.ABS_MT_POSITION_Y])
+
+	// Feed only the first frame, as a pad that goes quiet once contacts
+	// stop moving would.
+	for i := range rec.events {
+		require.Nil(t, r.Feed(&rec.events[i]))
+		if rec.events[i].Type == evdev.EV_SYN {
+			break
+		}
+	}
+
+	deadline, ok := r.Deadline()
+	require.True(t, ok)
+	assert.Equal(t, eventTime(&rec.events[0]).Add(600*time.Millisecond), deadline)
+	assert.Nil(t, r.Tick(deadline.Add(-time.Millisecond)))
+	assert.Equal(t, &Gesture{Type: GestureHold, Fingers: 4}, r.Tick(deadline))
+
+	_, ok = r.Deadline()
+	assert.False(t, ok, "a gesture fires once per touch")
+	assert.Nil(t, r.Tick(deadline.Add(time.Second)))
+}
+
+func TestGestureConfig_Validate(t *testing.T) {
+	ipc := []string{"spotlight", "toggle"}
+	valid := defaultGestureConfig()
+	valid.Bindings = []GestureBinding{
+		{Type: GestureSwipe, Fingers: 3, Direction: DirectionUp, IPC: ipc},
+		{Type: GesturePinch, Direction: DirectionIn, Command: "niri msg action toggle-overview"},
+		{Type: GestureHold, Fingers: 4, IPC: []string{"powermenu", "open"}},
+	}
+	require.NoError(t, valid.validate())
+
+	for name, mutate := range map[string]func(*GestureConfig){
+		"min fingers":     func(c *GestureConfig) { c.MinFingers = 1 },
+		"swipe threshold": func(c *GestureConfig) { c.SwipeThreshold = 1.5 },
+		"pinch threshold": func(c *GestureConfig) { c.PinchThreshold = 0 },
+		"hold time":       func(c *GestureConfig) { c.HoldTime = 0 },
+		"type":            func(c *GestureConfig) { c.Bindings = []GestureBinding{{Type: "tap", IPC: ipc}} },
+		"direction": func(c *GestureConfig) {
+			c.Bindings = []GestureBinding{{Type: GesturePinch, Direction: DirectionUp, IPC: ipc}}
+		},
+		"hold direction": func(c *GestureConfig) {
+			c.Bindings = []GestureBinding{{Type: GestureHold, Direction: DirectionIn, IPC: ipc}}
+		},
+		"fingers":      func(c *GestureConfig) { c.Bindings = []GestureBinding{{Type: GestureSwipe, Fingers: 2, IPC: ipc}} },
+		"no action":    func(c *GestureConfig) { c.Bindings = []GestureBinding{{Type: GestureSwipe}} },
+		"both actions": func(c *GestureConfig) { c.Bindings = []GestureBinding{{Type: GestureSwipe, IPC: ipc, Command: "true"}} },
+		"short ipc": func(c *GestureConfig) {
+			c.Bindings = []GestureBinding{{Type: GestureSwipe, IPC: []string{"spotlight"}}}
+		},
+	} {
+		cfg := defaultGestureConfig()
+		mutate(&cfg)
+		assert.Error(t, cfg.validate(), name)
+	}
+}
+
+type fakeGestureSource struct {
+	path   string
+	abs    map[evdev.EvCode]evdev.AbsInfo
+	events chan *evdev.InputEvent
+	mu     sync.Mutex
+	closed bool
+}
+
+func (s *fakeGestureSource) Path() string { return s.path }
+
+func (s *fakeGestureSource) ReadOne() (*evdev.InputEvent, error) {
+	event, ok := <-s.events
+	if !ok {
+		return nil, errors.New("device closed")
+	}
+	return event, nil
+}
+
+func (s *fakeGestureSource) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.closed {
+		s.closed = true
+		close(s.events)
+	}
+	return nil
+}
+
+func (s *fakeGestureSource) AbsInfos() (map[evdev.EvCode]evdev.AbsInfo, error) {
+	return s.abs, nil
+}
+
+func TestGesturer_DispatchAndSubscribe(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	rec := loadRecording(t, "swipe3_left.evemu")
+
+	touchpad := InputDevice{Path: "/dev/input/event7", Name: "Synaptics TM3276-022", Kinds: []string{KindTouchpad, KindPointer}}
+	keyboard := InputDevice{Path: "/dev/input/event3", Name: "kbd", Kinds: []string{KindKeyboard}}
+
+	var mu sync.Mutex
+	sources := make(map[string]*fakeGestureSource)
+	var ran []GestureBinding
+	oldOpen, oldRun := openGestureSource, runGestureAction
+	openGestureSource = func(path string) (gestureSource, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		source := &fakeGestureSource{path: path, abs: rec.abs, events: make(chan *evdev.InputEvent, len(rec.events))}
+		sources[path] = source
+		return source, nil
+	}
+	runGestureAction = func(b GestureBinding) error {
+		mu.Lock()
+		defer mu.Unlock()
+		ran = append(ran, b)
+		return nil
+	}
+	t.Cleanup(func() { openGestureSource, runGestureAction = oldOpen, oldRun })
+
+	g := newGesturer(func() []InputDevice { return []InputDevice{touchpad, keyboard} })
+	m := &Manager{gestures: g}
+	t.Cleanup(g.stop)
+
+	g.start()
+	state, err := m.GetGestureState()
+	require.NoError(t, err)
+	assert.False(t, state.Config.Enabled)
+	assert.Empty(t, state.Devices)
+
+	binding := GestureBinding{Type: GestureSwipe, Fingers: 3, Direction: DirectionLeft, IPC: []string{"workspace", "next"}}
+	cfg := defaultGestureConfig()
+	cfg.Enabled = true
+	cfg.Bindings = []GestureBinding{
+		{Type: GestureSwipe, Fingers: 4, IPC: []string{"overview", "toggle"}},
+		binding,
+		{Type: GestureSwipe, Command: "never"},
+	}
+	require.NoError(t, m.SetGestureConfig(cfg))
+	state, _ = m.GetGestureState()
+	assert.Equal(t, []string{touchpad.Path}, state.Devices, "only touchpads are watched")
+
+	ch := m.SubscribeGestures("test")
+	mu.Lock()
+	source := sources[touchpad.Path]
+	mu.Unlock()
+	for i := range rec.events {
+		source.events <- &rec.events[i]
+	}
+
+	select {
+	case gesture := <-ch:
+		assert.Equal(t, Gesture{
+			Type:      GestureSwipe,
+			Fingers:   3,
+			Direction: DirectionLeft,
+			Device:    touchpad.Path,
+			Action:    binding.describe(),
+		}, gesture)
+	case <-time.After(time.Second):
+		t.Fatal("no gesture published")
+	}
+	mu.Lock()
+	assert.Equal(t, []GestureBinding{binding}, ran, "only the first matching binding runs")
+	mu.Unlock()
+
+	g.deviceRemoved(touchpad.Path)
+	assert.True(t, source.closed)
+
+	require.NoError(t, m.SetGesturesEnabled(false))
+	saved, err := loadGestureConfig()
+	require.NoError(t, err)
+	assert.False(t, saved.Enabled)
+	assert.Len(t, saved.Bindings, 3)
+
+	m.UnsubscribeGestures("test")
+	_, ok := <-ch
+	assert.False(t, ok)
+}
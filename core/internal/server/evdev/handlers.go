@@ -1,13 +1,39 @@
 package evdev
 
 import (
+	"encoding/json"
+	"fmt"
+
 	"github.com/AvengeMedia/DankMaterialShell/core/internal/server/models"
+	"github.com/AvengeMedia/dankgo/ipc/params"
 )
 
 func HandleRequest(conn *models.Conn, req models.Request, m *Manager) {
 	switch req.Method {
 	case "evdev.getState":
 		handleGetState(conn, req, m)
+	case "evdev.listDevices":
+		models.Respond(conn, req.ID, m.GetDevices())
+	case "evdev.remap.getState":
+		respondRemapState(conn, req, m, nil)
+	case "evdev.remap.enable":
+		respondRemapState(conn, req, m, m.SetRemapEnabled(true))
+	case "evdev.remap.disable":
+		respondRemapState(conn, req, m, m.SetRemapEnabled(false))
+	case "evdev.remap.toggle":
+		handleRemapToggle(conn, req, m)
+	case "evdev.remap.setEnabled":
+		handleRemapSetEnabled(conn, req, m)
+	case "evdev.remap.setConfig":
+		handleRemapSetConfig(conn, req, m)
+	case "evdev.remap.reload":
+		respondRemapState(conn, req, m, m.ReloadRemap())
+	case "evdev.gestures.getState":
+		respondGestureState(conn, req, m, nil)
+	case "evdev.gestures.setEnabled":
+		handleGesturesSetEnabled(conn, req, m)
+	case "evdev.gestures.setConfig":
+		handleGesturesSetConfig(conn, req, m)
 	default:
 		models.RespondError(conn, req.ID, "unknown method: "+req.Method)
 	}
@@ -16,3 +42,107 @@ func HandleRequest(conn *models.Conn, req models.Request, m *Manager) {
 func handleGetState(conn *models.Conn, req models.Request, m *Manager) {
 	models.Respond(conn, req.ID, m.GetState())
 }
+
+func respondRemapState(conn *models.Conn, req models.Request, m *Manager, err error) {
+	if err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+
+	state, err := m.GetRemapState()
+	if err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+
+	models.Respond(conn, req.ID, state)
+}
+
+func handleRemapToggle(conn *models.Conn, req models.Request, m *Manager) {
+	state, err := m.GetRemapState()
+	if err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+
+	respondRemapState(conn, req, m, m.SetRemapEnabled(!state.Enabled))
+}
+
+func handleRemapSetEnabled(conn *models.Conn, req models.Request, m *Manager) {
+	enabled, err := params.Bool(req.Params, "enabled")
+	if err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+
+	respondRemapState(conn, req, m, m.SetRemapEnabled(enabled))
+}
+
+func handleRemapSetConfig(conn *models.Conn, req models.Request, m *Manager) {
+	configParam, ok := models.Get[any](req, "config")
+	if !ok {
+		models.RespondError(conn, req.ID, "missing or invalid 'config' parameter")
+		return
+	}
+
+	data, err := json.Marshal(configParam)
+	if err != nil {
+		models.RespondError(conn, req.ID, "missing or invalid 'config' parameter")
+		return
+	}
+
+	var cfg RemapConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		models.RespondError(conn, req.ID, fmt.Sprintf("invalid remap config: %v", err))
+		return
+	}
+
+	respondRemapState(conn, req, m, m.SetRemapConfig(cfg))
+}
+
+func respondGestureState(conn *models.Conn, req models.Request, m *Manager, err error) {
+	if err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+
+	state, err := m.GetGestureState()
+	if err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+
+	models.Respond(conn, req.ID, state)
+}
+
+func handleGesturesSetEnabled(conn *models.Conn, req models.Request, m *Manager) {
+	enabled, err := params.Bool(req.Params, "enabled")
+	if err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+
+	respondGestureState(conn, req, m, m.SetGesturesEnabled(enabled))
+}
+
+func handleGesturesSetConfig(conn *models.Conn, req models.Request, m *Manager) {
+	configParam, ok := models.Get[any](req, "config")
+	if !ok {
+		models.RespondError(conn, req.ID, "missing or invalid 'config' parameter")
+		return
+	}
+
+	data, err := json.Marshal(configParam)
+	if err != nil {
+		models.RespondError(conn, req.ID, "missing or invalid 'config' parameter")
+		return
+	}
+
+	cfg := defaultGestureConfig()
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		models.RespondError(conn, req.ID, fmt.Sprintf("invalid gesture config: %v", err))
+		return
+	}
+
+	respondGestureState(conn, req, m, m.SetGestureConfig(cfg))
+}
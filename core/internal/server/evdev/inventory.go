@@ -0,0 +1,175 @@
+package evdev
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	evdev "github.com/holoplot/go-evdev"
+)
+
+const (
+	KindKeyboard    = "keyboard"
+	KindPointer     = "pointer"
+	KindTouchpad    = "touchpad"
+	KindTouchscreen = "touchscreen"
+	KindTablet      = "tablet"
+	KindSwitch      = "switch"
+)
+
+const (
+	DeviceAdded   = "added"
+	DeviceRemoved = "removed"
+)
+
+// capableDevice is implemented by *evdev.InputDevice; mocks that don't
+// implement it are inventoried by name only.
+type capableDevice interface {
+	InputID() (evdev.InputID, error)
+	CapableTypes() []evdev.EvType
+	CapableEvents(t evdev.EvType) []evdev.EvCode
+	Properties() []evdev.EvProp
+}
+
+type deviceCaps struct {
+	types  map[evdev.EvType]bool
+	codes  map[evdev.EvType]map[evdev.EvCode]bool
+	direct bool
+}
+
+func (c deviceCaps) has(t evdev.EvType, code evdev.EvCode) bool {
+	return c.codes[t][code]
+}
+
+func describeDevice(device EvdevDevice) InputDevice {
+	name, _ := device.Name()
+	info := InputDevice{
+		Path:         device.Path(),
+		Name:         name,
+		Kinds:        []string{},
+		Capabilities: []string{},
+	}
+
+	keyboard := isKeyboard(device)
+	cd, ok := device.(capableDevice)
+	if !ok {
+		if keyboard {
+			info.Kinds = append(info.Kinds, KindKeyboard)
+		}
+		return info
+	}
+
+	if id, err := cd.InputID(); err == nil {
+		info.Vendor = fmt.Sprintf("%04x", id.Vendor)
+		info.Product = fmt.Sprintf("%04x", id.Product)
+		info.Bus = busName(id.BusType)
+	}
+
+	caps := deviceCaps{
+		types: make(map[evdev.EvType]bool),
+		codes: make(map[evdev.EvType]map[evdev.EvCode]bool),
+	}
+	for _, t := range cd.CapableTypes() {
+		caps.types[t] = true
+		set := make(map[evdev.EvCode]bool)
+		for _, code := range cd.CapableEvents(t) {
+			set[code] = true
+		}
+		caps.codes[t] = set
+		if name, ok := evdev.EVToString[t]; ok && t != evdev.EV_SYN {
+			info.Capabilities = append(info.Capabilities, strings.ToLower(strings.TrimPrefix(name, "EV_")))
+		}
+	}
+	caps.direct = slices.Contains(cd.Properties(), evdev.INPUT_PROP_DIRECT)
+	sort.Strings(info.Capabilities)
+
+	info.Kinds = classify(caps, keyboard)
+	return info
+}
+
+func busName(bus uint16) string {
+	name, ok := evdev.BUSToString[evdev.EvCode(bus)]
+	if !ok {
+		return fmt.Sprintf("0x%02x", bus)
+	}
+	return strings.ToLower(strings.TrimPrefix(name, "BUS_"))
+}
+
+// classify follows the same capability rules udev's input_id builtin uses
+// for ID_INPUT_{KEYBOARD,MOUSE,TOUCHPAD,TOUCHSCREEN,TABLET,SWITCH}.
+func classify(caps deviceCaps, keyboard bool) []string {
+	kinds := []string{}
+	if keyboard {
+		kinds = append(kinds, KindKeyboard)
+	}
+
+	hasAbsXY := caps.has(evdev.EV_ABS, evdev.ABS_X) && caps.has(evdev.EV_ABS, evdev.ABS_Y)
+	hasRelXY := caps.has(evdev.EV_REL, evdev.REL_X) && caps.has(evdev.EV_REL, evdev.REL_Y)
+
+	switch {
+	case hasAbsXY && (caps.has(evdev.EV_KEY, evdev.BTN_TOOL_PEN) || caps.has(evdev.EV_KEY, evdev.BTN_STYLUS)):
+		kinds = append(kinds, KindTablet)
+	case hasAbsXY && caps.has(evdev.EV_KEY, evdev.BTN_TOOL_FINGER) && !caps.direct:
+		kinds = append(kinds, KindTouchpad)
+	case hasAbsXY && caps.has(evdev.EV_KEY, evdev.BTN_TOUCH) && caps.direct:
+		kinds = append(kinds, KindTouchscreen)
+	case hasRelXY && caps.has(evdev.EV_KEY, evdev.BTN_MOUSE):
+		kinds = append(kinds, KindPointer)
+	}
+
+	if caps.types[evdev.EV_SW] {
+		kinds = append(kinds, KindSwitch)
+	}
+	return kinds
+}
+
+func (d InputDevice) is(kind string) bool {
+	return slices.Contains(d.Kinds, kind)
+}
+
+// inventoryLocked returns the inventory sorted by path. Caller holds
+// devicesMutex.
+func (m *Manager) inventoryLocked() []InputDevice {
+	devices := make([]InputDevice, 0, len(m.inventory))
+	for _, dev := range m.inventory {
+		devices = append(devices, dev)
+	}
+	sort.Slice(devices, func(i, j int) bool { return devices[i].Path < devices[j].Path })
+	return devices
+}
+
+func (m *Manager) GetDevices() []InputDevice {
+	m.devicesMutex.RLock()
+	defer m.devicesMutex.RUnlock()
+	return m.inventoryLocked()
+}
+
+func (m *Manager) SubscribeDevices(id string) chan DeviceEvent {
+	ch := make(chan DeviceEvent, 16)
+	m.deviceSubscribers.Store(id, ch)
+	return ch
+}
+
+func (m *Manager) UnsubscribeDevices(id string) {
+	if val, ok := m.deviceSubscribers.LoadAndDelete(id); ok {
+		close(val)
+	}
+}
+
+func (m *Manager) publishDeviceEvent(event DeviceEvent) {
+	m.deviceSubscribers.Range(func(key string, ch chan DeviceEvent) bool {
+		select {
+		case ch <- event:
+		default:
+		}
+		return true
+	})
+
+	devices := m.GetDevices()
+	m.stateMutex.Lock()
+	m.state.Devices = devices
+	newState := m.state
+	m.stateMutex.Unlock()
+	m.notifySubscribers(newState)
+}
@@ -0,0 +1,214 @@
+package evdev
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	evdev "github.com/holoplot/go-evdev"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	mocks "github.com/AvengeMedia/DankMaterialShell/core/internal/mocks/evdev"
+)
+
+type fakeInputDevice struct {
+	name   string
+	path   string
+	id     evdev.InputID
+	events map[evdev.EvType][]evdev.EvCode
+	props  []evdev.EvProp
+	closed bool
+}
+
+func (d *fakeInputDevice) Name() (string, error)               { return d.name, nil }
+func (d *fakeInputDevice) Path() string                        { return d.path }
+func (d *fakeInputDevice) Close() error                        { d.closed = true; return nil }
+func (d *fakeInputDevice) ReadOne() (*evdev.InputEvent, error) { return nil, io.EOF }
+func (d *fakeInputDevice) InputID() (evdev.InputID, error)     { return d.id, nil }
+func (d *fakeInputDevice) Properties() []evdev.EvProp          { return d.props }
+
+func (d *fakeInputDevice) State(t evdev.EvType) (evdev.StateMap, error) {
+	state := evdev.StateMap{}
+	for _, code := range d.events[t] {
+		state[code] = false
+	}
+	return state, nil
+}
+
+func (d *fakeInputDevice) CapableTypes() []evdev.EvType {
+	types := make([]evdev.EvType, 0, len(d.events))
+	for t := range d.events {
+		types = append(types, t)
+	}
+	return types
+}
+
+func (d *fakeInputDevice) CapableEvents(t evdev.EvType) []evdev.EvCode {
+	return d.events[t]
+}
+
+func touchpadDevice(path string) *fakeInputDevice {
+	return &fakeInputDevice{
+		name: "SYNA8004:00 06CB:CD8B Touchpad",
+		path: path,
+		id:   evdev.InputID{BusType: evdev.BUS_I2C, Vendor: 0x06cb, Product: 0xcd8b},
+		events: map[evdev.EvType][]evdev.EvCode{
+			evdev.EV_SYN: {0},
+			evdev.EV_KEY: {evdev.BTN_LEFT, evdev.BTN_TOOL_FINGER, evdev.BTN_TOUCH},
+			evdev.EV_ABS: {evdev.ABS_X, evdev.ABS_Y},
+		},
+		props: []evdev.EvProp{evdev.INPUT_PROP_POINTER},
+	}
+}
+
+func TestDescribeDevice_Classification(t *testing.T) {
+	tests := []struct {
+		name   string
+		device *fakeInputDevice
+		kinds  []string
+	}{
+		{"touchpad", touchpadDevice("/dev/input/event5"), []string{KindTouchpad}},
+		{"mouse", &fakeInputDevice{
+			name: "Logitech G502",
+			events: map[evdev.EvType][]evdev.EvCode{
+				evdev.EV_KEY: {evdev.BTN_LEFT, evdev.BTN_RIGHT},
+				evdev.EV_REL: {evdev.REL_X, evdev.REL_Y, evdev.REL_WHEEL},
+			},
+		}, []string{KindPointer}},
+		{"tablet", &fakeInputDevice{
+			name: "Wacom Intuos Pen",
+			events: map[evdev.EvType][]evdev.EvCode{
+				evdev.EV_KEY: {evdev.BTN_TOOL_PEN, evdev.BTN_STYLUS, evdev.BTN_TOUCH},
+				evdev.EV_ABS: {evdev.ABS_X, evdev.ABS_Y, evdev.ABS_PRESSURE},
+			},
+		}, []string{KindTablet}},
+		{"touchscreen", &fakeInputDevice{
+			name: "ELAN Touchscreen",
+			events: map[evdev.EvType][]evdev.EvCode{
+				evdev.EV_KEY: {evdev.BTN_TOUCH},
+				evdev.EV_ABS: {evdev.ABS_X, evdev.ABS_Y},
+			},
+			props: []evdev.EvProp{evdev.INPUT_PROP_DIRECT},
+		}, []string{KindTouchscreen}},
+		{"lid switch", &fakeInputDevice{
+			name:   "Lid Switch",
+			events: map[evdev.EvType][]evdev.EvCode{evdev.EV_SW: {evdev.SW_LID}},
+		}, []string{KindSwitch}},
+		{"keyboard", &fakeInputDevice{
+			name:   "AT Translated Set 2 keyboard",
+			events: map[evdev.EvType][]evdev.EvCode{evdev.EV_KEY: {30, 31}, evdev.EV_LED: {0, 1, 2}},
+		}, []string{KindKeyboard}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := describeDevice(tt.device)
+			assert.Equal(t, tt.kinds, info.Kinds)
+		})
+	}
+
+	info := describeDevice(touchpadDevice("/dev/input/event5"))
+	assert.Equal(t, "06cb", info.Vendor)
+	assert.Equal(t, "cd8b", info.Product)
+	assert.Equal(t, "i2c", info.Bus)
+	assert.Equal(t, []string{"abs", "key"}, info.Capabilities)
+}
+
+func TestDescribeDevice_MockWithoutCapabilities(t *testing.T) {
+	mockDevice := mocks.NewMockEvdevDevice(t)
+	mockDevice.EXPECT().Name().Return("USB kbd", nil)
+	mockDevice.EXPECT().Path().Return("/dev/input/event3")
+
+	info := describeDevice(mockDevice)
+	assert.Equal(t, "/dev/input/event3", info.Path)
+	assert.Equal(t, []string{KindKeyboard}, info.Kinds)
+}
+
+func TestManager_Hotplug(t *testing.T) {
+	touchpad := touchpadDevice("/dev/input/event9")
+	oldOpen := openDevice
+	openDevice = func(path string) (EvdevDevice, error) {
+		if path != touchpad.path {
+			return nil, errors.New("no such device")
+		}
+		return touchpad, nil
+	}
+	t.Cleanup(func() { openDevice = oldOpen })
+
+	m := &Manager{
+		monitoredPaths: make(map[string]bool),
+		state:          State{Available: true},
+		closeChan:      make(chan struct{}),
+	}
+	events := m.SubscribeDevices("test")
+	states := m.Subscribe("test")
+
+	m.addDevice("/dev/input/event7")
+	assert.Empty(t, m.GetDevices())
+
+	m.addDevice(touchpad.path)
+	event := <-events
+	assert.Equal(t, DeviceAdded, event.Type)
+	assert.Equal(t, []string{KindTouchpad}, event.Device.Kinds)
+	assert.True(t, touchpad.closed, "non-keyboards are inventoried, not monitored")
+
+	state := <-states
+	require.Len(t, state.Devices, 1)
+	assert.Equal(t, touchpad.path, state.Devices[0].Path)
+
+	m.addDevice(touchpad.path)
+	select {
+	case <-events:
+		t.Fatal("duplicate create must not emit an event")
+	default:
+	}
+
+	m.removeDevice(touchpad.path)
+	event = <-events
+	assert.Equal(t, DeviceRemoved, event.Type)
+	assert.Equal(t, touchpad.path, event.Device.Path)
+	assert.Empty(t, m.GetDevices())
+	assert.Empty(t, (<-states).Devices)
+
+	m.Close()
+	_, ok := <-events
+	assert.False(t, ok)
+}
+
+func TestManager_LockLeds(t *testing.T) {
+	mockDevice := mocks.NewMockEvdevDevice(t)
+	mockDevice.EXPECT().State(evdev.EvType(evLedType)).Return(evdev.StateMap{
+		ledNumlockKey:    true,
+		ledCapslockKey:   false,
+		ledScrolllockKey: true,
+	}, nil).Once()
+
+	m := &Manager{
+		devices:   []EvdevDevice{mockDevice},
+		state:     State{Available: true},
+		closeChan: make(chan struct{}),
+	}
+	ch := m.Subscribe("test")
+
+	m.readAndUpdateLockState(0)
+	state := <-ch
+	assert.True(t, state.NumLock)
+	assert.False(t, state.CapsLock)
+	assert.True(t, state.ScrollLock)
+
+	m.updateLedDirect(ledNumlockKey, false)
+	state = <-ch
+	assert.False(t, state.NumLock)
+	assert.True(t, state.ScrollLock)
+
+	m.updateLedDirect(ledNumlockKey, false)
+	select {
+	case <-ch:
+		t.Fatal("unchanged LED must not notify")
+	default:
+	}
+
+	assert.True(t, isLockKey(keyNumlockKey))
+	assert.False(t, isLockKey(30))
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -15,13 +16,28 @@ import (
 )
 
 const (
-	evKeyType      = 0x01
-	evLedType      = 0x11
-	keyCapslockKey = 58
-	ledCapslockKey = 1
-	keyStateOn     = 1
+	evKeyType        = 0x01
+	evRelType        = 0x02
+	evAbsType        = 0x03
+	evLedType        = 0x11
+	keyCapslockKey   = 58
+	keyNumlockKey    = 69
+	keyScrolllockKey = 70
+	ledNumlockKey    = 0
+	ledCapslockKey   = 1
+	ledScrolllockKey = 2
+	keyStateOn       = 1
 )
 
+// openDevice is swapped out in tests to simulate hotplug.
+var openDevice = func(path string) (EvdevDevice, error) {
+	device, err := evdev.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return device, nil
+}
+
 type EvdevDevice interface {
 	Name() (string, error)
 	Path() string
@@ -34,21 +50,31 @@ type Manager struct {
 	devices        []EvdevDevice
 	devicesMutex   sync.RWMutex
 	monitoredPaths map[string]bool
+	inventory      map[string]InputDevice
+	switchStates   map[string]map[string]bool
 	state          State
 	stateMutex     sync.RWMutex
 	subscribers    syncmap.Map[string, chan State]
+	activitySubs   syncmap.Map[string, chan struct{}]
+	pointers       map[string]EvdevDevice
+	pointerWanted  map[string]bool
 	closeChan      chan struct{}
 	closeOnce      sync.Once
 	watcher        *fsnotify.Watcher
+
+	deviceSubscribers syncmap.Map[string, chan DeviceEvent]
+
+	remap    *remapper
+	gestures *gesturer
 }
 
 func NewManager() (*Manager, error) {
-	devices, err := findKeyboards()
+	devices, inventory, err := scanInputDevices()
 	if err != nil {
-		return nil, fmt.Errorf("failed to find keyboards: %w", err)
+		return nil, err
 	}
 
-	initialCapsLock, _ := capsLockFromDevices(devices)
+	locks, _ := lockStateFromDevices(devices)
 
 	watcher, err := fsnotify.NewWatcher()
 	if err != nil {
@@ -61,31 +87,50 @@ func NewManager() (*Manager, error) {
 	}
 
 	monitoredPaths := make(map[string]bool)
+	switchStates := make(map[string]map[string]bool)
 	for _, device := range devices {
 		monitoredPaths[device.Path()] = true
+		if inventory[device.Path()].is(KindSwitch) {
+			switchStates[device.Path()] = readSwitches(device)
+		}
 	}
 
 	m := &Manager{
 		devices:        devices,
 		monitoredPaths: monitoredPaths,
-		state:          State{Available: true, CapsLock: initialCapsLock},
+		inventory:      inventory,
+		switchStates:   switchStates,
 
 		closeChan: make(chan struct{}),
 		watcher:   watcher,
 	}
+	m.state = State{
+		Available:  true,
+		CapsLock:   locks.caps,
+		NumLock:    locks.num,
+		ScrollLock: locks.scroll,
+		Devices:    m.inventoryLocked(),
+		Switches:   m.switchesLocked(),
+	}
 
 	for i, device := range devices {
 		go m.monitorDevice(device, i)
 	}
 
 	if watcher != nil {
-		go m.watchForNewKeyboards()
+		go m.watchForDevices()
 	}
 
+	m.remap = newRemapper(m.GetDevices)
+	m.remap.start()
+
+	m.gestures = newGesturer(m.GetDevices)
+	m.gestures.start()
+
 	return m, nil
 }
 
-func armNonBlocking(device EvdevDevice) {
+func armNonBlocking(device interface{ Path() string }) {
 	nb, ok := device.(interface{ NonBlock() error })
 	if !ok {
 		return
@@ -95,7 +140,13 @@ func armNonBlocking(device EvdevDevice) {
 	}
 }
 
-func capsLockFromDevices(devices []EvdevDevice) (bool, bool) {
+type lockState struct {
+	caps   bool
+	num    bool
+	scroll bool
+}
+
+func lockStateFromDevices(devices []EvdevDevice) (lockState, bool) {
 	for _, device := range devices {
 		if device == nil {
 			continue
@@ -107,46 +158,60 @@ func capsLockFromDevices(devices []EvdevDevice) (bool, bool) {
 			continue
 		}
 
-		return ledStates[ledCapslockKey], true
+		return lockState{
+			caps:   ledStates[ledCapslockKey],
+			num:    ledStates[ledNumlockKey],
+			scroll: ledStates[ledScrolllockKey],
+		}, true
 	}
 
-	return false, false
+	return lockState{}, false
+}
+
+func capsLockFromDevices(devices []EvdevDevice) (bool, bool) {
+	locks, ok := lockStateFromDevices(devices)
+	return locks.caps, ok
 }
 
-func findKeyboards() ([]EvdevDevice, error) {
+// scanInputDevices inventories every event node and keeps the monitored
+// ones open.
+func scanInputDevices() ([]EvdevDevice, map[string]InputDevice, error) {
 	pattern := "/dev/input/event*"
 	matches, err := filepath.Glob(pattern)
 	if err != nil {
-		return nil, fmt.Errorf("failed to glob input devices: %w", err)
+		return nil, nil, fmt.Errorf("failed to glob input devices: %w", err)
 	}
 
 	if len(matches) == 0 {
-		return nil, fmt.Errorf("no input devices found")
+		return nil, nil, fmt.Errorf("no input devices found")
 	}
 
-	var keyboards []EvdevDevice
+	var monitored []EvdevDevice
+	inventory := make(map[string]InputDevice)
 	for _, path := range matches {
-		device, err := evdev.Open(path)
+		device, err := openDevice(path)
 		if err != nil {
 			continue
 		}
 
-		if !isKeyboard(device) {
+		info := describeDevice(device)
+		inventory[path] = info
+
+		if !info.monitored() {
 			device.Close()
 			continue
 		}
 
-		deviceName, _ := device.Name()
 		armNonBlocking(device)
-		log.Debugf("Found keyboard: %s at %s", deviceName, path)
-		keyboards = append(keyboards, device)
+		log.Debugf("Found %v: %s at %s", info.Kinds, info.Name, path)
+		monitored = append(monitored, device)
 	}
 
-	if len(keyboards) == 0 {
-		return nil, fmt.Errorf("no keyboard device found")
+	if len(inventory) == 0 {
+		return nil, nil, fmt.Errorf("no accessible input devices found")
 	}
 
-	return keyboards, nil
+	return monitored, inventory, nil
 }
 
 func isKeyboard(device EvdevDevice) bool {
@@ -178,10 +243,10 @@ func isKeyboard(device EvdevDevice) bool {
 	return hasKeyA && hasKeyZ && hasEnter && len(keyStates) > 100
 }
 
-func (m *Manager) watchForNewKeyboards() {
+func (m *Manager) watchForDevices() {
 	defer func() {
 		if r := recover(); r != nil {
-			log.Errorf("Panic in keyboard hotplug monitor: %v", r)
+			log.Errorf("Panic in input hotplug monitor: %v", r)
 		}
 	}()
 
@@ -198,62 +263,105 @@ func (m *Manager) watchForNewKeyboards() {
 				continue
 			}
 
-			if event.Op&fsnotify.Create == fsnotify.Create {
+			switch {
+			case event.Op&fsnotify.Create == fsnotify.Create:
 				time.Sleep(100 * time.Millisecond)
-
-				m.devicesMutex.Lock()
-				if m.monitoredPaths[event.Name] {
-					m.devicesMutex.Unlock()
-					continue
-				}
-
-				device, err := evdev.Open(event.Name)
-				if err != nil {
-					m.devicesMutex.Unlock()
-					continue
-				}
-
-				if !isKeyboard(device) {
-					device.Close()
-					m.devicesMutex.Unlock()
-					continue
-				}
-
-				deviceName, _ := device.Name()
-				armNonBlocking(device)
-				log.Debugf("Hotplugged keyboard: %s at %s", deviceName, event.Name)
-
-				m.devices = append(m.devices, device)
-				m.monitoredPaths[event.Name] = true
-				deviceIndex := len(m.devices) - 1
-				m.devicesMutex.Unlock()
-
-				go m.monitorDevice(device, deviceIndex)
-			} else if event.Op&fsnotify.Remove == fsnotify.Remove {
-				m.devicesMutex.Lock()
-				if !m.monitoredPaths[event.Name] {
-					m.devicesMutex.Unlock()
-					continue
-				}
-
-				delete(m.monitoredPaths, event.Name)
-				for i, device := range m.devices {
-					if device != nil && device.Path() == event.Name {
-						log.Debugf("Keyboard removed: %s", event.Name)
-						device.Close()
-						m.devices[i] = nil
-						break
-					}
-				}
-				m.devicesMutex.Unlock()
+				m.addDevice(event.Name)
+			case event.Op&fsnotify.Remove == fsnotify.Remove:
+				m.removeDevice(event.Name)
 			}
 
 		case err, ok := <-m.watcher.Errors:
 			if !ok {
 				return
 			}
-			log.Warnf("Keyboard hotplug watcher error: %v", err)
+			log.Warnf("Input hotplug watcher error: %v", err)
+		}
+	}
+}
+
+func (m *Manager) addDevice(path string) {
+	m.devicesMutex.Lock()
+	if _, known := m.inventory[path]; known || m.monitoredPaths[path] {
+		m.devicesMutex.Unlock()
+		return
+	}
+
+	device, err := openDevice(path)
+	if err != nil {
+		m.devicesMutex.Unlock()
+		return
+	}
+
+	info := describeDevice(device)
+	if m.inventory == nil {
+		m.inventory = make(map[string]InputDevice)
+	}
+	m.inventory[path] = info
+
+	deviceIndex := -1
+	if info.pointing() && m.pointersWantedLocked() {
+		log.Debugf("Hotplugged %v: %s at %s", info.Kinds, info.Name, path)
+		m.trackPointerLocked(path, device)
+	} else if info.monitored() {
+		armNonBlocking(device)
+		log.Debugf("Hotplugged %v: %s at %s", info.Kinds, info.Name, path)
+		m.devices = append(m.devices, device)
+		m.monitoredPaths[path] = true
+		deviceIndex = len(m.devices) - 1
+	} else {
+		log.Debugf("Hotplugged input device: %s at %s (%v)", info.Name, path, info.Kinds)
+		device.Close()
+	}
+	m.devicesMutex.Unlock()
+
+	if deviceIndex >= 0 {
+		if info.is(KindSwitch) {
+			m.setDeviceSwitches(path, readSwitches(device))
+		}
+		go m.monitorDevice(device, deviceIndex)
+	}
+	m.publishDeviceEvent(DeviceEvent{Type: DeviceAdded, Device: info})
+
+	if m.remap != nil {
+		m.remap.deviceAdded(info)
+	}
+	if m.gestures != nil {
+		m.gestures.deviceAdded(info)
+	}
+}
+
+func (m *Manager) removeDevice(path string) {
+	m.devicesMutex.Lock()
+	info, known := m.inventory[path]
+	delete(m.inventory, path)
+	m.closePointerLocked(path)
+
+	if m.monitoredPaths[path] {
+		delete(m.monitoredPaths, path)
+		for i, device := range m.devices {
+			if device != nil && device.Path() == path {
+				log.Debugf("Input device removed: %s", path)
+				device.Close()
+				m.devices[i] = nil
+				break
+			}
+		}
+	}
+	m.devicesMutex.Unlock()
+
+	if m.remap != nil {
+		m.remap.deviceRemoved(path)
+	}
+	if m.gestures != nil {
+		m.gestures.deviceRemoved(path)
+	}
+
+	if known {
+		if info.is(KindSwitch) {
+			m.setDeviceSwitches(path, nil)
 		}
+		m.publishDeviceEvent(DeviceEvent{Type: DeviceRemoved, Device: info})
 	}
 }
 
@@ -285,12 +393,22 @@ func (m *Manager) monitorDevice(device EvdevDevice, deviceIndex int) {
 			continue
 		}
 
-		if event.Type == evKeyType && event.Code == keyCapslockKey && event.Value == keyStateOn {
+		switch event.Type {
+		case evKeyType, evRelType, evAbsType:
+			m.notifyActivity()
+		}
+
+		switch {
+		case event.Type == evKeyType && event.Value == keyStateOn && isLockKey(uint16(event.Code)):
 			time.Sleep(50 * time.Millisecond)
-			m.readAndUpdateCapsLockState(deviceIndex)
-		} else if event.Type == evLedType && event.Code == ledCapslockKey {
-			capsLockState := event.Value == keyStateOn
-			m.updateCapsLockStateDirect(capsLockState)
+			m.readAndUpdateLockState(deviceIndex)
+		case event.Type == evLedType:
+			m.updateLedDirect(uint16(event.Code), event.Value == keyStateOn)
+			if m.remap != nil && m.isRemapSink(device.Path()) {
+				m.remap.forwardLed(uint16(event.Code), event.Value)
+			}
+		case event.Type == evSwType:
+			m.updateSwitch(device.Path(), uint16(event.Code), event.Value != 0)
 		}
 	}
 }
@@ -311,7 +429,23 @@ func isClosedError(err error) bool {
 	}
 }
 
-func (m *Manager) readAndUpdateCapsLockState(deviceIndex int) {
+func isLockKey(code uint16) bool {
+	switch code {
+	case keyCapslockKey, keyNumlockKey, keyScrolllockKey:
+		return true
+	}
+	return false
+}
+
+func (m *Manager) isRemapSink(path string) bool {
+	m.devicesMutex.RLock()
+	defer m.devicesMutex.RUnlock()
+	return m.inventory[path].Name == remapDeviceName
+}
+
+func (m *Manager) readAndUpdateLockState(deviceIndex int) {
+	grabbing := m.remap != nil && m.remap.grabbing()
+
 	m.devicesMutex.RLock()
 	if deviceIndex >= len(m.devices) {
 		m.devicesMutex.RUnlock()
@@ -325,29 +459,58 @@ func (m *Manager) readAndUpdateCapsLockState(deviceIndex int) {
 		}
 		ordered = append(ordered, device)
 	}
+	// Grabbed keyboards only learn LED changes once forwarded, so the
+	// virtual keyboard is the authority while remapping.
+	if grabbing {
+		sink := slices.IndexFunc(ordered, func(d EvdevDevice) bool {
+			return d != nil && m.inventory[d.Path()].Name == remapDeviceName
+		})
+		if sink > 0 {
+			ordered[0], ordered[sink] = ordered[sink], ordered[0]
+		}
+	}
 	m.devicesMutex.RUnlock()
 
-	capsLockState, ok := capsLockFromDevices(ordered)
+	locks, ok := lockStateFromDevices(ordered)
 	if !ok {
-		log.Debug("No LED-capable device available for caps lock state")
+		log.Debug("No LED-capable device available for lock key state")
 		return
 	}
 
-	m.updateCapsLockStateDirect(capsLockState)
+	m.updateLockState(func(s *State) {
+		s.CapsLock = locks.caps
+		s.NumLock = locks.num
+		s.ScrollLock = locks.scroll
+	})
+}
+
+func (m *Manager) updateLedDirect(code uint16, on bool) {
+	switch code {
+	case ledCapslockKey:
+		m.updateCapsLockStateDirect(on)
+	case ledNumlockKey:
+		m.updateLockState(func(s *State) { s.NumLock = on })
+	case ledScrolllockKey:
+		m.updateLockState(func(s *State) { s.ScrollLock = on })
+	}
 }
 
 func (m *Manager) updateCapsLockStateDirect(capsLockState bool) {
+	m.updateLockState(func(s *State) { s.CapsLock = capsLockState })
+}
+
+func (m *Manager) updateLockState(apply func(*State)) {
 	m.stateMutex.Lock()
-	if m.state.CapsLock == capsLockState {
+	old := m.state
+	apply(&m.state)
+	if old.CapsLock == m.state.CapsLock && old.NumLock == m.state.NumLock && old.ScrollLock == m.state.ScrollLock {
 		m.stateMutex.Unlock()
 		return
 	}
-
-	m.state.CapsLock = capsLockState
 	newState := m.state
 	m.stateMutex.Unlock()
 
-	log.Debugf("Caps lock state: %v", newState.CapsLock)
+	log.Debugf("Lock state: caps=%v num=%v scroll=%v", newState.CapsLock, newState.NumLock, newState.ScrollLock)
 	m.notifySubscribers(newState)
 }
 
@@ -369,6 +532,36 @@ func (m *Manager) Unsubscribe(id string) {
 	}
 }
 
+// SubscribeActivity delivers a coalesced tick for key input, and for pointer
+// and touch input once WantPointerActivity is set for id; consumers only
+// care that the user is present, not about individual events.
+func (m *Manager) SubscribeActivity(id string) chan struct{} {
+	ch := make(chan struct{}, 1)
+	m.activitySubs.Store(id, ch)
+
+	m.devicesMutex.Lock()
+	m.syncPointersLocked()
+	m.devicesMutex.Unlock()
+	return ch
+}
+
+func (m *Manager) UnsubscribeActivity(id string) {
+	if val, ok := m.activitySubs.LoadAndDelete(id); ok {
+		close(val)
+	}
+	m.WantPointerActivity(id, false)
+}
+
+func (m *Manager) notifyActivity() {
+	m.activitySubs.Range(func(key string, ch chan struct{}) bool {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+		return true
+	})
+}
+
 func (m *Manager) notifySubscribers(state State) {
 	m.subscribers.Range(func(key string, ch chan State) bool {
 		select {
@@ -383,6 +576,13 @@ func (m *Manager) Close() {
 	m.closeOnce.Do(func() {
 		close(m.closeChan)
 
+		if m.remap != nil {
+			m.remap.stop()
+		}
+		if m.gestures != nil {
+			m.gestures.stop()
+		}
+
 		if m.watcher != nil {
 			m.watcher.Close()
 		}
@@ -396,6 +596,9 @@ func (m *Manager) Close() {
 				log.Warnf("Error closing evdev device: %v", err)
 			}
 		}
+		for path := range m.pointers {
+			m.closePointerLocked(path)
+		}
 		m.devicesMutex.Unlock()
 
 		m.subscribers.Range(func(key string, ch chan State) bool {
@@ -403,6 +606,16 @@ func (m *Manager) Close() {
 			m.subscribers.Delete(key)
 			return true
 		})
+		m.activitySubs.Range(func(key string, ch chan struct{}) bool {
+			close(ch)
+			m.activitySubs.Delete(key)
+			return true
+		})
+		m.deviceSubscribers.Range(func(key string, ch chan DeviceEvent) bool {
+			close(ch)
+			m.deviceSubscribers.Delete(key)
+			return true
+		})
 	})
 }
 
@@ -131,7 +131,7 @@ func TestManager_UpdateCapsLock(t *testing.T) {
 	mockDevice.EXPECT().State(evdev.EvType(evLedType)).Return(ledStateOn, nil).Once()
 
 	go func() {
-		m.readAndUpdateCapsLockState(0)
+		m.readAndUpdateLockState(0)
 	}()
 
 	newState := <-ch
@@ -141,7 +141,7 @@ func TestManager_UpdateCapsLock(t *testing.T) {
 	mockDevice.EXPECT().State(evdev.EvType(evLedType)).Return(ledStateOff, nil).Once()
 
 	go func() {
-		m.readAndUpdateCapsLockState(0)
+		m.readAndUpdateLockState(0)
 	}()
 
 	newState = <-ch
@@ -259,6 +259,36 @@ func TestManager_MonitorDevice(t *testing.T) {
 	})
 }
 
+func TestManager_ActivitySubscribers(t *testing.T) {
+	mockDevice := mocks.NewMockEvdevDevice(t)
+
+	keyEvent := &evdev.InputEvent{Type: evKeyType, Code: 30, Value: keyStateOn}
+	mockDevice.EXPECT().ReadOne().Return(keyEvent, nil).Times(3)
+	mockDevice.EXPECT().ReadOne().Return(nil, errors.New("device closed")).Maybe()
+	mockDevice.EXPECT().Close().Return(nil).Maybe()
+
+	m := &Manager{
+		devices:   []EvdevDevice{mockDevice},
+		state:     State{Available: true},
+		closeChan: make(chan struct{}),
+	}
+
+	ch := m.SubscribeActivity("test")
+	m.monitorDevice(mockDevice, 0)
+
+	_, ok := <-ch
+	assert.True(t, ok)
+	select {
+	case <-ch:
+		t.Fatal("activity ticks should be coalesced")
+	default:
+	}
+
+	m.Close()
+	_, ok = <-ch
+	assert.False(t, ok)
+}
+
 func TestIsClosedError(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -360,3 +390,27 @@ func TestHasInputGroupAccess(t *testing.T) {
 	result := hasInputGroupAccess()
 	t.Logf("hasInputGroupAccess: %v", result)
 }
+
+func TestManager_LockStatePrefersRemapSink(t *testing.T) {
+	physical := mocks.NewMockEvdevDevice(t)
+	physical.EXPECT().Path().Return("/dev/input/event3").Maybe()
+	physical.EXPECT().State(evdev.EvType(evLedType)).Return(evdev.StateMap{ledCapslockKey: false}, nil).Maybe()
+
+	sink := mocks.NewMockEvdevDevice(t)
+	sink.EXPECT().Path().Return("/dev/input/event20").Maybe()
+	sink.EXPECT().State(evdev.EvType(evLedType)).Return(evdev.StateMap{ledCapslockKey: true}, nil).Once()
+
+	r := newRemapper(nil)
+	r.sessions["/dev/input/event3"] = &remapSession{}
+	m := &Manager{
+		devices: []EvdevDevice{physical, sink},
+		inventory: map[string]InputDevice{
+			"/dev/input/event3":  {Name: "AT Translated Set 2 keyboard"},
+			"/dev/input/event20": {Name: remapDeviceName},
+		},
+		remap: r,
+	}
+
+	m.readAndUpdateLockState(0)
+	assert.True(t, m.GetState().CapsLock, "the grabbed keyboard's stale LEDs are ignored")
+}
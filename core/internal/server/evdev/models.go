@@ -1,6 +1,29 @@
 package evdev
 
 type State struct {
-	Available bool `json:"available"`
-	CapsLock  bool `json:"capsLock"`
+	Available  bool          `json:"available"`
+	CapsLock   bool          `json:"capsLock"`
+	NumLock    bool          `json:"numLock"`
+	ScrollLock bool          `json:"scrollLock"`
+	Devices    []InputDevice `json:"devices"`
+	// Switches holds lid/tabletMode/headphoneInsert/microphoneInsert/dock
+	// for switches present on this machine.
+	Switches map[string]bool `json:"switches"`
+}
+
+// InputDevice is an inventory entry for any /dev/input/event* node, not
+// only the keyboards the manager monitors.
+type InputDevice struct {
+	Path         string   `json:"path"`
+	Name         string   `json:"name"`
+	Vendor       string   `json:"vendor,omitempty"`
+	Product      string   `json:"product,omitempty"`
+	Bus          string   `json:"bus,omitempty"`
+	Kinds        []string `json:"kinds"`
+	Capabilities []string `json:"capabilities"`
+}
+
+type DeviceEvent struct {
+	Type   string      `json:"type"`
+	Device InputDevice `json:"device"`
 }
@@ -0,0 +1,117 @@
+package evdev
+
+import (
+	"time"
+
+	"github.com/AvengeMedia/DankMaterialShell/core/internal/log"
+)
+
+// pointing reports whether the device only matters as a source of activity.
+// Pointing devices stay closed unless a consumer asks for them, so
+// mouse-rate events don't flow through the server for nothing.
+func (d InputDevice) pointing() bool {
+	return !d.monitored() && (d.is(KindPointer) || d.is(KindTouchpad) || d.is(KindTouchscreen))
+}
+
+// WantPointerActivity makes pointer and touch input count as activity for
+// the activity subscriber id. Keyboard input always counts.
+func (m *Manager) WantPointerActivity(id string, want bool) {
+	m.devicesMutex.Lock()
+	defer m.devicesMutex.Unlock()
+
+	if want {
+		if m.pointerWanted == nil {
+			m.pointerWanted = make(map[string]bool)
+		}
+		m.pointerWanted[id] = true
+	} else {
+		delete(m.pointerWanted, id)
+	}
+	m.syncPointersLocked()
+}
+
+// pointersWantedLocked reports whether an activity subscriber wants pointer
+// input. Caller holds devicesMutex.
+func (m *Manager) pointersWantedLocked() bool {
+	for id := range m.pointerWanted {
+		if _, ok := m.activitySubs.Load(id); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// syncPointersLocked opens or closes the pointing devices to match demand.
+// Caller holds devicesMutex.
+func (m *Manager) syncPointersLocked() {
+	if !m.pointersWantedLocked() {
+		for path := range m.pointers {
+			m.closePointerLocked(path)
+		}
+		return
+	}
+	for path, info := range m.inventory {
+		if info.pointing() {
+			m.openPointerLocked(path)
+		}
+	}
+}
+
+func (m *Manager) openPointerLocked(path string) {
+	if _, open := m.pointers[path]; open {
+		return
+	}
+	device, err := openDevice(path)
+	if err != nil {
+		log.Debugf("evdev: open %s for activity: %v", path, err)
+		return
+	}
+	m.trackPointerLocked(path, device)
+}
+
+func (m *Manager) trackPointerLocked(path string, device EvdevDevice) {
+	armNonBlocking(device)
+	if m.pointers == nil {
+		m.pointers = make(map[string]EvdevDevice)
+	}
+	m.pointers[path] = device
+	go m.monitorPointer(device)
+}
+
+func (m *Manager) closePointerLocked(path string) {
+	device, open := m.pointers[path]
+	if !open {
+		return
+	}
+	delete(m.pointers, path)
+	if err := device.Close(); err != nil && !isClosedError(err) {
+		log.Warnf("Error closing evdev device: %v", err)
+	}
+}
+
+// monitorPointer turns motion, touch and button events into activity until
+// the device is closed.
+func (m *Manager) monitorPointer(device EvdevDevice) {
+	for {
+		event, err := device.ReadOne()
+		if err != nil {
+			if isClosedError(err) {
+				return
+			}
+			select {
+			case <-m.closeChan:
+				return
+			case <-time.After(100 * time.Millisecond):
+			}
+			continue
+		}
+
+		if event == nil {
+			continue
+		}
+		switch event.Type {
+		case evKeyType, evRelType, evAbsType:
+			m.notifyActivity()
+		}
+	}
+}
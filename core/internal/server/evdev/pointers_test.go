@@ -0,0 +1,60 @@
+package evdev
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	evdev "github.com/holoplot/go-evdev"
+	"github.com/stretchr/testify/assert"
+
+	mocks "github.com/AvengeMedia/DankMaterialShell/core/internal/mocks/evdev"
+)
+
+func TestManager_PointerActivityGated(t *testing.T) {
+	const path = "/dev/input/event5"
+	assert.False(t, InputDevice{Kinds: []string{KindTouchpad}}.monitored())
+	assert.True(t, InputDevice{Kinds: []string{KindTouchpad}}.pointing())
+
+	mockDevice := mocks.NewMockEvdevDevice(t)
+	motion := &evdev.InputEvent{Type: evAbsType, Code: evdev.ABS_X, Value: 3}
+	mockDevice.EXPECT().ReadOne().Return(motion, nil).Once()
+	mockDevice.EXPECT().ReadOne().Return(nil, errors.New("device closed")).Maybe()
+	mockDevice.EXPECT().Close().Return(nil).Times(2)
+
+	opens := 0
+	oldOpen := openDevice
+	openDevice = func(string) (EvdevDevice, error) {
+		opens++
+		return mockDevice, nil
+	}
+	t.Cleanup(func() { openDevice = oldOpen })
+
+	m := &Manager{
+		inventory: map[string]InputDevice{path: describeDevice(touchpadDevice(path))},
+		closeChan: make(chan struct{}),
+	}
+
+	m.WantPointerActivity("kbd", true)
+	assert.Zero(t, opens, "no subscriber, no pointer traffic")
+
+	m.SubscribeActivity("other")
+	assert.Zero(t, opens, "only subscribers that want pointers open them")
+
+	ch := m.SubscribeActivity("kbd")
+	assert.Equal(t, 1, opens)
+
+	select {
+	case <-ch:
+	case <-time.After(time.Second):
+		t.Fatal("touchpad motion should count as activity")
+	}
+
+	m.WantPointerActivity("kbd", false)
+	assert.Empty(t, m.pointers)
+
+	m.WantPointerActivity("kbd", true)
+	m.UnsubscribeActivity("kbd")
+	assert.Equal(t, 2, opens)
+	assert.Empty(t, m.pointers)
+}
@@ -0,0 +1,439 @@
+package evdev
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	evdev "github.com/holoplot/go-evdev"
+)
+
+const (
+	remapDefaultTapTimeout = 200
+	remapDeviceName        = "DankMaterialShell Remapper"
+)
+
+// RemapConfig is persisted to remap.json and hot-reloaded on change.
+type RemapConfig struct {
+	Enabled bool `json:"enabled"`
+	// TapTimeout is how long, in ms, a dual-role key may be held and
+	// still count as a tap.
+	TapTimeout int         `json:"tapTimeout"`
+	Rules      []RemapRule `json:"rules"`
+}
+
+// RemapRule applies to the keyboards its Match selects; the first
+// matching rule wins, so device-specific rules go before catch-alls.
+type RemapRule struct {
+	Match    RemapMatch          `json:"match"`
+	Remap    map[string]string   `json:"remap,omitempty"`
+	DualRole map[string]DualRole `json:"dualRole,omitempty"`
+	Layers   []RemapLayer        `json:"layers,omitempty"`
+}
+
+// RemapMatch selects devices by case-insensitive name substring and/or
+// hex vendor/product ID. An empty match selects every keyboard.
+type RemapMatch struct {
+	Name    string `json:"name,omitempty"`
+	Vendor  string `json:"vendor,omitempty"`
+	Product string `json:"product,omitempty"`
+}
+
+type DualRole struct {
+	Tap  string `json:"tap"`
+	Hold string `json:"hold"`
+}
+
+// RemapLayer is active while Key is held, or flips on each press when
+// Toggle is set.
+type RemapLayer struct {
+	Name   string            `json:"name"`
+	Key    string            `json:"key"`
+	Toggle bool              `json:"toggle,omitempty"`
+	Remap  map[string]string `json:"remap"`
+}
+
+func defaultRemapConfig() RemapConfig {
+	return RemapConfig{TapTimeout: remapDefaultTapTimeout, Rules: []RemapRule{}}
+}
+
+func remapConfigPath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "DankMaterialShell", "remap.json"), nil
+}
+
+func loadRemapConfig() (RemapConfig, error) {
+	cfg := defaultRemapConfig()
+	path, err := remapConfigPath()
+	if err != nil {
+		return cfg, err
+	}
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return cfg, nil
+	case err != nil:
+		return cfg, err
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return defaultRemapConfig(), fmt.Errorf("invalid remap config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+func saveRemapConfig(cfg RemapConfig) error {
+	path, err := remapConfigPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+var keyAliases = map[string]string{
+	"CAPS":   "KEY_CAPSLOCK",
+	"ESCAPE": "KEY_ESC",
+	"CTRL":   "KEY_LEFTCTRL",
+	"SHIFT":  "KEY_LEFTSHIFT",
+	"ALT":    "KEY_LEFTALT",
+	"ALTGR":  "KEY_RIGHTALT",
+	"SUPER":  "KEY_LEFTMETA",
+	"META":   "KEY_LEFTMETA",
+	"RETURN": "KEY_ENTER",
+}
+
+// parseKeyName accepts kernel names (KEY_CAPSLOCK, BTN_SIDE), the same
+// without the KEY_ prefix in any case, and a few common aliases.
+func parseKeyName(name string) (uint16, error) {
+	upper := strings.ToUpper(strings.TrimSpace(name))
+	if alias, ok := keyAliases[upper]; ok {
+		upper = alias
+	}
+	if !strings.HasPrefix(upper, "KEY_") && !strings.HasPrefix(upper, "BTN_") {
+		upper = "KEY_" + upper
+	}
+	code, ok := evdev.KEYFromString[upper]
+	if !ok {
+		return 0, fmt.Errorf("unknown key: %s", name)
+	}
+	return uint16(code), nil
+}
+
+type dualRoleKeys struct {
+	tap  uint16
+	hold uint16
+}
+
+type remapLayer struct {
+	name   string
+	key    uint16
+	toggle bool
+	remap  map[uint16]uint16
+}
+
+type compiledRule struct {
+	match  RemapMatch
+	remap  map[uint16]uint16
+	dual   map[uint16]dualRoleKeys
+	layers []remapLayer
+}
+
+func compileKeyMap(m map[string]string) (map[uint16]uint16, error) {
+	out := make(map[uint16]uint16, len(m))
+	for from, to := range m {
+		fromCode, err := parseKeyName(from)
+		if err != nil {
+			return nil, err
+		}
+		toCode, err := parseKeyName(to)
+		if err != nil {
+			return nil, err
+		}
+		out[fromCode] = toCode
+	}
+	return out, nil
+}
+
+func compileRule(rule RemapRule) (*compiledRule, error) {
+	remap, err := compileKeyMap(rule.Remap)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &compiledRule{match: rule.Match, remap: remap, dual: make(map[uint16]dualRoleKeys)}
+	for key, role := range rule.DualRole {
+		code, err := parseKeyName(key)
+		if err != nil {
+			return nil, err
+		}
+		tap, err := parseKeyName(role.Tap)
+		if err != nil {
+			return nil, err
+		}
+		hold, err := parseKeyName(role.Hold)
+		if err != nil {
+			return nil, err
+		}
+		c.dual[code] = dualRoleKeys{tap: tap, hold: hold}
+	}
+
+	for _, layer := range rule.Layers {
+		key, err := parseKeyName(layer.Key)
+		if err != nil {
+			return nil, fmt.Errorf("layer %q: %w", layer.Name, err)
+		}
+		keys, err := compileKeyMap(layer.Remap)
+		if err != nil {
+			return nil, fmt.Errorf("layer %q: %w", layer.Name, err)
+		}
+		c.layers = append(c.layers, remapLayer{name: layer.Name, key: key, toggle: layer.Toggle, remap: keys})
+	}
+	return c, nil
+}
+
+func compileRemapConfig(cfg RemapConfig) ([]*compiledRule, error) {
+	if cfg.TapTimeout < 0 {
+		return nil, fmt.Errorf("tapTimeout must not be negative")
+	}
+	rules := make([]*compiledRule, 0, len(cfg.Rules))
+	for i, rule := range cfg.Rules {
+		c, err := compileRule(rule)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i, err)
+		}
+		rules = append(rules, c)
+	}
+	return rules, nil
+}
+
+func (m RemapMatch) matches(info InputDevice) bool {
+	if m.Name != "" && !strings.Contains(strings.ToLower(info.Name), strings.ToLower(m.Name)) {
+		return false
+	}
+	if m.Vendor != "" && !strings.EqualFold(m.Vendor, info.Vendor) {
+		return false
+	}
+	if m.Product != "" && !strings.EqualFold(m.Product, info.Product) {
+		return false
+	}
+	return true
+}
+
+// matchRule returns the index of the first rule for a keyboard, or -1.
+// The remapper's own virtual device is never matched.
+func matchRule(rules []*compiledRule, info InputDevice) int {
+	if !info.is(KindKeyboard) || info.Name == remapDeviceName {
+		return -1
+	}
+	for i, rule := range rules {
+		if rule.match.matches(info) {
+			return i
+		}
+	}
+	return -1
+}
+
+type keyEvent struct {
+	code  uint16
+	value int32
+}
+
+const (
+	keyRelease = 0
+	keyPress   = 1
+	keyRepeat  = 2
+)
+
+type pendingDual struct {
+	code  uint16
+	role  dualRoleKeys
+	since time.Time
+}
+
+// remapEngine turns one device's key events into output key events. It
+// holds no device or timer state so the whole pipeline can be driven from
+// tests with synthetic events and clock values.
+type remapEngine struct {
+	rule       *compiledRule
+	tapTimeout time.Duration
+
+	layers  []bool
+	pressed map[uint16]uint16
+	holding map[uint16]uint16
+	pending *pendingDual
+}
+
+func newRemapEngine(rule *compiledRule, tapTimeout time.Duration) *remapEngine {
+	return &remapEngine{
+		rule:       rule,
+		tapTimeout: tapTimeout,
+		layers:     make([]bool, len(rule.layers)),
+		pressed:    make(map[uint16]uint16),
+		holding:    make(map[uint16]uint16),
+	}
+}
+
+func (e *remapEngine) layerFor(code uint16) int {
+	for i, layer := range e.rule.layers {
+		if layer.key == code {
+			return i
+		}
+	}
+	return -1
+}
+
+// lookup resolves through active layers, most recently defined first,
+// then the base remap.
+func (e *remapEngine) lookup(code uint16) uint16 {
+	for i := len(e.rule.layers) - 1; i >= 0; i-- {
+		if !e.layers[i] {
+			continue
+		}
+		if out, ok := e.rule.layers[i].remap[code]; ok {
+			return out
+		}
+	}
+	if out, ok := e.rule.remap[code]; ok {
+		return out
+	}
+	return code
+}
+
+// resolvePending commits a pending dual-role key to its hold role.
+func (e *remapEngine) resolvePending() []keyEvent {
+	if e.pending == nil {
+		return nil
+	}
+	p := e.pending
+	e.pending = nil
+	e.holding[p.code] = p.role.hold
+	return []keyEvent{{p.role.hold, keyPress}}
+}
+
+func (e *remapEngine) Process(code uint16, value int32, now time.Time) []keyEvent {
+	if layer := e.layerFor(code); layer >= 0 {
+		var out []keyEvent
+		switch {
+		case value == keyPress:
+			out = e.resolvePending()
+			if e.rule.layers[layer].toggle {
+				e.layers[layer] = !e.layers[layer]
+			} else {
+				e.layers[layer] = true
+			}
+		case value == keyRelease && !e.rule.layers[layer].toggle:
+			e.layers[layer] = false
+		}
+		return out
+	}
+
+	if role, ok := e.rule.dual[code]; ok {
+		switch value {
+		case keyPress:
+			out := e.resolvePending()
+			e.pending = &pendingDual{code: code, role: role, since: now}
+			return out
+		case keyRelease:
+			if e.pending != nil && e.pending.code == code {
+				since := e.pending.since
+				e.pending = nil
+				if now.Sub(since) < e.tapTimeout {
+					return []keyEvent{{role.tap, keyPress}, {role.tap, keyRelease}}
+				}
+				return nil
+			}
+			if hold, ok := e.holding[code]; ok {
+				delete(e.holding, code)
+				return []keyEvent{{hold, keyRelease}}
+			}
+			return nil
+		default:
+			if hold, ok := e.holding[code]; ok {
+				return []keyEvent{{hold, keyRepeat}}
+			}
+			return nil
+		}
+	}
+
+	switch value {
+	case keyPress:
+		out := e.resolvePending()
+		mapped := e.lookup(code)
+		e.pressed[code] = mapped
+		return append(out, keyEvent{mapped, keyPress})
+	case keyRelease:
+		// Release what was pressed even if a layer changed in between.
+		mapped, ok := e.pressed[code]
+		if !ok {
+			mapped = e.lookup(code)
+		}
+		delete(e.pressed, code)
+		return []keyEvent{{mapped, keyRelease}}
+	default:
+		if mapped, ok := e.pressed[code]; ok {
+			return []keyEvent{{mapped, keyRepeat}}
+		}
+		return nil
+	}
+}
+
+// Deadline reports when a pending dual-role key turns into its hold role
+// if nothing else happens.
+func (e *remapEngine) Deadline() (time.Time, bool) {
+	if e.pending == nil {
+		return time.Time{}, false
+	}
+	return e.pending.since.Add(e.tapTimeout), true
+}
+
+// Tick commits a pending dual-role key once its tap timeout has passed,
+// so holding Caps and clicking the mouse still sends Ctrl.
+func (e *remapEngine) Tick(now time.Time) []keyEvent {
+	deadline, ok := e.Deadline()
+	if !ok || now.Before(deadline) {
+		return nil
+	}
+	return e.resolvePending()
+}
+
+// ReleaseAll lifts every output key still down so detaching a device
+// never leaves a stuck modifier behind.
+func (e *remapEngine) ReleaseAll() []keyEvent {
+	var out []keyEvent
+	for _, mapped := range e.pressed {
+		out = append(out, keyEvent{mapped, keyRelease})
+	}
+	for _, hold := range e.holding {
+		out = append(out, keyEvent{hold, keyRelease})
+	}
+	e.pressed = make(map[uint16]uint16)
+	e.holding = make(map[uint16]uint16)
+	e.pending = nil
+	for i := range e.layers {
+		e.layers[i] = false
+	}
+	return out
+}
+
+func (e *remapEngine) activeLayers() []string {
+	active := []string{}
+	for i, on := range e.layers {
+		if on {
+			active = append(active, e.rule.layers[i].name)
+		}
+	}
+	return active
+}
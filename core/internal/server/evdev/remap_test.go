@@ -0,0 +1,411 @@
+package evdev
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	evdev "github.com/holoplot/go-evdev"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mustRule(t *testing.T, rule RemapRule) *compiledRule {
+	t.Helper()
+	c, err := compileRule(rule)
+	require.NoError(t, err)
+	return c
+}
+
+func capsRule(t *testing.T) *compiledRule {
+	return mustRule(t, RemapRule{
+		Remap:    map[string]string{"KEY_A": "b"},
+		DualRole: map[string]DualRole{"caps": {Tap: "esc", Hold: "ctrl"}},
+		Layers: []RemapLayer{
+			{Name: "nav", Key: "KEY_RIGHTALT", Remap: map[string]string{"h": "left", "l": "right"}},
+			{Name: "num", Key: "KEY_SCROLLLOCK", Toggle: true, Remap: map[string]string{"j": "KEY_1"}},
+		},
+	})
+}
+
+func TestParseKeyName(t *testing.T) {
+	for name, want := range map[string]uint16{
+		"KEY_CAPSLOCK": evdev.KEY_CAPSLOCK,
+		"capslock":     evdev.KEY_CAPSLOCK,
+		"Caps":         evdev.KEY_CAPSLOCK,
+		"escape":       evdev.KEY_ESC,
+		"BTN_SIDE":     evdev.BTN_SIDE,
+		" super ":      evdev.KEY_LEFTMETA,
+	} {
+		code, err := parseKeyName(name)
+		require.NoError(t, err, name)
+		assert.Equal(t, want, code, name)
+	}
+
+	_, err := parseKeyName("hyper-banana")
+	assert.Error(t, err)
+
+	_, err = compileRemapConfig(RemapConfig{Rules: []RemapRule{{Layers: []RemapLayer{{Name: "x", Key: "nope"}}}}})
+	assert.ErrorContains(t, err, `rule 0: layer "x"`)
+}
+
+func TestRemapEngine_SimpleRemap(t *testing.T) {
+	e := newRemapEngine(capsRule(t), 200*time.Millisecond)
+	now := time.Now()
+
+	assert.Equal(t, []keyEvent{{evdev.KEY_B, keyPress}}, e.Process(evdev.KEY_A, keyPress, now))
+	assert.Equal(t, []keyEvent{{evdev.KEY_B, keyRepeat}}, e.Process(evdev.KEY_A, keyRepeat, now))
+	assert.Equal(t, []keyEvent{{evdev.KEY_B, keyRelease}}, e.Process(evdev.KEY_A, keyRelease, now))
+	assert.Equal(t, []keyEvent{{evdev.KEY_Q, keyPress}}, e.Process(evdev.KEY_Q, keyPress, now), "unmapped keys pass through")
+}
+
+func TestRemapEngine_DualRole(t *testing.T) {
+	e := newRemapEngine(capsRule(t), 200*time.Millisecond)
+	now := time.Now()
+
+	t.Run("tap", func(t *testing.T) {
+		assert.Empty(t, e.Process(evdev.KEY_CAPSLOCK, keyPress, now))
+		assert.Empty(t, e.Process(evdev.KEY_CAPSLOCK, keyRepeat, now.Add(50*time.Millisecond)))
+		assert.Equal(t, []keyEvent{{evdev.KEY_ESC, keyPress}, {evdev.KEY_ESC, keyRelease}},
+			e.Process(evdev.KEY_CAPSLOCK, keyRelease, now.Add(100*time.Millisecond)))
+	})
+
+	t.Run("hold with another key", func(t *testing.T) {
+		assert.Empty(t, e.Process(evdev.KEY_CAPSLOCK, keyPress, now))
+		assert.Equal(t, []keyEvent{{evdev.KEY_LEFTCTRL, keyPress}, {evdev.KEY_C, keyPress}},
+			e.Process(evdev.KEY_C, keyPress, now.Add(20*time.Millisecond)))
+		assert.Equal(t, []keyEvent{{evdev.KEY_C, keyRelease}}, e.Process(evdev.KEY_C, keyRelease, now))
+		assert.Equal(t, []keyEvent{{evdev.KEY_LEFTCTRL, keyRelease}}, e.Process(evdev.KEY_CAPSLOCK, keyRelease, now))
+	})
+
+	t.Run("hold past timeout", func(t *testing.T) {
+		assert.Empty(t, e.Process(evdev.KEY_CAPSLOCK, keyPress, now))
+		deadline, ok := e.Deadline()
+		require.True(t, ok)
+		assert.Equal(t, now.Add(200*time.Millisecond), deadline)
+
+		assert.Empty(t, e.Tick(now.Add(100*time.Millisecond)))
+		assert.Equal(t, []keyEvent{{evdev.KEY_LEFTCTRL, keyPress}}, e.Tick(deadline))
+		_, ok = e.Deadline()
+		assert.False(t, ok)
+
+		assert.Equal(t, []keyEvent{{evdev.KEY_LEFTCTRL, keyRepeat}}, e.Process(evdev.KEY_CAPSLOCK, keyRepeat, now))
+		assert.Equal(t, []keyEvent{{evdev.KEY_LEFTCTRL, keyRelease}}, e.Process(evdev.KEY_CAPSLOCK, keyRelease, now))
+	})
+
+	t.Run("long press without tick sends nothing", func(t *testing.T) {
+		assert.Empty(t, e.Process(evdev.KEY_CAPSLOCK, keyPress, now))
+		assert.Empty(t, e.Process(evdev.KEY_CAPSLOCK, keyRelease, now.Add(time.Second)))
+	})
+}
+
+func TestRemapEngine_Layers(t *testing.T) {
+	e := newRemapEngine(capsRule(t), 200*time.Millisecond)
+	now := time.Now()
+
+	assert.Empty(t, e.Process(evdev.KEY_RIGHTALT, keyPress, now), "layer keys are swallowed")
+	assert.Equal(t, []string{"nav"}, e.activeLayers())
+	assert.Equal(t, []keyEvent{{evdev.KEY_LEFT, keyPress}}, e.Process(evdev.KEY_H, keyPress, now))
+	assert.Equal(t, []keyEvent{{evdev.KEY_B, keyPress}}, e.Process(evdev.KEY_A, keyPress, now), "base remap still applies")
+
+	assert.Empty(t, e.Process(evdev.KEY_RIGHTALT, keyRelease, now))
+	assert.Equal(t, []keyEvent{{evdev.KEY_LEFT, keyRelease}}, e.Process(evdev.KEY_H, keyRelease, now),
+		"release matches what was pressed, not the current layer")
+	assert.Equal(t, []keyEvent{{evdev.KEY_H, keyPress}}, e.Process(evdev.KEY_H, keyPress, now))
+
+	e.Process(evdev.KEY_SCROLLLOCK, keyPress, now)
+	e.Process(evdev.KEY_SCROLLLOCK, keyRelease, now)
+	assert.Equal(t, []string{"num"}, e.activeLayers(), "toggle layers survive release")
+	assert.Equal(t, []keyEvent{{evdev.KEY_1, keyPress}}, e.Process(evdev.KEY_J, keyPress, now))
+	e.Process(evdev.KEY_SCROLLLOCK, keyPress, now)
+	assert.Empty(t, e.activeLayers())
+
+	released := e.ReleaseAll()
+	assert.ElementsMatch(t, []keyEvent{
+		{evdev.KEY_B, keyRelease},
+		{evdev.KEY_H, keyRelease},
+		{evdev.KEY_1, keyRelease},
+	}, released)
+	assert.Empty(t, e.ReleaseAll())
+}
+
+func TestMatchRule(t *testing.T) {
+	rules, err := compileRemapConfig(RemapConfig{Rules: []RemapRule{
+		{Match: RemapMatch{Vendor: "046D", Product: "c52b"}},
+		{Match: RemapMatch{Name: "translated"}},
+		{},
+	}})
+	require.NoError(t, err)
+
+	kbd := func(name, vendor, product string) InputDevice {
+		return InputDevice{Name: name, Vendor: vendor, Product: product, Kinds: []string{KindKeyboard}}
+	}
+	assert.Equal(t, 0, matchRule(rules, kbd("Logitech Unifying", "046d", "c52b")))
+	assert.Equal(t, 1, matchRule(rules, kbd("AT Translated Set 2 keyboard", "0001", "0001")))
+	assert.Equal(t, 2, matchRule(rules, kbd("Keychron K2", "05ac", "024f")))
+	assert.Equal(t, -1, matchRule(rules, kbd(remapDeviceName, "1d6b", "0dee")), "never grab our own output")
+	assert.Equal(t, -1, matchRule(rules, InputDevice{Name: "Touchpad", Kinds: []string{KindTouchpad}}))
+}
+
+type fakeSink struct {
+	mu     sync.Mutex
+	events []evdev.InputEvent
+	closed bool
+}
+
+func (s *fakeSink) WriteOne(event *evdev.InputEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, *event)
+	return nil
+}
+
+func (s *fakeSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	return nil
+}
+
+// keys returns the written key events, checking each is followed by a
+// SYN_REPORT.
+func (s *fakeSink) keys(t *testing.T) []keyEvent {
+	t.Helper()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var keys []keyEvent
+	for i, ev := range s.events {
+		if ev.Type != evKeyType {
+			continue
+		}
+		require.Less(t, i+1, len(s.events))
+		assert.Equal(t, evdev.EvType(evSynType), s.events[i+1].Type)
+		keys = append(keys, keyEvent{uint16(ev.Code), ev.Value})
+	}
+	return keys
+}
+
+type fakeRemapSource struct {
+	fakeInputDevice
+	events  chan *evdev.InputEvent
+	mu      sync.Mutex
+	grabbed bool
+	pressed bool
+	leds    map[evdev.EvCode]int32
+}
+
+func newFakeRemapSource(path, name string) *fakeRemapSource {
+	return &fakeRemapSource{
+		fakeInputDevice: fakeInputDevice{name: name, path: path},
+		events:          make(chan *evdev.InputEvent, 16),
+	}
+}
+
+func (d *fakeRemapSource) ReadOne() (*evdev.InputEvent, error) {
+	event, ok := <-d.events
+	if !ok {
+		return nil, errors.New("device closed")
+	}
+	return event, nil
+}
+
+func (d *fakeRemapSource) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.closed {
+		d.closed = true
+		close(d.events)
+	}
+	return nil
+}
+
+func (d *fakeRemapSource) State(t evdev.EvType) (evdev.StateMap, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return evdev.StateMap{evdev.KEY_ENTER: d.pressed}, nil
+}
+
+func (d *fakeRemapSource) Grab() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.grabbed = true
+	return nil
+}
+
+func (d *fakeRemapSource) Ungrab() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.grabbed = false
+	return nil
+}
+
+func (d *fakeRemapSource) WriteOne(event *evdev.InputEvent) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if event.Type == evLedType {
+		if d.leds == nil {
+			d.leds = make(map[evdev.EvCode]int32)
+		}
+		d.leds[event.Code] = event.Value
+	}
+	return nil
+}
+
+func (d *fakeRemapSource) led(code evdev.EvCode) int32 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.leds[code]
+}
+
+func (d *fakeRemapSource) isGrabbed() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.grabbed
+}
+
+func TestRemapSession_Pipeline(t *testing.T) {
+	sink := &fakeSink{}
+	source := newFakeRemapSource("/dev/input/event3", "kbd")
+	session := newRemapSession(InputDevice{Path: source.path}, 0,
+		newRemapEngine(capsRule(t), 20*time.Millisecond), source, &remapSink{sink: sink})
+	go session.run()
+
+	for _, ev := range []evdev.InputEvent{
+		{Type: evMscType, Code: evdev.MSC_SCAN, Value: 0x1e},
+		{Type: evKeyType, Code: evdev.KEY_A, Value: keyPress},
+		{Type: evSynType, Code: evdev.SYN_REPORT},
+		{Type: evKeyType, Code: evdev.KEY_CAPSLOCK, Value: keyPress},
+		{Type: evSynType, Code: evdev.SYN_REPORT},
+	} {
+		source.events <- &ev
+	}
+
+	require.Eventually(t, func() bool { return len(sink.keys(t)) == 2 }, time.Second, 5*time.Millisecond,
+		"the tap timeout commits the hold role without further input")
+	assert.Equal(t, []keyEvent{{evdev.KEY_B, keyPress}, {evdev.KEY_LEFTCTRL, keyPress}}, sink.keys(t))
+
+	session.stop()
+	assert.ElementsMatch(t, []keyEvent{{evdev.KEY_B, keyRelease}, {evdev.KEY_LEFTCTRL, keyRelease}}, sink.keys(t)[2:],
+		"stopping releases everything still held")
+	assert.True(t, source.closed)
+}
+
+func setupRemapper(t *testing.T, devices ...InputDevice) (*remapper, *fakeSink, map[string]*fakeRemapSource) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	sources := make(map[string]*fakeRemapSource)
+	sink := &fakeSink{}
+	oldOpen, oldCreate := openRemapSource, createRemapSink
+	openRemapSource = func(path string) (remapSource, error) {
+		source := newFakeRemapSource(path, "kbd")
+		sources[path] = source
+		return source, nil
+	}
+	createRemapSink = func() (eventSink, error) { return sink, nil }
+	t.Cleanup(func() { openRemapSource, createRemapSink = oldOpen, oldCreate })
+
+	r := newRemapper(func() []InputDevice { return devices })
+	t.Cleanup(r.stop)
+	return r, sink, sources
+}
+
+func TestRemapper_ApplyAndHotplug(t *testing.T) {
+	keyboard := InputDevice{Path: "/dev/input/event3", Name: "AT Translated Set 2 keyboard", Kinds: []string{KindKeyboard}}
+	mouse := InputDevice{Path: "/dev/input/event4", Name: "Mouse", Kinds: []string{KindPointer}}
+	r, sink, sources := setupRemapper(t, keyboard, mouse)
+	m := &Manager{remap: r}
+
+	require.NoError(t, m.SetRemapConfig(RemapConfig{Rules: []RemapRule{{Remap: map[string]string{"a": "b"}}}}))
+	state, err := m.GetRemapState()
+	require.NoError(t, err)
+	assert.False(t, state.Running)
+	assert.Equal(t, remapDefaultTapTimeout, state.Config.TapTimeout)
+	assert.Empty(t, sources)
+
+	require.NoError(t, m.SetRemapEnabled(true))
+	state, _ = m.GetRemapState()
+	assert.True(t, state.Running)
+	require.Len(t, state.Devices, 1)
+	assert.Equal(t, keyboard.Path, state.Devices[0].Path)
+	require.Contains(t, sources, keyboard.Path)
+	assert.True(t, sources[keyboard.Path].isGrabbed())
+
+	sources[keyboard.Path].events <- &evdev.InputEvent{Type: evKeyType, Code: evdev.KEY_A, Value: keyPress}
+	require.Eventually(t, func() bool { return len(sink.keys(t)) == 1 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, uint16(evdev.KEY_B), sink.keys(t)[0].code)
+
+	second := InputDevice{Path: "/dev/input/event9", Name: "USB Keyboard", Kinds: []string{KindKeyboard}}
+	r.deviceAdded(second)
+	r.deviceAdded(second)
+	state, _ = m.GetRemapState()
+	assert.Len(t, state.Devices, 2)
+
+	r.deviceRemoved(keyboard.Path)
+	assert.False(t, sources[keyboard.Path].isGrabbed())
+	state, _ = m.GetRemapState()
+	assert.Len(t, state.Devices, 1)
+
+	require.NoError(t, m.SetRemapEnabled(false))
+	state, _ = m.GetRemapState()
+	assert.False(t, state.Running)
+	assert.Empty(t, state.Devices)
+	assert.True(t, sink.closed)
+
+	saved, err := loadRemapConfig()
+	require.NoError(t, err)
+	assert.False(t, saved.Enabled)
+	assert.Len(t, saved.Rules, 1)
+}
+
+func TestRemapper_ForwardsLeds(t *testing.T) {
+	keyboard := InputDevice{Path: "/dev/input/event3", Name: "AT Translated Set 2 keyboard", Kinds: []string{KindKeyboard}}
+	r, _, sources := setupRemapper(t, keyboard)
+	m := &Manager{remap: r}
+	require.NoError(t, m.SetRemapConfig(RemapConfig{Enabled: true, Rules: []RemapRule{{Remap: map[string]string{"a": "b"}}}}))
+	assert.True(t, r.grabbing())
+
+	r.forwardLed(ledCapslockKey, 1)
+	assert.Equal(t, int32(1), sources[keyboard.Path].led(ledCapslockKey))
+
+	second := InputDevice{Path: "/dev/input/event9", Name: "USB Keyboard", Kinds: []string{KindKeyboard}}
+	r.deviceAdded(second)
+	assert.Equal(t, int32(1), sources[second.Path].led(ledCapslockKey), "newly grabbed keyboards get the current LEDs")
+}
+
+func TestRemapper_ReloadFromFile(t *testing.T) {
+	keyboard := InputDevice{Path: "/dev/input/event3", Name: "kbd", Kinds: []string{KindKeyboard}}
+	r, _, sources := setupRemapper(t, keyboard)
+	m := &Manager{remap: r}
+
+	path, err := remapConfigPath()
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(`{"enabled": true, "rules": [{"remap": {"caps": "esc"}}]}`), 0o644))
+
+	require.NoError(t, m.ReloadRemap())
+	assert.Contains(t, sources, keyboard.Path)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"enabled": true, "rules": [{"remap": {"caps": "bogus"}}]}`), 0o644))
+	assert.ErrorContains(t, m.ReloadRemap(), "unknown key: bogus")
+	state, _ := m.GetRemapState()
+	assert.Len(t, state.Devices, 1, "an invalid file keeps the running config")
+
+	assert.ErrorIs(t, (&Manager{}).SetRemapEnabled(true), errRemapUnavailable)
+}
+
+func TestWaitForKeyRelease(t *testing.T) {
+	source := newFakeRemapSource("/dev/input/event3", "kbd")
+	source.pressed = true
+	start := time.Now()
+	go func() {
+		time.Sleep(2 * remapGrabInterval)
+		source.mu.Lock()
+		source.pressed = false
+		source.mu.Unlock()
+	}()
+	waitForKeyRelease(source)
+	assert.Less(t, time.Since(start), remapGrabWait)
+}
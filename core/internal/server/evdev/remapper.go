@@ -0,0 +1,589 @@
+package evdev
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/AvengeMedia/DankMaterialShell/core/internal/log"
+	"github.com/fsnotify/fsnotify"
+	evdev "github.com/holoplot/go-evdev"
+)
+
+const (
+	evSynType = 0x00
+	evMscType = 0x04
+
+	remapReloadDelay  = 200 * time.Millisecond
+	remapGrabWait     = 2 * time.Second
+	remapGrabInterval = 50 * time.Millisecond
+)
+
+// remapSource is a keyboard the remapper reads exclusively. LED writes go
+// back to it since the compositor only drives the virtual keyboard's LEDs.
+type remapSource interface {
+	EvdevDevice
+	Grab() error
+	Ungrab() error
+	WriteOne(event *evdev.InputEvent) error
+}
+
+// eventSink receives the remapped stream; in production a uinput device.
+type eventSink interface {
+	WriteOne(event *evdev.InputEvent) error
+	Close() error
+}
+
+var openRemapSource = func(path string) (remapSource, error) {
+	return evdev.Open(path)
+}
+
+var createRemapSink = func() (eventSink, error) {
+	keys := make([]evdev.EvCode, 0, evdev.KEY_MAX)
+	for code := evdev.EvCode(1); code < evdev.KEY_MAX; code++ {
+		keys = append(keys, code)
+	}
+	return evdev.CreateDevice(remapDeviceName, evdev.InputID{
+		BusType: evdev.BUS_VIRTUAL,
+		Vendor:  0x1d6b,
+		Product: 0x0dee,
+		Version: 1,
+	}, map[evdev.EvType][]evdev.EvCode{
+		evdev.EV_KEY: keys,
+		evdev.EV_REL: {evdev.REL_X, evdev.REL_Y, evdev.REL_WHEEL, evdev.REL_HWHEEL},
+		evdev.EV_LED: {evdev.LED_NUML, evdev.LED_CAPSL, evdev.LED_SCROLLL},
+	})
+}
+
+type RemapDevice struct {
+	Path   string   `json:"path"`
+	Name   string   `json:"name"`
+	Rule   int      `json:"rule"`
+	Layers []string `json:"layers"`
+}
+
+type RemapState struct {
+	Enabled bool          `json:"enabled"`
+	Running bool          `json:"running"`
+	Error   string        `json:"error,omitempty"`
+	Config  RemapConfig   `json:"config"`
+	Devices []RemapDevice `json:"devices"`
+}
+
+// remapSink serialises writes from every grabbed keyboard onto the one
+// virtual device.
+type remapSink struct {
+	mu   sync.Mutex
+	sink eventSink
+}
+
+func (s *remapSink) write(events ...evdev.InputEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range events {
+		if err := s.sink.WriteOne(&events[i]); err != nil {
+			log.Warnf("evdev remap: write: %v", err)
+			return
+		}
+	}
+}
+
+func (s *remapSink) emitKeys(keys []keyEvent) {
+	if len(keys) == 0 {
+		return
+	}
+	events := make([]evdev.InputEvent, 0, len(keys)*2)
+	for _, k := range keys {
+		events = append(events,
+			evdev.InputEvent{Type: evKeyType, Code: evdev.EvCode(k.code), Value: k.value},
+			evdev.InputEvent{Type: evSynType, Code: evdev.SYN_REPORT},
+		)
+	}
+	s.write(events...)
+}
+
+// remapSession is the event pipeline for one grabbed keyboard.
+type remapSession struct {
+	info   InputDevice
+	rule   int
+	source remapSource
+	sink   *remapSink
+
+	mu     sync.Mutex
+	engine *remapEngine
+	timer  *time.Timer
+	done   chan struct{}
+}
+
+func newRemapSession(info InputDevice, rule int, engine *remapEngine, source remapSource, sink *remapSink) *remapSession {
+	return &remapSession{
+		info:   info,
+		rule:   rule,
+		source: source,
+		sink:   sink,
+		engine: engine,
+		done:   make(chan struct{}),
+	}
+}
+
+func (s *remapSession) handle(event *evdev.InputEvent, now time.Time) {
+	switch event.Type {
+	case evKeyType:
+		s.mu.Lock()
+		keys := s.engine.Process(uint16(event.Code), event.Value, now)
+		s.scheduleLocked(now)
+		s.mu.Unlock()
+		s.sink.emitKeys(keys)
+	case evSynType, evMscType:
+		// Reports are re-synced per key, and scan codes no longer match.
+	default:
+		s.sink.write(*event, evdev.InputEvent{Type: evSynType, Code: evdev.SYN_REPORT})
+	}
+}
+
+func (s *remapSession) scheduleLocked(now time.Time) {
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	deadline, ok := s.engine.Deadline()
+	if !ok {
+		return
+	}
+	s.timer = time.AfterFunc(deadline.Sub(now), func() { s.tick(time.Now()) })
+}
+
+func (s *remapSession) tick(now time.Time) {
+	s.mu.Lock()
+	keys := s.engine.Tick(now)
+	s.mu.Unlock()
+	s.sink.emitKeys(keys)
+}
+
+func (s *remapSession) run() {
+	defer close(s.done)
+	for {
+		event, err := s.source.ReadOne()
+		if err != nil {
+			if isClosedError(err) {
+				return
+			}
+			log.Debugf("evdev remap: %s: %v", s.info.Path, err)
+			time.Sleep(100 * time.Millisecond)
+			continue
+		}
+		if event != nil {
+			s.handle(event, time.Now())
+		}
+	}
+}
+
+func (s *remapSession) setLeds(leds map[uint16]int32) {
+	for code, value := range leds {
+		events := []evdev.InputEvent{
+			{Type: evLedType, Code: evdev.EvCode(code), Value: value},
+			{Type: evSynType, Code: evdev.SYN_REPORT},
+		}
+		for i := range events {
+			if err := s.source.WriteOne(&events[i]); err != nil {
+				log.Debugf("evdev remap: set LED on %s: %v", s.info.Path, err)
+				return
+			}
+		}
+	}
+}
+
+func (s *remapSession) layers() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.engine.activeLayers()
+}
+
+func (s *remapSession) stop() {
+	s.mu.Lock()
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+	keys := s.engine.ReleaseAll()
+	s.mu.Unlock()
+	s.sink.emitKeys(keys)
+
+	if err := s.source.Ungrab(); err != nil && !isClosedError(err) {
+		log.Debugf("evdev remap: ungrab %s: %v", s.info.Path, err)
+	}
+	s.source.Close()
+	<-s.done
+}
+
+// remapper grabs matching keyboards and re-emits their events through a
+// uinput device. The compositor sees only the virtual keyboard.
+type remapper struct {
+	mu        sync.Mutex
+	config    RemapConfig
+	rules     []*compiledRule
+	err       string
+	sink      *remapSink
+	sessions  map[string]*remapSession
+	inventory func() []InputDevice
+	// leds is the lock LED state last set on the virtual keyboard.
+	leds map[uint16]int32
+
+	watcher     *fsnotify.Watcher
+	reloadTimer *time.Timer
+	stopChan    chan struct{}
+	stopOnce    sync.Once
+}
+
+func newRemapper(inventory func() []InputDevice) *remapper {
+	return &remapper{
+		config:    defaultRemapConfig(),
+		sessions:  make(map[string]*remapSession),
+		inventory: inventory,
+		leds:      make(map[uint16]int32),
+		stopChan:  make(chan struct{}),
+	}
+}
+
+func (r *remapper) start() {
+	r.reload()
+
+	path, err := remapConfigPath()
+	if err != nil {
+		return
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.Warnf("evdev remap: config watcher unavailable: %v", err)
+		return
+	}
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		log.Debugf("evdev remap: create %s: %v", dir, err)
+	}
+	if err := watcher.Add(dir); err != nil {
+		log.Warnf("evdev remap: watch %s, hot reload disabled: %v", dir, err)
+		watcher.Close()
+		return
+	}
+	r.watcher = watcher
+	go r.watchConfig(filepath.Base(path))
+}
+
+func (r *remapper) watchConfig(name string) {
+	for {
+		select {
+		case <-r.stopChan:
+			return
+		case event, ok := <-r.watcher.Events:
+			if !ok {
+				return
+			}
+			if filepath.Base(event.Name) != name {
+				continue
+			}
+			r.mu.Lock()
+			if r.reloadTimer != nil {
+				r.reloadTimer.Stop()
+			}
+			r.reloadTimer = time.AfterFunc(remapReloadDelay, r.reload)
+			r.mu.Unlock()
+		case err, ok := <-r.watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Warnf("evdev remap: config watcher error: %v", err)
+		}
+	}
+}
+
+// reload re-reads remap.json; an invalid file keeps the running config.
+func (r *remapper) reload() {
+	cfg, err := loadRemapConfig()
+	if err != nil {
+		log.Warnf("evdev remap: %v", err)
+		r.mu.Lock()
+		r.err = err.Error()
+		r.mu.Unlock()
+		return
+	}
+	if err := r.apply(cfg); err != nil {
+		log.Warnf("evdev remap: %v", err)
+	}
+}
+
+func (r *remapper) apply(cfg RemapConfig) error {
+	rules, err := compileRemapConfig(cfg)
+	if err != nil {
+		r.mu.Lock()
+		r.err = err.Error()
+		r.mu.Unlock()
+		return err
+	}
+
+	r.mu.Lock()
+	if reflect.DeepEqual(r.config, cfg) && (r.sink != nil) == cfg.Enabled && r.err == "" {
+		r.mu.Unlock()
+		return nil
+	}
+	r.config = cfg
+	r.rules = rules
+	r.err = ""
+	sessions := r.detachAllLocked()
+	r.mu.Unlock()
+
+	for _, s := range sessions {
+		s.stop()
+	}
+
+	if !cfg.Enabled {
+		r.closeSink()
+		return nil
+	}
+
+	if err := r.ensureSink(); err != nil {
+		r.mu.Lock()
+		r.err = err.Error()
+		r.mu.Unlock()
+		return err
+	}
+	for _, info := range r.inventory() {
+		r.deviceAdded(info)
+	}
+	return nil
+}
+
+func (r *remapper) ensureSink() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.sink != nil {
+		return nil
+	}
+	sink, err := createRemapSink()
+	if err != nil {
+		return fmt.Errorf("create uinput device: %w", err)
+	}
+	r.sink = &remapSink{sink: sink}
+	return nil
+}
+
+func (r *remapper) closeSink() {
+	r.mu.Lock()
+	sink := r.sink
+	r.sink = nil
+	r.mu.Unlock()
+	if sink != nil {
+		sink.sink.Close()
+	}
+}
+
+func (r *remapper) detachAllLocked() []*remapSession {
+	sessions := make([]*remapSession, 0, len(r.sessions))
+	for path, s := range r.sessions {
+		sessions = append(sessions, s)
+		delete(r.sessions, path)
+	}
+	return sessions
+}
+
+// deviceAdded grabs a keyboard if a rule matches it. Grabbing while a key
+// is down would leave it stuck in the compositor, so wait for release.
+func (r *remapper) deviceAdded(info InputDevice) {
+	r.mu.Lock()
+	index := matchRule(r.rules, info)
+	_, attached := r.sessions[info.Path]
+	if !r.config.Enabled || r.sink == nil || index < 0 || attached {
+		r.mu.Unlock()
+		return
+	}
+	rule := r.rules[index]
+	tapTimeout := time.Duration(r.config.TapTimeout) * time.Millisecond
+	sink := r.sink
+	r.mu.Unlock()
+
+	source, err := openRemapSource(info.Path)
+	if err != nil {
+		log.Warnf("evdev remap: open %s: %v", info.Path, err)
+		return
+	}
+	waitForKeyRelease(source)
+	if err := source.Grab(); err != nil {
+		log.Warnf("evdev remap: grab %s: %v", info.Path, err)
+		source.Close()
+		return
+	}
+	armNonBlocking(source)
+
+	session := newRemapSession(info, index, newRemapEngine(rule, tapTimeout), source, sink)
+
+	r.mu.Lock()
+	if _, attached := r.sessions[info.Path]; attached || r.sink != sink {
+		r.mu.Unlock()
+		source.Ungrab()
+		source.Close()
+		return
+	}
+	r.sessions[info.Path] = session
+	leds := maps.Clone(r.leds)
+	r.mu.Unlock()
+
+	log.Infof("evdev remap: grabbed %s (%s) with rule %d", info.Name, info.Path, index)
+	session.setLeds(leds)
+	go session.run()
+}
+
+// forwardLed mirrors an LED change on the virtual keyboard to every grabbed
+// keyboard.
+func (r *remapper) forwardLed(code uint16, value int32) {
+	r.mu.Lock()
+	r.leds[code] = value
+	sessions := make([]*remapSession, 0, len(r.sessions))
+	for _, s := range r.sessions {
+		sessions = append(sessions, s)
+	}
+	r.mu.Unlock()
+
+	for _, s := range sessions {
+		s.setLeds(map[uint16]int32{code: value})
+	}
+}
+
+// grabbing reports whether any keyboard is grabbed, in which case only the
+// virtual keyboard sees lock keys and LED updates.
+func (r *remapper) grabbing() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.sessions) > 0
+}
+
+func waitForKeyRelease(device EvdevDevice) {
+	deadline := time.Now().Add(remapGrabWait)
+	for time.Now().Before(deadline) {
+		states, err := device.State(evKeyType)
+		if err != nil {
+			return
+		}
+		down := false
+		for _, on := range states {
+			if on {
+				down = true
+				break
+			}
+		}
+		if !down {
+			return
+		}
+		time.Sleep(remapGrabInterval)
+	}
+}
+
+func (r *remapper) deviceRemoved(path string) {
+	r.mu.Lock()
+	session, ok := r.sessions[path]
+	delete(r.sessions, path)
+	r.mu.Unlock()
+	if ok {
+		session.stop()
+	}
+}
+
+func (r *remapper) state() RemapState {
+	r.mu.Lock()
+	state := RemapState{
+		Enabled: r.config.Enabled,
+		Running: r.sink != nil,
+		Error:   r.err,
+		Config:  r.config,
+		Devices: make([]RemapDevice, 0, len(r.sessions)),
+	}
+	sessions := make([]*remapSession, 0, len(r.sessions))
+	for _, s := range r.sessions {
+		sessions = append(sessions, s)
+	}
+	r.mu.Unlock()
+
+	for _, s := range sessions {
+		state.Devices = append(state.Devices, RemapDevice{
+			Path:   s.info.Path,
+			Name:   s.info.Name,
+			Rule:   s.rule,
+			Layers: s.layers(),
+		})
+	}
+	sort.Slice(state.Devices, func(i, j int) bool { return state.Devices[i].Path < state.Devices[j].Path })
+	return state
+}
+
+func (r *remapper) stop() {
+	r.stopOnce.Do(func() {
+		close(r.stopChan)
+		if r.watcher != nil {
+			r.watcher.Close()
+		}
+		r.mu.Lock()
+		if r.reloadTimer != nil {
+			r.reloadTimer.Stop()
+		}
+		sessions := r.detachAllLocked()
+		r.mu.Unlock()
+		for _, s := range sessions {
+			s.stop()
+		}
+		r.closeSink()
+	})
+}
+
+var errRemapUnavailable = errors.New("remapper not available")
+
+func (m *Manager) GetRemapState() (RemapState, error) {
+	if m.remap == nil {
+		return RemapState{}, errRemapUnavailable
+	}
+	return m.remap.state(), nil
+}
+
+// SetRemapConfig validates, applies and persists a remap config.
+func (m *Manager) SetRemapConfig(cfg RemapConfig) error {
+	if m.remap == nil {
+		return errRemapUnavailable
+	}
+	if cfg.TapTimeout == 0 {
+		cfg.TapTimeout = remapDefaultTapTimeout
+	}
+	if cfg.Rules == nil {
+		cfg.Rules = []RemapRule{}
+	}
+	if _, err := compileRemapConfig(cfg); err != nil {
+		return err
+	}
+	if err := saveRemapConfig(cfg); err != nil {
+		return fmt.Errorf("save remap config: %w", err)
+	}
+	return m.remap.apply(cfg)
+}
+
+func (m *Manager) SetRemapEnabled(enabled bool) error {
+	state, err := m.GetRemapState()
+	if err != nil {
+		return err
+	}
+	cfg := state.Config
+	cfg.Enabled = enabled
+	return m.SetRemapConfig(cfg)
+}
+
+func (m *Manager) ReloadRemap() error {
+	if m.remap == nil {
+		return errRemapUnavailable
+	}
+	m.remap.reload()
+	if state := m.remap.state(); state.Error != "" {
+		return errors.New(state.Error)
+	}
+	return nil
+}
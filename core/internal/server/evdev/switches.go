@@ -0,0 +1,111 @@
+package evdev
+
+import (
+	"maps"
+
+	"github.com/AvengeMedia/DankMaterialShell/core/internal/log"
+)
+
+const evSwType = 0x05
+
+const (
+	SwitchLid              = "lid"
+	SwitchTabletMode       = "tabletMode"
+	SwitchHeadphoneInsert  = "headphoneInsert"
+	SwitchMicrophoneInsert = "microphoneInsert"
+	SwitchDock             = "dock"
+)
+
+var switchNames = map[uint16]string{
+	0x00: SwitchLid,
+	0x01: SwitchTabletMode,
+	0x02: SwitchHeadphoneInsert,
+	0x04: SwitchMicrophoneInsert,
+	0x05: SwitchDock,
+}
+
+// monitored reports whether the manager keeps the device open: keyboards
+// for lock keys and switches for lid/tablet-mode/jack state.
+func (d InputDevice) monitored() bool {
+	return d.is(KindKeyboard) || d.is(KindSwitch)
+}
+
+func readSwitches(device EvdevDevice) map[string]bool {
+	states, err := device.State(evSwType)
+	if err != nil {
+		log.Debugf("evdev: switch state for %s: %v", device.Path(), err)
+		return nil
+	}
+
+	switches := make(map[string]bool)
+	for code, on := range states {
+		if name, ok := switchNames[uint16(code)]; ok {
+			switches[name] = on
+		}
+	}
+	return switches
+}
+
+// switchesLocked merges per-device switch state; a switch is on if any
+// device reports it on. Caller holds devicesMutex.
+func (m *Manager) switchesLocked() map[string]bool {
+	merged := make(map[string]bool)
+	for _, switches := range m.switchStates {
+		for name, on := range switches {
+			merged[name] = merged[name] || on
+		}
+	}
+	return merged
+}
+
+func (m *Manager) setDeviceSwitches(path string, switches map[string]bool) {
+	m.devicesMutex.Lock()
+	if len(switches) == 0 {
+		delete(m.switchStates, path)
+	} else {
+		if m.switchStates == nil {
+			m.switchStates = make(map[string]map[string]bool)
+		}
+		m.switchStates[path] = switches
+	}
+	merged := m.switchesLocked()
+	m.devicesMutex.Unlock()
+
+	m.stateMutex.Lock()
+	if maps.Equal(m.state.Switches, merged) {
+		m.stateMutex.Unlock()
+		return
+	}
+	m.state.Switches = merged
+	newState := m.state
+	m.stateMutex.Unlock()
+
+	log.Debugf("Switch state: %v", merged)
+	m.notifySubscribers(newState)
+}
+
+func (m *Manager) updateSwitch(path string, code uint16, on bool) {
+	name, ok := switchNames[code]
+	if !ok {
+		return
+	}
+
+	m.devicesMutex.RLock()
+	switches := maps.Clone(m.switchStates[path])
+	m.devicesMutex.RUnlock()
+
+	if switches == nil {
+		switches = make(map[string]bool)
+	}
+	switches[name] = on
+	m.setDeviceSwitches(path, switches)
+}
+
+// Switch returns the state of a named switch and whether any device
+// provides it.
+func (m *Manager) Switch(name string) (on bool, ok bool) {
+	m.stateMutex.RLock()
+	defer m.stateMutex.RUnlock()
+	on, ok = m.state.Switches[name]
+	return on, ok
+}
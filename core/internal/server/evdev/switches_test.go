@@ -0,0 +1,104 @@
+package evdev
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	evdev "github.com/holoplot/go-evdev"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSwitchDevice struct {
+	fakeInputDevice
+	mu     sync.Mutex
+	on     map[evdev.EvCode]bool
+	events []*evdev.InputEvent
+}
+
+func (d *fakeSwitchDevice) State(t evdev.EvType) (evdev.StateMap, error) {
+	state := evdev.StateMap{}
+	for _, code := range d.fakeInputDevice.events[t] {
+		state[code] = t == evdev.EV_SW && d.on[code]
+	}
+	return state, nil
+}
+
+func (d *fakeSwitchDevice) ReadOne() (*evdev.InputEvent, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.events) == 0 {
+		return nil, errors.New("device closed")
+	}
+	event := d.events[0]
+	d.events = d.events[1:]
+	return event, nil
+}
+
+func TestManager_Switches(t *testing.T) {
+	lid := &fakeSwitchDevice{
+		fakeInputDevice: fakeInputDevice{
+			name:   "Lid Switch",
+			path:   "/dev/input/event0",
+			events: map[evdev.EvType][]evdev.EvCode{evdev.EV_SW: {evdev.SW_LID}},
+		},
+	}
+	convertible := &fakeSwitchDevice{
+		fakeInputDevice: fakeInputDevice{
+			name: "Intel HID switches",
+			path: "/dev/input/event12",
+			events: map[evdev.EvType][]evdev.EvCode{
+				evdev.EV_SW: {evdev.SW_TABLET_MODE, evdev.SW_HEADPHONE_INSERT, evdev.SW_RFKILL_ALL},
+			},
+		},
+		on: map[evdev.EvCode]bool{evdev.SW_TABLET_MODE: true},
+	}
+
+	oldOpen := openDevice
+	openDevice = func(path string) (EvdevDevice, error) {
+		switch path {
+		case lid.path:
+			return lid, nil
+		case convertible.path:
+			return convertible, nil
+		}
+		return nil, errors.New("no such device")
+	}
+	t.Cleanup(func() { openDevice = oldOpen })
+
+	m := &Manager{
+		monitoredPaths: make(map[string]bool),
+		state:          State{Available: true},
+		closeChan:      make(chan struct{}),
+	}
+	defer m.Close()
+
+	m.addDevice(lid.path)
+	m.addDevice(convertible.path)
+	assert.False(t, lid.closed, "switch devices stay open for monitoring")
+
+	closed, ok := m.Switch(SwitchLid)
+	assert.True(t, ok)
+	assert.False(t, closed)
+	tablet, _ := m.Switch(SwitchTabletMode)
+	assert.True(t, tablet)
+	assert.Equal(t, map[string]bool{
+		SwitchLid:             false,
+		SwitchTabletMode:      true,
+		SwitchHeadphoneInsert: false,
+	}, m.GetState().Switches, "unknown switches like rfkill are not reported")
+
+	ch := m.Subscribe("test")
+	lid.events = []*evdev.InputEvent{{Type: evSwType, Code: evdev.SW_LID, Value: 1}}
+	m.monitorDevice(lid, 0)
+
+	state := <-ch
+	assert.True(t, state.Switches[SwitchLid])
+
+	m.removeDevice(convertible.path)
+	state = <-ch
+	_, ok = state.Switches[SwitchTabletMode]
+	assert.False(t, ok, "removed device's switches disappear")
+	require.True(t, state.Switches[SwitchLid])
+}
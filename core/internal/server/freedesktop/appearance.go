@@ -0,0 +1,169 @@
+package freedesktop
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/AvengeMedia/DankMaterialShell/core/internal/log"
+	"github.com/AvengeMedia/DankMaterialShell/core/internal/utils"
+	"github.com/fsnotify/fsnotify"
+)
+
+const (
+	colorSchemeDefault     uint32 = 0
+	colorSchemePreferDark  uint32 = 1
+	colorSchemePreferLight uint32 = 2
+
+	// SettingsData.AnimationSpeed.None in the shell.
+	animationSpeedNone = 0
+	// matugenContrast ranges from -1 to 1; from here up the shell counts as
+	// asking for higher contrast.
+	highContrastThreshold = 0.5
+)
+
+// shellSettings is the subset of the shell's settings.json that feeds the
+// portal.
+type shellSettings struct {
+	AnimationSpeed  *int     `json:"animationSpeed"`
+	MatugenContrast *float64 `json:"matugenContrast"`
+}
+
+type shellSession struct {
+	IsLightMode *bool `json:"isLightMode"`
+}
+
+func shellSettingsPath() string {
+	return filepath.Join(utils.XDGConfigHome(), "DankMaterialShell", "settings.json")
+}
+
+func shellSessionPath() string {
+	return filepath.Join(utils.XDGStateHome(), "DankMaterialShell", "session.json")
+}
+
+func shellColorsPath() string {
+	return filepath.Join(utils.XDGCacheHome(), "DankMaterialShell", "dms-colors.json")
+}
+
+func readJSONFile(path string, v any) bool {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		log.Debugf("freedesktop: failed to parse %s: %v", path, err)
+		return false
+	}
+	return true
+}
+
+// applyShellSettings folds settings.json into a.
+func applyShellSettings(a *Appearance, path string) {
+	var settings shellSettings
+	if !readJSONFile(path, &settings) {
+		return
+	}
+	a.ReducedMotion = 0
+	if settings.AnimationSpeed != nil && *settings.AnimationSpeed == animationSpeedNone {
+		a.ReducedMotion = 1
+	}
+	a.Contrast = 0
+	if settings.MatugenContrast != nil && *settings.MatugenContrast >= highContrastThreshold {
+		a.Contrast = 1
+	}
+}
+
+// applyShellSession folds the light/dark mode from session.json into a.
+func applyShellSession(a *Appearance, path string) {
+	var session shellSession
+	if !readJSONFile(path, &session) || session.IsLightMode == nil {
+		return
+	}
+	a.ColorScheme = colorSchemeFor(*session.IsLightMode)
+}
+
+// primaryColor returns the matugen primary for the given mode from a
+// dms-colors.json file.
+func primaryColor(path string, light bool) string {
+	var colors struct {
+		Colors struct {
+			Dark  map[string]string `json:"dark"`
+			Light map[string]string `json:"light"`
+		} `json:"colors"`
+	}
+	if !readJSONFile(path, &colors) {
+		return ""
+	}
+	if light {
+		return colors.Colors.Light["primary"]
+	}
+	return colors.Colors.Dark["primary"]
+}
+
+func colorSchemeFor(light bool) uint32 {
+	if light {
+		return colorSchemePreferLight
+	}
+	return colorSchemePreferDark
+}
+
+// loadShellAppearance builds the initial appearance from the shell's
+// persisted settings, session and last generated colors.
+func loadShellAppearance() Appearance {
+	a := Appearance{ColorScheme: colorSchemeDefault}
+	applyShellSettings(&a, shellSettingsPath())
+	applyShellSession(&a, shellSessionPath())
+	if a.ColorScheme != colorSchemeDefault {
+		a.AccentColor = primaryColor(shellColorsPath(), a.ColorScheme == colorSchemePreferLight)
+	}
+	return a
+}
+
+// watchShellFiles re-reads settings.json and session.json when the shell
+// rewrites them so motion, contrast and mode changes reach the portal without
+// waiting for a theme rebuild.
+func (m *Manager) watchShellFiles() {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.Warnf("Failed to create shell settings watcher: %v", err)
+		return
+	}
+	defer watcher.Close()
+
+	settingsPath := shellSettingsPath()
+	sessionPath := shellSessionPath()
+	for _, dir := range []string{filepath.Dir(settingsPath), filepath.Dir(sessionPath)} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			log.Warnf("Failed to create %s: %v", dir, err)
+			continue
+		}
+		if err := watcher.Add(dir); err != nil {
+			log.Warnf("Failed to watch %s: %v", dir, err)
+		}
+	}
+
+	for {
+		select {
+		case <-m.stopChan:
+			return
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Op&(fsnotify.Write|fsnotify.Create) == 0 {
+				continue
+			}
+			switch event.Name {
+			case settingsPath:
+				m.updateAppearance(func(a *Appearance) { applyShellSettings(a, settingsPath) })
+			case sessionPath:
+				m.updateAppearance(func(a *Appearance) { applyShellSession(a, sessionPath) })
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Warnf("Shell settings watcher error: %v", err)
+		}
+	}
+}
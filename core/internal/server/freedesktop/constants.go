@@ -10,6 +10,11 @@ const (
 	dbusPortalPath              = "/org/freedesktop/portal/desktop"
 	dbusPortalSettingsInterface = "org.freedesktop.portal.Settings"
 
+	dbusPortalImplName                = "org.freedesktop.impl.portal.desktop.dms"
+	dbusPortalImplSettingsInterface   = "org.freedesktop.impl.portal.Settings"
+	dbusPortalImplScreenshotInterface = "org.freedesktop.impl.portal.Screenshot"
+	dbusPortalImplRequestInterface    = "org.freedesktop.impl.portal.Request"
+
 	dbusPropsInterface = "org.freedesktop.DBus.Properties"
 
 	dbusScreensaverName      = "org.freedesktop.ScreenSaver"
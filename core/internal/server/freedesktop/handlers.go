@@ -25,6 +25,8 @@ func HandleRequest(conn *models.Conn, req models.Request, manager *Manager) {
 		handleGetUserIconFile(conn, req, manager)
 	case "freedesktop.settings.getColorScheme":
 		handleGetColorScheme(conn, req, manager)
+	case "freedesktop.settings.getAppearance":
+		handleGetAppearance(conn, req, manager)
 	case "freedesktop.settings.setIconTheme":
 		handleSetIconTheme(conn, req, manager)
 	default:
@@ -137,6 +139,10 @@ func handleGetColorScheme(conn *models.Conn, req models.Request, manager *Manage
 	models.Respond(conn, req.ID, map[string]uint32{"colorScheme": state.Settings.ColorScheme})
 }
 
+func handleGetAppearance(conn *models.Conn, req models.Request, manager *Manager) {
+	models.Respond(conn, req.ID, manager.GetAppearance())
+}
+
 func handleSetIconTheme(conn *models.Conn, req models.Request, manager *Manager) {
 	iconTheme, err := params.String(req.Params, "iconTheme")
 	if err != nil {
@@ -33,12 +33,18 @@ func NewManager() (*Manager, error) {
 		systemConn:  systemConn,
 		sessionConn: sessionConn,
 		currentUID:  uint64(os.Getuid()),
+		stopChan:    make(chan struct{}),
 	}
 
 	m.initializeAccounts()
+	// Serve the portal backend before querying the frontend so the first
+	// read can already be answered by us.
+	m.initializePortal()
 	m.initializeSettings()
 	m.initializeScreensaver()
 
+	go m.watchShellFiles()
+
 	return m, nil
 }
 
@@ -296,6 +302,12 @@ func (m *Manager) NotifySubscribers() {
 }
 
 func (m *Manager) Close() {
+	m.closeOnce.Do(func() {
+		if m.stopChan != nil {
+			close(m.stopChan)
+		}
+	})
+
 	m.subscribers.Range(func(key string, ch chan FreedeskState) bool {
 		close(ch)
 		m.subscribers.Delete(key)
@@ -309,6 +321,9 @@ func (m *Manager) Close() {
 	})
 
 	if m.sessionConn != nil {
+		if m.portalClaimed {
+			m.sessionConn.ReleaseName(dbusPortalImplName)
+		}
 		m.sessionConn.RemoveMatchSignal(
 			dbus.WithMatchInterface(dbusPortalSettingsInterface),
 			dbus.WithMatchMember("SettingChanged"),
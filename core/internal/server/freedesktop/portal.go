@@ -0,0 +1,256 @@
+package freedesktop
+
+import (
+	"strings"
+	"time"
+
+	"github.com/AvengeMedia/DankMaterialShell/core/internal/log"
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/introspect"
+	"github.com/godbus/dbus/v5/prop"
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+const (
+	appearanceNamespace = "org.freedesktop.appearance"
+
+	appearanceColorScheme   = "color-scheme"
+	appearanceAccentColor   = "accent-color"
+	appearanceContrast      = "contrast"
+	appearanceReducedMotion = "reduced-motion"
+
+	portalSettingsVersion uint32 = 1
+)
+
+var errPortalNotFound = dbus.NewError("org.freedesktop.portal.Error.NotFound", []any{"Requested setting not found"})
+
+// portalSettingsHandler implements org.freedesktop.impl.portal.Settings so
+// xdg-desktop-portal can serve DMS's appearance to GTK4/libadwaita and
+// Flatpak apps.
+type portalSettingsHandler struct {
+	manager *Manager
+}
+
+func portalSettingsIntrospectIface() introspect.Interface {
+	return introspect.Interface{
+		Name: dbusPortalImplSettingsInterface,
+		Methods: []introspect.Method{
+			{
+				Name: "ReadAll",
+				Args: []introspect.Arg{
+					{Name: "namespaces", Type: "as", Direction: "in"},
+					{Name: "value", Type: "a{sa{sv}}", Direction: "out"},
+				},
+			},
+			{
+				Name: "Read",
+				Args: []introspect.Arg{
+					{Name: "namespace", Type: "s", Direction: "in"},
+					{Name: "key", Type: "s", Direction: "in"},
+					{Name: "value", Type: "v", Direction: "out"},
+				},
+			},
+		},
+		Signals: []introspect.Signal{
+			{
+				Name: "SettingChanged",
+				Args: []introspect.Arg{
+					{Name: "namespace", Type: "s"},
+					{Name: "key", Type: "s"},
+					{Name: "value", Type: "v"},
+				},
+			},
+		},
+		Properties: []introspect.Property{
+			{Name: "version", Type: "u", Access: "read"},
+		},
+	}
+}
+
+func (m *Manager) initializePortal() {
+	appearance := loadShellAppearance()
+	m.stateMutex.Lock()
+	m.state.Portal.Appearance = appearance
+	m.stateMutex.Unlock()
+
+	if m.sessionConn == nil {
+		return
+	}
+
+	path := dbus.ObjectPath(dbusPortalPath)
+	if err := m.sessionConn.Export(&portalSettingsHandler{manager: m}, path, dbusPortalImplSettingsInterface); err != nil {
+		log.Warnf("Failed to export portal settings backend: %v", err)
+		return
+	}
+	if err := m.sessionConn.Export(&portalScreenshotHandler{manager: m}, path, dbusPortalImplScreenshotInterface); err != nil {
+		log.Warnf("Failed to export portal screenshot backend: %v", err)
+		return
+	}
+	if _, err := prop.Export(m.sessionConn, path, prop.Map{
+		dbusPortalImplSettingsInterface: {
+			"version": {Value: portalSettingsVersion, Emit: prop.EmitConst},
+		},
+		dbusPortalImplScreenshotInterface: {
+			"version": {Value: portalScreenshotVersion, Emit: prop.EmitConst},
+		},
+	}); err != nil {
+		log.Warnf("Failed to export portal properties: %v", err)
+	}
+	node := &introspect.Node{
+		Name: dbusPortalPath,
+		Interfaces: []introspect.Interface{
+			introspect.IntrospectData,
+			prop.IntrospectData,
+			portalSettingsIntrospectIface(),
+			portalScreenshotIntrospectIface(),
+		},
+	}
+	if err := m.sessionConn.Export(introspect.NewIntrospectable(node), path, "org.freedesktop.DBus.Introspectable"); err != nil {
+		log.Warnf("Failed to export portal introspectable: %v", err)
+	}
+
+	reply, err := m.sessionConn.RequestName(dbusPortalImplName, dbus.NameFlagDoNotQueue)
+	if err != nil {
+		log.Warnf("Failed to request portal name %s: %v", dbusPortalImplName, err)
+		return
+	}
+	if reply != dbus.RequestNameReplyPrimaryOwner {
+		log.Infof("Portal name %s already owned by another process", dbusPortalImplName)
+		return
+	}
+
+	m.portalClaimed = true
+	m.stateMutex.Lock()
+	m.state.Portal.Available = true
+	m.stateMutex.Unlock()
+
+	log.Infof("Claimed %s on session bus", dbusPortalImplName)
+}
+
+// appearanceValues returns the org.freedesktop.appearance keys as portal
+// variants.
+func appearanceValues(a Appearance) map[string]dbus.Variant {
+	return map[string]dbus.Variant{
+		appearanceColorScheme:   dbus.MakeVariant(a.ColorScheme),
+		appearanceAccentColor:   dbus.MakeVariant(accentColorValue(a.AccentColor)),
+		appearanceContrast:      dbus.MakeVariant(a.Contrast),
+		appearanceReducedMotion: dbus.MakeVariant(a.ReducedMotion),
+	}
+}
+
+// rgbTuple is the portal's (ddd) sRGB triple.
+type rgbTuple struct {
+	R, G, B float64
+}
+
+// accentColorValue converts a hex color to the portal's (ddd) form. The spec
+// treats components outside [0,1] as "no accent color".
+func accentColorValue(hex string) rgbTuple {
+	c, err := colorful.Hex(hex)
+	if err != nil {
+		return rgbTuple{-1, -1, -1}
+	}
+	return rgbTuple{c.R, c.G, c.B}
+}
+
+// namespaceMatches implements ReadAll's filter: no patterns or an empty
+// pattern match everything and a trailing '*' matches a prefix.
+func namespaceMatches(patterns []string, namespace string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, p := range patterns {
+		switch {
+		case p == "", p == namespace:
+			return true
+		case strings.HasSuffix(p, "*") && strings.HasPrefix(namespace, strings.TrimSuffix(p, "*")):
+			return true
+		}
+	}
+	return false
+}
+
+func (h *portalSettingsHandler) ReadAll(namespaces []string) (map[string]map[string]dbus.Variant, *dbus.Error) {
+	result := map[string]map[string]dbus.Variant{}
+	if namespaceMatches(namespaces, appearanceNamespace) {
+		result[appearanceNamespace] = appearanceValues(h.manager.GetAppearance())
+	}
+	return result, nil
+}
+
+func (h *portalSettingsHandler) Read(namespace, key string) (dbus.Variant, *dbus.Error) {
+	if namespace != appearanceNamespace {
+		return dbus.Variant{}, errPortalNotFound
+	}
+	value, ok := appearanceValues(h.manager.GetAppearance())[key]
+	if !ok {
+		return dbus.Variant{}, errPortalNotFound
+	}
+	return value, nil
+}
+
+func (m *Manager) GetAppearance() Appearance {
+	m.stateMutex.RLock()
+	defer m.stateMutex.RUnlock()
+	return m.state.Portal.Appearance
+}
+
+// SetTheme records the mode and primary color of a finished theme build.
+func (m *Manager) SetTheme(light bool, primary string) {
+	m.updateAppearance(func(a *Appearance) {
+		a.ColorScheme = colorSchemeFor(light)
+		if primary != "" {
+			a.AccentColor = primary
+		}
+	})
+}
+
+// updateAppearance applies fn to the served appearance and emits
+// SettingChanged for every key whose value changed.
+func (m *Manager) updateAppearance(fn func(*Appearance)) {
+	m.stateMutex.Lock()
+	old := m.state.Portal.Appearance
+	updated := old
+	fn(&updated)
+	m.state.Portal.Appearance = updated
+	m.stateMutex.Unlock()
+
+	if updated == old {
+		return
+	}
+
+	if m.portalClaimed && updated.ColorScheme != old.ColorScheme {
+		// The frontend re-broadcasts our change; it is not an external one.
+		m.selfEchoMu.Lock()
+		m.selfEchoes = append(m.selfEchoes, colorSchemeEcho{value: updated.ColorScheme, expires: time.Now().Add(10 * time.Second)})
+		m.selfEchoMu.Unlock()
+	}
+
+	values := appearanceValues(updated)
+	for _, key := range changedAppearanceKeys(old, updated) {
+		m.emitSettingChanged(key, values[key])
+	}
+	m.NotifySubscribers()
+}
+
+func changedAppearanceKeys(old, updated Appearance) []string {
+	oldValues := appearanceValues(old)
+	newValues := appearanceValues(updated)
+	var keys []string
+	for _, key := range []string{appearanceColorScheme, appearanceAccentColor, appearanceContrast, appearanceReducedMotion} {
+		if newValues[key].String() != oldValues[key].String() {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+func (m *Manager) emitSettingChanged(key string, value dbus.Variant) {
+	if !m.portalClaimed || m.sessionConn == nil {
+		return
+	}
+	if err := m.sessionConn.Emit(dbus.ObjectPath(dbusPortalPath), dbusPortalImplSettingsInterface+".SettingChanged",
+		appearanceNamespace, key, value); err != nil {
+		log.Warnf("Failed to emit portal SettingChanged for %s: %v", key, err)
+	}
+}
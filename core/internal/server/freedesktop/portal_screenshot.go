@@ -0,0 +1,144 @@
+package freedesktop
+
+import (
+	"net/url"
+	"sync/atomic"
+
+	"github.com/AvengeMedia/DankMaterialShell/core/internal/log"
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/introspect"
+)
+
+const (
+	portalResponseSuccess   uint32 = 0
+	portalResponseCancelled uint32 = 1
+	portalResponseOther     uint32 = 2
+
+	portalScreenshotVersion uint32 = 2
+)
+
+// CaptureEngine runs the interactive overlays behind the Screenshot portal.
+// The server provides DMS's screenshot and color picker engines.
+type CaptureEngine interface {
+	// Screenshot saves a PNG and returns its path, or "" if the user
+	// cancelled. Interactive captures let the user select a region.
+	Screenshot(interactive bool) (string, error)
+	// PickColor returns the picked sRGB color in [0,1], or nil if the user
+	// cancelled.
+	PickColor() (*[3]float64, error)
+}
+
+func (m *Manager) SetCaptureEngine(engine CaptureEngine) {
+	m.stateMutex.Lock()
+	m.captureEngine = engine
+	m.stateMutex.Unlock()
+}
+
+func (m *Manager) getCaptureEngine() CaptureEngine {
+	m.stateMutex.RLock()
+	defer m.stateMutex.RUnlock()
+	return m.captureEngine
+}
+
+type portalScreenshotHandler struct {
+	manager *Manager
+}
+
+// portalRequest is the org.freedesktop.impl.portal.Request object exported
+// at a call's handle while the overlay is up. The overlay cannot be torn
+// down from outside, so Close only discards the result.
+type portalRequest struct {
+	closed atomic.Bool
+}
+
+func (r *portalRequest) Close() *dbus.Error {
+	r.closed.Store(true)
+	return nil
+}
+
+func portalScreenshotIntrospectIface() introspect.Interface {
+	requestArgs := []introspect.Arg{
+		{Name: "handle", Type: "o", Direction: "in"},
+		{Name: "app_id", Type: "s", Direction: "in"},
+		{Name: "parent_window", Type: "s", Direction: "in"},
+		{Name: "options", Type: "a{sv}", Direction: "in"},
+		{Name: "response", Type: "u", Direction: "out"},
+		{Name: "results", Type: "a{sv}", Direction: "out"},
+	}
+	return introspect.Interface{
+		Name: dbusPortalImplScreenshotInterface,
+		Methods: []introspect.Method{
+			{Name: "Screenshot", Args: requestArgs},
+			{Name: "PickColor", Args: requestArgs},
+		},
+		Properties: []introspect.Property{
+			{Name: "version", Type: "u", Access: "read"},
+		},
+	}
+}
+
+// runRequest exports a Request object at handle for the duration of fn and
+// serializes overlays: a second request while one is showing fails.
+func (m *Manager) runRequest(handle dbus.ObjectPath, fn func(CaptureEngine) (uint32, map[string]dbus.Variant)) (uint32, map[string]dbus.Variant) {
+	engine := m.getCaptureEngine()
+	if engine == nil {
+		log.Warn("Portal request rejected: no capture engine available")
+		return portalResponseOther, map[string]dbus.Variant{}
+	}
+	if !m.overlayMu.TryLock() {
+		log.Warn("Portal request rejected: another screenshot or color pick is in progress")
+		return portalResponseOther, map[string]dbus.Variant{}
+	}
+	defer m.overlayMu.Unlock()
+
+	request := &portalRequest{}
+	if m.sessionConn != nil && handle.IsValid() {
+		if err := m.sessionConn.Export(request, handle, dbusPortalImplRequestInterface); err != nil {
+			log.Warnf("Failed to export portal request %s: %v", handle, err)
+		} else {
+			defer m.sessionConn.Export(nil, handle, dbusPortalImplRequestInterface)
+		}
+	}
+
+	response, results := fn(engine)
+	if request.closed.Load() {
+		return portalResponseCancelled, map[string]dbus.Variant{}
+	}
+	return response, results
+}
+
+func (h *portalScreenshotHandler) Screenshot(handle dbus.ObjectPath, appID, parentWindow string, options map[string]dbus.Variant) (uint32, map[string]dbus.Variant, *dbus.Error) {
+	interactive := false
+	if v, ok := options["interactive"]; ok {
+		interactive, _ = v.Value().(bool)
+	}
+
+	response, results := h.manager.runRequest(handle, func(engine CaptureEngine) (uint32, map[string]dbus.Variant) {
+		path, err := engine.Screenshot(interactive)
+		switch {
+		case err != nil:
+			log.Warnf("Portal screenshot for %q failed: %v", appID, err)
+			return portalResponseOther, map[string]dbus.Variant{}
+		case path == "":
+			return portalResponseCancelled, map[string]dbus.Variant{}
+		}
+		uri := (&url.URL{Scheme: "file", Path: path}).String()
+		return portalResponseSuccess, map[string]dbus.Variant{"uri": dbus.MakeVariant(uri)}
+	})
+	return response, results, nil
+}
+
+func (h *portalScreenshotHandler) PickColor(handle dbus.ObjectPath, appID, parentWindow string, options map[string]dbus.Variant) (uint32, map[string]dbus.Variant, *dbus.Error) {
+	response, results := h.manager.runRequest(handle, func(engine CaptureEngine) (uint32, map[string]dbus.Variant) {
+		color, err := engine.PickColor()
+		switch {
+		case err != nil:
+			log.Warnf("Portal color pick for %q failed: %v", appID, err)
+			return portalResponseOther, map[string]dbus.Variant{}
+		case color == nil:
+			return portalResponseCancelled, map[string]dbus.Variant{}
+		}
+		return portalResponseSuccess, map[string]dbus.Variant{"color": dbus.MakeVariant(rgbTuple{color[0], color[1], color[2]})}
+	})
+	return response, results, nil
+}
@@ -0,0 +1,112 @@
+package freedesktop
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeCaptureEngine struct {
+	screenshot func(interactive bool) (string, error)
+	pickColor  func() (*[3]float64, error)
+}
+
+func (f *fakeCaptureEngine) Screenshot(interactive bool) (string, error) {
+	return f.screenshot(interactive)
+}
+
+func (f *fakeCaptureEngine) PickColor() (*[3]float64, error) {
+	return f.pickColor()
+}
+
+func newCaptureManager(engine *fakeCaptureEngine) *Manager {
+	m := &Manager{state: &FreedeskState{}}
+	m.SetCaptureEngine(engine)
+	return m
+}
+
+func TestPortalScreenshot(t *testing.T) {
+	engine := &fakeCaptureEngine{}
+	h := &portalScreenshotHandler{manager: newCaptureManager(engine)}
+	handle := dbus.ObjectPath("/org/freedesktop/portal/desktop/request/1_1/t")
+
+	var gotInteractive bool
+	engine.screenshot = func(interactive bool) (string, error) {
+		gotInteractive = interactive
+		return "/home/user/Pictures/Screenshots/shot 1.png", nil
+	}
+
+	response, results, derr := h.Screenshot(handle, "org.example.App", "", map[string]dbus.Variant{
+		"interactive": dbus.MakeVariant(true),
+	})
+	require.Nil(t, derr)
+	assert.True(t, gotInteractive)
+	assert.Equal(t, portalResponseSuccess, response)
+	assert.Equal(t, "file:///home/user/Pictures/Screenshots/shot%201.png", results["uri"].Value())
+
+	_, _, _ = h.Screenshot(handle, "org.example.App", "", map[string]dbus.Variant{})
+	assert.False(t, gotInteractive, "non-interactive by default")
+
+	engine.screenshot = func(bool) (string, error) { return "", nil }
+	response, results, _ = h.Screenshot(handle, "org.example.App", "", nil)
+	assert.Equal(t, portalResponseCancelled, response)
+	assert.Empty(t, results)
+
+	engine.screenshot = func(bool) (string, error) { return "", errors.New("no screencopy") }
+	response, _, _ = h.Screenshot(handle, "org.example.App", "", nil)
+	assert.Equal(t, portalResponseOther, response)
+
+	h.manager.SetCaptureEngine(nil)
+	response, _, _ = h.Screenshot(handle, "org.example.App", "", nil)
+	assert.Equal(t, portalResponseOther, response, "no engine")
+}
+
+func TestPortalPickColor(t *testing.T) {
+	engine := &fakeCaptureEngine{pickColor: func() (*[3]float64, error) {
+		return &[3]float64{1, 0, 0.2}, nil
+	}}
+	h := &portalScreenshotHandler{manager: newCaptureManager(engine)}
+
+	response, results, derr := h.PickColor("/request/1", "org.example.App", "", nil)
+	require.Nil(t, derr)
+	assert.Equal(t, portalResponseSuccess, response)
+	assert.Equal(t, rgbTuple{1, 0, 0.2}, results["color"].Value())
+	assert.Equal(t, "(ddd)", results["color"].Signature().String())
+
+	engine.pickColor = func() (*[3]float64, error) { return nil, nil }
+	response, _, _ = h.PickColor("/request/1", "org.example.App", "", nil)
+	assert.Equal(t, portalResponseCancelled, response)
+}
+
+func TestPortalRequest_OneOverlayAtATime(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	engine := &fakeCaptureEngine{pickColor: func() (*[3]float64, error) {
+		close(started)
+		<-release
+		return &[3]float64{}, nil
+	}}
+	h := &portalScreenshotHandler{manager: newCaptureManager(engine)}
+
+	done := make(chan uint32)
+	go func() {
+		response, _, _ := h.PickColor("/request/1", "a", "", nil)
+		done <- response
+	}()
+	<-started
+
+	response, _, _ := h.Screenshot("/request/2", "b", "", nil)
+	assert.Equal(t, portalResponseOther, response)
+
+	close(release)
+	assert.Equal(t, portalResponseSuccess, <-done)
+}
+
+func TestPortalRequest_Close(t *testing.T) {
+	r := &portalRequest{}
+	assert.Nil(t, r.Close())
+	assert.True(t, r.closed.Load())
+}
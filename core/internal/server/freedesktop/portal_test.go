@@ -0,0 +1,134 @@
+package freedesktop
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeShellFile(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+}
+
+func setShellDirs(t *testing.T) {
+	t.Helper()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+}
+
+func TestNamespaceMatches(t *testing.T) {
+	assert.True(t, namespaceMatches(nil, appearanceNamespace))
+	assert.True(t, namespaceMatches([]string{""}, appearanceNamespace))
+	assert.True(t, namespaceMatches([]string{appearanceNamespace}, appearanceNamespace))
+	assert.True(t, namespaceMatches([]string{"org.freedesktop.*"}, appearanceNamespace))
+	assert.False(t, namespaceMatches([]string{"org.gnome.*"}, appearanceNamespace))
+	assert.False(t, namespaceMatches([]string{"org.freedesktop.appearanc"}, appearanceNamespace))
+}
+
+func TestAccentColorValue(t *testing.T) {
+	assert.Equal(t, rgbTuple{1, 0, 0}, accentColorValue("#ff0000"))
+	unset := accentColorValue("")
+	assert.Less(t, unset.R, 0.0, "no accent is signalled out of range")
+	assert.Equal(t, "(ddd)", dbus.MakeVariant(accentColorValue("#abcdef")).Signature().String())
+}
+
+func TestPortalSettingsHandler_Read(t *testing.T) {
+	m := &Manager{state: &FreedeskState{Portal: PortalState{Appearance: Appearance{
+		ColorScheme:   colorSchemePreferLight,
+		AccentColor:   "#0000ff",
+		ReducedMotion: 1,
+	}}}}
+	h := &portalSettingsHandler{manager: m}
+
+	all, derr := h.ReadAll([]string{"org.freedesktop.*"})
+	require.Nil(t, derr)
+	require.Contains(t, all, appearanceNamespace)
+	assert.Equal(t, uint32(2), all[appearanceNamespace][appearanceColorScheme].Value())
+	assert.Equal(t, uint32(0), all[appearanceNamespace][appearanceContrast].Value())
+	assert.Equal(t, uint32(1), all[appearanceNamespace][appearanceReducedMotion].Value())
+
+	none, derr := h.ReadAll([]string{"org.kde.kdeglobals"})
+	require.Nil(t, derr)
+	assert.Empty(t, none)
+
+	v, derr := h.Read(appearanceNamespace, appearanceAccentColor)
+	require.Nil(t, derr)
+	assert.Equal(t, rgbTuple{0, 0, 1}, v.Value())
+
+	_, derr = h.Read(appearanceNamespace, "nope")
+	assert.Equal(t, errPortalNotFound, derr)
+	_, derr = h.Read("org.gnome.desktop.interface", appearanceColorScheme)
+	assert.Equal(t, errPortalNotFound, derr)
+}
+
+func TestManager_SetTheme(t *testing.T) {
+	m := &Manager{state: &FreedeskState{}}
+	ch := m.Subscribe("test")
+
+	m.SetTheme(true, "#112233")
+	assert.Equal(t, Appearance{ColorScheme: colorSchemePreferLight, AccentColor: "#112233"}, m.GetAppearance())
+	select {
+	case state := <-ch:
+		assert.Equal(t, "#112233", state.Portal.Appearance.AccentColor)
+	default:
+		t.Fatal("expected a state update")
+	}
+
+	// A build without a primary keeps the previous accent.
+	m.SetTheme(false, "")
+	assert.Equal(t, Appearance{ColorScheme: colorSchemePreferDark, AccentColor: "#112233"}, m.GetAppearance())
+	<-ch
+
+	m.SetTheme(false, "#112233")
+	select {
+	case <-ch:
+		t.Fatal("unchanged appearance must not notify")
+	default:
+	}
+	assert.Empty(t, m.selfEchoes, "no echo is expected while the portal name is not ours")
+}
+
+func TestChangedAppearanceKeys(t *testing.T) {
+	old := Appearance{ColorScheme: colorSchemePreferDark, AccentColor: "#ff0000"}
+	assert.Empty(t, changedAppearanceKeys(old, old))
+
+	updated := old
+	updated.ColorScheme = colorSchemePreferLight
+	updated.AccentColor = "#FF0000"
+	updated.Contrast = 1
+	assert.Equal(t, []string{appearanceColorScheme, appearanceContrast}, changedAppearanceKeys(old, updated),
+		"hex case differences are not a change")
+}
+
+func TestLoadShellAppearance(t *testing.T) {
+	setShellDirs(t)
+	assert.Equal(t, Appearance{}, loadShellAppearance(), "missing files leave everything at no preference")
+
+	writeShellFile(t, shellSettingsPath(), `{"animationSpeed": 0, "matugenContrast": 0.6}`)
+	writeShellFile(t, shellSessionPath(), `{"isLightMode": true}`)
+	writeShellFile(t, shellColorsPath(), `{"colors": {"dark": {"primary": "#aaaaaa"}, "light": {"primary": "#222222"}}}`)
+
+	assert.Equal(t, Appearance{
+		ColorScheme:   colorSchemePreferLight,
+		AccentColor:   "#222222",
+		Contrast:      1,
+		ReducedMotion: 1,
+	}, loadShellAppearance())
+
+	writeShellFile(t, shellSettingsPath(), `{"animationSpeed": 2, "matugenContrast": 0.2}`)
+	a := Appearance{Contrast: 1, ReducedMotion: 1}
+	applyShellSettings(&a, shellSettingsPath())
+	assert.Equal(t, Appearance{}, a)
+
+	writeShellFile(t, shellSettingsPath(), `{`)
+	a = Appearance{ReducedMotion: 1}
+	applyShellSettings(&a, shellSettingsPath())
+	assert.Equal(t, uint32(1), a.ReducedMotion, "an unreadable file keeps the previous values")
+}
@@ -35,6 +35,22 @@ type SettingsState struct {
 	ColorScheme uint32 `json:"colorScheme"`
 }
 
+// Appearance is what the portal Settings backend serves under the
+// org.freedesktop.appearance namespace.
+type Appearance struct {
+	// ColorScheme is 0 (no preference), 1 (prefer dark) or 2 (prefer light).
+	ColorScheme uint32 `json:"colorScheme"`
+	// AccentColor is the matugen primary as #rrggbb, empty when unknown.
+	AccentColor   string `json:"accentColor"`
+	Contrast      uint32 `json:"contrast"`
+	ReducedMotion uint32 `json:"reducedMotion"`
+}
+
+type PortalState struct {
+	Available  bool       `json:"available"`
+	Appearance Appearance `json:"appearance"`
+}
+
 type ScreensaverInhibitor struct {
 	Cookie    uint32 `json:"cookie"`
 	AppName   string `json:"appName"`
@@ -54,6 +70,7 @@ type FreedeskState struct {
 	Accounts    AccountsState    `json:"accounts"`
 	Settings    SettingsState    `json:"settings"`
 	Screensaver ScreensaverState `json:"screensaver"`
+	Portal      PortalState      `json:"portal"`
 }
 
 type Manager struct {
@@ -73,4 +90,11 @@ type Manager struct {
 	selfEchoes                    []colorSchemeEcho
 	// registered on sessionConn by watchSettingsChanges; guarded by stateMutex
 	settingsSignals chan *dbus.Signal
+	portalClaimed   bool
+	// guarded by stateMutex
+	captureEngine CaptureEngine
+	// held while a screenshot or color picker overlay is showing
+	overlayMu sync.Mutex
+	stopChan  chan struct{}
+	closeOnce sync.Once
 }
@@ -0,0 +1,91 @@
+package idle
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/AvengeMedia/DankMaterialShell/core/internal/server/models"
+	"github.com/AvengeMedia/dankgo/ipc/params"
+)
+
+func HandleRequest(conn *models.Conn, req models.Request, m *Manager) {
+	switch req.Method {
+	case "idle.getState":
+		models.Respond(conn, req.ID, m.GetState())
+	case "idle.setEnabled":
+		handleSetEnabled(conn, req, m)
+	case "idle.setConfig":
+		handleSetConfig(conn, req, m)
+	case "idle.subscribe":
+		handleSubscribe(conn, req, m)
+	default:
+		models.RespondError(conn, req.ID, "unknown method: "+req.Method)
+	}
+}
+
+func handleSetEnabled(conn *models.Conn, req models.Request, m *Manager) {
+	enabled, err := params.Bool(req.Params, "enabled")
+	if err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+
+	if err := m.SetEnabled(enabled); err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+
+	models.Respond(conn, req.ID, m.GetState())
+}
+
+// handleSetConfig merges the given fields over the current config.
+func handleSetConfig(conn *models.Conn, req models.Request, m *Manager) {
+	configParam, ok := models.Get[any](req, "config")
+	if !ok {
+		models.RespondError(conn, req.ID, "missing or invalid 'config' parameter")
+		return
+	}
+
+	data, err := json.Marshal(configParam)
+	if err != nil {
+		models.RespondError(conn, req.ID, "missing or invalid 'config' parameter")
+		return
+	}
+
+	cfg := m.GetState().Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		models.RespondError(conn, req.ID, fmt.Sprintf("invalid idle config: %v", err))
+		return
+	}
+
+	if err := m.SetConfig(cfg); err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+
+	models.Respond(conn, req.ID, m.GetState())
+}
+
+func handleSubscribe(conn *models.Conn, req models.Request, m *Manager) {
+	clientID := fmt.Sprintf("idle-%d", req.ID)
+
+	ch := m.Subscribe(clientID)
+	defer m.Unsubscribe(clientID)
+
+	initialState := m.GetState()
+	if err := conn.WriteResponse(models.Response[State]{
+		ID:     req.ID,
+		Result: &initialState,
+	}); err != nil {
+		return
+	}
+
+	for state := range ch {
+		if err := conn.WriteResponse(models.Response[State]{
+			ID:     req.ID,
+			Result: &state,
+		}); err != nil {
+			return
+		}
+	}
+}
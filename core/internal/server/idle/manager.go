@@ -0,0 +1,692 @@
+package idle
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/AvengeMedia/DankMaterialShell/core/internal/log"
+	"github.com/AvengeMedia/DankMaterialShell/core/internal/server/brightness"
+	"github.com/AvengeMedia/DankMaterialShell/core/internal/server/freedesktop"
+	"github.com/AvengeMedia/DankMaterialShell/core/internal/server/loginctl"
+	"github.com/AvengeMedia/DankMaterialShell/core/internal/server/wlcontext"
+	"github.com/AvengeMedia/dankgo/syncmap"
+)
+
+const (
+	ActionDim     = "dim"
+	ActionLock    = "lock"
+	ActionDPMS    = "dpms"
+	ActionSuspend = "suspend"
+)
+
+const (
+	ProfileAC      = "ac"
+	ProfileBattery = "battery"
+)
+
+const maxStageTimeout = 24 * 60 * 60
+
+var validActions = []string{ActionDim, ActionLock, ActionDPMS, ActionSuspend}
+
+// pollInterval is how often the power source is re-read; it has no change
+// signal we can rely on.
+var pollInterval = 5 * time.Second
+
+// Stage runs Action once the seat has been idle for Timeout seconds.
+type Stage struct {
+	Action  string `json:"action"`
+	Timeout int    `json:"timeout"`
+}
+
+// Config is persisted to idle.json. An empty Battery profile reuses AC.
+// DimPercent is relative to the level before dimming; FadeTime (ms) applies
+// to both dimming and undimming.
+type Config struct {
+	Enabled           bool    `json:"enabled"`
+	AC                []Stage `json:"ac"`
+	Battery           []Stage `json:"battery"`
+	DimPercent        int     `json:"dimPercent"`
+	FadeTime          int     `json:"fadeTime"`
+	RespectInhibitors bool    `json:"respectInhibitors"`
+}
+
+type State struct {
+	Config
+	Available bool   `json:"available"`
+	Profile   string `json:"profile"`
+	// Stage is the deepest stage whose action ran, or "" while active.
+	Stage      string   `json:"stage"`
+	Idle       bool     `json:"idle"`
+	Inhibited  bool     `json:"inhibited"`
+	Inhibitors []string `json:"inhibitors"`
+}
+
+// Notifier arms one compositor idle timer per stage. Callbacks may run on
+// any goroutine.
+type Notifier interface {
+	Arm(timeouts []time.Duration, idled, resumed func(stage int))
+	SetDPMS(on bool)
+	Close()
+}
+
+// Session is the subset of loginctl.Manager the stages use.
+type Session interface {
+	GetState() loginctl.SessionState
+	Lock() error
+	Suspend() error
+	SetIdleHint(idle bool) error
+}
+
+// Backlight is the subset of brightness.Manager used to dim.
+type Backlight interface {
+	DimBacklights(percent int, fade time.Duration) error
+	UndimBacklights(fade time.Duration) error
+}
+
+type Manager struct {
+	notifier  Notifier
+	onBattery func() bool
+
+	mu          sync.Mutex
+	config      Config
+	session     Session
+	backlight   Backlight
+	battery     bool
+	screensaver []string
+	logind      []string
+
+	// Armed stages, sorted by timeout. gen discards callbacks from
+	// notifications that were replaced by a later Arm.
+	gen      int
+	stages   []Stage
+	idled    []bool
+	applied  []bool
+	idleHint bool
+	rearm    bool
+
+	events      chan func()
+	subscribers syncmap.Map[string, chan State]
+	stopChan    chan struct{}
+	wg          sync.WaitGroup
+	closeOnce   sync.Once
+}
+
+func defaultConfig() Config {
+	return Config{
+		AC: []Stage{
+			{Action: ActionDim, Timeout: 120},
+			{Action: ActionLock, Timeout: 300},
+			{Action: ActionDPMS, Timeout: 360},
+		},
+		Battery: []Stage{
+			{Action: ActionDim, Timeout: 120},
+			{Action: ActionLock, Timeout: 300},
+			{Action: ActionDPMS, Timeout: 360},
+			{Action: ActionSuspend, Timeout: 1800},
+		},
+		DimPercent:        30,
+		FadeTime:          500,
+		RespectInhibitors: true,
+	}
+}
+
+func configPath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "DankMaterialShell", "idle.json"), nil
+}
+
+func loadConfig() Config {
+	cfg := defaultConfig()
+	path, err := configPath()
+	if err != nil {
+		return cfg
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		log.Warnf("Invalid idle config %s: %v", path, err)
+		return defaultConfig()
+	}
+	if err := cfg.validate(); err != nil {
+		log.Warnf("Invalid idle config %s: %v", path, err)
+		return defaultConfig()
+	}
+	return cfg
+}
+
+func saveConfig(cfg Config) error {
+	path, err := configPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+func validateStages(profile string, stages []Stage) error {
+	seen := make(map[string]bool, len(stages))
+	for _, stage := range stages {
+		if !slices.Contains(validActions, stage.Action) {
+			return fmt.Errorf("%s: unknown action: %s", profile, stage.Action)
+		}
+		if seen[stage.Action] {
+			return fmt.Errorf("%s: duplicate %s stage", profile, stage.Action)
+		}
+		seen[stage.Action] = true
+		if stage.Timeout < 1 || stage.Timeout > maxStageTimeout {
+			return fmt.Errorf("%s: %s timeout must be between 1 and %d seconds", profile, stage.Action, maxStageTimeout)
+		}
+	}
+	return nil
+}
+
+func (cfg Config) validate() error {
+	if err := validateStages(ProfileAC, cfg.AC); err != nil {
+		return err
+	}
+	if err := validateStages(ProfileBattery, cfg.Battery); err != nil {
+		return err
+	}
+	switch {
+	case cfg.DimPercent < 0 || cfg.DimPercent > 100:
+		return fmt.Errorf("dimPercent must be between 0 and 100")
+	case cfg.FadeTime < 0 || cfg.FadeTime > 10000:
+		return fmt.Errorf("fadeTime must be between 0 and 10000 ms")
+	}
+	return nil
+}
+
+// profileStages returns the stages for the power source, sorted by timeout.
+func (cfg Config) profileStages(battery bool) []Stage {
+	stages := cfg.AC
+	if battery && len(cfg.Battery) > 0 {
+		stages = cfg.Battery
+	}
+	stages = slices.Clone(stages)
+	sort.SliceStable(stages, func(i, j int) bool { return stages[i].Timeout < stages[j].Timeout })
+	return stages
+}
+
+// NewManager binds ext-idle-notify on the shared Wayland connection. It
+// must run before the context's dispatcher starts.
+func NewManager(wlCtx wlcontext.WaylandContext) (*Manager, error) {
+	notifier, err := newWaylandNotifier(wlCtx)
+	if err != nil {
+		return nil, err
+	}
+	return newManager(notifier, loadConfig(), brightness.OnBattery), nil
+}
+
+func newManager(notifier Notifier, cfg Config, onBattery func() bool) *Manager {
+	m := &Manager{
+		notifier:  notifier,
+		onBattery: onBattery,
+		config:    cfg,
+		battery:   onBattery(),
+		events:    make(chan func(), 64),
+		stopChan:  make(chan struct{}),
+	}
+
+	m.arm()
+
+	m.wg.Add(1)
+	go m.run()
+	return m
+}
+
+// run serializes stage transitions and their side effects.
+func (m *Manager) run() {
+	defer m.wg.Done()
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.stopChan:
+			return
+		case fn := <-m.events:
+			fn()
+		case <-ticker.C:
+			m.poll()
+		}
+	}
+}
+
+func (m *Manager) post(fn func()) {
+	select {
+	case m.events <- fn:
+	case <-m.stopChan:
+	}
+}
+
+func (m *Manager) arm() {
+	m.mu.Lock()
+	var stages []Stage
+	if m.config.Enabled {
+		stages = m.config.profileStages(m.battery)
+	}
+	m.gen++
+	gen := m.gen
+	m.stages = stages
+	m.idled = make([]bool, len(stages))
+	m.applied = make([]bool, len(stages))
+	m.rearm = false
+	m.mu.Unlock()
+
+	timeouts := make([]time.Duration, len(stages))
+	for i, stage := range stages {
+		timeouts[i] = time.Duration(stage.Timeout) * time.Second
+	}
+	m.notifier.Arm(timeouts,
+		func(stage int) { m.post(func() { m.handleIdled(gen, stage) }) },
+		func(stage int) { m.post(func() { m.handleResumed(gen, stage) }) },
+	)
+}
+
+// requestRearm restarts the timers now, or once the user is back if a stage
+// already ran; re-arming then would strand a dimmed or blanked screen.
+func (m *Manager) requestRearm() {
+	m.mu.Lock()
+	busy := slices.Contains(m.applied, true)
+	if busy {
+		m.rearm = true
+	}
+	m.mu.Unlock()
+
+	if !busy {
+		m.arm()
+	}
+}
+
+func (m *Manager) handleIdled(gen, i int) {
+	m.mu.Lock()
+	if gen != m.gen || i >= len(m.stages) || m.idled[i] {
+		m.mu.Unlock()
+		return
+	}
+	m.idled[i] = true
+	stage := m.stages[i]
+	m.mu.Unlock()
+
+	if blockers := m.inhibitors(); len(blockers) > 0 {
+		log.Infof("Idle: %s stage inhibited by %v", stage.Action, blockers)
+		m.notifySubscribers()
+		return
+	}
+
+	m.mu.Lock()
+	if gen != m.gen || !m.idled[i] {
+		m.mu.Unlock()
+		return
+	}
+	m.applied[i] = true
+	setHint := !m.idleHint
+	m.idleHint = true
+	session := m.session
+	m.mu.Unlock()
+
+	log.Infof("Idle: entering %s stage after %ds", stage.Action, stage.Timeout)
+	if setHint && session != nil {
+		if err := session.SetIdleHint(true); err != nil {
+			log.Debugf("Idle: failed to set idle hint: %v", err)
+		}
+	}
+	m.enter(stage)
+	m.notifySubscribers()
+}
+
+func (m *Manager) handleResumed(gen, i int) {
+	m.mu.Lock()
+	if gen != m.gen || i >= len(m.stages) || !m.idled[i] {
+		m.mu.Unlock()
+		return
+	}
+	m.idled[i] = false
+	undo := m.applied[i]
+	m.applied[i] = false
+	stage := m.stages[i]
+	active := !slices.Contains(m.idled, true)
+	clearHint := active && m.idleHint
+	if clearHint {
+		m.idleHint = false
+	}
+	rearm := active && m.rearm
+	session := m.session
+	m.mu.Unlock()
+
+	if undo {
+		log.Debugf("Idle: leaving %s stage", stage.Action)
+		m.leave(stage)
+	}
+	if clearHint && session != nil {
+		if err := session.SetIdleHint(false); err != nil {
+			log.Debugf("Idle: failed to clear idle hint: %v", err)
+		}
+	}
+	if rearm {
+		m.arm()
+	}
+	m.notifySubscribers()
+}
+
+func (m *Manager) fade() time.Duration {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return time.Duration(m.config.FadeTime) * time.Millisecond
+}
+
+func (m *Manager) enter(stage Stage) {
+	m.mu.Lock()
+	session, backlight, dimPercent := m.session, m.backlight, m.config.DimPercent
+	m.mu.Unlock()
+
+	var err error
+	switch stage.Action {
+	case ActionDim:
+		if backlight != nil {
+			err = backlight.DimBacklights(dimPercent, m.fade())
+		}
+	case ActionLock:
+		if session != nil && !session.GetState().Locked {
+			err = session.Lock()
+		}
+	case ActionDPMS:
+		m.notifier.SetDPMS(false)
+	case ActionSuspend:
+		if session != nil {
+			err = session.Suspend()
+		}
+	}
+	if err != nil {
+		log.Warnf("Idle: %s stage failed: %v", stage.Action, err)
+	}
+}
+
+// leave undoes reversible stages; a lock or suspend stays done.
+func (m *Manager) leave(stage Stage) {
+	m.mu.Lock()
+	backlight := m.backlight
+	m.mu.Unlock()
+
+	switch stage.Action {
+	case ActionDim:
+		if backlight != nil {
+			if err := backlight.UndimBacklights(m.fade()); err != nil {
+				log.Warnf("Idle: failed to undim: %v", err)
+			}
+		}
+	case ActionDPMS:
+		m.notifier.SetDPMS(true)
+	}
+}
+
+// inhibitors returns everything that currently holds off the stages.
+func (m *Manager) inhibitors() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.config.RespectInhibitors {
+		return nil
+	}
+	return append(slices.Clone(m.screensaver), m.logind...)
+}
+
+func (m *Manager) poll() {
+	m.mu.Lock()
+	enabled := m.config.Enabled
+	m.mu.Unlock()
+	if !enabled {
+		return
+	}
+
+	changed := false
+	battery := m.onBattery()
+	m.mu.Lock()
+	if battery != m.battery {
+		m.battery = battery
+		changed = true
+		log.Infof("Idle: switching to %s profile", m.profileLocked())
+	}
+	m.mu.Unlock()
+
+	if changed {
+		m.requestRearm()
+		m.notifySubscribers()
+	}
+}
+
+func (m *Manager) profileLocked() string {
+	if m.battery && len(m.config.Battery) > 0 {
+		return ProfileBattery
+	}
+	return ProfileAC
+}
+
+func (m *Manager) setScreensaverInhibitors(state freedesktop.ScreensaverState) {
+	inhibitors := make([]string, 0, len(state.Inhibitors))
+	for _, inhibitor := range state.Inhibitors {
+		inhibitors = append(inhibitors, fmt.Sprintf("%s: %s", inhibitor.AppName, inhibitor.Reason))
+	}
+
+	m.mu.Lock()
+	released := len(m.screensaver) > 0 && len(inhibitors) == 0 && len(m.logind) == 0
+	m.screensaver = inhibitors
+	m.mu.Unlock()
+
+	// Timers kept running while inhibited; restart them so the stages count
+	// from the release rather than firing at once.
+	if released {
+		m.requestRearm()
+	}
+	m.notifySubscribers()
+}
+
+// setLogindInhibitors takes the idle blockers from the inhibitors loginctl
+// tracks.
+func (m *Manager) setLogindInhibitors(inhibitors []loginctl.Inhibitor) {
+	blockers := make([]string, 0)
+	for _, inhibitor := range inhibitors {
+		if inhibitor.Blocks("idle") {
+			blockers = append(blockers, fmt.Sprintf("%s: %s", inhibitor.Who, inhibitor.Why))
+		}
+	}
+
+	m.mu.Lock()
+	if slices.Equal(m.logind, blockers) {
+		m.mu.Unlock()
+		return
+	}
+	released := len(m.logind) > 0 && len(blockers) == 0 && len(m.screensaver) == 0
+	m.logind = blockers
+	m.mu.Unlock()
+
+	if released {
+		m.requestRearm()
+	}
+	m.notifySubscribers()
+}
+
+func (m *Manager) SetConfig(cfg Config) error {
+	if cfg.AC == nil {
+		cfg.AC = []Stage{}
+	}
+	if cfg.Battery == nil {
+		cfg.Battery = []Stage{}
+	}
+	if err := cfg.validate(); err != nil {
+		return err
+	}
+	if err := saveConfig(cfg); err != nil {
+		log.Warnf("Failed to save idle config: %v", err)
+	}
+
+	done := make(chan struct{})
+	m.post(func() {
+		defer close(done)
+		m.mu.Lock()
+		m.config = cfg
+		var undo []Stage
+		if !cfg.Enabled {
+			for i, applied := range m.applied {
+				if applied {
+					undo = append(undo, m.stages[i])
+					m.applied[i] = false
+				}
+			}
+		}
+		m.mu.Unlock()
+
+		for _, stage := range undo {
+			m.leave(stage)
+		}
+		m.requestRearm()
+	})
+	select {
+	case <-done:
+	case <-m.stopChan:
+	}
+
+	m.notifySubscribers()
+	return nil
+}
+
+func (m *Manager) SetEnabled(enabled bool) error {
+	cfg := m.GetState().Config
+	cfg.Enabled = enabled
+	return m.SetConfig(cfg)
+}
+
+func (m *Manager) GetState() State {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	state := State{
+		Config:     m.config,
+		Available:  true,
+		Profile:    m.profileLocked(),
+		Inhibitors: append(slices.Clone(m.screensaver), m.logind...),
+	}
+	state.AC = slices.Clone(m.config.AC)
+	state.Battery = slices.Clone(m.config.Battery)
+	if state.Inhibitors == nil {
+		state.Inhibitors = []string{}
+	}
+	state.Inhibited = m.config.RespectInhibitors && len(state.Inhibitors) > 0
+	for i, stage := range m.stages {
+		state.Idle = state.Idle || m.idled[i]
+		if m.applied[i] {
+			state.Stage = stage.Action
+		}
+	}
+	return state
+}
+
+func (m *Manager) Subscribe(id string) chan State {
+	ch := make(chan State, 16)
+	m.subscribers.Store(id, ch)
+	return ch
+}
+
+func (m *Manager) Unsubscribe(id string) {
+	if val, ok := m.subscribers.LoadAndDelete(id); ok {
+		close(val)
+	}
+}
+
+func (m *Manager) notifySubscribers() {
+	state := m.GetState()
+	m.subscribers.Range(func(key string, ch chan State) bool {
+		select {
+		case ch <- state:
+		default:
+		}
+		return true
+	})
+}
+
+// WatchLoginctl lets stages lock, suspend and set the session idle hint,
+// and makes the logind idle inhibitors in its state hold the stages off.
+func (m *Manager) WatchLoginctl(lm *loginctl.Manager) {
+	m.mu.Lock()
+	m.session = lm
+	m.mu.Unlock()
+
+	ch := lm.Subscribe("idle")
+	initial := lm.GetState()
+	m.post(func() { m.setLogindInhibitors(initial.Inhibitors) })
+
+	go func() {
+		defer lm.Unsubscribe("idle")
+		for {
+			select {
+			case <-m.stopChan:
+				return
+			case state, ok := <-ch:
+				if !ok {
+					return
+				}
+				m.post(func() { m.setLogindInhibitors(state.Inhibitors) })
+			}
+		}
+	}()
+}
+
+// WatchBrightness lets the dim stage fade the backlights.
+func (m *Manager) WatchBrightness(bm *brightness.Manager) {
+	m.mu.Lock()
+	m.backlight = bm
+	m.mu.Unlock()
+}
+
+// WatchFreedesktop makes org.freedesktop.ScreenSaver inhibitors hold the
+// stages off.
+func (m *Manager) WatchFreedesktop(fm *freedesktop.Manager) {
+	ch := fm.SubscribeScreensaver("idle")
+	initial := fm.GetScreensaverState()
+	m.post(func() { m.setScreensaverInhibitors(initial) })
+
+	go func() {
+		defer fm.UnsubscribeScreensaver("idle")
+		for {
+			select {
+			case <-m.stopChan:
+				return
+			case state, ok := <-ch:
+				if !ok {
+					return
+				}
+				m.post(func() { m.setScreensaverInhibitors(state) })
+			}
+		}
+	}()
+}
+
+func (m *Manager) Close() {
+	m.closeOnce.Do(func() {
+		close(m.stopChan)
+		m.wg.Wait()
+		m.notifier.Close()
+
+		m.subscribers.Range(func(key string, ch chan State) bool {
+			close(ch)
+			m.subscribers.Delete(key)
+			return true
+		})
+	})
+}
@@ -0,0 +1,270 @@
+package idle
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AvengeMedia/DankMaterialShell/core/internal/server/freedesktop"
+	"github.com/AvengeMedia/DankMaterialShell/core/internal/server/loginctl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeNotifier struct {
+	mu       sync.Mutex
+	timeouts []time.Duration
+	idled    func(int)
+	resumed  func(int)
+	arms     int
+	dpms     []bool
+}
+
+func (n *fakeNotifier) Arm(timeouts []time.Duration, idled, resumed func(int)) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.timeouts, n.idled, n.resumed = timeouts, idled, resumed
+	n.arms++
+}
+
+func (n *fakeNotifier) SetDPMS(on bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.dpms = append(n.dpms, on)
+}
+
+func (n *fakeNotifier) Close() {}
+
+func (n *fakeNotifier) armed() ([]time.Duration, int) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.timeouts, n.arms
+}
+
+func (n *fakeNotifier) idle(stage int) {
+	n.mu.Lock()
+	fn := n.idled
+	n.mu.Unlock()
+	fn(stage)
+}
+
+func (n *fakeNotifier) resume(stage int) {
+	n.mu.Lock()
+	fn := n.resumed
+	n.mu.Unlock()
+	fn(stage)
+}
+
+type fakeSession struct {
+	mu       sync.Mutex
+	locked   bool
+	suspends int
+	hints    []bool
+}
+
+func (s *fakeSession) GetState() loginctl.SessionState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return loginctl.SessionState{Locked: s.locked}
+}
+
+func (s *fakeSession) Lock() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.locked = true
+	return nil
+}
+
+func (s *fakeSession) Suspend() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.suspends++
+	return nil
+}
+
+func (s *fakeSession) SetIdleHint(idle bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hints = append(s.hints, idle)
+	return nil
+}
+
+type fakeBacklight struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (b *fakeBacklight) DimBacklights(percent int, fade time.Duration) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.calls = append(b.calls, "dim")
+	return nil
+}
+
+func (b *fakeBacklight) UndimBacklights(fade time.Duration) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.calls = append(b.calls, "undim")
+	return nil
+}
+
+func (b *fakeBacklight) history() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]string(nil), b.calls...)
+}
+
+// flush waits until the actor has drained everything queued so far.
+func flush(m *Manager) {
+	done := make(chan struct{})
+	m.post(func() { close(done) })
+	<-done
+}
+
+func newTestManager(t *testing.T) (*Manager, *fakeNotifier, *fakeSession, *fakeBacklight) {
+	t.Helper()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	cfg := defaultConfig()
+	cfg.Enabled = true
+	notifier := &fakeNotifier{}
+	m := newManager(notifier, cfg, func() bool { return false })
+	session := &fakeSession{}
+	backlight := &fakeBacklight{}
+	m.session = session
+	m.backlight = backlight
+	t.Cleanup(m.Close)
+	return m, notifier, session, backlight
+}
+
+func TestManager_StagesEnterAndLeave(t *testing.T) {
+	m, notifier, session, backlight := newTestManager(t)
+
+	timeouts, _ := notifier.armed()
+	assert.Equal(t, []time.Duration{2 * time.Minute, 5 * time.Minute, 6 * time.Minute}, timeouts)
+
+	ch := m.Subscribe("test")
+	notifier.idle(0)
+	flush(m)
+	state := <-ch
+	assert.Equal(t, ActionDim, state.Stage)
+	assert.True(t, state.Idle)
+	assert.Equal(t, []string{"dim"}, backlight.history())
+
+	notifier.idle(1)
+	notifier.idle(2)
+	flush(m)
+	assert.Equal(t, ActionDPMS, m.GetState().Stage)
+	assert.True(t, session.GetState().Locked)
+	assert.Equal(t, []bool{false}, notifier.dpms)
+
+	for i := range 3 {
+		notifier.resume(i)
+	}
+	flush(m)
+	state = m.GetState()
+	assert.Empty(t, state.Stage)
+	assert.False(t, state.Idle)
+	assert.Equal(t, []string{"dim", "undim"}, backlight.history())
+	assert.Equal(t, []bool{false, true}, notifier.dpms)
+	assert.True(t, session.GetState().Locked, "resuming does not unlock")
+	assert.Equal(t, []bool{true, false}, session.hints)
+}
+
+func TestManager_Inhibitors(t *testing.T) {
+	m, notifier, _, backlight := newTestManager(t)
+
+	m.post(func() {
+		m.setScreensaverInhibitors(freedesktop.ScreensaverState{
+			Inhibitors: []freedesktop.ScreensaverInhibitor{{AppName: "mpv", Reason: "Playing"}},
+		})
+	})
+	notifier.idle(0)
+	flush(m)
+	state := m.GetState()
+	assert.True(t, state.Inhibited)
+	assert.Equal(t, []string{"mpv: Playing"}, state.Inhibitors)
+	assert.Empty(t, state.Stage)
+	assert.Empty(t, backlight.history())
+
+	_, arms := notifier.armed()
+	m.post(func() { m.setScreensaverInhibitors(freedesktop.ScreensaverState{}) })
+	flush(m)
+	_, rearmed := notifier.armed()
+	assert.Equal(t, arms+1, rearmed, "releasing the last inhibitor restarts the timers")
+
+	m.post(func() {
+		m.setLogindInhibitors([]loginctl.Inhibitor{
+			{What: "sleep", Who: "updater", Why: "Installing", Mode: "delay"},
+			{What: "idle:sleep", Who: "Firefox", Why: "Video", Mode: "block"},
+		})
+	})
+	notifier.idle(0)
+	flush(m)
+	state = m.GetState()
+	assert.Equal(t, []string{"Firefox: Video"}, state.Inhibitors)
+	assert.Empty(t, state.Stage)
+
+	cfg := m.GetState().Config
+	cfg.RespectInhibitors = false
+	require.NoError(t, m.SetConfig(cfg))
+	notifier.idle(0)
+	flush(m)
+	assert.Equal(t, ActionDim, m.GetState().Stage)
+}
+
+func TestManager_BatteryProfile(t *testing.T) {
+	m, notifier, _, _ := newTestManager(t)
+	assert.Equal(t, ProfileAC, m.GetState().Profile)
+
+	notifier.idle(0)
+	flush(m)
+
+	m.post(func() {
+		m.onBattery = func() bool { return true }
+		m.poll()
+	})
+	flush(m)
+
+	timeouts, _ := notifier.armed()
+	assert.Len(t, timeouts, 3, "re-arm waits until the user is back")
+	assert.Equal(t, ProfileBattery, m.GetState().Profile)
+
+	notifier.resume(0)
+	flush(m)
+	timeouts, _ = notifier.armed()
+	assert.Equal(t, []time.Duration{2 * time.Minute, 5 * time.Minute, 6 * time.Minute, 30 * time.Minute}, timeouts)
+}
+
+func TestManager_SetConfig(t *testing.T) {
+	m, notifier, _, backlight := newTestManager(t)
+
+	notifier.idle(0)
+	flush(m)
+	require.NoError(t, m.SetEnabled(false))
+	assert.Equal(t, []string{"dim", "undim"}, backlight.history(), "disabling undoes applied stages")
+	timeouts, _ := notifier.armed()
+	assert.Empty(t, timeouts)
+
+	cfg := defaultConfig()
+	cfg.Enabled = true
+	cfg.AC = []Stage{{Action: ActionLock, Timeout: 60}, {Action: ActionDim, Timeout: 30}}
+	require.NoError(t, m.SetConfig(cfg))
+	timeouts, _ = notifier.armed()
+	assert.Equal(t, []time.Duration{30 * time.Second, time.Minute}, timeouts, "stages are armed in timeout order")
+	assert.Equal(t, cfg, loadConfig())
+
+	for name, mutate := range map[string]func(*Config){
+		"action": func(c *Config) { c.AC = []Stage{{Action: "hibernate", Timeout: 60}} },
+		"duplicate": func(c *Config) {
+			c.Battery = []Stage{{Action: ActionDim, Timeout: 60}, {Action: ActionDim, Timeout: 90}}
+		},
+		"timeout":     func(c *Config) { c.AC = []Stage{{Action: ActionDim, Timeout: 0}} },
+		"dim percent": func(c *Config) { c.DimPercent = 101 },
+		"fade time":   func(c *Config) { c.FadeTime = -1 },
+	} {
+		bad := defaultConfig()
+		mutate(&bad)
+		assert.Error(t, m.SetConfig(bad), name)
+	}
+}
@@ -0,0 +1,150 @@
+package idle
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/AvengeMedia/DankMaterialShell/core/internal/log"
+	"github.com/AvengeMedia/DankMaterialShell/core/internal/proto/ext_idle_notify"
+	"github.com/AvengeMedia/DankMaterialShell/core/internal/proto/wlr_output_power"
+	"github.com/AvengeMedia/DankMaterialShell/core/internal/server/wlcontext"
+	wlclient "github.com/AvengeMedia/dankgo/wayland/client"
+)
+
+// waylandNotifier drives ext_idle_notifier_v1 and, for the DPMS stage,
+// zwlr_output_power_manager_v1. Everything after setup runs on the shared
+// context's dispatcher via Post.
+type waylandNotifier struct {
+	wlCtx    wlcontext.WaylandContext
+	notifier *ext_idle_notify.ExtIdleNotifierV1
+	seat     *wlclient.Seat
+	powerMgr *wlr_output_power.ZwlrOutputPowerManagerV1
+	outputs  map[uint32]*wlclient.Output
+
+	notifications []*ext_idle_notify.ExtIdleNotificationV1
+}
+
+func newWaylandNotifier(wlCtx wlcontext.WaylandContext) (*waylandNotifier, error) {
+	display := wlCtx.Display()
+	ctx := display.Context()
+
+	registry, err := display.GetRegistry()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get registry: %w", err)
+	}
+
+	n := &waylandNotifier{
+		wlCtx:   wlCtx,
+		outputs: make(map[uint32]*wlclient.Output),
+	}
+
+	registry.SetGlobalHandler(func(e wlclient.RegistryGlobalEvent) {
+		switch e.Interface {
+		case ext_idle_notify.ExtIdleNotifierV1InterfaceName:
+			notifier := ext_idle_notify.NewExtIdleNotifierV1(ctx)
+			if err := registry.Bind(e.Name, e.Interface, min(e.Version, 1), notifier); err != nil {
+				log.Errorf("Failed to bind ext_idle_notifier_v1: %v", err)
+				return
+			}
+			n.notifier = notifier
+		case "wl_seat":
+			if n.seat != nil {
+				return
+			}
+			seat := wlclient.NewSeat(ctx)
+			if err := registry.Bind(e.Name, e.Interface, min(e.Version, 1), seat); err != nil {
+				log.Errorf("Failed to bind wl_seat: %v", err)
+				return
+			}
+			n.seat = seat
+		case wlr_output_power.ZwlrOutputPowerManagerV1InterfaceName:
+			powerMgr := wlr_output_power.NewZwlrOutputPowerManagerV1(ctx)
+			if err := registry.Bind(e.Name, e.Interface, min(e.Version, 1), powerMgr); err != nil {
+				log.Errorf("Failed to bind zwlr_output_power_manager_v1: %v", err)
+				return
+			}
+			n.powerMgr = powerMgr
+		case "wl_output":
+			output := wlclient.NewOutput(ctx)
+			if err := registry.Bind(e.Name, e.Interface, min(e.Version, 4), output); err != nil {
+				log.Errorf("Failed to bind wl_output: %v", err)
+				return
+			}
+			n.outputs[e.Name] = output
+		}
+	})
+
+	registry.SetGlobalRemoveHandler(func(e wlclient.RegistryGlobalRemoveEvent) {
+		delete(n.outputs, e.Name)
+	})
+
+	display.Roundtrip()
+	display.Roundtrip()
+
+	if n.notifier == nil {
+		return nil, fmt.Errorf("compositor does not support ext_idle_notifier_v1")
+	}
+	if n.seat == nil {
+		return nil, fmt.Errorf("no seat available")
+	}
+	if n.powerMgr == nil {
+		log.Warn("Idle: compositor does not support wlr-output-power-management, dpms stage disabled")
+	}
+
+	return n, nil
+}
+
+func (n *waylandNotifier) Arm(timeouts []time.Duration, idled, resumed func(stage int)) {
+	n.wlCtx.Post(func() {
+		n.destroyNotifications()
+		for i, timeout := range timeouts {
+			notification, err := n.notifier.GetIdleNotification(uint32(timeout.Milliseconds()), n.seat)
+			if err != nil {
+				log.Warnf("Idle: failed to create %s notification: %v", timeout, err)
+				continue
+			}
+			notification.SetIdledHandler(func(ext_idle_notify.ExtIdleNotificationV1IdledEvent) { idled(i) })
+			notification.SetResumedHandler(func(ext_idle_notify.ExtIdleNotificationV1ResumedEvent) { resumed(i) })
+			n.notifications = append(n.notifications, notification)
+		}
+	})
+}
+
+func (n *waylandNotifier) destroyNotifications() {
+	for _, notification := range n.notifications {
+		notification.Destroy()
+	}
+	n.notifications = nil
+}
+
+func (n *waylandNotifier) SetDPMS(on bool) {
+	mode := uint32(wlr_output_power.ZwlrOutputPowerV1ModeOff)
+	if on {
+		mode = uint32(wlr_output_power.ZwlrOutputPowerV1ModeOn)
+	}
+
+	n.wlCtx.Post(func() {
+		if n.powerMgr == nil {
+			return
+		}
+		for _, output := range n.outputs {
+			powerCtrl, err := n.powerMgr.GetOutputPower(output)
+			if err != nil {
+				log.Warnf("Idle: failed to get output power control: %v", err)
+				continue
+			}
+			powerCtrl.SetMode(mode)
+			powerCtrl.Destroy()
+		}
+	})
+}
+
+func (n *waylandNotifier) Close() {
+	n.wlCtx.Post(func() {
+		n.destroyNotifications()
+		n.notifier.Destroy()
+		if n.powerMgr != nil {
+			n.powerMgr.Destroy()
+		}
+	})
+}
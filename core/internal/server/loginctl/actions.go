@@ -2,6 +2,8 @@ package loginctl
 
 import (
 	"fmt"
+
+	"github.com/godbus/dbus/v5"
 )
 
 func (m *Manager) Lock() error {
@@ -99,3 +101,31 @@ func (m *Manager) SetSleepInhibitorEnabled(enabled bool) {
 		m.releaseSleepInhibitor()
 	}
 }
+
+func (m *Manager) callManager(method, what string) error {
+	return m.callManagerInteractive(method, what, false)
+}
+
+// callManagerInteractive lets polkit prompt for authentication when
+// interactive is set, which logind needs for "challenge" answers.
+func (m *Manager) callManagerInteractive(method, what string, interactive bool) error {
+	if m.managerObj == nil {
+		return fmt.Errorf("manager object not available")
+	}
+	flags := dbus.Flags(0)
+	if interactive {
+		flags = dbus.FlagAllowInteractiveAuthorization
+	}
+	if err := m.managerObj.Call(dbusManagerInterface+"."+method, flags, interactive).Err; err != nil {
+		return fmt.Errorf("failed to %s: %w", what, err)
+	}
+	return nil
+}
+
+func (m *Manager) PowerOff() error {
+	return m.callManager("PowerOff", "power off")
+}
+
+func (m *Manager) Reboot() error {
+	return m.callManager("Reboot", "reboot")
+}
@@ -2,6 +2,7 @@ package loginctl
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/AvengeMedia/DankMaterialShell/core/internal/server/models"
 	"github.com/AvengeMedia/dankgo/ipc/params"
@@ -29,6 +30,32 @@ func HandleRequest(conn *models.Conn, req models.Request, manager *Manager) {
 		handleLockerReady(conn, req, manager)
 	case "loginctl.terminate":
 		handleTerminate(conn, req, manager)
+	case "loginctl.getCapabilities":
+		models.Respond(conn, req.ID, manager.RefreshCapabilities())
+	case "loginctl.suspend":
+		handleSleep(conn, req, manager, SleepSuspend)
+	case "loginctl.hibernate":
+		handleSleep(conn, req, manager, SleepHibernate)
+	case "loginctl.suspendThenHibernate":
+		handleSleep(conn, req, manager, SleepSuspendThenHibernate)
+	case "loginctl.hybridSleep":
+		handleSleep(conn, req, manager, SleepHybridSleep)
+	case "loginctl.getWakeAlarm":
+		respondWakeAlarm(conn, req)
+	case "loginctl.setWakeAlarm":
+		handleSetWakeAlarm(conn, req)
+	case "loginctl.clearWakeAlarm":
+		handleClearWakeAlarm(conn, req)
+	case "loginctl.listInhibitors":
+		handleListInhibitors(conn, req, manager)
+	case "loginctl.killInhibitor":
+		handleKillInhibitor(conn, req, manager)
+	case "loginctl.schedule.set":
+		handleScheduleSet(conn, req, manager)
+	case "loginctl.schedule.get":
+		models.Respond(conn, req.ID, manager.GetPowerSchedule())
+	case "loginctl.schedule.cancel":
+		handleScheduleCancel(conn, req, manager)
 	case "loginctl.subscribe":
 		handleSubscribe(conn, req, manager)
 	default:
@@ -139,6 +166,163 @@ func handleTerminate(conn *models.Conn, req models.Request, manager *Manager) {
 	models.Respond(conn, req.ID, models.SuccessResult{Success: true, Message: "terminated"})
 }
 
+func handleListInhibitors(conn *models.Conn, req models.Request, manager *Manager) {
+	if err := manager.refreshInhibitors(); err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+	models.Respond(conn, req.ID, manager.GetState().Inhibitors)
+}
+
+func handleKillInhibitor(conn *models.Conn, req models.Request, manager *Manager) {
+	pid, err := params.Int(req.Params, "pid")
+	if err != nil || pid <= 0 {
+		models.RespondError(conn, req.ID, "missing or invalid 'pid' parameter")
+		return
+	}
+
+	if err := manager.KillInhibitor(uint32(pid), params.BoolOpt(req.Params, "force", false)); err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+	models.Respond(conn, req.ID, models.SuccessResult{Success: true, Message: "signalled"})
+}
+
+// timeParam reads an RFC 3339 time from atKey or a delay in seconds from
+// delayKey. ok is false when neither is given.
+func timeParam(req models.Request, atKey, delayKey string) (at time.Time, ok bool, err error) {
+	if value := params.StringOpt(req.Params, atKey, ""); value != "" {
+		at, err = time.Parse(time.RFC3339, value)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("invalid '%s' parameter: expected RFC 3339 time", atKey)
+		}
+		return at, true, nil
+	}
+	if delay := params.IntOpt(req.Params, delayKey, 0); delay > 0 {
+		return time.Now().Add(time.Duration(delay) * time.Second), true, nil
+	}
+	return time.Time{}, false, nil
+}
+
+// handleSleep enters a sleep state, first programming an RTC wake alarm
+// when wakeAt or wakeIn is given.
+func handleSleep(conn *models.Conn, req models.Request, manager *Manager, state string) {
+	wakeAt, wake, err := timeParam(req, "wakeAt", "wakeIn")
+	if err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+
+	if wake {
+		err = manager.SleepUntil(state, wakeAt)
+	} else {
+		err = manager.Sleep(state)
+	}
+	if err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+	models.Respond(conn, req.ID, models.SuccessResult{Success: true, Message: state})
+}
+
+type wakeAlarmResult struct {
+	Supported bool       `json:"supported"`
+	At        *time.Time `json:"at"`
+}
+
+func respondWakeAlarm(conn *models.Conn, req models.Request) {
+	result := wakeAlarmResult{Supported: wakeAlarmSupported()}
+	if result.Supported {
+		at, err := WakeAlarm()
+		if err != nil {
+			models.RespondError(conn, req.ID, err.Error())
+			return
+		}
+		if !at.IsZero() {
+			result.At = &at
+		}
+	}
+	models.Respond(conn, req.ID, result)
+}
+
+func handleSetWakeAlarm(conn *models.Conn, req models.Request) {
+	at, ok, err := timeParam(req, "at", "delay")
+	if err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+	if !ok {
+		models.RespondError(conn, req.ID, "missing 'at' or 'delay' parameter")
+		return
+	}
+
+	if err := SetWakeAlarm(at); err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+	respondWakeAlarm(conn, req)
+}
+
+func handleClearWakeAlarm(conn *models.Conn, req models.Request) {
+	if err := ClearWakeAlarm(); err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+	respondWakeAlarm(conn, req)
+}
+
+// handleScheduleSet takes either an RFC 3339 "at" or a "delay" in seconds.
+func handleScheduleSet(conn *models.Conn, req models.Request, manager *Manager) {
+	action, err := params.String(req.Params, "action")
+	if err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+
+	at, ok, err := timeParam(req, "at", "delay")
+	if err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+	if !ok {
+		models.RespondError(conn, req.ID, "missing 'at' or 'delay' parameter")
+		return
+	}
+
+	var warnings []int
+	if raw, ok := params.Any(req.Params, "warnings"); ok {
+		list, ok := raw.([]any)
+		if !ok {
+			models.RespondError(conn, req.ID, "missing or invalid 'warnings' parameter")
+			return
+		}
+		warnings = make([]int, 0, len(list))
+		for _, v := range list {
+			f, ok := v.(float64)
+			if !ok {
+				models.RespondError(conn, req.ID, "missing or invalid 'warnings' parameter")
+				return
+			}
+			warnings = append(warnings, int(f))
+		}
+	}
+
+	schedule, err := manager.SchedulePowerAction(action, at, warnings)
+	if err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+	models.Respond(conn, req.ID, schedule)
+}
+
+func handleScheduleCancel(conn *models.Conn, req models.Request, manager *Manager) {
+	if !manager.CancelPowerSchedule() {
+		models.Respond(conn, req.ID, models.SuccessResult{Success: true, Message: "nothing scheduled"})
+		return
+	}
+	models.Respond(conn, req.ID, models.SuccessResult{Success: true, Message: "schedule cancelled"})
+}
+
 func handleSubscribe(conn *models.Conn, req models.Request, manager *Manager) {
 	clientID := fmt.Sprintf("client-%p", conn)
 	stateChan := manager.Subscribe(clientID)
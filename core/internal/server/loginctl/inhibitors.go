@@ -0,0 +1,145 @@
+package loginctl
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// logind has no change signal for inhibitors, so they are re-read on this
+// interval.
+var inhibitorPollInterval = 5 * time.Second
+
+var procRoot = "/proc"
+
+// killProcess is swapped out in tests.
+var killProcess = syscall.Kill
+
+// Inhibitor is a lock taken through logind's Inhibit call, by us or by
+// another process (systemd-inhibit, media players, package managers).
+type Inhibitor struct {
+	What string `json:"what"`
+	Who  string `json:"who"`
+	Why  string `json:"why"`
+	Mode string `json:"mode"`
+	UID  uint32 `json:"uid"`
+	PID  uint32 `json:"pid"`
+	// Process is the holder's command name, when it can be read.
+	Process string `json:"process,omitempty"`
+}
+
+// Blocks reports whether the inhibitor blocks (rather than delays) the
+// given operation, e.g. "idle" or "sleep".
+func (i Inhibitor) Blocks(what string) bool {
+	return i.Mode == "block" && slices.Contains(strings.Split(i.What, ":"), what)
+}
+
+func (m *Manager) ListInhibitors() ([]Inhibitor, error) {
+	if m.managerObj == nil {
+		return nil, fmt.Errorf("manager object not available")
+	}
+
+	var raw []struct {
+		What string
+		Who  string
+		Why  string
+		Mode string
+		UID  uint32
+		PID  uint32
+	}
+	if err := m.managerObj.Call(dbusManagerInterface+".ListInhibitors", 0).Store(&raw); err != nil {
+		return nil, fmt.Errorf("failed to list inhibitors: %w", err)
+	}
+
+	inhibitors := make([]Inhibitor, 0, len(raw))
+	for _, r := range raw {
+		inhibitors = append(inhibitors, Inhibitor{
+			What:    r.What,
+			Who:     r.Who,
+			Why:     r.Why,
+			Mode:    r.Mode,
+			UID:     r.UID,
+			PID:     r.PID,
+			Process: processName(r.PID),
+		})
+	}
+	return inhibitors, nil
+}
+
+func processName(pid uint32) string {
+	data, err := os.ReadFile(filepath.Join(procRoot, strconv.FormatUint(uint64(pid), 10), "comm"))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// refreshInhibitors re-reads logind's inhibitors into the session state.
+func (m *Manager) refreshInhibitors() error {
+	inhibitors, err := m.ListInhibitors()
+	if err != nil {
+		return err
+	}
+
+	m.stateMutex.Lock()
+	changed := !slices.Equal(m.state.Inhibitors, inhibitors)
+	if changed {
+		m.state.Inhibitors = inhibitors
+	}
+	m.stateMutex.Unlock()
+
+	if changed {
+		m.notifySubscribers()
+	}
+	return nil
+}
+
+func (m *Manager) inhibitorLoop() {
+	defer m.notifierWg.Done()
+	ticker := time.NewTicker(inhibitorPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.stopChan:
+			return
+		case <-ticker.C:
+			m.refreshInhibitors()
+		}
+	}
+}
+
+// KillInhibitor signals the process holding an inhibitor. Only processes of
+// the current user that hold an inhibitor right now are eligible, and never
+// the server itself.
+func (m *Manager) KillInhibitor(pid uint32, force bool) error {
+	if err := m.refreshInhibitors(); err != nil {
+		return err
+	}
+	if int(pid) == os.Getpid() {
+		return fmt.Errorf("refusing to kill the DMS server")
+	}
+
+	inhibitors := m.GetState().Inhibitors
+	idx := slices.IndexFunc(inhibitors, func(i Inhibitor) bool { return i.PID == pid })
+	if idx < 0 {
+		return fmt.Errorf("no inhibitor held by pid %d", pid)
+	}
+	if int(inhibitors[idx].UID) != os.Getuid() {
+		return fmt.Errorf("pid %d belongs to another user", pid)
+	}
+
+	sig := syscall.SIGTERM
+	if force {
+		sig = syscall.SIGKILL
+	}
+	if err := killProcess(int(pid), sig); err != nil {
+		return fmt.Errorf("failed to signal pid %d: %w", pid, err)
+	}
+	return nil
+}
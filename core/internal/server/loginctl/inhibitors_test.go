@@ -0,0 +1,128 @@
+package loginctl
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+
+	mockdbus "github.com/AvengeMedia/DankMaterialShell/core/internal/mocks/github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInhibitor_Blocks(t *testing.T) {
+	i := Inhibitor{What: "sleep:idle:handle-lid-switch", Mode: "block"}
+	assert.True(t, i.Blocks("idle"))
+	assert.True(t, i.Blocks("sleep"))
+	assert.False(t, i.Blocks("shutdown"))
+
+	i.Mode = "delay"
+	assert.False(t, i.Blocks("sleep"), "delay inhibitors only postpone")
+}
+
+type rawInhibitor struct {
+	What, Who, Why, Mode string
+	UID, PID             uint32
+}
+
+func fakeProc(t *testing.T, comms map[int]string) {
+	t.Helper()
+	root := t.TempDir()
+	for pid, comm := range comms {
+		dir := filepath.Join(root, strconv.Itoa(pid))
+		require.NoError(t, os.MkdirAll(dir, 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "comm"), []byte(comm+"\n"), 0o644))
+	}
+	old := procRoot
+	procRoot = root
+	t.Cleanup(func() { procRoot = old })
+}
+
+func expectInhibitors(obj *mockdbus.MockBusObject, raw ...rawInhibitor) {
+	obj.EXPECT().Call("org.freedesktop.login1.Manager.ListInhibitors", dbus.Flags(0)).Return(&dbus.Call{
+		Body: []any{raw},
+	}).Once()
+}
+
+func TestManager_ListInhibitors(t *testing.T) {
+	fakeProc(t, map[int]string{4242: "mpv"})
+
+	mockManagerObj := mockdbus.NewMockBusObject(t)
+	expectInhibitors(mockManagerObj,
+		rawInhibitor{"sleep", "DankMaterialShell", "Lock before suspend", "delay", 1000, 42},
+		rawInhibitor{"idle", "mpv", "Playing video", "block", 1000, 4242},
+	)
+
+	m := &Manager{managerObj: mockManagerObj}
+	inhibitors, err := m.ListInhibitors()
+	require.NoError(t, err)
+	require.Len(t, inhibitors, 2)
+	assert.Empty(t, inhibitors[0].Process)
+	assert.Equal(t, Inhibitor{What: "idle", Who: "mpv", Why: "Playing video", Mode: "block", UID: 1000, PID: 4242, Process: "mpv"}, inhibitors[1])
+
+	_, err = (&Manager{}).ListInhibitors()
+	assert.Error(t, err)
+}
+
+func TestManager_RefreshInhibitors(t *testing.T) {
+	fakeProc(t, nil)
+	mockManagerObj := mockdbus.NewMockBusObject(t)
+	m := &Manager{state: &SessionState{}, managerObj: mockManagerObj, dirty: make(chan struct{}, 1)}
+
+	apt := rawInhibitor{"shutdown:sleep", "apt", "Upgrading packages", "block", 0, 900}
+	expectInhibitors(mockManagerObj, apt)
+	require.NoError(t, m.refreshInhibitors())
+	assert.Len(t, m.GetState().Inhibitors, 1)
+	assert.Len(t, m.dirty, 1, "a change notifies subscribers")
+	<-m.dirty
+
+	expectInhibitors(mockManagerObj, apt)
+	require.NoError(t, m.refreshInhibitors())
+	assert.Empty(t, m.dirty, "an unchanged list does not")
+
+	expectInhibitors(mockManagerObj)
+	require.NoError(t, m.refreshInhibitors())
+	assert.Empty(t, m.GetState().Inhibitors)
+	assert.Len(t, m.dirty, 1)
+}
+
+func TestManager_KillInhibitor(t *testing.T) {
+	fakeProc(t, nil)
+	type kill struct {
+		pid int
+		sig syscall.Signal
+	}
+	var killed []kill
+	old := killProcess
+	killProcess = func(pid int, sig syscall.Signal) error {
+		killed = append(killed, kill{pid, sig})
+		return nil
+	}
+	t.Cleanup(func() { killProcess = old })
+
+	uid := uint32(os.Getuid())
+	inhibitors := []rawInhibitor{
+		{"sleep", "systemd-inhibit", "Backup", "block", uid, 5000},
+		{"shutdown", "packagekitd", "Updating", "block", uid + 1, 6000},
+		{"sleep", "DankMaterialShell", "Lock before suspend", "delay", uid, uint32(os.Getpid())},
+	}
+	mockManagerObj := mockdbus.NewMockBusObject(t)
+	m := &Manager{state: &SessionState{}, managerObj: mockManagerObj}
+
+	expectInhibitors(mockManagerObj, inhibitors...)
+	require.NoError(t, m.KillInhibitor(5000, false))
+	expectInhibitors(mockManagerObj, inhibitors...)
+	require.NoError(t, m.KillInhibitor(5000, true))
+	assert.Equal(t, []kill{{5000, syscall.SIGTERM}, {5000, syscall.SIGKILL}}, killed)
+
+	expectInhibitors(mockManagerObj, inhibitors...)
+	assert.ErrorContains(t, m.KillInhibitor(6000, false), "another user")
+	expectInhibitors(mockManagerObj, inhibitors...)
+	assert.Error(t, m.KillInhibitor(uint32(os.Getpid()), false))
+	expectInhibitors(mockManagerObj, inhibitors...)
+	assert.ErrorContains(t, m.KillInhibitor(7000, false), "no inhibitor")
+	assert.Len(t, killed, 2)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"slices"
 	"sync"
 	"time"
 
@@ -25,6 +26,8 @@ func NewManager() (*Manager, error) {
 		conn:     conn,
 		dirty:    make(chan struct{}, 1),
 		signals:  make(chan *dbus.Signal, 256),
+
+		scheduleWake: make(chan struct{}, 1),
 	}
 	m.sleepInhibitorEnabled.Store(true)
 
@@ -36,9 +39,21 @@ func NewManager() (*Manager, error) {
 		fmt.Fprintf(os.Stderr, "sleep inhibitor unavailable: %v\n", err)
 	}
 
+	m.RefreshCapabilities()
+
 	m.notifierWg.Add(1)
 	go m.notifier()
 
+	m.restoreSchedule()
+	m.notifierWg.Add(1)
+	go m.scheduleLoop()
+
+	if err := m.refreshInhibitors(); err != nil {
+		fmt.Fprintf(os.Stderr, "loginctl: %v\n", err)
+	}
+	m.notifierWg.Add(1)
+	go m.inhibitorLoop()
+
 	if err := m.startSignalPump(); err != nil {
 		m.Close()
 		return nil, err
@@ -389,6 +404,15 @@ func (m *Manager) acquireSleepInhibitor() error {
 	return nil
 }
 
+// InhibitSleep takes a logind delay lock on sleep for another manager that
+// has work to finish before suspend. Closing the file releases it.
+func (m *Manager) InhibitSleep(why string) (*os.File, error) {
+	if m.managerObj == nil {
+		return nil, fmt.Errorf("manager object not available")
+	}
+	return m.inhibit("sleep", "DankMaterialShell", why, "delay")
+}
+
 func (m *Manager) inhibit(what, who, why, mode string) (*os.File, error) {
 	var fd dbus.UnixFD
 	err := m.managerObj.Call(dbusManagerInterface+".Inhibit", 0, what, who, why, mode).Store(&fd)
@@ -480,6 +504,15 @@ func stateChangedMeaningfully(old, new *SessionState) bool {
 	if old.PreparingForSleep != new.PreparingForSleep {
 		return true
 	}
+	if old.Capabilities != new.Capabilities {
+		return true
+	}
+	if !slices.Equal(old.Inhibitors, new.Inhibitors) {
+		return true
+	}
+	if !schedulesEqual(old.Schedule, new.Schedule) {
+		return true
+	}
 	return false
 }
 
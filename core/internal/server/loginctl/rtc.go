@@ -0,0 +1,116 @@
+package loginctl
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var rtcWakeAlarmPath = "/sys/class/rtc/rtc0/wakealarm"
+
+// runPrivileged runs a command through polkit when the wakealarm file is
+// not writable by the user. Swapped out in tests.
+var runPrivileged = func(name string, args ...string) error {
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return fmt.Errorf("%s not found", name)
+	}
+	out, err := exec.Command("pkexec", append([]string{path}, args...)...).CombinedOutput()
+	if err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return fmt.Errorf("%w: %s", err, msg)
+		}
+		return err
+	}
+	return nil
+}
+
+const maxWakeAhead = 365 * 24 * time.Hour
+
+func wakeAlarmSupported() bool {
+	_, err := os.Stat(rtcWakeAlarmPath)
+	return err == nil
+}
+
+// WakeAlarm returns the programmed RTC wake time, or the zero time when no
+// alarm is set.
+func WakeAlarm() (time.Time, error) {
+	data, err := os.ReadFile(rtcWakeAlarmPath)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("RTC wake alarm not available: %w", err)
+	}
+	value := strings.TrimSpace(string(data))
+	if value == "" {
+		return time.Time{}, nil
+	}
+	epoch, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid wakealarm value %q", value)
+	}
+	return time.Unix(epoch, 0), nil
+}
+
+// writeWakeAlarm writes the sysfs file directly, which works when a udev
+// rule grants the user access, and otherwise falls back to rtcwake via
+// pkexec.
+func writeWakeAlarm(epoch int64) error {
+	// The kernel rejects a new alarm while one is pending, so clear first.
+	err := os.WriteFile(rtcWakeAlarmPath, []byte("0"), 0)
+	if err == nil && epoch > 0 {
+		err = os.WriteFile(rtcWakeAlarmPath, []byte(strconv.FormatInt(epoch, 10)), 0)
+	}
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, os.ErrPermission) {
+		return fmt.Errorf("failed to write wakealarm: %w", err)
+	}
+
+	args := []string{"-m", "disable"}
+	if epoch > 0 {
+		args = []string{"-m", "no", "-t", strconv.FormatInt(epoch, 10)}
+	}
+	if err := runPrivileged("rtcwake", args...); err != nil {
+		return fmt.Errorf("wakealarm is not writable and rtcwake failed: %w", err)
+	}
+	return nil
+}
+
+// SetWakeAlarm programs the RTC to wake the machine at the given time.
+func SetWakeAlarm(at time.Time) error {
+	if !wakeAlarmSupported() {
+		return fmt.Errorf("RTC wake alarm not supported: %s missing", rtcWakeAlarmPath)
+	}
+	now := time.Now()
+	if !at.After(now) {
+		return fmt.Errorf("wake time must be in the future")
+	}
+	if at.Sub(now) > maxWakeAhead {
+		return fmt.Errorf("wake time must be within a year")
+	}
+	return writeWakeAlarm(at.Unix())
+}
+
+func ClearWakeAlarm() error {
+	if !wakeAlarmSupported() {
+		return fmt.Errorf("RTC wake alarm not supported: %s missing", rtcWakeAlarmPath)
+	}
+	return writeWakeAlarm(0)
+}
+
+// SleepUntil programs a wake alarm and then enters the sleep state. The
+// alarm is cleared again if the sleep request fails.
+func (m *Manager) SleepUntil(state string, wakeAt time.Time) error {
+	if err := SetWakeAlarm(wakeAt); err != nil {
+		return err
+	}
+	if err := m.Sleep(state); err != nil {
+		ClearWakeAlarm()
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,344 @@
+package loginctl
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/AvengeMedia/DankMaterialShell/core/internal/log"
+	"github.com/AvengeMedia/DankMaterialShell/core/internal/notify"
+)
+
+const (
+	ScheduleActionPoweroff  = "poweroff"
+	ScheduleActionReboot    = "reboot"
+	ScheduleActionSuspend   = "suspend"
+	ScheduleActionHibernate = "hibernate"
+	ScheduleActionLogout    = "logout"
+	ScheduleActionLock      = "lock"
+)
+
+var scheduleActions = []string{
+	ScheduleActionPoweroff,
+	ScheduleActionReboot,
+	ScheduleActionSuspend,
+	ScheduleActionHibernate,
+	ScheduleActionLogout,
+	ScheduleActionLock,
+}
+
+var defaultScheduleWarnings = []int{600, 300, 60}
+
+const (
+	maxScheduleAhead = 7 * 24 * time.Hour
+	// A schedule restored more than this long after it was due was missed
+	// while the machine was off and is dropped rather than run at login.
+	scheduleGrace = 5 * time.Minute
+	// Go timers stop during suspend, so the loop re-reads the wall clock at
+	// least this often.
+	scheduleMaxWait = 30 * time.Second
+)
+
+// PowerSchedule runs Action at At. Warnings are seconds before At at which a
+// notification is shown, largest first.
+type PowerSchedule struct {
+	Action   string    `json:"action"`
+	At       time.Time `json:"at"`
+	Warnings []int     `json:"warnings"`
+}
+
+var sendScheduleWarning = func(summary, body string) {
+	if _, err := notify.Send(notify.Notification{
+		Icon:    "system-shutdown",
+		Summary: summary,
+		Body:    body,
+		Timeout: 10000,
+	}); err != nil {
+		log.Debugf("loginctl: schedule warning: %v", err)
+	}
+}
+
+func schedulePath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "DankMaterialShell", "power-schedule.json"), nil
+}
+
+func loadSchedule() (*PowerSchedule, error) {
+	path, err := schedulePath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var s PowerSchedule
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func saveSchedule(s *PowerSchedule) error {
+	path, err := schedulePath()
+	if err != nil {
+		return err
+	}
+	if s == nil {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+func schedulesEqual(a, b *PowerSchedule) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Action == b.Action && a.At.Equal(b.At) && slices.Equal(a.Warnings, b.Warnings)
+}
+
+// warningsPassed counts the warnings already due at now.
+func (s *PowerSchedule) warningsPassed(now time.Time) int {
+	n := 0
+	for _, w := range s.Warnings {
+		if now.Before(s.At.Add(-time.Duration(w) * time.Second)) {
+			break
+		}
+		n++
+	}
+	return n
+}
+
+// SchedulePowerAction replaces any pending schedule. A nil warnings slice
+// uses the defaults; warnings that are already due are skipped.
+func (m *Manager) SchedulePowerAction(action string, at time.Time, warnings []int) (*PowerSchedule, error) {
+	if !slices.Contains(scheduleActions, action) {
+		return nil, fmt.Errorf("unknown action: %s", action)
+	}
+	now := time.Now()
+	if !at.After(now) {
+		return nil, fmt.Errorf("scheduled time must be in the future")
+	}
+	if at.Sub(now) > maxScheduleAhead {
+		return nil, fmt.Errorf("scheduled time must be within %s", maxScheduleAhead)
+	}
+	if warnings == nil {
+		warnings = defaultScheduleWarnings
+	}
+	warnings = slices.Clone(warnings)
+	for _, w := range warnings {
+		if w < 1 || w > 86400 {
+			return nil, fmt.Errorf("warnings must be between 1 and 86400 seconds")
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(warnings)))
+	warnings = slices.Compact(warnings)
+
+	s := &PowerSchedule{Action: action, At: at.Round(time.Second), Warnings: warnings}
+	if err := saveSchedule(s); err != nil {
+		log.Warnf("loginctl: failed to save power schedule: %v", err)
+	}
+
+	m.scheduleMu.Lock()
+	m.schedule = s
+	m.scheduleWarned = s.warningsPassed(now)
+	m.scheduleMu.Unlock()
+
+	log.Infof("loginctl: %s scheduled at %s", action, s.At.Format(time.RFC3339))
+	m.publishSchedule(s)
+	return s, nil
+}
+
+func (m *Manager) CancelPowerSchedule() bool {
+	m.scheduleMu.Lock()
+	s := m.schedule
+	m.schedule = nil
+	m.scheduleMu.Unlock()
+
+	if s == nil {
+		return false
+	}
+	if err := saveSchedule(nil); err != nil {
+		log.Warnf("loginctl: failed to clear power schedule: %v", err)
+	}
+	log.Infof("loginctl: scheduled %s cancelled", s.Action)
+	m.publishSchedule(nil)
+	return true
+}
+
+func (m *Manager) GetPowerSchedule() *PowerSchedule {
+	m.scheduleMu.Lock()
+	defer m.scheduleMu.Unlock()
+	return m.schedule
+}
+
+func (m *Manager) publishSchedule(s *PowerSchedule) {
+	m.stateMutex.Lock()
+	m.state.Schedule = s
+	m.stateMutex.Unlock()
+	m.notifySubscribers()
+
+	if m.scheduleWake != nil {
+		select {
+		case m.scheduleWake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// restoreSchedule picks up a schedule persisted by a previous server.
+func (m *Manager) restoreSchedule() {
+	s, err := loadSchedule()
+	if err != nil {
+		log.Warnf("loginctl: failed to load power schedule: %v", err)
+		return
+	}
+	if s == nil {
+		return
+	}
+	if time.Since(s.At) > scheduleGrace {
+		log.Infof("loginctl: dropping missed %s scheduled at %s", s.Action, s.At.Format(time.RFC3339))
+		if err := saveSchedule(nil); err != nil {
+			log.Warnf("loginctl: failed to clear power schedule: %v", err)
+		}
+		return
+	}
+
+	m.scheduleMu.Lock()
+	m.schedule = s
+	m.scheduleWarned = 0
+	m.scheduleMu.Unlock()
+	m.publishSchedule(s)
+}
+
+func (m *Manager) scheduleLoop() {
+	defer m.notifierWg.Done()
+	timer := time.NewTimer(scheduleMaxWait)
+	defer timer.Stop()
+
+	for {
+		timer.Reset(m.checkSchedule(time.Now()))
+		select {
+		case <-m.stopChan:
+			return
+		case <-m.scheduleWake:
+		case <-timer.C:
+		}
+	}
+}
+
+// checkSchedule sends due warnings, runs the action once due and returns
+// how long to wait before checking again.
+func (m *Manager) checkSchedule(now time.Time) time.Duration {
+	m.scheduleMu.Lock()
+	s := m.schedule
+	if s == nil {
+		m.scheduleMu.Unlock()
+		return scheduleMaxWait
+	}
+
+	if !now.Before(s.At) {
+		m.schedule = nil
+		m.scheduleMu.Unlock()
+
+		if err := saveSchedule(nil); err != nil {
+			log.Warnf("loginctl: failed to clear power schedule: %v", err)
+		}
+		m.publishSchedule(nil)
+		m.runScheduledAction(s.Action)
+		return scheduleMaxWait
+	}
+
+	// Only the most recent of several overdue warnings is shown, e.g. after
+	// resuming from suspend.
+	warn := false
+	if passed := s.warningsPassed(now); passed > m.scheduleWarned {
+		m.scheduleWarned = passed
+		warn = true
+	}
+	next := s.At.Sub(now)
+	if m.scheduleWarned < len(s.Warnings) {
+		next = s.At.Add(-time.Duration(s.Warnings[m.scheduleWarned]) * time.Second).Sub(now)
+	}
+	m.scheduleMu.Unlock()
+
+	if warn {
+		remaining := s.At.Sub(now).Round(time.Second)
+		sendScheduleWarning(scheduleSummary(s.Action, remaining),
+			fmt.Sprintf("Scheduled for %s. Cancel from the power menu.", s.At.Local().Format("15:04")))
+	}
+	return min(next, scheduleMaxWait)
+}
+
+func scheduleSummary(action string, remaining time.Duration) string {
+	var verb string
+	switch action {
+	case ScheduleActionPoweroff:
+		verb = "Shutting down"
+	case ScheduleActionReboot:
+		verb = "Restarting"
+	case ScheduleActionSuspend:
+		verb = "Suspending"
+	case ScheduleActionHibernate:
+		verb = "Hibernating"
+	case ScheduleActionLogout:
+		verb = "Logging out"
+	case ScheduleActionLock:
+		verb = "Locking"
+	}
+
+	minutes := int((remaining + 59*time.Second) / time.Minute)
+	switch {
+	case remaining < time.Minute:
+		return fmt.Sprintf("%s in %ds", verb, int(remaining.Seconds()))
+	case minutes == 1:
+		return verb + " in 1 minute"
+	default:
+		return fmt.Sprintf("%s in %d minutes", verb, minutes)
+	}
+}
+
+func (m *Manager) runScheduledAction(action string) {
+	log.Infof("loginctl: running scheduled %s", action)
+
+	var err error
+	switch action {
+	case ScheduleActionPoweroff:
+		err = m.PowerOff()
+	case ScheduleActionReboot:
+		err = m.Reboot()
+	case ScheduleActionSuspend:
+		err = m.Suspend()
+	case ScheduleActionHibernate:
+		err = m.Hibernate()
+	case ScheduleActionLogout:
+		err = m.Terminate()
+	case ScheduleActionLock:
+		err = m.Lock()
+	}
+	if err != nil {
+		log.Warnf("loginctl: scheduled %s failed: %v", action, err)
+		sendScheduleWarning("Scheduled action failed", err.Error())
+	}
+}
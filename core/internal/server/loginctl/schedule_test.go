@@ -0,0 +1,124 @@
+package loginctl
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	mockdbus "github.com/AvengeMedia/DankMaterialShell/core/internal/mocks/github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func captureScheduleWarnings(t *testing.T) *[]string {
+	t.Helper()
+	var warnings []string
+	old := sendScheduleWarning
+	sendScheduleWarning = func(summary, body string) { warnings = append(warnings, summary) }
+	t.Cleanup(func() { sendScheduleWarning = old })
+	return &warnings
+}
+
+func TestSchedulePowerAction_Validation(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	m := &Manager{state: &SessionState{}}
+	now := time.Now()
+
+	_, err := m.SchedulePowerAction("explode", now.Add(time.Hour), nil)
+	assert.Error(t, err)
+	_, err = m.SchedulePowerAction(ScheduleActionReboot, now.Add(-time.Minute), nil)
+	assert.Error(t, err, "past")
+	_, err = m.SchedulePowerAction(ScheduleActionReboot, now.Add(8*24*time.Hour), nil)
+	assert.Error(t, err, "too far ahead")
+	_, err = m.SchedulePowerAction(ScheduleActionReboot, now.Add(time.Hour), []int{0})
+	assert.Error(t, err, "warning")
+
+	s, err := m.SchedulePowerAction(ScheduleActionSuspend, now.Add(time.Hour), []int{60, 900, 60})
+	require.NoError(t, err)
+	assert.Equal(t, []int{900, 60}, s.Warnings)
+	assert.Equal(t, s, m.GetState().Schedule)
+
+	saved, err := loadSchedule()
+	require.NoError(t, err)
+	assert.True(t, schedulesEqual(s, saved))
+
+	assert.True(t, m.CancelPowerSchedule())
+	assert.False(t, m.CancelPowerSchedule())
+	assert.Nil(t, m.GetState().Schedule)
+	saved, err = loadSchedule()
+	require.NoError(t, err)
+	assert.Nil(t, saved)
+}
+
+func TestCheckSchedule_WarnsThenRuns(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	warnings := captureScheduleWarnings(t)
+
+	mockManagerObj := mockdbus.NewMockBusObject(t)
+	mockManagerObj.EXPECT().Call("org.freedesktop.login1.Manager.PowerOff", dbus.Flags(0), false).Return(&dbus.Call{}).Once()
+	m := &Manager{state: &SessionState{}, managerObj: mockManagerObj}
+
+	s, err := m.SchedulePowerAction(ScheduleActionPoweroff, time.Now().Add(20*time.Minute), nil)
+	require.NoError(t, err)
+	at := s.At
+
+	assert.Equal(t, scheduleMaxWait, m.checkSchedule(at.Add(-20*time.Minute)))
+	assert.Empty(t, *warnings)
+
+	assert.Equal(t, scheduleMaxWait, m.checkSchedule(at.Add(-6*time.Minute)))
+	assert.Equal(t, []string{"Shutting down in 6 minutes"}, *warnings)
+	assert.Equal(t, 10*time.Second, m.checkSchedule(at.Add(-5*time.Minute-10*time.Second)), "wakes for the next warning")
+	assert.Len(t, *warnings, 1, "each warning is shown once")
+
+	// Waking from suspend past both remaining warnings shows only the last.
+	m.checkSchedule(at.Add(-30 * time.Second))
+	assert.Equal(t, []string{"Shutting down in 6 minutes", "Shutting down in 30s"}, *warnings)
+
+	m.checkSchedule(at)
+	assert.Nil(t, m.GetPowerSchedule())
+	assert.Nil(t, m.GetState().Schedule)
+	m.checkSchedule(at.Add(time.Second))
+}
+
+func TestRestoreSchedule(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	captureScheduleWarnings(t)
+
+	missed := &PowerSchedule{Action: ScheduleActionReboot, At: time.Now().Add(-time.Hour).Round(time.Second), Warnings: []int{60}}
+	require.NoError(t, saveSchedule(missed))
+	m := &Manager{state: &SessionState{}}
+	m.restoreSchedule()
+	assert.Nil(t, m.GetPowerSchedule(), "schedules missed while the machine was off are dropped")
+	path, err := schedulePath()
+	require.NoError(t, err)
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+
+	pending := &PowerSchedule{Action: ScheduleActionLock, At: time.Now().Add(time.Hour).Round(time.Second), Warnings: []int{60}}
+	require.NoError(t, saveSchedule(pending))
+	m.restoreSchedule()
+	assert.True(t, schedulesEqual(pending, m.GetState().Schedule))
+
+	require.NoError(t, os.WriteFile(filepath.Join(filepath.Dir(path), "power-schedule.json"), []byte("{"), 0o644))
+	_, err = loadSchedule()
+	assert.Error(t, err)
+}
+
+func TestScheduleSummary(t *testing.T) {
+	assert.Equal(t, "Restarting in 10 minutes", scheduleSummary(ScheduleActionReboot, 10*time.Minute))
+	assert.Equal(t, "Suspending in 5 minutes", scheduleSummary(ScheduleActionSuspend, 4*time.Minute+30*time.Second))
+	assert.Equal(t, "Logging out in 1 minute", scheduleSummary(ScheduleActionLogout, time.Minute))
+	assert.Equal(t, "Locking in 45s", scheduleSummary(ScheduleActionLock, 45*time.Second))
+}
+
+func TestStateChangedMeaningfully_Schedule(t *testing.T) {
+	at := time.Now()
+	old := &SessionState{}
+	updated := &SessionState{Schedule: &PowerSchedule{Action: ScheduleActionReboot, At: at}}
+	assert.True(t, stateChangedMeaningfully(old, updated))
+
+	same := &SessionState{Schedule: &PowerSchedule{Action: ScheduleActionReboot, At: at}}
+	assert.False(t, stateChangedMeaningfully(updated, same))
+}
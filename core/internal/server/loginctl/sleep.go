@@ -0,0 +1,117 @@
+package loginctl
+
+import (
+	"fmt"
+)
+
+const (
+	SleepSuspend              = "suspend"
+	SleepHibernate            = "hibernate"
+	SleepSuspendThenHibernate = "suspend-then-hibernate"
+	SleepHybridSleep          = "hybrid-sleep"
+)
+
+// sleepMethods maps each sleep state to its logind method name; the
+// capability probe is the same name prefixed with "Can".
+var sleepMethods = map[string]string{
+	SleepSuspend:              "Suspend",
+	SleepHibernate:            "Hibernate",
+	SleepSuspendThenHibernate: "SuspendThenHibernate",
+	SleepHybridSleep:          "HybridSleep",
+}
+
+// PowerCapabilities holds logind's answer for each sleep state: "yes",
+// "challenge" (allowed after authentication), "no" (denied by policy) or
+// "na" (not supported by the hardware or configuration).
+type PowerCapabilities struct {
+	Suspend              string `json:"suspend"`
+	Hibernate            string `json:"hibernate"`
+	SuspendThenHibernate string `json:"suspendThenHibernate"`
+	HybridSleep          string `json:"hybridSleep"`
+	WakeAlarm            bool   `json:"wakeAlarm"`
+}
+
+func (m *Manager) canSleep(state string) (string, error) {
+	if m.managerObj == nil {
+		return "", fmt.Errorf("manager object not available")
+	}
+	var result string
+	if err := m.managerObj.Call(dbusManagerInterface+".Can"+sleepMethods[state], 0).Store(&result); err != nil {
+		return "", fmt.Errorf("failed to query %s support: %w", state, err)
+	}
+	return result, nil
+}
+
+// RefreshCapabilities probes logind and the RTC and stores the result in the
+// session state.
+func (m *Manager) RefreshCapabilities() PowerCapabilities {
+	caps := PowerCapabilities{WakeAlarm: wakeAlarmSupported()}
+	for state, field := range map[string]*string{
+		SleepSuspend:              &caps.Suspend,
+		SleepHibernate:            &caps.Hibernate,
+		SleepSuspendThenHibernate: &caps.SuspendThenHibernate,
+		SleepHybridSleep:          &caps.HybridSleep,
+	} {
+		result, err := m.canSleep(state)
+		if err != nil {
+			result = "na"
+		}
+		*field = result
+	}
+
+	m.stateMutex.Lock()
+	changed := m.state.Capabilities != caps
+	m.state.Capabilities = caps
+	m.stateMutex.Unlock()
+	if changed {
+		m.notifySubscribers()
+	}
+	return caps
+}
+
+func sleepUnsupported(state, can string) error {
+	switch can {
+	case "na":
+		if state == SleepSuspend {
+			return fmt.Errorf("%s is not supported on this system", state)
+		}
+		return fmt.Errorf("%s is not supported on this system (is swap with a resume device configured?)", state)
+	case "no":
+		return fmt.Errorf("%s is not permitted by the system policy", state)
+	}
+	return fmt.Errorf("%s is not available (logind reports %q)", state, can)
+}
+
+// Sleep enters the given sleep state after checking logind supports it. A
+// "challenge" answer makes the call interactive so polkit can ask for
+// authentication instead of denying it outright.
+func (m *Manager) Sleep(state string) error {
+	method, ok := sleepMethods[state]
+	if !ok {
+		return fmt.Errorf("unknown sleep state: %s", state)
+	}
+	can, err := m.canSleep(state)
+	if err != nil {
+		return err
+	}
+	if can != "yes" && can != "challenge" {
+		return sleepUnsupported(state, can)
+	}
+	return m.callManagerInteractive(method, state, can == "challenge")
+}
+
+func (m *Manager) Suspend() error {
+	return m.Sleep(SleepSuspend)
+}
+
+func (m *Manager) Hibernate() error {
+	return m.Sleep(SleepHibernate)
+}
+
+func (m *Manager) SuspendThenHibernate() error {
+	return m.Sleep(SleepSuspendThenHibernate)
+}
+
+func (m *Manager) HybridSleep() error {
+	return m.Sleep(SleepHybridSleep)
+}
@@ -0,0 +1,134 @@
+package loginctl
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	mockdbus "github.com/AvengeMedia/DankMaterialShell/core/internal/mocks/github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func expectCan(obj *mockdbus.MockBusObject, method, result string) {
+	obj.EXPECT().Call("org.freedesktop.login1.Manager.Can"+method, dbus.Flags(0)).Return(&dbus.Call{
+		Body: []any{result},
+	}).Once()
+}
+
+func fakeWakeAlarm(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "wakealarm")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	old := rtcWakeAlarmPath
+	rtcWakeAlarmPath = path
+	t.Cleanup(func() { rtcWakeAlarmPath = old })
+	return path
+}
+
+func TestManager_RefreshCapabilities(t *testing.T) {
+	fakeWakeAlarm(t, "")
+	mockManagerObj := mockdbus.NewMockBusObject(t)
+	expectCan(mockManagerObj, "Suspend", "yes")
+	expectCan(mockManagerObj, "Hibernate", "na")
+	expectCan(mockManagerObj, "SuspendThenHibernate", "na")
+	expectCan(mockManagerObj, "HybridSleep", "challenge")
+
+	m := &Manager{state: &SessionState{}, managerObj: mockManagerObj}
+	caps := m.RefreshCapabilities()
+	assert.Equal(t, PowerCapabilities{
+		Suspend:              "yes",
+		Hibernate:            "na",
+		SuspendThenHibernate: "na",
+		HybridSleep:          "challenge",
+		WakeAlarm:            true,
+	}, caps)
+	assert.Equal(t, caps, m.GetState().Capabilities)
+}
+
+func TestManager_Sleep(t *testing.T) {
+	mockManagerObj := mockdbus.NewMockBusObject(t)
+	m := &Manager{managerObj: mockManagerObj}
+
+	expectCan(mockManagerObj, "HybridSleep", "challenge")
+	mockManagerObj.EXPECT().Call("org.freedesktop.login1.Manager.HybridSleep", dbus.FlagAllowInteractiveAuthorization, true).Return(&dbus.Call{}).Once()
+	require.NoError(t, m.HybridSleep())
+
+	expectCan(mockManagerObj, "Suspend", "yes")
+	mockManagerObj.EXPECT().Call("org.freedesktop.login1.Manager.Suspend", dbus.Flags(0), false).Return(&dbus.Call{}).Once()
+	require.NoError(t, m.Suspend())
+
+	expectCan(mockManagerObj, "Hibernate", "na")
+	assert.ErrorContains(t, m.Hibernate(), "not supported")
+
+	expectCan(mockManagerObj, "SuspendThenHibernate", "no")
+	assert.ErrorContains(t, m.SuspendThenHibernate(), "not permitted")
+
+	assert.Error(t, m.Sleep("standby"))
+}
+
+func TestWakeAlarm(t *testing.T) {
+	path := fakeWakeAlarm(t, "\n")
+	at, err := WakeAlarm()
+	require.NoError(t, err)
+	assert.True(t, at.IsZero())
+
+	wake := time.Now().Add(8 * time.Hour).Truncate(time.Second)
+	require.NoError(t, SetWakeAlarm(wake))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, strconv.FormatInt(wake.Unix(), 10), string(data))
+	at, err = WakeAlarm()
+	require.NoError(t, err)
+	assert.True(t, wake.Equal(at))
+
+	assert.Error(t, SetWakeAlarm(time.Now().Add(-time.Minute)))
+	assert.Error(t, SetWakeAlarm(time.Now().Add(2*maxWakeAhead)))
+
+	require.NoError(t, ClearWakeAlarm())
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "0", string(data))
+
+	rtcWakeAlarmPath = filepath.Join(t.TempDir(), "missing")
+	assert.ErrorContains(t, SetWakeAlarm(wake), "not supported")
+}
+
+func TestWakeAlarm_PrivilegedFallback(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("file permissions do not apply to root")
+	}
+	path := fakeWakeAlarm(t, "")
+	require.NoError(t, os.Chmod(path, 0o444))
+
+	var calls [][]string
+	old := runPrivileged
+	runPrivileged = func(name string, args ...string) error {
+		calls = append(calls, append([]string{name}, args...))
+		return nil
+	}
+	t.Cleanup(func() { runPrivileged = old })
+
+	wake := time.Now().Add(time.Hour)
+	require.NoError(t, SetWakeAlarm(wake))
+	require.NoError(t, ClearWakeAlarm())
+	assert.Equal(t, [][]string{
+		{"rtcwake", "-m", "no", "-t", strconv.FormatInt(wake.Unix(), 10)},
+		{"rtcwake", "-m", "disable"},
+	}, calls)
+}
+
+func TestManager_SleepUntil_ClearsAlarmOnFailure(t *testing.T) {
+	path := fakeWakeAlarm(t, "")
+	mockManagerObj := mockdbus.NewMockBusObject(t)
+	expectCan(mockManagerObj, "Hibernate", "na")
+	m := &Manager{managerObj: mockManagerObj}
+
+	assert.Error(t, m.SleepUntil(SleepHibernate, time.Now().Add(time.Hour)))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "0", string(data))
+}
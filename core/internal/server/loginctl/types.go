@@ -30,6 +30,10 @@ type SessionState struct {
 	Seat              string `json:"seat"`
 	VTNr              uint32 `json:"vtnr"`
 	PreparingForSleep bool   `json:"preparingForSleep"`
+
+	Capabilities PowerCapabilities `json:"capabilities"`
+	Inhibitors   []Inhibitor       `json:"inhibitors"`
+	Schedule     *PowerSchedule    `json:"schedule,omitempty"`
 }
 
 type EventType string
@@ -68,4 +72,8 @@ type Manager struct {
 	lockTimer             *time.Timer
 	sleepInhibitorEnabled atomic.Bool
 	fallbackDelay         time.Duration
+	scheduleMu            sync.Mutex
+	schedule              *PowerSchedule
+	scheduleWarned        int
+	scheduleWake          chan struct{}
 }
@@ -1,7 +1,11 @@
 package notifyactions
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/AvengeMedia/DankMaterialShell/core/internal/server/models"
+	"github.com/AvengeMedia/dankgo/ipc/params"
 )
 
 func HandleRequest(conn *models.Conn, req models.Request, manager *Manager) {
@@ -9,6 +13,10 @@ func HandleRequest(conn *models.Conn, req models.Request, manager *Manager) {
 	case "notify.watchAction":
 		handleWatchAction(conn, req, manager)
 	default:
+		if strings.HasPrefix(req.Method, "notify.history.") {
+			handleHistoryRequest(conn, req, manager.History())
+			return
+		}
 		models.RespondError(conn, req.ID, "unknown method")
 	}
 }
@@ -27,3 +35,119 @@ func handleWatchAction(conn *models.Conn, req models.Request, manager *Manager)
 	manager.Watch(uint32(id), path)
 	models.Respond(conn, req.ID, "ok")
 }
+
+func handleHistoryRequest(conn *models.Conn, req models.Request, history *History) {
+	if history == nil {
+		models.RespondError(conn, req.ID, "notification history not available")
+		return
+	}
+
+	switch req.Method {
+	case "notify.history.list", "notify.history.search":
+		handleHistorySearch(conn, req, history)
+	case "notify.history.delete":
+		handleHistoryDelete(conn, req, history)
+	case "notify.history.clearApp":
+		handleHistoryClearApp(conn, req, history)
+	case "notify.history.clear":
+		handleHistoryClear(conn, req, history)
+	case "notify.history.markRead":
+		handleHistoryMarkRead(conn, req, history)
+	default:
+		models.RespondError(conn, req.ID, "unknown method")
+	}
+}
+
+func handleHistorySearch(conn *models.Conn, req models.Request, history *History) {
+	q := HistoryQuery{
+		Query:   params.StringOpt(req.Params, "query", ""),
+		AppName: params.StringOpt(req.Params, "appName", ""),
+		Unread:  params.BoolOpt(req.Params, "unread", false),
+		Limit:   params.IntOpt(req.Params, "limit", 50),
+		Offset:  params.IntOpt(req.Params, "offset", 0),
+	}
+	if b, ok := models.Get[float64](req, "before"); ok {
+		v := int64(b)
+		q.Before = &v
+	}
+	if a, ok := models.Get[float64](req, "after"); ok {
+		v := int64(a)
+		q.After = &v
+	}
+
+	models.Respond(conn, req.ID, history.Search(q))
+}
+
+func handleHistoryDelete(conn *models.Conn, req models.Request, history *History) {
+	id, err := params.Int(req.Params, "id")
+	if err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+
+	if err := history.Delete(uint64(id)); err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+
+	models.Respond(conn, req.ID, models.SuccessResult{Success: true, Message: "entry deleted"})
+}
+
+func handleHistoryClearApp(conn *models.Conn, req models.Request, history *History) {
+	appName, err := params.String(req.Params, "appName")
+	if err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+
+	removed, err := history.ClearApp(appName)
+	if err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+
+	models.Respond(conn, req.ID, models.SuccessResult{Success: true, Message: fmt.Sprintf("%d entries deleted", removed)})
+}
+
+func handleHistoryClear(conn *models.Conn, req models.Request, history *History) {
+	removed, err := history.Clear()
+	if err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+
+	models.Respond(conn, req.ID, models.SuccessResult{Success: true, Message: fmt.Sprintf("%d entries deleted", removed)})
+}
+
+// handleHistoryMarkRead marks the entries in "ids", or all of them when the
+// parameter is absent.
+func handleHistoryMarkRead(conn *models.Conn, req models.Request, history *History) {
+	var ids []uint64
+	if raw, ok := params.Any(req.Params, "ids"); ok {
+		list, ok := raw.([]any)
+		if !ok {
+			models.RespondError(conn, req.ID, "missing or invalid 'ids' parameter")
+			return
+		}
+		for _, v := range list {
+			f, ok := v.(float64)
+			if !ok || f <= 0 {
+				models.RespondError(conn, req.ID, "missing or invalid 'ids' parameter")
+				return
+			}
+			ids = append(ids, uint64(f))
+		}
+		if len(ids) == 0 {
+			models.Respond(conn, req.ID, models.SuccessResult{Success: true, Message: "0 entries marked read"})
+			return
+		}
+	}
+
+	changed, err := history.MarkRead(ids)
+	if err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+
+	models.Respond(conn, req.ID, models.SuccessResult{Success: true, Message: fmt.Sprintf("%d entries marked read", changed)})
+}
@@ -0,0 +1,380 @@
+package notifyactions
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	historyBucket = "notifications"
+	// maxHistoryEntries bounds the database; the oldest entries go first.
+	maxHistoryEntries = 2000
+)
+
+const (
+	UrgencyLow      uint8 = 0
+	UrgencyNormal   uint8 = 1
+	UrgencyCritical uint8 = 2
+)
+
+var errHistoryEntryNotFound = errors.New("entry not found")
+
+type HistoryAction struct {
+	ID    string `json:"id"`
+	Label string `json:"label"`
+}
+
+// HistoryEntry is one recorded Notify call and what became of it.
+type HistoryEntry struct {
+	ID             uint64          `json:"id"`
+	NotificationID uint32          `json:"notificationId"`
+	AppName        string          `json:"appName"`
+	AppIcon        string          `json:"appIcon,omitempty"`
+	Summary        string          `json:"summary"`
+	Body           string          `json:"body"`
+	Urgency        uint8           `json:"urgency"`
+	Actions        []HistoryAction `json:"actions,omitempty"`
+	Hints          map[string]any  `json:"hints,omitempty"`
+	ExpireTimeout  int32           `json:"expireTimeout"`
+	Timestamp      time.Time       `json:"timestamp"`
+	UpdatedAt      *time.Time      `json:"updatedAt,omitempty"`
+	ClosedAt       *time.Time      `json:"closedAt,omitempty"`
+	CloseReason    uint32          `json:"closeReason,omitempty"`
+	InvokedAction  string          `json:"invokedAction,omitempty"`
+	Read           bool            `json:"read"`
+}
+
+type HistoryQuery struct {
+	Query   string `json:"query"`
+	AppName string `json:"appName"`
+	Unread  bool   `json:"unread"`
+	Limit   int    `json:"limit"`
+	Offset  int    `json:"offset"`
+	Before  *int64 `json:"before,omitempty"`
+	After   *int64 `json:"after,omitempty"`
+}
+
+type HistoryResult struct {
+	Entries []HistoryEntry `json:"entries"`
+	Total   int            `json:"total"`
+	Unread  int            `json:"unread"`
+	HasMore bool           `json:"hasMore"`
+}
+
+// History persists notifications in a bbolt database, newest last.
+type History struct {
+	db *bolt.DB
+}
+
+func historyDBPath() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		cacheDir = filepath.Join(homeDir, ".cache")
+	}
+
+	dir := filepath.Join(cacheDir, "DankMaterialShell", "notifications")
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "db"), nil
+}
+
+func OpenHistory(path string) (*History, error) {
+	db, err := bolt.Open(path, 0o644, &bolt.Options{
+		Timeout: 1 * time.Second,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte(historyBucket))
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &History{db: db}, nil
+}
+
+func (h *History) Close() error {
+	return h.db.Close()
+}
+
+func itob(v uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, v)
+	return b
+}
+
+// Add stores entry under a new ID and returns it.
+func (h *History) Add(entry HistoryEntry) (uint64, error) {
+	err := h.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(historyBucket))
+
+		id, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+		entry.ID = id
+
+		encoded, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		if err := b.Put(itob(id), encoded); err != nil {
+			return err
+		}
+
+		return trimHistoryInTx(b, maxHistoryEntries)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return entry.ID, nil
+}
+
+func trimHistoryInTx(b *bolt.Bucket, max int) error {
+	c := b.Cursor()
+	var count int
+	for k, _ := c.Last(); k != nil; k, _ = c.Prev() {
+		if count < max {
+			count++
+			continue
+		}
+		if err := b.Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h *History) Get(id uint64) (*HistoryEntry, error) {
+	var entry HistoryEntry
+	err := h.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket([]byte(historyBucket)).Get(itob(id))
+		if v == nil {
+			return errHistoryEntryNotFound
+		}
+		return json.Unmarshal(v, &entry)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// Update applies fn to a stored entry. A missing entry, e.g. one trimmed or
+// deleted while its notification was still open, is not an error.
+func (h *History) Update(id uint64, fn func(*HistoryEntry)) error {
+	return h.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(historyBucket))
+		v := b.Get(itob(id))
+		if v == nil {
+			return nil
+		}
+
+		var entry HistoryEntry
+		if err := json.Unmarshal(v, &entry); err != nil {
+			return err
+		}
+		fn(&entry)
+		entry.ID = id
+
+		encoded, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		return b.Put(itob(id), encoded)
+	})
+}
+
+// matchesTerms reports whether every lowercase term occurs in the entry's
+// app name, summary or body.
+func (e *HistoryEntry) matchesTerms(terms []string) bool {
+	if len(terms) == 0 {
+		return true
+	}
+	text := strings.ToLower(e.AppName + "\n" + e.Summary + "\n" + e.Body)
+	for _, term := range terms {
+		if !strings.Contains(text, term) {
+			return false
+		}
+	}
+	return true
+}
+
+// Search returns matching entries newest first.
+func (h *History) Search(q HistoryQuery) HistoryResult {
+	if q.Limit <= 0 {
+		q.Limit = 50
+	}
+	if q.Limit > 500 {
+		q.Limit = 500
+	}
+	q.Offset = max(q.Offset, 0)
+
+	terms := strings.Fields(strings.ToLower(q.Query))
+
+	var all []HistoryEntry
+	unread := 0
+	_ = h.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket([]byte(historyBucket)).Cursor()
+		for k, v := c.Last(); k != nil; k, v = c.Prev() {
+			var entry HistoryEntry
+			if err := json.Unmarshal(v, &entry); err != nil {
+				continue
+			}
+
+			if q.AppName != "" && !strings.EqualFold(entry.AppName, q.AppName) {
+				continue
+			}
+			if q.Unread && entry.Read {
+				continue
+			}
+			if q.Before != nil && entry.Timestamp.Unix() >= *q.Before {
+				continue
+			}
+			if q.After != nil && entry.Timestamp.Unix() <= *q.After {
+				continue
+			}
+			if !entry.matchesTerms(terms) {
+				continue
+			}
+
+			if !entry.Read {
+				unread++
+			}
+			all = append(all, entry)
+		}
+		return nil
+	})
+
+	total := len(all)
+	start := min(q.Offset, total)
+	end := min(start+q.Limit, total)
+
+	entries := all[start:end]
+	if entries == nil {
+		entries = []HistoryEntry{}
+	}
+	return HistoryResult{
+		Entries: entries,
+		Total:   total,
+		Unread:  unread,
+		HasMore: end < total,
+	}
+}
+
+func (h *History) Delete(id uint64) error {
+	return h.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(historyBucket))
+		if b.Get(itob(id)) == nil {
+			return errHistoryEntryNotFound
+		}
+		return b.Delete(itob(id))
+	})
+}
+
+// deleteWhere removes every entry match accepts, plus any that no longer
+// decode, and returns how many went.
+func (h *History) deleteWhere(match func(*HistoryEntry) bool) (int, error) {
+	removed := 0
+	err := h.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(historyBucket))
+
+		var toDelete [][]byte
+		c := b.Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			var entry HistoryEntry
+			if err := json.Unmarshal(v, &entry); err != nil || match(&entry) {
+				toDelete = append(toDelete, append([]byte(nil), k...))
+			}
+		}
+		for _, k := range toDelete {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		removed = len(toDelete)
+		return nil
+	})
+	return removed, err
+}
+
+// ClearApp deletes every entry from appName, matched case-insensitively.
+func (h *History) ClearApp(appName string) (int, error) {
+	if appName == "" {
+		return 0, fmt.Errorf("app name required")
+	}
+	return h.deleteWhere(func(e *HistoryEntry) bool {
+		return strings.EqualFold(e.AppName, appName)
+	})
+}
+
+func (h *History) Clear() (int, error) {
+	return h.deleteWhere(func(*HistoryEntry) bool { return true })
+}
+
+// MarkRead marks the given entries read, or every entry when ids is empty,
+// and returns how many changed.
+func (h *History) MarkRead(ids []uint64) (int, error) {
+	changed := 0
+	err := h.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(historyBucket))
+
+		mark := func(k, v []byte) error {
+			var entry HistoryEntry
+			if err := json.Unmarshal(v, &entry); err != nil || entry.Read {
+				return nil
+			}
+			entry.Read = true
+			encoded, err := json.Marshal(entry)
+			if err != nil {
+				return err
+			}
+			changed++
+			return b.Put(k, encoded)
+		}
+
+		if len(ids) > 0 {
+			for _, id := range ids {
+				if v := b.Get(itob(id)); v != nil {
+					if err := mark(itob(id), v); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		}
+
+		updates := map[string][]byte{}
+		if err := b.ForEach(func(k, v []byte) error {
+			updates[string(k)] = v
+			return nil
+		}); err != nil {
+			return err
+		}
+		for k, v := range updates {
+			if err := mark([]byte(k), v); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return changed, err
+}
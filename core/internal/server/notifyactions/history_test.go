@@ -0,0 +1,101 @@
+package notifyactions
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestHistory(t *testing.T) *History {
+	t.Helper()
+	h, err := OpenHistory(filepath.Join(t.TempDir(), "notifications.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { h.Close() })
+	return h
+}
+
+func addEntry(t *testing.T, h *History, app, summary, body string) uint64 {
+	t.Helper()
+	id, err := h.Add(HistoryEntry{AppName: app, Summary: summary, Body: body, Timestamp: time.Now()})
+	require.NoError(t, err)
+	return id
+}
+
+func TestHistory_SearchNewestFirst(t *testing.T) {
+	h := newTestHistory(t)
+	addEntry(t, h, "Firefox", "Download complete", "report.pdf")
+	addEntry(t, h, "Discord", "New message", "lunch at noon?")
+	addEntry(t, h, "firefox", "Download failed", "archive.zip")
+
+	all := h.Search(HistoryQuery{})
+	require.Len(t, all.Entries, 3)
+	assert.Equal(t, "Download failed", all.Entries[0].Summary)
+	assert.Equal(t, 3, all.Unread)
+
+	byApp := h.Search(HistoryQuery{AppName: "FIREFOX"})
+	assert.Equal(t, 2, byApp.Total)
+
+	terms := h.Search(HistoryQuery{Query: "download PDF"})
+	require.Len(t, terms.Entries, 1, "every term must match")
+	assert.Equal(t, "report.pdf", terms.Entries[0].Body)
+
+	page := h.Search(HistoryQuery{Limit: 2})
+	assert.Len(t, page.Entries, 2)
+	assert.True(t, page.HasMore)
+
+	negative := h.Search(HistoryQuery{Limit: -1, Offset: -5})
+	assert.Len(t, negative.Entries, 3, "negative offset and limit fall back to defaults")
+
+	none := h.Search(HistoryQuery{Query: "nothing"})
+	assert.NotNil(t, none.Entries)
+	assert.Empty(t, none.Entries)
+}
+
+func TestHistory_DeleteClearAndMarkRead(t *testing.T) {
+	h := newTestHistory(t)
+	first := addEntry(t, h, "Firefox", "one", "")
+	addEntry(t, h, "Discord", "two", "")
+	third := addEntry(t, h, "firefox", "three", "")
+
+	changed, err := h.MarkRead([]uint64{first, 999})
+	require.NoError(t, err)
+	assert.Equal(t, 1, changed)
+	unread := h.Search(HistoryQuery{Unread: true})
+	assert.Equal(t, 2, unread.Total)
+
+	changed, err = h.MarkRead(nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, changed)
+	assert.Equal(t, 0, h.Search(HistoryQuery{}).Unread)
+
+	require.NoError(t, h.Delete(third))
+	assert.ErrorIs(t, h.Delete(third), errHistoryEntryNotFound)
+
+	removed, err := h.ClearApp("FireFox")
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+	_, err = h.ClearApp("")
+	assert.Error(t, err)
+
+	removed, err = h.Clear()
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+	assert.Equal(t, 0, h.Search(HistoryQuery{}).Total)
+}
+
+func TestHistory_TrimsOldest(t *testing.T) {
+	h := newTestHistory(t)
+	for range maxHistoryEntries + 3 {
+		addEntry(t, h, "app", "n", "")
+	}
+
+	result := h.Search(HistoryQuery{Limit: 500, Offset: maxHistoryEntries - 1})
+	assert.Equal(t, maxHistoryEntries, result.Total)
+	_, err := h.Get(3)
+	assert.ErrorIs(t, err, errHistoryEntryNotFound)
+	_, err = h.Get(4)
+	assert.NoError(t, err)
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/godbus/dbus/v5"
 
 	"github.com/AvengeMedia/DankMaterialShell/core/internal/log"
+	"github.com/AvengeMedia/dankgo/syncmap"
 )
 
 const (
@@ -18,11 +19,21 @@ const (
 	closed          = notifyInterface + ".NotificationClosed"
 )
 
+// Incoming is a notification the notification server accepted, with the ID
+// it assigned.
+type Incoming struct {
+	ID    uint32
+	Entry HistoryEntry
+}
+
 type Manager struct {
-	conn    *dbus.Conn
-	signals chan *dbus.Signal
-	mu      sync.Mutex
-	watched map[uint32]string
+	conn     *dbus.Conn
+	signals  chan *dbus.Signal
+	mu       sync.Mutex
+	watched  map[uint32]string
+	history  *History
+	incoming syncmap.Map[string, chan Incoming]
+	stop     chan struct{}
 }
 
 func NewManager() (*Manager, error) {
@@ -37,12 +48,61 @@ func NewManager() (*Manager, error) {
 		conn:    conn,
 		signals: make(chan *dbus.Signal, 32),
 		watched: make(map[uint32]string),
+		stop:    make(chan struct{}),
 	}
 	conn.Signal(m.signals)
 	go m.loop()
+	m.startHistory()
 	return m, nil
 }
 
+// startHistory opens the history database and starts recording. Without it
+// the manager still serves action watches.
+func (m *Manager) startHistory() {
+	path, err := historyDBPath()
+	if err != nil {
+		log.Warnf("notifyactions: no history database path: %v", err)
+		return
+	}
+	history, err := OpenHistory(path)
+	if err != nil {
+		log.Warnf("notifyactions: failed to open history database: %v", err)
+		return
+	}
+	m.history = history
+	rec := newRecorder(history)
+	rec.emit = m.publishIncoming
+	go rec.run(m.stop)
+}
+
+// SubscribeIncoming streams every notification the recorder sees accepted.
+// It needs the history database, like the recorder itself.
+func (m *Manager) SubscribeIncoming(id string) chan Incoming {
+	ch := make(chan Incoming, 16)
+	m.incoming.Store(id, ch)
+	return ch
+}
+
+func (m *Manager) UnsubscribeIncoming(id string) {
+	if val, ok := m.incoming.LoadAndDelete(id); ok {
+		close(val)
+	}
+}
+
+func (m *Manager) publishIncoming(n Incoming) {
+	m.incoming.Range(func(key string, ch chan Incoming) bool {
+		select {
+		case ch <- n:
+		default:
+		}
+		return true
+	})
+}
+
+func (m *Manager) History() *History {
+	return m.history
+}
+
 func (m *Manager) Watch(id uint32, path string) {
 	m.mu.Lock()
 	m.watched[id] = path
@@ -53,6 +113,15 @@ func (m *Manager) Close() {
 	m.conn.RemoveSignal(m.signals)
 	_ = m.conn.RemoveMatchSignal(dbus.WithMatchObjectPath(notifyPath), dbus.WithMatchInterface(notifyInterface))
 	close(m.signals)
+	close(m.stop)
+	if m.history != nil {
+		_ = m.history.Close()
+	}
+	m.incoming.Range(func(key string, ch chan Incoming) bool {
+		close(ch)
+		m.incoming.Delete(key)
+		return true
+	})
 }
 
 func (m *Manager) loop() {
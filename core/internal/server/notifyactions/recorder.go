@@ -0,0 +1,461 @@
+package notifyactions
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+
+	"github.com/AvengeMedia/DankMaterialShell/core/internal/log"
+)
+
+const (
+	notifyMember         = "Notify"
+	dbusName             = "org.freedesktop.DBus"
+	becomeMonitor        = dbusName + ".Monitoring.BecomeMonitor"
+	getNameOwner         = dbusName + ".GetNameOwner"
+	monitorRetryInterval = 5 * time.Second
+	// maxPendingCalls bounds Notify calls still waiting for their reply; a
+	// server that never answers must not grow the map forever.
+	maxPendingCalls = 256
+	// maxLiveNotifications bounds notifications that were shown but never
+	// closed; the oldest is forgotten first.
+	maxLiveNotifications = 1024
+	// godbus drops monitored messages once this buffer is full, so it has to
+	// absorb a burst while the receive loop hands messages to the writer.
+	monitorBuffer = 4096
+	// maxQueuedMessages bounds messages waiting for the history writer.
+	maxQueuedMessages = 16384
+)
+
+type callKey struct {
+	sender string
+	serial uint32
+}
+
+// recorder turns monitored notification traffic into history entries. It
+// sees each Notify call, the server's reply carrying the assigned ID, and
+// the NotificationClosed and ActionInvoked signals for that ID.
+type recorder struct {
+	history *History
+	now     func() time.Time
+	// emit, when set, is told about each notification once the server has
+	// assigned its ID.
+	emit func(Incoming)
+
+	mu      sync.Mutex
+	pending map[callKey]uint64
+	live    map[uint32]uint64
+	// owner is the notification server the live IDs belong to. Only the
+	// writer touches it.
+	owner string
+
+	// The receive loop only queues work; database writes happen on the
+	// writer goroutine so a burst can't back up into godbus and be dropped.
+	queueMu sync.Mutex
+	queue   []func()
+	queued  chan struct{}
+}
+
+func newRecorder(history *History) *recorder {
+	return &recorder{
+		history: history,
+		now:     time.Now,
+		pending: make(map[callKey]uint64),
+		live:    make(map[uint32]uint64),
+		queued:  make(chan struct{}, 1),
+	}
+}
+
+// monitorRules selects the traffic the recorder needs. Replies are limited
+// to the notification server's unique name, so the monitor has to restart
+// when that name changes hands.
+func monitorRules(owner string) []string {
+	rules := []string{
+		fmt.Sprintf("type='method_call',interface='%s',member='%s'", notifyInterface, notifyMember),
+		fmt.Sprintf("type='signal',interface='%s',member='NotificationClosed'", notifyInterface),
+		fmt.Sprintf("type='signal',interface='%s',member='ActionInvoked'", notifyInterface),
+		fmt.Sprintf("type='signal',sender='%s',interface='%s',member='NameOwnerChanged',arg0='%s'", dbusName, dbusName, notifyInterface),
+	}
+	if owner != "" {
+		rules = append(rules,
+			fmt.Sprintf("type='method_return',sender='%s'", owner),
+			fmt.Sprintf("type='error',sender='%s'", owner),
+		)
+	}
+	return rules
+}
+
+// run records until stop closes, reconnecting when the notification server
+// changes or the monitor connection drops.
+func (r *recorder) run(stop <-chan struct{}) {
+	go r.write(stop)
+
+	for {
+		conn, msgs, err := r.connect()
+		if err != nil {
+			log.Warnf("notifyactions: history monitor: %v", err)
+		} else {
+			restart := r.consume(msgs, stop)
+			conn.Close()
+			if restart {
+				continue
+			}
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-time.After(monitorRetryInterval):
+		}
+	}
+}
+
+func (r *recorder) connect() (*dbus.Conn, chan *dbus.Message, error) {
+	conn, err := dbus.SessionBusPrivate()
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := conn.Auth(nil); err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	if err := conn.Hello(); err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+
+	var owner string
+	if err := conn.BusObject().Call(getNameOwner, 0, notifyInterface).Store(&owner); err != nil {
+		log.Debugf("notifyactions: no notification server yet: %v", err)
+	}
+
+	if err := conn.BusObject().Call(becomeMonitor, 0, monitorRules(owner), uint32(0)).Err; err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("BecomeMonitor: %w", err)
+	}
+
+	// From here on every message is monitored traffic; take it all instead of
+	// letting the connection dispatch it.
+	msgs := make(chan *dbus.Message, monitorBuffer)
+	conn.Eavesdrop(msgs)
+
+	r.enqueue(func() { r.reset(owner) })
+	return conn, msgs, nil
+}
+
+// reset forgets the calls in flight on a new monitor connection, and the
+// live IDs too when the notification server changed: a restarted server
+// hands out its IDs afresh.
+func (r *recorder) reset(owner string) {
+	r.mu.Lock()
+	clear(r.pending)
+	if owner != r.owner {
+		clear(r.live)
+		r.owner = owner
+	}
+	r.mu.Unlock()
+}
+
+func (r *recorder) enqueue(work func()) {
+	r.queueMu.Lock()
+	if len(r.queue) >= maxQueuedMessages {
+		r.queueMu.Unlock()
+		log.Warnf("notifyactions: history writer is behind, dropping a message")
+		return
+	}
+	r.queue = append(r.queue, work)
+	r.queueMu.Unlock()
+
+	select {
+	case r.queued <- struct{}{}:
+	default:
+	}
+}
+
+// write applies queued work in order until stop closes.
+func (r *recorder) write(stop <-chan struct{}) {
+	for {
+		select {
+		case <-stop:
+			return
+		case <-r.queued:
+		}
+
+		r.queueMu.Lock()
+		work := r.queue
+		r.queue = nil
+		r.queueMu.Unlock()
+
+		for _, fn := range work {
+			fn()
+		}
+	}
+}
+
+// consume handles messages until stop closes (false) or the monitor needs
+// a fresh connection (true).
+func (r *recorder) consume(msgs <-chan *dbus.Message, stop <-chan struct{}) bool {
+	for {
+		select {
+		case <-stop:
+			return false
+		case msg, ok := <-msgs:
+			if !ok {
+				return false
+			}
+			if isOwnerChange(msg) {
+				return true
+			}
+			r.enqueue(func() { r.handleMessage(msg) })
+		}
+	}
+}
+
+func headerString(msg *dbus.Message, field dbus.HeaderField) string {
+	v, ok := msg.Headers[field]
+	if !ok {
+		return ""
+	}
+	s, _ := v.Value().(string)
+	return s
+}
+
+func headerSerial(msg *dbus.Message, field dbus.HeaderField) uint32 {
+	v, ok := msg.Headers[field]
+	if !ok {
+		return 0
+	}
+	u, _ := v.Value().(uint32)
+	return u
+}
+
+func isOwnerChange(msg *dbus.Message) bool {
+	return msg.Type == dbus.TypeSignal &&
+		headerString(msg, dbus.FieldInterface) == dbusName &&
+		headerString(msg, dbus.FieldMember) == "NameOwnerChanged"
+}
+
+func (r *recorder) handleMessage(msg *dbus.Message) {
+	switch msg.Type {
+	case dbus.TypeMethodCall:
+		if headerString(msg, dbus.FieldInterface) == notifyInterface && headerString(msg, dbus.FieldMember) == notifyMember {
+			r.recordNotify(msg)
+		}
+	case dbus.TypeMethodReply:
+		r.recordReply(msg)
+	case dbus.TypeError:
+		r.mu.Lock()
+		delete(r.pending, callKey{headerString(msg, dbus.FieldDestination), headerSerial(msg, dbus.FieldReplySerial)})
+		r.mu.Unlock()
+	case dbus.TypeSignal:
+		if headerString(msg, dbus.FieldInterface) != notifyInterface {
+			return
+		}
+		switch headerString(msg, dbus.FieldMember) {
+		case "NotificationClosed":
+			r.recordClosed(msg.Body)
+		case "ActionInvoked":
+			r.recordAction(msg.Body)
+		}
+	}
+}
+
+// parseNotify decodes the arguments of
+// Notify(app_name, replaces_id, app_icon, summary, body, actions, hints, expire_timeout).
+func parseNotify(body []any) (HistoryEntry, uint32, bool) {
+	if len(body) != 8 {
+		return HistoryEntry{}, 0, false
+	}
+	appName, ok1 := body[0].(string)
+	replacesID, ok2 := body[1].(uint32)
+	appIcon, ok3 := body[2].(string)
+	summary, ok4 := body[3].(string)
+	text, ok5 := body[4].(string)
+	actions, ok6 := body[5].([]string)
+	hints, ok7 := body[6].(map[string]dbus.Variant)
+	timeout, ok8 := body[7].(int32)
+	if !ok1 || !ok2 || !ok3 || !ok4 || !ok5 || !ok6 || !ok7 || !ok8 {
+		return HistoryEntry{}, 0, false
+	}
+
+	entry := HistoryEntry{
+		AppName:       appName,
+		AppIcon:       appIcon,
+		Summary:       summary,
+		Body:          text,
+		Urgency:       UrgencyNormal,
+		ExpireTimeout: timeout,
+	}
+	for i := 0; i+1 < len(actions); i += 2 {
+		entry.Actions = append(entry.Actions, HistoryAction{ID: actions[i], Label: actions[i+1]})
+	}
+	for key, v := range hints {
+		if key == "urgency" {
+			if u, ok := v.Value().(byte); ok && u <= UrgencyCritical {
+				entry.Urgency = u
+			}
+		}
+		if value, ok := hintValue(v); ok {
+			if entry.Hints == nil {
+				entry.Hints = make(map[string]any)
+			}
+			entry.Hints[key] = value
+		}
+	}
+	return entry, replacesID, true
+}
+
+// hintValue keeps the scalar hints; image data and other structured values
+// are dropped rather than bloating the database.
+func hintValue(v dbus.Variant) (any, bool) {
+	switch value := v.Value().(type) {
+	case string, bool, byte, int16, uint16, int32, uint32, int64, uint64, float64:
+		return value, true
+	default:
+		return nil, false
+	}
+}
+
+func (r *recorder) recordNotify(msg *dbus.Message) {
+	entry, replacesID, ok := parseNotify(msg.Body)
+	if !ok {
+		return
+	}
+	now := r.now()
+	key := callKey{headerString(msg, dbus.FieldSender), msg.Serial()}
+
+	r.mu.Lock()
+	recordID, replacing := r.live[replacesID]
+	replacing = replacing && replacesID != 0
+	r.mu.Unlock()
+
+	if replacing {
+		err := r.history.Update(recordID, func(e *HistoryEntry) {
+			entry.NotificationID = e.NotificationID
+			entry.Timestamp = e.Timestamp
+			entry.UpdatedAt = &now
+			*e = entry
+		})
+		if err != nil {
+			log.Warnf("notifyactions: failed to update notification history: %v", err)
+			return
+		}
+	} else {
+		entry.Timestamp = now
+		id, err := r.history.Add(entry)
+		if err != nil {
+			log.Warnf("notifyactions: failed to record notification: %v", err)
+			return
+		}
+		recordID = id
+	}
+
+	r.mu.Lock()
+	if len(r.pending) >= maxPendingCalls {
+		clear(r.pending)
+	}
+	r.pending[key] = recordID
+	r.mu.Unlock()
+}
+
+func (r *recorder) recordReply(msg *dbus.Message) {
+	key := callKey{headerString(msg, dbus.FieldDestination), headerSerial(msg, dbus.FieldReplySerial)}
+
+	r.mu.Lock()
+	recordID, ok := r.pending[key]
+	delete(r.pending, key)
+	r.mu.Unlock()
+	if !ok || len(msg.Body) < 1 {
+		return
+	}
+	id, ok := msg.Body[0].(uint32)
+	if !ok {
+		return
+	}
+
+	r.mu.Lock()
+	if _, known := r.live[id]; !known && len(r.live) >= maxLiveNotifications {
+		r.forgetOldestLocked()
+	}
+	r.live[id] = recordID
+	r.mu.Unlock()
+
+	if err := r.history.Update(recordID, func(e *HistoryEntry) { e.NotificationID = id }); err != nil {
+		log.Warnf("notifyactions: failed to update notification history: %v", err)
+		return
+	}
+	if r.emit == nil {
+		return
+	}
+	if entry, err := r.history.Get(recordID); err == nil {
+		r.emit(Incoming{ID: id, Entry: *entry})
+	}
+}
+
+// forgetOldestLocked drops the live notification recorded first. Caller
+// holds mu.
+func (r *recorder) forgetOldestLocked() {
+	var oldestID uint32
+	var oldest uint64
+	first := true
+	for id, recordID := range r.live {
+		if first || recordID < oldest {
+			oldestID, oldest, first = id, recordID, false
+		}
+	}
+	delete(r.live, oldestID)
+}
+
+func (r *recorder) recordClosed(body []any) {
+	if len(body) < 2 {
+		return
+	}
+	id, ok1 := body[0].(uint32)
+	reason, ok2 := body[1].(uint32)
+	if !ok1 || !ok2 {
+		return
+	}
+
+	r.mu.Lock()
+	recordID, ok := r.live[id]
+	delete(r.live, id)
+	r.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	now := r.now()
+	if err := r.history.Update(recordID, func(e *HistoryEntry) {
+		e.ClosedAt = &now
+		e.CloseReason = reason
+	}); err != nil {
+		log.Warnf("notifyactions: failed to update notification history: %v", err)
+	}
+}
+
+func (r *recorder) recordAction(body []any) {
+	if len(body) < 2 {
+		return
+	}
+	id, ok1 := body[0].(uint32)
+	action, ok2 := body[1].(string)
+	if !ok1 || !ok2 {
+		return
+	}
+
+	r.mu.Lock()
+	recordID, ok := r.live[id]
+	r.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	if err := r.history.Update(recordID, func(e *HistoryEntry) {
+		e.InvokedAction = action
+		e.Read = true
+	}); err != nil {
+		log.Warnf("notifyactions: failed to update notification history: %v", err)
+	}
+}
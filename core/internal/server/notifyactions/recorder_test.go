@@ -0,0 +1,203 @@
+package notifyactions
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testClient = ":1.42"
+
+func notifyCall(t *testing.T, serial uint32, replacesID uint32, summary string, hints map[string]dbus.Variant) *dbus.Message {
+	t.Helper()
+	msg := &dbus.Message{
+		Type: dbus.TypeMethodCall,
+		Headers: map[dbus.HeaderField]dbus.Variant{
+			dbus.FieldSender:    dbus.MakeVariant(testClient),
+			dbus.FieldPath:      dbus.MakeVariant(dbus.ObjectPath(notifyPath)),
+			dbus.FieldInterface: dbus.MakeVariant(notifyInterface),
+			dbus.FieldMember:    dbus.MakeVariant(notifyMember),
+		},
+		Body: []any{"Mail", replacesID, "mail-unread", summary, "body text",
+			[]string{"default", "Open", "archive", "Archive"}, hints, int32(-1)},
+	}
+	return withSerial(t, msg, serial)
+}
+
+// withSerial round-trips msg through the wire format, the only way to give
+// it a serial outside a connection.
+func withSerial(t *testing.T, msg *dbus.Message, serial uint32) *dbus.Message {
+	t.Helper()
+	var buf bytes.Buffer
+	msg.Headers[dbus.FieldSignature] = dbus.MakeVariant(dbus.SignatureOf(msg.Body...))
+	require.NoError(t, msg.EncodeTo(&buf, binary.LittleEndian))
+	raw := buf.Bytes()
+	binary.LittleEndian.PutUint32(raw[8:12], serial)
+	decoded, err := dbus.DecodeMessage(bytes.NewReader(raw))
+	require.NoError(t, err)
+	return decoded
+}
+
+func notifyReply(serial uint32, id uint32) *dbus.Message {
+	return &dbus.Message{
+		Type: dbus.TypeMethodReply,
+		Headers: map[dbus.HeaderField]dbus.Variant{
+			dbus.FieldDestination: dbus.MakeVariant(testClient),
+			dbus.FieldReplySerial: dbus.MakeVariant(serial),
+		},
+		Body: []any{id},
+	}
+}
+
+func notifySignal(member string, body ...any) *dbus.Message {
+	return &dbus.Message{
+		Type: dbus.TypeSignal,
+		Headers: map[dbus.HeaderField]dbus.Variant{
+			dbus.FieldPath:      dbus.MakeVariant(dbus.ObjectPath(notifyPath)),
+			dbus.FieldInterface: dbus.MakeVariant(notifyInterface),
+			dbus.FieldMember:    dbus.MakeVariant(member),
+		},
+		Body: body,
+	}
+}
+
+func TestParseNotify(t *testing.T) {
+	entry, replacesID, ok := parseNotify(notifyCall(t, 1, 7, "Hi", map[string]dbus.Variant{
+		"urgency":       dbus.MakeVariant(byte(2)),
+		"desktop-entry": dbus.MakeVariant("thunderbird"),
+		"image-data":    dbus.MakeVariant([]any{int32(1), int32(1)}),
+	}).Body)
+	require.True(t, ok)
+	assert.Equal(t, uint32(7), replacesID)
+	assert.Equal(t, UrgencyCritical, entry.Urgency)
+	assert.Equal(t, []HistoryAction{{"default", "Open"}, {"archive", "Archive"}}, entry.Actions)
+	assert.Equal(t, "thunderbird", entry.Hints["desktop-entry"])
+	assert.NotContains(t, entry.Hints, "image-data", "structured hints are not stored")
+
+	_, _, ok = parseNotify([]any{"too", "short"})
+	assert.False(t, ok)
+}
+
+func TestMonitorRules(t *testing.T) {
+	assert.Len(t, monitorRules(""), 4)
+	rules := monitorRules(":1.7")
+	assert.Contains(t, rules, "type='method_return',sender=':1.7'")
+}
+
+func TestRecorder_Lifecycle(t *testing.T) {
+	h := newTestHistory(t)
+	r := newRecorder(h)
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	r.now = func() time.Time { return now }
+	var incoming []Incoming
+	r.emit = func(n Incoming) { incoming = append(incoming, n) }
+
+	r.handleMessage(notifyCall(t, 10, 0, "First", nil))
+	r.handleMessage(notifyReply(10, 55))
+	require.Len(t, incoming, 1)
+	assert.Equal(t, uint32(55), incoming[0].ID)
+	assert.Equal(t, "First", incoming[0].Entry.Summary)
+
+	entries := h.Search(HistoryQuery{}).Entries
+	require.Len(t, entries, 1)
+	assert.Equal(t, uint32(55), entries[0].NotificationID)
+	assert.Equal(t, "Mail", entries[0].AppName)
+	assert.Equal(t, UrgencyNormal, entries[0].Urgency)
+	assert.Empty(t, r.pending)
+
+	// Replacing an open notification updates its entry in place.
+	r.handleMessage(notifyCall(t, 11, 55, "Second", nil))
+	r.handleMessage(notifyReply(11, 55))
+	entries = h.Search(HistoryQuery{}).Entries
+	require.Len(t, entries, 1)
+	assert.Equal(t, "Second", entries[0].Summary)
+	assert.Equal(t, uint32(55), entries[0].NotificationID)
+	require.NotNil(t, entries[0].UpdatedAt)
+
+	r.handleMessage(notifySignal("ActionInvoked", uint32(55), "archive"))
+	r.handleMessage(notifySignal("NotificationClosed", uint32(55), uint32(2)))
+	entry, err := h.Get(entries[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, "archive", entry.InvokedAction)
+	assert.True(t, entry.Read)
+	require.NotNil(t, entry.ClosedAt)
+	assert.Equal(t, uint32(2), entry.CloseReason)
+	assert.Empty(t, r.live)
+
+	// Once closed, reusing the ID starts a new entry.
+	r.handleMessage(notifyCall(t, 12, 55, "Third", nil))
+	assert.Equal(t, 2, h.Search(HistoryQuery{}).Total)
+
+	// Signals for notifications sent before recording began are ignored.
+	r.handleMessage(notifySignal("NotificationClosed", uint32(99), uint32(1)))
+	assert.Equal(t, 2, h.Search(HistoryQuery{}).Total)
+}
+
+func TestIsOwnerChange(t *testing.T) {
+	msg := &dbus.Message{
+		Type: dbus.TypeSignal,
+		Headers: map[dbus.HeaderField]dbus.Variant{
+			dbus.FieldInterface: dbus.MakeVariant(dbusName),
+			dbus.FieldMember:    dbus.MakeVariant("NameOwnerChanged"),
+		},
+	}
+	assert.True(t, isOwnerChange(msg))
+	assert.False(t, isOwnerChange(notifySignal("NotificationClosed", uint32(1), uint32(1))))
+}
+
+func TestRecorder_ConsumeHandsOffToWriter(t *testing.T) {
+	h := newTestHistory(t)
+	r := newRecorder(h)
+
+	msgs := make(chan *dbus.Message, 2)
+	msgs <- notifyCall(t, 10, 0, "First", nil)
+	msgs <- notifyReply(10, 55)
+	close(msgs)
+	assert.False(t, r.consume(msgs, nil))
+	assert.Zero(t, h.Search(HistoryQuery{}).Total, "the receive loop must not write")
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go r.write(stop)
+
+	require.Eventually(t, func() bool {
+		entries := h.Search(HistoryQuery{}).Entries
+		return len(entries) == 1 && entries[0].NotificationID == 55
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestRecorder_ResetOnOwnerChange(t *testing.T) {
+	r := newRecorder(newTestHistory(t))
+	r.reset(":1.5")
+
+	r.handleMessage(notifyCall(t, 10, 0, "First", nil))
+	r.handleMessage(notifyReply(10, 55))
+	r.handleMessage(notifyCall(t, 11, 0, "Second", nil))
+	require.Len(t, r.live, 1)
+	require.Len(t, r.pending, 1)
+
+	r.reset(":1.5")
+	assert.Empty(t, r.pending)
+	assert.Len(t, r.live, 1, "a reconnect to the same server keeps its IDs")
+
+	r.reset(":1.9")
+	assert.Empty(t, r.live, "a restarted server reuses IDs")
+}
+
+func TestRecorder_LiveIsBounded(t *testing.T) {
+	r := newRecorder(newTestHistory(t))
+	for i := range maxLiveNotifications {
+		r.live[uint32(1000+i)] = uint64(1000 + i)
+	}
+
+	r.handleMessage(notifyCall(t, 10, 0, "First", nil))
+	r.handleMessage(notifyReply(10, 55))
+	assert.Len(t, r.live, maxLiveNotifications)
+	assert.NotContains(t, r.live, uint32(1000), "the oldest is forgotten")
+	assert.Contains(t, r.live, uint32(55))
+}
@@ -0,0 +1,209 @@
+package notifyrules
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// override is DND set by hand. Without an end time it lasts until the
+// automatic state it was set against changes, so a schedule starting or
+// ending still takes effect.
+type override struct {
+	active  bool
+	profile string
+	until   time.Time
+	autoKey string
+}
+
+func parseClock(s string) (hour, minute int, err error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid time %q: use HH:MM", s)
+	}
+	return t.Hour(), t.Minute(), nil
+}
+
+func (s Schedule) validate() error {
+	if _, _, err := parseClock(s.Start); err != nil {
+		return err
+	}
+	if _, _, err := parseClock(s.End); err != nil {
+		return err
+	}
+	for _, day := range s.Days {
+		if day < 0 || day > 6 {
+			return fmt.Errorf("invalid day %d: use 0 (Sunday) to 6", day)
+		}
+	}
+	return nil
+}
+
+// window returns the occurrence of s that starts on day's date.
+func (s Schedule) window(day time.Time) (start, end time.Time) {
+	sh, sm, _ := parseClock(s.Start)
+	eh, em, _ := parseClock(s.End)
+	start = time.Date(day.Year(), day.Month(), day.Day(), sh, sm, 0, 0, day.Location())
+	end = time.Date(day.Year(), day.Month(), day.Day(), eh, em, 0, 0, day.Location())
+	if !end.After(start) {
+		end = end.AddDate(0, 0, 1)
+	}
+	return start, end
+}
+
+func (s Schedule) onDay(day time.Time) bool {
+	return len(s.Days) == 0 || slices.Contains(s.Days, int(day.Weekday()))
+}
+
+// activeAt reports whether now falls in a window of s, and when that window
+// ends. Yesterday's window is checked for ones running past midnight.
+func (s Schedule) activeAt(now time.Time) (bool, time.Time) {
+	for _, offset := range []int{-1, 0} {
+		day := now.AddDate(0, 0, offset)
+		if !s.onDay(day) {
+			continue
+		}
+		start, end := s.window(day)
+		if !now.Before(start) && now.Before(end) {
+			return true, end
+		}
+	}
+	return false, time.Time{}
+}
+
+// nextStart returns the first window start after now within a week.
+func (s Schedule) nextStart(now time.Time) (time.Time, bool) {
+	for offset := range 8 {
+		day := now.AddDate(0, 0, offset)
+		if !s.onDay(day) {
+			continue
+		}
+		if start, _ := s.window(day); start.After(now) {
+			return start, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func (p Profile) validate() error {
+	if p.ID == "" {
+		return fmt.Errorf("profile id required")
+	}
+	for _, s := range p.Schedules {
+		if err := s.validate(); err != nil {
+			return fmt.Errorf("profile %s: %w", p.ID, err)
+		}
+	}
+	if slices.Contains(p.AllowApps, "") {
+		return fmt.Errorf("profile %s: empty app in allowApps", p.ID)
+	}
+	return nil
+}
+
+func validateProfiles(profiles []Profile) error {
+	seen := make(map[string]bool, len(profiles))
+	for _, p := range profiles {
+		if seen[p.ID] {
+			return fmt.Errorf("duplicate profile id: %s", p.ID)
+		}
+		seen[p.ID] = true
+		if err := p.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func findProfile(profiles []Profile, id string) *Profile {
+	for i := range profiles {
+		if profiles[i].ID == id {
+			return &profiles[i]
+		}
+	}
+	return nil
+}
+
+func earliest(a *time.Time, b time.Time) *time.Time {
+	if b.IsZero() || (a != nil && !b.Before(*a)) {
+		return a
+	}
+	return &b
+}
+
+// autoDND resolves the profile the schedules and reported activity turn on:
+// the first enabled profile with an active trigger. next is the soonest time
+// any schedule starts or ends.
+func autoDND(profiles []Profile, now time.Time, fullscreen, screenSharing bool) (state DNDState, next *time.Time) {
+	for _, p := range profiles {
+		if !p.Enabled {
+			continue
+		}
+
+		scheduled := false
+		var until time.Time
+		for _, s := range p.Schedules {
+			if active, end := s.activeAt(now); active {
+				next = earliest(next, end)
+				if end.After(until) {
+					until = end
+				}
+				scheduled = true
+			}
+			if start, ok := s.nextStart(now); ok {
+				next = earliest(next, start)
+			}
+		}
+
+		if state.Active {
+			continue
+		}
+		switch {
+		case scheduled:
+			state = DNDState{Active: true, Profile: p.ID, Reason: ReasonSchedule, Until: &until}
+		case p.WhenScreenSharing && screenSharing:
+			state = DNDState{Active: true, Profile: p.ID, Reason: ReasonScreenSharing}
+		case p.WhenFullscreen && fullscreen:
+			state = DNDState{Active: true, Profile: p.ID, Reason: ReasonFullscreen}
+		}
+	}
+	return state, next
+}
+
+func autoKey(state DNDState) string {
+	if !state.Active {
+		return ""
+	}
+	return state.Profile + "/" + state.Reason
+}
+
+// resolveDND combines the automatic state with a manual override, dropping
+// the override once it has run out. It returns the override still in force.
+func resolveDND(profiles []Profile, o *override, now time.Time, fullscreen, screenSharing bool) (DNDState, *override) {
+	auto, next := autoDND(profiles, now, fullscreen, screenSharing)
+
+	if o != nil {
+		expired := !o.until.IsZero() && !now.Before(o.until)
+		stale := o.until.IsZero() && o.autoKey != autoKey(auto)
+		if expired || stale {
+			o = nil
+		}
+	}
+
+	state := auto
+	if o != nil {
+		state = DNDState{Active: o.active}
+		if o.active {
+			state.Profile = o.profile
+			state.Reason = ReasonManual
+		}
+		if !o.until.IsZero() {
+			until := o.until
+			state.Until = &until
+			next = earliest(next, o.until)
+		}
+	}
+	state.Fullscreen = fullscreen
+	state.ScreenSharing = screenSharing
+	state.NextTransition = next
+	return state, o
+}
@@ -0,0 +1,124 @@
+package notifyrules
+
+import (
+	"testing"
+	"time"
+
+	"github.com/AvengeMedia/DankMaterialShell/core/internal/server/notifyactions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// 2026-03-02 is a Monday.
+func at(day, hour, minute int) time.Time {
+	return time.Date(2026, 3, day, hour, minute, 0, 0, time.Local)
+}
+
+func TestSchedule_Windows(t *testing.T) {
+	evenings := Schedule{Start: "18:00", End: "08:00", Days: []int{1, 2, 3, 4, 5}}
+
+	active, end := evenings.activeAt(at(2, 19, 0))
+	assert.True(t, active)
+	assert.Equal(t, at(3, 8, 0), end)
+
+	active, _ = evenings.activeAt(at(3, 7, 59))
+	assert.True(t, active, "Monday's window runs into Tuesday morning")
+
+	active, _ = evenings.activeAt(at(2, 7, 0))
+	assert.False(t, active, "Sunday evening is not scheduled")
+
+	next, ok := evenings.nextStart(at(6, 20, 0))
+	require.True(t, ok)
+	assert.Equal(t, at(9, 18, 0), next, "Friday's window is followed by Monday's")
+
+	assert.Error(t, Schedule{Start: "25:00", End: "01:00"}.validate())
+	assert.Error(t, Schedule{Start: "10:00", End: "11:00", Days: []int{7}}.validate())
+}
+
+func TestResolveDND(t *testing.T) {
+	profiles := []Profile{
+		{ID: "evening", Enabled: true, Schedules: []Schedule{{Start: "18:00", End: "22:00"}}},
+		{ID: "focus", Enabled: true, WhenFullscreen: true, WhenScreenSharing: true},
+		{ID: "off", Enabled: false, WhenFullscreen: true},
+	}
+
+	state, o := resolveDND(profiles, nil, at(2, 12, 0), false, false)
+	assert.False(t, state.Active)
+	assert.Nil(t, o)
+	require.NotNil(t, state.NextTransition)
+	assert.Equal(t, at(2, 18, 0), *state.NextTransition)
+
+	state, _ = resolveDND(profiles, nil, at(2, 12, 0), false, true)
+	assert.Equal(t, "focus", state.Profile)
+	assert.Equal(t, ReasonScreenSharing, state.Reason)
+
+	state, _ = resolveDND(profiles, nil, at(2, 19, 0), true, false)
+	assert.Equal(t, "evening", state.Profile, "earlier profiles win")
+	assert.Equal(t, ReasonSchedule, state.Reason)
+	assert.Equal(t, at(2, 22, 0), *state.Until)
+
+	// Turning DND off by hand lasts until the schedule ends.
+	off := &override{active: false, autoKey: "evening/" + ReasonSchedule}
+	state, o = resolveDND(profiles, off, at(2, 20, 0), false, false)
+	assert.False(t, state.Active)
+	assert.Same(t, off, o)
+	state, o = resolveDND(profiles, off, at(2, 22, 0), false, false)
+	assert.False(t, state.Active)
+	assert.Nil(t, o)
+
+	timed := &override{active: true, until: at(2, 13, 0)}
+	state, _ = resolveDND(profiles, timed, at(2, 12, 0), false, false)
+	assert.Equal(t, ReasonManual, state.Reason)
+	assert.Equal(t, at(2, 13, 0), *state.NextTransition)
+	_, o = resolveDND(profiles, timed, at(2, 13, 0), false, false)
+	assert.Nil(t, o)
+}
+
+func TestManager_DNDAndRules(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	m := newManager(Config{Rules: []Rule{}, Profiles: []Profile{}}, func() time.Time { return at(2, 12, 0) })
+	t.Cleanup(m.Close)
+	ch := m.Subscribe("test")
+
+	require.NoError(t, m.SetProfiles([]Profile{{ID: "focus", Enabled: true, WhenFullscreen: true}}))
+	<-ch
+
+	fullscreen := true
+	m.SetActivity(&fullscreen, nil)
+	state := <-ch
+	assert.True(t, state.DND.Active)
+	assert.Equal(t, ReasonFullscreen, state.DND.Reason)
+
+	rule, err := m.AddRule(Rule{Enabled: true, Match: Match{App: "Slack"}, Action: Action{Suppress: true}})
+	require.NoError(t, err)
+	assert.Equal(t, "rule-1", rule.ID)
+	<-ch
+	assert.False(t, m.Evaluate(Notification{AppName: "Slack"}).Show)
+	assert.True(t, m.Evaluate(Notification{AppName: "Other"}).Muted)
+
+	decisions := m.SubscribeDecisions("test")
+	m.decide(7, notificationFrom(notifyactions.HistoryEntry{
+		AppName: "chat",
+		Hints:   map[string]any{"desktop-entry": "slack"},
+	}))
+	decided := <-decisions
+	assert.Equal(t, uint32(7), decided.ID)
+	assert.Equal(t, "slack", decided.Notification.DesktopEntry)
+	assert.True(t, decided.Decision.Suppressed, "the desktop entry matches the app rule")
+
+	require.NoError(t, m.SetDND(false, "", 0))
+	assert.False(t, m.GetDND().Active)
+	assert.Error(t, m.SetDND(true, "missing", 0))
+
+	fullscreen = false
+	m.SetActivity(&fullscreen, nil)
+	assert.False(t, m.GetDND().Active)
+
+	reloaded := loadConfig()
+	assert.Len(t, reloaded.Rules, 1)
+	assert.Len(t, reloaded.Profiles, 1)
+
+	require.NoError(t, m.RemoveRule("rule-1"))
+	assert.Error(t, m.RemoveRule("rule-1"))
+}
@@ -0,0 +1,194 @@
+package notifyrules
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/AvengeMedia/DankMaterialShell/core/internal/server/models"
+	"github.com/AvengeMedia/dankgo/ipc/params"
+)
+
+func HandleRequest(conn *models.Conn, req models.Request, m *Manager) {
+	switch req.Method {
+	case "notify.rules.getState", "notify.dnd.getState":
+		models.Respond(conn, req.ID, m.GetState())
+	case "notify.rules.set":
+		handleSetRules(conn, req, m)
+	case "notify.rules.add":
+		handleAddRule(conn, req, m)
+	case "notify.rules.remove":
+		handleRemoveRule(conn, req, m)
+	case "notify.rules.evaluate":
+		handleEvaluate(conn, req, m)
+	case "notify.rules.subscribe", "notify.dnd.subscribe":
+		handleSubscribe(conn, req, m)
+	case "notify.dnd.set":
+		handleSetDND(conn, req, m)
+	case "notify.dnd.clear":
+		m.ClearDND()
+		models.Respond(conn, req.ID, m.GetState())
+	case "notify.dnd.setProfiles":
+		handleSetProfiles(conn, req, m)
+	case "notify.dnd.setActivity":
+		handleSetActivity(conn, req, m)
+	default:
+		models.RespondError(conn, req.ID, "unknown method: "+req.Method)
+	}
+}
+
+// decodeParam re-decodes a JSON-shaped parameter into v.
+func decodeParam(req models.Request, key string, v any) error {
+	raw, ok := models.Get[any](req, key)
+	if !ok {
+		return fmt.Errorf("missing or invalid '%s' parameter", key)
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return fmt.Errorf("missing or invalid '%s' parameter", key)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("invalid '%s' parameter: %v", key, err)
+	}
+	return nil
+}
+
+func handleSetRules(conn *models.Conn, req models.Request, m *Manager) {
+	var rules []Rule
+	if err := decodeParam(req, "rules", &rules); err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+
+	if err := m.SetRules(rules); err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+
+	models.Respond(conn, req.ID, m.GetState())
+}
+
+func handleAddRule(conn *models.Conn, req models.Request, m *Manager) {
+	rule := Rule{Enabled: true}
+	if err := decodeParam(req, "rule", &rule); err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+
+	added, err := m.AddRule(rule)
+	if err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+
+	models.Respond(conn, req.ID, added)
+}
+
+func handleRemoveRule(conn *models.Conn, req models.Request, m *Manager) {
+	id, err := params.String(req.Params, "id")
+	if err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+
+	if err := m.RemoveRule(id); err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+
+	models.Respond(conn, req.ID, models.SuccessResult{Success: true, Message: "rule removed"})
+}
+
+func handleEvaluate(conn *models.Conn, req models.Request, m *Manager) {
+	n := Notification{
+		AppName:      params.StringOpt(req.Params, "appName", ""),
+		DesktopEntry: params.StringOpt(req.Params, "desktopEntry", ""),
+		Summary:      params.StringOpt(req.Params, "summary", ""),
+		Body:         params.StringOpt(req.Params, "body", ""),
+		Category:     params.StringOpt(req.Params, "category", ""),
+		Urgency:      UrgencyNormal,
+		Timeout:      int32(params.IntOpt(req.Params, "timeout", -1)),
+	}
+	if urgency, ok := models.Get[float64](req, "urgency"); ok {
+		if urgency < 0 || urgency > float64(UrgencyCritical) {
+			models.RespondError(conn, req.ID, "invalid urgency parameter")
+			return
+		}
+		n.Urgency = uint8(urgency)
+	}
+
+	models.Respond(conn, req.ID, m.Evaluate(n))
+}
+
+func handleSetDND(conn *models.Conn, req models.Request, m *Manager) {
+	enabled, err := params.Bool(req.Params, "enabled")
+	if err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+	profile := params.StringOpt(req.Params, "profile", "")
+	duration := time.Duration(params.IntOpt(req.Params, "duration", 0)) * time.Second
+
+	if err := m.SetDND(enabled, profile, duration); err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+
+	models.Respond(conn, req.ID, m.GetState())
+}
+
+func handleSetProfiles(conn *models.Conn, req models.Request, m *Manager) {
+	var profiles []Profile
+	if err := decodeParam(req, "profiles", &profiles); err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+
+	if err := m.SetProfiles(profiles); err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+
+	models.Respond(conn, req.ID, m.GetState())
+}
+
+func handleSetActivity(conn *models.Conn, req models.Request, m *Manager) {
+	var fullscreen, screenSharing *bool
+	if v, ok := models.Get[bool](req, "fullscreen"); ok {
+		fullscreen = &v
+	}
+	if v, ok := models.Get[bool](req, "screenSharing"); ok {
+		screenSharing = &v
+	}
+	if fullscreen == nil && screenSharing == nil {
+		models.RespondError(conn, req.ID, "missing 'fullscreen' or 'screenSharing' parameter")
+		return
+	}
+
+	m.SetActivity(fullscreen, screenSharing)
+	models.Respond(conn, req.ID, m.GetDND())
+}
+
+func handleSubscribe(conn *models.Conn, req models.Request, m *Manager) {
+	clientID := fmt.Sprintf("notify-rules-%d", req.ID)
+
+	ch := m.Subscribe(clientID)
+	defer m.Unsubscribe(clientID)
+
+	initialState := m.GetState()
+	if err := conn.WriteResponse(models.Response[State]{
+		ID:     req.ID,
+		Result: &initialState,
+	}); err != nil {
+		return
+	}
+
+	for state := range ch {
+		if err := conn.WriteResponse(models.Response[State]{
+			ID:     req.ID,
+			Result: &state,
+		}); err != nil {
+			return
+		}
+	}
+}
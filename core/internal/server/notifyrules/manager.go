@@ -0,0 +1,440 @@
+package notifyrules
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/AvengeMedia/DankMaterialShell/core/internal/log"
+	"github.com/AvengeMedia/DankMaterialShell/core/internal/server/notifyactions"
+	"github.com/AvengeMedia/dankgo/syncmap"
+)
+
+type Manager struct {
+	mu            sync.RWMutex
+	config        Config
+	rules         []compiledRule
+	override      *override
+	fullscreen    bool
+	screenSharing bool
+	dnd           DNDState
+
+	now           func() time.Time
+	subscribers   syncmap.Map[string, chan State]
+	decisions     syncmap.Map[string, chan Decided]
+	updateTrigger chan struct{}
+	stopChan      chan struct{}
+	wg            sync.WaitGroup
+	closeOnce     sync.Once
+}
+
+func configPath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "DankMaterialShell", "notify-rules.json"), nil
+}
+
+func loadConfig() Config {
+	cfg := Config{Rules: []Rule{}, Profiles: []Profile{}}
+	path, err := configPath()
+	if err != nil {
+		return cfg
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg
+	}
+	var loaded Config
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		log.Warnf("Invalid notification rules %s: %v", path, err)
+		return cfg
+	}
+	if _, err := compileRules(loaded.Rules); err != nil {
+		log.Warnf("Invalid notification rules %s: %v", path, err)
+		return cfg
+	}
+	if err := validateProfiles(loaded.Profiles); err != nil {
+		log.Warnf("Invalid notification rules %s: %v", path, err)
+		return cfg
+	}
+	if loaded.Rules != nil {
+		cfg.Rules = loaded.Rules
+	}
+	if loaded.Profiles != nil {
+		cfg.Profiles = loaded.Profiles
+	}
+	return cfg
+}
+
+func saveConfig(cfg Config) error {
+	path, err := configPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+func NewManager() *Manager {
+	return newManager(loadConfig(), time.Now)
+}
+
+func newManager(cfg Config, now func() time.Time) *Manager {
+	rules, _ := compileRules(cfg.Rules)
+	m := &Manager{
+		config:        cfg,
+		rules:         rules,
+		now:           now,
+		updateTrigger: make(chan struct{}, 1),
+		stopChan:      make(chan struct{}),
+	}
+	m.refresh()
+
+	m.wg.Add(1)
+	go m.schedulerLoop()
+	return m
+}
+
+// schedulerLoop re-resolves DND whenever a schedule or manual DND is due to
+// change.
+func (m *Manager) schedulerLoop() {
+	defer m.wg.Done()
+
+	for {
+		wait := 24 * time.Hour
+		if next := m.GetDND().NextTransition; next != nil {
+			wait = max(next.Sub(m.now()), time.Second)
+		}
+		timer := time.NewTimer(wait)
+
+		select {
+		case <-m.stopChan:
+			timer.Stop()
+			return
+		case <-m.updateTrigger:
+			timer.Stop()
+		case <-timer.C:
+			m.refresh()
+		}
+	}
+}
+
+func (m *Manager) triggerUpdate() {
+	select {
+	case m.updateTrigger <- struct{}{}:
+	default:
+	}
+}
+
+// resolve recomputes the DND state and reports whether it changed.
+func (m *Manager) resolve() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	state, o := resolveDND(m.config.Profiles, m.override, m.now(), m.fullscreen, m.screenSharing)
+	m.override = o
+	changed := !dndEqual(m.dnd, state)
+	m.dnd = state
+	return changed
+}
+
+func (m *Manager) refresh() {
+	if m.resolve() {
+		m.notifySubscribers()
+	}
+}
+
+func timesEqual(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+func dndEqual(a, b DNDState) bool {
+	return a.Active == b.Active &&
+		a.Profile == b.Profile &&
+		a.Reason == b.Reason &&
+		a.Fullscreen == b.Fullscreen &&
+		a.ScreenSharing == b.ScreenSharing &&
+		timesEqual(a.Until, b.Until) &&
+		timesEqual(a.NextTransition, b.NextTransition)
+}
+
+func (m *Manager) GetState() State {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return State{
+		Rules:    slices.Clone(m.config.Rules),
+		Profiles: slices.Clone(m.config.Profiles),
+		DND:      m.dnd,
+	}
+}
+
+func (m *Manager) GetDND() DNDState {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.dnd
+}
+
+// Evaluate decides how the notification server should present n. The
+// server has to ask before showing n; that is the only point where
+// suppress and muted can still take effect.
+func (m *Manager) Evaluate(n Notification) Decision {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return evaluate(m.rules, m.dnd, findProfile(m.config.Profiles, m.dnd.Profile), n)
+}
+
+func (m *Manager) applyConfig(cfg Config, rules []compiledRule) {
+	if err := saveConfig(cfg); err != nil {
+		log.Warnf("Failed to save notification rules: %v", err)
+	}
+
+	m.mu.Lock()
+	m.config = cfg
+	m.rules = rules
+	m.mu.Unlock()
+
+	m.resolve()
+	m.notifySubscribers()
+	m.triggerUpdate()
+}
+
+func (m *Manager) SetRules(rules []Rule) error {
+	if rules == nil {
+		rules = []Rule{}
+	}
+	compiled, err := compileRules(rules)
+	if err != nil {
+		return err
+	}
+
+	m.mu.RLock()
+	cfg := Config{Rules: rules, Profiles: m.config.Profiles}
+	m.mu.RUnlock()
+
+	m.applyConfig(cfg, compiled)
+	return nil
+}
+
+// AddRule appends rule, or replaces the rule with the same ID in place. A
+// rule without an ID gets one.
+func (m *Manager) AddRule(rule Rule) (Rule, error) {
+	m.mu.RLock()
+	rules := slices.Clone(m.config.Rules)
+	m.mu.RUnlock()
+
+	if rule.ID == "" {
+		for n := len(rules) + 1; ; n++ {
+			id := fmt.Sprintf("rule-%d", n)
+			if !slices.ContainsFunc(rules, func(r Rule) bool { return r.ID == id }) {
+				rule.ID = id
+				break
+			}
+		}
+	}
+
+	if i := slices.IndexFunc(rules, func(r Rule) bool { return r.ID == rule.ID }); i >= 0 {
+		rules[i] = rule
+	} else {
+		rules = append(rules, rule)
+	}
+	return rule, m.SetRules(rules)
+}
+
+func (m *Manager) RemoveRule(id string) error {
+	m.mu.RLock()
+	rules := slices.Clone(m.config.Rules)
+	m.mu.RUnlock()
+
+	i := slices.IndexFunc(rules, func(r Rule) bool { return r.ID == id })
+	if i < 0 {
+		return fmt.Errorf("rule not found: %s", id)
+	}
+	return m.SetRules(slices.Delete(rules, i, i+1))
+}
+
+func (m *Manager) SetProfiles(profiles []Profile) error {
+	if profiles == nil {
+		profiles = []Profile{}
+	}
+	if err := validateProfiles(profiles); err != nil {
+		return err
+	}
+
+	m.mu.RLock()
+	cfg := Config{Rules: m.config.Rules, Profiles: profiles}
+	rules := m.rules
+	m.mu.RUnlock()
+
+	m.applyConfig(cfg, rules)
+	return nil
+}
+
+// SetDND turns DND on or off by hand, optionally with a profile's exceptions
+// and for a limited time. A zero duration lasts until the next automatic
+// change.
+func (m *Manager) SetDND(active bool, profile string, duration time.Duration) error {
+	if duration < 0 {
+		return fmt.Errorf("duration must not be negative")
+	}
+
+	m.mu.Lock()
+	if profile != "" && findProfile(m.config.Profiles, profile) == nil {
+		m.mu.Unlock()
+		return fmt.Errorf("profile not found: %s", profile)
+	}
+	now := m.now()
+	auto, _ := autoDND(m.config.Profiles, now, m.fullscreen, m.screenSharing)
+	o := &override{active: active, profile: profile, autoKey: autoKey(auto)}
+	if duration > 0 {
+		o.until = now.Add(duration)
+	}
+	m.override = o
+	m.mu.Unlock()
+
+	m.refresh()
+	m.triggerUpdate()
+	return nil
+}
+
+// ClearDND drops manual DND so the schedules and activity decide again.
+func (m *Manager) ClearDND() {
+	m.mu.Lock()
+	m.override = nil
+	m.mu.Unlock()
+
+	m.refresh()
+	m.triggerUpdate()
+}
+
+// SetActivity records whether a fullscreen window or a screen share is
+// active. The shell reports both, since only it tracks the compositor's
+// toplevels and screencasts. Nil leaves a value unchanged.
+func (m *Manager) SetActivity(fullscreen, screenSharing *bool) {
+	m.mu.Lock()
+	if fullscreen != nil {
+		m.fullscreen = *fullscreen
+	}
+	if screenSharing != nil {
+		m.screenSharing = *screenSharing
+	}
+	m.mu.Unlock()
+
+	m.refresh()
+	m.triggerUpdate()
+}
+
+// decide evaluates a notification the server accepted and publishes the
+// decision.
+func (m *Manager) decide(id uint32, n Notification) Decided {
+	d := Decided{ID: id, Notification: n, Decision: m.Evaluate(n)}
+	m.decisions.Range(func(key string, ch chan Decided) bool {
+		select {
+		case ch <- d:
+		default:
+		}
+		return true
+	})
+	return d
+}
+
+func notificationFrom(entry notifyactions.HistoryEntry) Notification {
+	n := Notification{
+		AppName: entry.AppName,
+		Summary: entry.Summary,
+		Body:    entry.Body,
+		Urgency: entry.Urgency,
+		Timeout: entry.ExpireTimeout,
+	}
+	n.DesktopEntry, _ = entry.Hints["desktop-entry"].(string)
+	n.Category, _ = entry.Hints["category"].(string)
+	return n
+}
+
+// WatchNotifications evaluates the rules for every notification the
+// notification server accepts. The decisions come after the fact and are
+// advisory, e.g. for history or clients that didn't ask Evaluate.
+func (m *Manager) WatchNotifications(am *notifyactions.Manager) {
+	ch := am.SubscribeIncoming("notify-rules")
+
+	m.wg.Go(func() {
+		defer am.UnsubscribeIncoming("notify-rules")
+		for {
+			select {
+			case <-m.stopChan:
+				return
+			case n, ok := <-ch:
+				if !ok {
+					return
+				}
+				m.decide(n.ID, notificationFrom(n.Entry))
+			}
+		}
+	})
+}
+
+func (m *Manager) SubscribeDecisions(id string) chan Decided {
+	ch := make(chan Decided, 16)
+	m.decisions.Store(id, ch)
+	return ch
+}
+
+func (m *Manager) UnsubscribeDecisions(id string) {
+	if val, ok := m.decisions.LoadAndDelete(id); ok {
+		close(val)
+	}
+}
+
+func (m *Manager) Subscribe(id string) chan State {
+	ch := make(chan State, 16)
+	m.subscribers.Store(id, ch)
+	return ch
+}
+
+func (m *Manager) Unsubscribe(id string) {
+	if val, ok := m.subscribers.LoadAndDelete(id); ok {
+		close(val)
+	}
+}
+
+func (m *Manager) notifySubscribers() {
+	state := m.GetState()
+	m.subscribers.Range(func(key string, ch chan State) bool {
+		select {
+		case ch <- state:
+		default:
+		}
+		return true
+	})
+}
+
+func (m *Manager) Close() {
+	m.closeOnce.Do(func() {
+		close(m.stopChan)
+		m.wg.Wait()
+
+		m.subscribers.Range(func(key string, ch chan State) bool {
+			close(ch)
+			m.subscribers.Delete(key)
+			return true
+		})
+		m.decisions.Range(func(key string, ch chan Decided) bool {
+			close(ch)
+			m.decisions.Delete(key)
+			return true
+		})
+	})
+}
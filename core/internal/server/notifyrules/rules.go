@@ -0,0 +1,146 @@
+package notifyrules
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var urgencyNames = map[string]uint8{
+	"low":      UrgencyLow,
+	"normal":   UrgencyNormal,
+	"critical": UrgencyCritical,
+}
+
+type compiledRule struct {
+	Rule
+	summary *regexp.Regexp
+	body    *regexp.Regexp
+}
+
+func compileRule(rule Rule) (compiledRule, error) {
+	c := compiledRule{Rule: rule}
+	if rule.ID == "" {
+		return c, fmt.Errorf("rule id required")
+	}
+	if rule.Match.Urgency != "" {
+		if _, ok := urgencyNames[rule.Match.Urgency]; !ok {
+			return c, fmt.Errorf("rule %s: unknown urgency: %s", rule.ID, rule.Match.Urgency)
+		}
+	}
+	if rule.Action.Urgency != "" {
+		if _, ok := urgencyNames[rule.Action.Urgency]; !ok {
+			return c, fmt.Errorf("rule %s: unknown urgency: %s", rule.ID, rule.Action.Urgency)
+		}
+	}
+	if rule.Action.Timeout != nil && *rule.Action.Timeout < 0 {
+		return c, fmt.Errorf("rule %s: timeout must not be negative", rule.ID)
+	}
+
+	var err error
+	if rule.Match.Summary != "" {
+		if c.summary, err = regexp.Compile(rule.Match.Summary); err != nil {
+			return c, fmt.Errorf("rule %s: invalid summary pattern: %w", rule.ID, err)
+		}
+	}
+	if rule.Match.Body != "" {
+		if c.body, err = regexp.Compile(rule.Match.Body); err != nil {
+			return c, fmt.Errorf("rule %s: invalid body pattern: %w", rule.ID, err)
+		}
+	}
+	return c, nil
+}
+
+func compileRules(rules []Rule) ([]compiledRule, error) {
+	compiled := make([]compiledRule, 0, len(rules))
+	seen := make(map[string]bool, len(rules))
+	for _, rule := range rules {
+		if seen[rule.ID] {
+			return nil, fmt.Errorf("duplicate rule id: %s", rule.ID)
+		}
+		seen[rule.ID] = true
+		c, err := compileRule(rule)
+		if err != nil {
+			return nil, err
+		}
+		compiled = append(compiled, c)
+	}
+	return compiled, nil
+}
+
+func matchesApp(want string, n Notification) bool {
+	return want == "" || strings.EqualFold(want, n.AppName) || strings.EqualFold(want, n.DesktopEntry)
+}
+
+func (c *compiledRule) matches(n Notification) bool {
+	if !c.Enabled || !matchesApp(c.Match.App, n) {
+		return false
+	}
+	if c.Match.Urgency != "" && urgencyNames[c.Match.Urgency] != n.Urgency {
+		return false
+	}
+	if c.Match.Category != "" && n.Category != c.Match.Category && !strings.HasPrefix(n.Category, c.Match.Category+".") {
+		return false
+	}
+	if c.summary != nil && !c.summary.MatchString(n.Summary) {
+		return false
+	}
+	if c.body != nil && !c.body.MatchString(n.Body) {
+		return false
+	}
+	return true
+}
+
+// evaluate applies rules to n, then holds it back if dnd is active and
+// nothing lets it through. Rules match against the notification as sent,
+// while DND sees the urgency the rules leave it with.
+func evaluate(rules []compiledRule, dnd DNDState, profile *Profile, n Notification) Decision {
+	d := Decision{
+		Urgency: n.Urgency,
+		Timeout: n.Timeout,
+		Rules:   []string{},
+	}
+
+	bypass := false
+	for i := range rules {
+		rule := &rules[i]
+		if !rule.matches(n) {
+			continue
+		}
+		d.Rules = append(d.Rules, rule.ID)
+		if rule.Action.Suppress {
+			d.Suppressed = true
+		}
+		if rule.Action.Urgency != "" {
+			d.Urgency = urgencyNames[rule.Action.Urgency]
+		}
+		if rule.Action.Timeout != nil {
+			d.Timeout = *rule.Action.Timeout
+		}
+		if rule.Action.Sound != "" {
+			d.Sound = rule.Action.Sound
+		}
+		bypass = bypass || rule.Action.BypassDND
+	}
+
+	if dnd.Active && !bypass {
+		d.Muted = !dndAllows(profile, n, d.Urgency)
+	}
+	d.Show = !d.Suppressed && !d.Muted
+	return d
+}
+
+// dndAllows reports whether a DND mode lets a notification through. Manual
+// DND without a profile only lets critical notifications through.
+func dndAllows(profile *Profile, n Notification, urgency uint8) bool {
+	if profile == nil {
+		return urgency == UrgencyCritical
+	}
+	if profile.AllowCritical && urgency == UrgencyCritical {
+		return true
+	}
+	return slices.ContainsFunc(profile.AllowApps, func(app string) bool {
+		return matchesApp(app, n)
+	})
+}
@@ -0,0 +1,93 @@
+package notifyrules
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mustCompile(t *testing.T, rules ...Rule) []compiledRule {
+	t.Helper()
+	compiled, err := compileRules(rules)
+	require.NoError(t, err)
+	return compiled
+}
+
+func TestCompileRules_Validation(t *testing.T) {
+	_, err := compileRules([]Rule{{ID: "a"}, {ID: "a"}})
+	assert.ErrorContains(t, err, "duplicate")
+
+	_, err = compileRules([]Rule{{ID: ""}})
+	assert.ErrorContains(t, err, "id required")
+
+	_, err = compileRules([]Rule{{ID: "a", Match: Match{Summary: "("}}})
+	assert.ErrorContains(t, err, "summary pattern")
+
+	_, err = compileRules([]Rule{{ID: "a", Action: Action{Urgency: "urgent"}}})
+	assert.ErrorContains(t, err, "unknown urgency")
+
+	negative := int32(-5)
+	_, err = compileRules([]Rule{{ID: "a", Action: Action{Timeout: &negative}}})
+	assert.Error(t, err)
+}
+
+func TestEvaluate_Rules(t *testing.T) {
+	timeout := int32(2000)
+	rules := mustCompile(t,
+		Rule{ID: "builds", Enabled: true,
+			Match:  Match{Summary: `(?i)build (passed|failed)`},
+			Action: Action{Urgency: "low", Timeout: &timeout}},
+		Rule{ID: "chat", Enabled: true,
+			Match:  Match{Category: "im"},
+			Action: Action{Sound: "message-new-instant"}},
+		Rule{ID: "disabled", Enabled: false,
+			Action: Action{Suppress: true}},
+	)
+
+	build := evaluate(rules, DNDState{}, nil, Notification{AppName: "CI", Summary: "Build failed", Urgency: UrgencyNormal, Timeout: -1})
+	assert.True(t, build.Show)
+	assert.Equal(t, UrgencyLow, build.Urgency)
+	assert.Equal(t, int32(2000), build.Timeout)
+	assert.Equal(t, []string{"builds"}, build.Rules)
+
+	chat := evaluate(rules, DNDState{}, nil, Notification{AppName: "Slack", Category: "im.received", Timeout: -1})
+	assert.Equal(t, "message-new-instant", chat.Sound)
+	assert.Equal(t, int32(-1), chat.Timeout)
+
+	other := evaluate(rules, DNDState{}, nil, Notification{AppName: "x", Category: "imaginary"})
+	assert.Empty(t, other.Rules, "category matches whole components only")
+	assert.True(t, other.Show)
+}
+
+func TestEvaluate_DND(t *testing.T) {
+	rules := mustCompile(t,
+		Rule{ID: "pages", Enabled: true,
+			Match:  Match{App: "pagerduty"},
+			Action: Action{BypassDND: true, Urgency: "critical"}},
+		Rule{ID: "mute-slack", Enabled: true,
+			Match:  Match{App: "Slack"},
+			Action: Action{Suppress: true}},
+	)
+	dnd := DNDState{Active: true, Profile: "work"}
+	profile := &Profile{ID: "work", AllowApps: []string{"signal"}}
+
+	page := evaluate(rules, dnd, profile, Notification{AppName: "PagerDuty", Summary: "Disk full"})
+	assert.True(t, page.Show)
+	assert.False(t, page.Muted)
+	assert.Equal(t, UrgencyCritical, page.Urgency)
+
+	mail := evaluate(rules, dnd, profile, Notification{AppName: "Thunderbird", Urgency: UrgencyCritical})
+	assert.True(t, mail.Muted, "critical only passes when the profile allows it")
+	assert.False(t, mail.Show)
+
+	signal := evaluate(rules, dnd, profile, Notification{AppName: "Signal Desktop", DesktopEntry: "signal"})
+	assert.True(t, signal.Show, "allowed apps match the desktop entry too")
+
+	slack := evaluate(rules, DNDState{}, nil, Notification{AppName: "slack"})
+	assert.True(t, slack.Suppressed)
+	assert.False(t, slack.Show)
+
+	manual := evaluate(rules, DNDState{Active: true}, nil, Notification{AppName: "x", Urgency: UrgencyCritical})
+	assert.True(t, manual.Show, "manual DND without a profile lets critical through")
+}
@@ -0,0 +1,129 @@
+package notifyrules
+
+import "time"
+
+const (
+	UrgencyLow      uint8 = 0
+	UrgencyNormal   uint8 = 1
+	UrgencyCritical uint8 = 2
+)
+
+const (
+	ReasonManual        = "manual"
+	ReasonSchedule      = "schedule"
+	ReasonFullscreen    = "fullscreen"
+	ReasonScreenSharing = "screenSharing"
+)
+
+// Match selects notifications. Empty fields match anything; Summary and Body
+// are Go regular expressions.
+type Match struct {
+	// App is compared case-insensitively with the app name and the
+	// desktop-entry hint.
+	App     string `json:"app,omitempty"`
+	Summary string `json:"summary,omitempty"`
+	Body    string `json:"body,omitempty"`
+	// Urgency is "low", "normal" or "critical".
+	Urgency string `json:"urgency,omitempty"`
+	// Category matches the category hint itself or any subcategory, so "im"
+	// matches "im.received".
+	Category string `json:"category,omitempty"`
+}
+
+type Action struct {
+	Suppress bool   `json:"suppress,omitempty"`
+	Urgency  string `json:"urgency,omitempty"`
+	// Timeout replaces the expire timeout in ms; 0 means never expire.
+	Timeout   *int32 `json:"timeout,omitempty"`
+	Sound     string `json:"sound,omitempty"`
+	BypassDND bool   `json:"bypassDnd,omitempty"`
+}
+
+// Rule applies Action to matching notifications. Every enabled rule that
+// matches applies, in order, so later rules win.
+type Rule struct {
+	ID      string `json:"id"`
+	Name    string `json:"name,omitempty"`
+	Enabled bool   `json:"enabled"`
+	Match   Match  `json:"match"`
+	Action  Action `json:"action"`
+}
+
+// Schedule is a daily window in local time. A window whose end is not after
+// its start runs past midnight; Days (0 = Sunday) name the days it starts on,
+// every day when empty.
+type Schedule struct {
+	Days  []int  `json:"days,omitempty"`
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// Profile is a do-not-disturb mode that turns on during its schedules or
+// while a fullscreen window or screen share is reported.
+type Profile struct {
+	ID                string     `json:"id"`
+	Name              string     `json:"name,omitempty"`
+	Enabled           bool       `json:"enabled"`
+	Schedules         []Schedule `json:"schedules,omitempty"`
+	WhenFullscreen    bool       `json:"whenFullscreen,omitempty"`
+	WhenScreenSharing bool       `json:"whenScreenSharing,omitempty"`
+	AllowCritical     bool       `json:"allowCritical"`
+	AllowApps         []string   `json:"allowApps,omitempty"`
+}
+
+// Config is persisted to notify-rules.json.
+type Config struct {
+	Rules    []Rule    `json:"rules"`
+	Profiles []Profile `json:"profiles"`
+}
+
+type DNDState struct {
+	Active bool `json:"active"`
+	// Profile is the active profile's ID; manual DND may have none.
+	Profile        string     `json:"profile,omitempty"`
+	Reason         string     `json:"reason,omitempty"`
+	Until          *time.Time `json:"until,omitempty"`
+	Fullscreen     bool       `json:"fullscreen"`
+	ScreenSharing  bool       `json:"screenSharing"`
+	NextTransition *time.Time `json:"nextTransition,omitempty"`
+}
+
+type State struct {
+	Rules    []Rule    `json:"rules"`
+	Profiles []Profile `json:"profiles"`
+	DND      DNDState  `json:"dnd"`
+}
+
+// Notification is what the rules see of an incoming notification.
+type Notification struct {
+	AppName      string `json:"appName"`
+	DesktopEntry string `json:"desktopEntry,omitempty"`
+	Summary      string `json:"summary"`
+	Body         string `json:"body"`
+	Urgency      uint8  `json:"urgency"`
+	Category     string `json:"category,omitempty"`
+	Timeout      int32  `json:"timeout"`
+}
+
+// Decision tells the notification server how to present a notification.
+// Muted notifications still belong in the history, just without a popup or
+// sound.
+type Decision struct {
+	Show       bool     `json:"show"`
+	Suppressed bool     `json:"suppressed"`
+	Muted      bool     `json:"muted"`
+	Urgency    uint8    `json:"urgency"`
+	Timeout    int32    `json:"timeout"`
+	Sound      string   `json:"sound,omitempty"`
+	Rules      []string `json:"rules"`
+}
+
+// Decided is published for each notification the server accepts, keyed by
+// the ID the server assigned. It is advisory: it is only known after the
+// server replied, by which time the shell has usually shown the
+// notification, so suppressing or muting must go through Evaluate first.
+type Decided struct {
+	ID           uint32       `json:"id"`
+	Notification Notification `json:"notification"`
+	Decision     Decision     `json:"decision"`
+}
@@ -0,0 +1,82 @@
+package orientation
+
+import (
+	"fmt"
+
+	"github.com/AvengeMedia/DankMaterialShell/core/internal/server/models"
+	"github.com/AvengeMedia/dankgo/ipc/params"
+)
+
+func HandleRequest(conn *models.Conn, req models.Request, m *Manager) {
+	switch req.Method {
+	case "orientation.getState":
+		models.Respond(conn, req.ID, m.GetState())
+	case "orientation.setLocked":
+		handleSetLocked(conn, req, m)
+	case "orientation.toggleLock":
+		if err := m.ToggleLocked(); err != nil {
+			models.RespondError(conn, req.ID, err.Error())
+			return
+		}
+		models.Respond(conn, req.ID, m.GetState())
+	case "orientation.configure":
+		handleConfigure(conn, req, m)
+	case "orientation.subscribe":
+		handleSubscribe(conn, req, m)
+	default:
+		models.RespondError(conn, req.ID, "unknown method: "+req.Method)
+	}
+}
+
+func handleSetLocked(conn *models.Conn, req models.Request, m *Manager) {
+	locked, err := params.Bool(req.Params, "locked")
+	if err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+
+	if err := m.SetLocked(locked); err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+
+	models.Respond(conn, req.ID, m.GetState())
+}
+
+func handleConfigure(conn *models.Conn, req models.Request, m *Manager) {
+	cfg := m.GetState().Config
+	cfg.Locked = params.BoolOpt(req.Params, "locked", cfg.Locked)
+	cfg.TabletOnly = params.BoolOpt(req.Params, "tabletOnly", cfg.TabletOnly)
+	cfg.Output = params.StringOpt(req.Params, "output", cfg.Output)
+
+	if err := m.Configure(cfg); err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+
+	models.Respond(conn, req.ID, m.GetState())
+}
+
+func handleSubscribe(conn *models.Conn, req models.Request, m *Manager) {
+	clientID := fmt.Sprintf("orientation-%d", req.ID)
+
+	ch := m.Subscribe(clientID)
+	defer m.Unsubscribe(clientID)
+
+	initialState := m.GetState()
+	if err := conn.WriteResponse(models.Response[State]{
+		ID:     req.ID,
+		Result: &initialState,
+	}); err != nil {
+		return
+	}
+
+	for state := range ch {
+		if err := conn.WriteResponse(models.Response[State]{
+			ID:     req.ID,
+			Result: &state,
+		}); err != nil {
+			return
+		}
+	}
+}
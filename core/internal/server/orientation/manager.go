@@ -0,0 +1,453 @@
+package orientation
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AvengeMedia/DankMaterialShell/core/internal/log"
+	"github.com/AvengeMedia/DankMaterialShell/core/internal/server/evdev"
+	"github.com/AvengeMedia/DankMaterialShell/core/internal/server/wlroutput"
+	"github.com/AvengeMedia/dankgo/syncmap"
+)
+
+const (
+	OrientationNormal    = "normal"
+	OrientationBottomUp  = "bottom-up"
+	OrientationLeftUp    = "left-up"
+	OrientationRightUp   = "right-up"
+	OrientationUndefined = "undefined"
+)
+
+// wl_output transforms; the flipped variants add 4.
+var orientationTransforms = map[string]int32{
+	OrientationNormal:   0,
+	OrientationLeftUp:   1,
+	OrientationBottomUp: 2,
+	OrientationRightUp:  3,
+}
+
+const transformFlipped = 4
+
+// debounceDelay keeps a device being picked up or swung around from
+// triggering a modeset per intermediate reading.
+var debounceDelay = 750 * time.Millisecond
+
+var internalPanelPrefixes = []string{"eDP", "LVDS", "DSI"}
+
+type Config struct {
+	Locked     bool   `json:"locked"`
+	TabletOnly bool   `json:"tabletOnly"`
+	Output     string `json:"output,omitempty"`
+}
+
+type State struct {
+	Config
+	Available   bool   `json:"available"`
+	Sensor      string `json:"sensor"`
+	Orientation string `json:"orientation"`
+	Panel       string `json:"panel,omitempty"`
+	Transform   int32  `json:"transform"`
+	TabletMode  *bool  `json:"tabletMode,omitempty"`
+	Active      bool   `json:"active"`
+}
+
+// OutputController is the subset of wlroutput.Manager used to rotate the
+// panel.
+type OutputController interface {
+	GetState() wlroutput.State
+	ApplyConfiguration(heads []wlroutput.HeadConfig, test bool) error
+}
+
+type Manager struct {
+	outputs OutputController
+	sensor  Sensor
+
+	mu          sync.Mutex
+	config      Config
+	reported    string
+	orientation string
+	tabletMode  *bool
+	timer       *time.Timer
+
+	subscribers syncmap.Map[string, chan State]
+	stopChan    chan struct{}
+	wg          sync.WaitGroup
+	closeOnce   sync.Once
+}
+
+func defaultConfig() Config {
+	return Config{TabletOnly: true}
+}
+
+func configPath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "DankMaterialShell", "orientation.json"), nil
+}
+
+func loadConfig() Config {
+	cfg := defaultConfig()
+	path, err := configPath()
+	if err != nil {
+		return cfg
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		log.Warnf("Invalid orientation config %s: %v", path, err)
+		return defaultConfig()
+	}
+	return cfg
+}
+
+func saveConfig(cfg Config) error {
+	path, err := configPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// NewManager claims the accelerometer and starts rotating the internal
+// panel through outputs.
+func NewManager(outputs OutputController) (*Manager, error) {
+	if outputs == nil {
+		return nil, fmt.Errorf("output management unavailable")
+	}
+
+	sensor, err := NewSensor()
+	if err != nil {
+		return nil, err
+	}
+
+	m := newManager(outputs, sensor, loadConfig())
+	if m.GetState().Panel == "" {
+		log.Infof("Orientation: no internal panel found yet, sensor %s", sensor.Name())
+	}
+	return m, nil
+}
+
+func newManager(outputs OutputController, sensor Sensor, cfg Config) *Manager {
+	m := &Manager{
+		outputs:     outputs,
+		sensor:      sensor,
+		config:      cfg,
+		reported:    OrientationUndefined,
+		orientation: OrientationUndefined,
+		stopChan:    make(chan struct{}),
+	}
+
+	m.wg.Add(1)
+	go m.run()
+	return m
+}
+
+func (m *Manager) run() {
+	defer m.wg.Done()
+	for {
+		select {
+		case <-m.stopChan:
+			return
+		case orientation, ok := <-m.sensor.Orientations():
+			if !ok {
+				return
+			}
+			m.noteReading(orientation)
+		}
+	}
+}
+
+func (m *Manager) noteReading(orientation string) {
+	if _, ok := orientationTransforms[orientation]; !ok {
+		return
+	}
+
+	m.mu.Lock()
+	m.reported = orientation
+	if m.timer != nil {
+		m.timer.Stop()
+	}
+	m.timer = time.AfterFunc(debounceDelay, m.settle)
+	m.mu.Unlock()
+}
+
+// settle runs once readings have been stable for debounceDelay.
+func (m *Manager) settle() {
+	select {
+	case <-m.stopChan:
+		return
+	default:
+	}
+
+	m.mu.Lock()
+	changed := m.orientation != m.reported
+	m.orientation = m.reported
+	m.mu.Unlock()
+
+	if !changed {
+		return
+	}
+	m.apply()
+	m.notifySubscribers()
+}
+
+// activeLocked reports whether sensor readings should rotate the panel.
+// Tablet-only mode is ignored on devices without a tablet-mode switch.
+func (m *Manager) activeLocked() bool {
+	if m.config.Locked {
+		return false
+	}
+	if m.config.TabletOnly && m.tabletMode != nil && !*m.tabletMode {
+		return false
+	}
+	return true
+}
+
+func (m *Manager) apply() {
+	m.mu.Lock()
+	active := m.activeLocked()
+	orientation := m.orientation
+	cfg := m.config
+	m.mu.Unlock()
+
+	if !active {
+		return
+	}
+	if err := m.rotate(cfg.Output, orientation); err != nil {
+		log.Warnf("Orientation: %v", err)
+	}
+}
+
+func (m *Manager) rotate(override, orientation string) error {
+	base, ok := orientationTransforms[orientation]
+	if !ok {
+		return nil
+	}
+
+	outputs := m.outputs.GetState().Outputs
+	panel := findPanel(outputs, override)
+	if panel == nil {
+		return fmt.Errorf("no internal panel to rotate")
+	}
+
+	transform := base | panel.Transform&transformFlipped
+	if panel.Transform == transform {
+		return nil
+	}
+
+	log.Infof("Orientation: rotating %s to %s (transform %d)", panel.Name, orientation, transform)
+	if err := m.outputs.ApplyConfiguration(headConfigs(outputs, panel.Name, transform), false); err != nil {
+		return fmt.Errorf("rotate %s: %w", panel.Name, err)
+	}
+	return nil
+}
+
+// headConfigs keeps every other head as it is; only the panel's transform
+// changes.
+func headConfigs(outputs []wlroutput.Output, panel string, transform int32) []wlroutput.HeadConfig {
+	heads := make([]wlroutput.HeadConfig, 0, len(outputs))
+	for _, out := range outputs {
+		head := wlroutput.HeadConfig{Name: out.Name, Enabled: out.Enabled}
+		if out.Enabled {
+			head.Position = &struct{ X, Y int32 }{out.X, out.Y}
+			t := out.Transform
+			if out.Name == panel {
+				t = transform
+			}
+			head.Transform = &t
+		}
+		heads = append(heads, head)
+	}
+	return heads
+}
+
+func isInternalPanel(out wlroutput.Output) bool {
+	for _, prefix := range internalPanelPrefixes {
+		if strings.HasPrefix(out.Name, prefix) || strings.HasPrefix(out.DRMConnector, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// findPanel returns the configured output, or the first enabled internal
+// panel.
+func findPanel(outputs []wlroutput.Output, override string) *wlroutput.Output {
+	for i := range outputs {
+		out := &outputs[i]
+		if !out.Enabled {
+			continue
+		}
+		if override != "" {
+			if out.Name == override {
+				return out
+			}
+			continue
+		}
+		if isInternalPanel(*out) {
+			return out
+		}
+	}
+	return nil
+}
+
+// SetTabletMode feeds the SW_TABLET_MODE switch. Leaving tablet mode with
+// tablet-only rotation restores the normal orientation.
+func (m *Manager) SetTabletMode(on bool) {
+	m.mu.Lock()
+	if m.tabletMode != nil && *m.tabletMode == on {
+		m.mu.Unlock()
+		return
+	}
+	m.tabletMode = &on
+	restore := !on && m.config.TabletOnly && !m.config.Locked
+	cfg := m.config
+	m.mu.Unlock()
+
+	if restore {
+		if err := m.rotate(cfg.Output, OrientationNormal); err != nil {
+			log.Warnf("Orientation: %v", err)
+		}
+	} else {
+		m.apply()
+	}
+	m.notifySubscribers()
+}
+
+// SetLocked freezes the current rotation; unlocking snaps to the sensor.
+func (m *Manager) SetLocked(locked bool) error {
+	m.mu.Lock()
+	cfg := m.config
+	m.mu.Unlock()
+
+	cfg.Locked = locked
+	return m.Configure(cfg)
+}
+
+func (m *Manager) ToggleLocked() error {
+	return m.SetLocked(!m.GetState().Locked)
+}
+
+func (m *Manager) Configure(cfg Config) error {
+	if cfg.Output != "" && findPanel(m.outputs.GetState().Outputs, cfg.Output) == nil {
+		return fmt.Errorf("output not found: %s", cfg.Output)
+	}
+
+	m.mu.Lock()
+	m.config = cfg
+	m.mu.Unlock()
+
+	if err := saveConfig(cfg); err != nil {
+		log.Warnf("Failed to save orientation config: %v", err)
+	}
+
+	m.apply()
+	m.notifySubscribers()
+	return nil
+}
+
+func (m *Manager) GetState() State {
+	m.mu.Lock()
+	state := State{
+		Config:      m.config,
+		Available:   true,
+		Sensor:      m.sensor.Name(),
+		Orientation: m.orientation,
+		Active:      m.activeLocked(),
+	}
+	if m.tabletMode != nil {
+		tablet := *m.tabletMode
+		state.TabletMode = &tablet
+	}
+	m.mu.Unlock()
+
+	if panel := findPanel(m.outputs.GetState().Outputs, state.Output); panel != nil {
+		state.Panel = panel.Name
+		state.Transform = panel.Transform
+	}
+	return state
+}
+
+func (m *Manager) Subscribe(id string) chan State {
+	ch := make(chan State, 16)
+	m.subscribers.Store(id, ch)
+	return ch
+}
+
+func (m *Manager) Unsubscribe(id string) {
+	if val, ok := m.subscribers.LoadAndDelete(id); ok {
+		close(val)
+	}
+}
+
+func (m *Manager) notifySubscribers() {
+	state := m.GetState()
+	m.subscribers.Range(func(key string, ch chan State) bool {
+		select {
+		case ch <- state:
+		default:
+		}
+		return true
+	})
+}
+
+// WatchEvdev restricts rotation to tablet mode on convertibles that expose
+// SW_TABLET_MODE.
+func (m *Manager) WatchEvdev(em *evdev.Manager) {
+	states := em.Subscribe("orientation")
+	if on, ok := em.Switch(evdev.SwitchTabletMode); ok {
+		m.SetTabletMode(on)
+	}
+
+	go func() {
+		defer em.Unsubscribe("orientation")
+		for {
+			select {
+			case <-m.stopChan:
+				return
+			case state, ok := <-states:
+				if !ok {
+					return
+				}
+				if on, ok := state.Switches[evdev.SwitchTabletMode]; ok {
+					m.SetTabletMode(on)
+				}
+			}
+		}
+	}()
+}
+
+func (m *Manager) Close() {
+	m.closeOnce.Do(func() {
+		close(m.stopChan)
+		m.mu.Lock()
+		if m.timer != nil {
+			m.timer.Stop()
+		}
+		m.mu.Unlock()
+		m.sensor.Close()
+		m.wg.Wait()
+
+		m.subscribers.Range(func(key string, ch chan State) bool {
+			close(ch)
+			m.subscribers.Delete(key)
+			return true
+		})
+	})
+}
@@ -0,0 +1,191 @@
+package orientation
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/AvengeMedia/DankMaterialShell/core/internal/server/wlroutput"
+)
+
+type fakeSensor struct {
+	ch chan string
+}
+
+func (s *fakeSensor) Name() string                { return "fake" }
+func (s *fakeSensor) Orientations() <-chan string { return s.ch }
+func (s *fakeSensor) Close()                      {}
+
+type fakeOutputs struct {
+	mu      sync.Mutex
+	outputs []wlroutput.Output
+	applied [][]wlroutput.HeadConfig
+}
+
+func (f *fakeOutputs) GetState() wlroutput.State {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return wlroutput.State{Outputs: append([]wlroutput.Output(nil), f.outputs...)}
+}
+
+func (f *fakeOutputs) ApplyConfiguration(heads []wlroutput.HeadConfig, test bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.applied = append(f.applied, heads)
+	for _, head := range heads {
+		for i := range f.outputs {
+			if f.outputs[i].Name == head.Name && head.Transform != nil {
+				f.outputs[i].Transform = *head.Transform
+			}
+		}
+	}
+	return nil
+}
+
+func (f *fakeOutputs) transform(name string) int32 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, out := range f.outputs {
+		if out.Name == name {
+			return out.Transform
+		}
+	}
+	return -1
+}
+
+func (f *fakeOutputs) applyCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.applied)
+}
+
+func setupManager(t *testing.T, cfg Config) (*Manager, *fakeSensor, *fakeOutputs) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	oldDelay := debounceDelay
+	debounceDelay = 10 * time.Millisecond
+	t.Cleanup(func() { debounceDelay = oldDelay })
+
+	outputs := &fakeOutputs{outputs: []wlroutput.Output{
+		{Name: "eDP-1", Enabled: true},
+		{Name: "DP-2", Enabled: true, X: 1920, Transform: 1},
+		{Name: "HDMI-A-1", Enabled: false},
+	}}
+	sensor := &fakeSensor{ch: make(chan string, 1)}
+	m := newManager(outputs, sensor, cfg)
+	t.Cleanup(m.Close)
+	return m, sensor, outputs
+}
+
+func TestManager_RotatesPanel(t *testing.T) {
+	m, sensor, outputs := setupManager(t, defaultConfig())
+	ch := m.Subscribe("test")
+
+	sensor.ch <- OrientationLeftUp
+	state := <-ch
+	assert.Equal(t, OrientationLeftUp, state.Orientation)
+	assert.Equal(t, "eDP-1", state.Panel)
+	assert.Equal(t, int32(1), state.Transform)
+	assert.True(t, state.Active, "no tablet-mode switch means always active")
+
+	require.Equal(t, 1, outputs.applyCount())
+	heads := outputs.applied[0]
+	require.Len(t, heads, 3)
+	assert.Equal(t, int32(1), *heads[1].Transform, "other heads keep their transform")
+	assert.Equal(t, int32(1920), heads[1].Position.X)
+	assert.False(t, heads[2].Enabled)
+	assert.Nil(t, heads[2].Transform)
+
+	sensor.ch <- OrientationUndefined
+	sensor.ch <- OrientationLeftUp
+	select {
+	case <-ch:
+		t.Fatal("repeated orientation must not notify")
+	case <-time.After(50 * time.Millisecond):
+	}
+	assert.Equal(t, 1, outputs.applyCount())
+}
+
+func TestManager_Debounce(t *testing.T) {
+	m, _, outputs := setupManager(t, defaultConfig())
+	ch := m.Subscribe("test")
+
+	m.noteReading(OrientationRightUp)
+	m.noteReading(OrientationBottomUp)
+	m.noteReading(OrientationNormal)
+	m.noteReading(OrientationBottomUp)
+
+	state := <-ch
+	assert.Equal(t, OrientationBottomUp, state.Orientation)
+	assert.Equal(t, int32(2), outputs.transform("eDP-1"))
+	assert.Equal(t, 1, outputs.applyCount(), "intermediate readings are dropped")
+}
+
+func TestManager_LockAndTabletMode(t *testing.T) {
+	m, _, outputs := setupManager(t, defaultConfig())
+	ch := m.Subscribe("test")
+
+	require.NoError(t, m.SetLocked(true))
+	<-ch
+	m.noteReading(OrientationRightUp)
+	state := <-ch
+	assert.Equal(t, OrientationRightUp, state.Orientation)
+	assert.False(t, state.Active)
+	assert.Equal(t, int32(0), outputs.transform("eDP-1"), "locked rotation stays put")
+
+	require.NoError(t, m.ToggleLocked())
+	<-ch
+	assert.Equal(t, int32(3), outputs.transform("eDP-1"), "unlocking snaps to the sensor")
+
+	m.SetTabletMode(false)
+	state = <-ch
+	require.NotNil(t, state.TabletMode)
+	assert.False(t, state.Active)
+	assert.Equal(t, int32(0), outputs.transform("eDP-1"), "leaving tablet mode restores normal")
+
+	m.SetTabletMode(true)
+	<-ch
+	assert.Equal(t, int32(3), outputs.transform("eDP-1"))
+
+	_, err := os.Stat(filepath.Join(os.Getenv("XDG_CONFIG_HOME"), "DankMaterialShell", "orientation.json"))
+	assert.NoError(t, err)
+	assert.Equal(t, defaultConfig(), loadConfig())
+}
+
+func TestManager_ConfigureOutput(t *testing.T) {
+	m, _, outputs := setupManager(t, defaultConfig())
+
+	assert.Error(t, m.Configure(Config{Output: "HDMI-A-1"}), "disabled outputs can't be rotated")
+
+	require.NoError(t, m.Configure(Config{Output: "DP-2"}))
+	m.noteReading(OrientationBottomUp)
+	require.Eventually(t, func() bool { return outputs.transform("DP-2") == 2 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, int32(0), outputs.transform("eDP-1"))
+}
+
+func TestRotate_KeepsFlip(t *testing.T) {
+	outputs := &fakeOutputs{outputs: []wlroutput.Output{{Name: "DSI-1", Enabled: true, Transform: 4}}}
+	m := &Manager{outputs: outputs}
+
+	require.NoError(t, m.rotate("", OrientationRightUp))
+	assert.Equal(t, int32(7), outputs.transform("DSI-1"))
+
+	outputs.outputs = []wlroutput.Output{{Name: "DP-1", Enabled: true}}
+	assert.Error(t, m.rotate("", OrientationNormal))
+}
+
+func TestFindPanel_DRMConnector(t *testing.T) {
+	outputs := []wlroutput.Output{
+		{Name: "Built-in", Enabled: false, DRMConnector: "eDP-1"},
+		{Name: "DP-1", Enabled: true},
+		{Name: "Built-in", Enabled: true, DRMConnector: "eDP-1"},
+	}
+	panel := findPanel(outputs, "")
+	require.NotNil(t, panel)
+	assert.Same(t, &outputs[2], panel)
+}
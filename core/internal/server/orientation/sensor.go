@@ -0,0 +1,343 @@
+package orientation
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AvengeMedia/DankMaterialShell/core/internal/log"
+	"github.com/AvengeMedia/dankgo/dbusutil"
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	sensorProxyDest  = "net.hadess.SensorProxy"
+	sensorProxyPath  = "/net/hadess/SensorProxy"
+	sensorProxyIface = "net.hadess.SensorProxy"
+
+	iioPollInterval = 500 * time.Millisecond
+
+	// Tilt, in degrees from flat, an axis needs before it decides the
+	// orientation. Matches iio-sensor-proxy's thresholds.
+	tiltThreshold = 35.0
+)
+
+var iioSysfsRoot = "/sys/bus/iio/devices"
+
+// Sensor delivers accelerometer orientations using iio-sensor-proxy's
+// names: normal, bottom-up, left-up, right-up.
+type Sensor interface {
+	Name() string
+	Orientations() <-chan string
+	Close()
+}
+
+// NewSensor prefers iio-sensor-proxy, which applies the per-device mount
+// matrix quirks from hwdb, and falls back to polling the iio sysfs nodes.
+func NewSensor() (Sensor, error) {
+	sensor, err := newSensorProxyAccel()
+	if err == nil {
+		return sensor, nil
+	}
+	log.Debugf("iio-sensor-proxy accelerometer unavailable: %v", err)
+
+	return newIIOAccelSensor(iioSysfsRoot)
+}
+
+type sensorProxyAccel struct {
+	conn         *dbus.Conn
+	obj          dbus.BusObject
+	signals      chan *dbus.Signal
+	orientations chan string
+	stopChan     chan struct{}
+	wg           sync.WaitGroup
+	once         sync.Once
+}
+
+func newSensorProxyAccel() (*sensorProxyAccel, error) {
+	conn, err := dbus.ConnectSystemBus()
+	if err != nil {
+		return nil, fmt.Errorf("connect to system bus: %w", err)
+	}
+
+	obj := conn.Object(sensorProxyDest, sensorProxyPath)
+	hasAccel, err := obj.GetProperty(sensorProxyIface + ".HasAccelerometer")
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("query sensor proxy: %w", err)
+	}
+	if !dbusutil.AsOr(hasAccel, false) {
+		conn.Close()
+		return nil, fmt.Errorf("no accelerometer")
+	}
+
+	if err := obj.Call(sensorProxyIface+".ClaimAccelerometer", 0).Err; err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("claim accelerometer: %w", err)
+	}
+
+	s := &sensorProxyAccel{
+		conn:         conn,
+		obj:          obj,
+		signals:      make(chan *dbus.Signal, 16),
+		orientations: make(chan string, 1),
+		stopChan:     make(chan struct{}),
+	}
+
+	if err := conn.AddMatchSignal(
+		dbus.WithMatchObjectPath(sensorProxyPath),
+		dbus.WithMatchInterface("org.freedesktop.DBus.Properties"),
+		dbus.WithMatchMember("PropertiesChanged"),
+	); err != nil {
+		s.Close()
+		return nil, fmt.Errorf("watch sensor proxy: %w", err)
+	}
+	conn.Signal(s.signals)
+
+	if value, err := obj.GetProperty(sensorProxyIface + ".AccelerometerOrientation"); err == nil {
+		s.push(dbusutil.AsOr(value, OrientationUndefined))
+	}
+
+	s.wg.Add(1)
+	go s.run()
+	return s, nil
+}
+
+func (s *sensorProxyAccel) Name() string { return "iio-sensor-proxy" }
+
+func (s *sensorProxyAccel) Orientations() <-chan string { return s.orientations }
+
+func (s *sensorProxyAccel) run() {
+	defer s.wg.Done()
+	for {
+		select {
+		case <-s.stopChan:
+			return
+		case sig, ok := <-s.signals:
+			if !ok {
+				return
+			}
+			if sig == nil || len(sig.Body) < 2 {
+				continue
+			}
+			if iface, _ := sig.Body[0].(string); iface != sensorProxyIface {
+				continue
+			}
+			changed, ok := sig.Body[1].(map[string]dbus.Variant)
+			if !ok {
+				continue
+			}
+			if value, ok := dbusutil.Get[string](changed, "AccelerometerOrientation"); ok {
+				s.push(value)
+			}
+		}
+	}
+}
+
+func (s *sensorProxyAccel) push(orientation string) {
+	select {
+	case <-s.orientations:
+	default:
+	}
+	s.orientations <- orientation
+}
+
+func (s *sensorProxyAccel) Close() {
+	s.once.Do(func() {
+		close(s.stopChan)
+		s.conn.RemoveSignal(s.signals)
+		s.wg.Wait()
+		if err := s.obj.Call(sensorProxyIface+".ReleaseAccelerometer", 0).Err; err != nil {
+			log.Debugf("Failed to release accelerometer: %v", err)
+		}
+		s.conn.Close()
+	})
+}
+
+type iioAccelSensor struct {
+	device       string
+	axes         [3]string
+	scale        float64
+	mount        [3][3]float64
+	orientations chan string
+	stopChan     chan struct{}
+	wg           sync.WaitGroup
+	once         sync.Once
+}
+
+func newIIOAccelSensor(root string) (*iioAccelSensor, error) {
+	devices, err := filepath.Glob(filepath.Join(root, "iio:device*"))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, dev := range devices {
+		s := &iioAccelSensor{
+			device:       filepath.Base(dev),
+			scale:        1,
+			mount:        identityMatrix(),
+			orientations: make(chan string, 1),
+			stopChan:     make(chan struct{}),
+		}
+
+		for i, axis := range []string{"x", "y", "z"} {
+			s.axes[i] = filepath.Join(dev, "in_accel_"+axis+"_raw")
+		}
+		if !fileExists(s.axes[0]) || !fileExists(s.axes[1]) {
+			continue
+		}
+		if v, err := readFloatFile(filepath.Join(dev, "in_accel_scale")); err == nil {
+			s.scale = v
+		}
+		if data, err := os.ReadFile(filepath.Join(dev, "in_accel_mount_matrix")); err == nil {
+			if mount, ok := parseMountMatrix(string(data)); ok {
+				s.mount = mount
+			}
+		}
+
+		if _, err := s.read(); err != nil {
+			log.Debugf("Skipping iio device %s: %v", s.device, err)
+			continue
+		}
+
+		s.wg.Add(1)
+		go s.run()
+		return s, nil
+	}
+
+	return nil, fmt.Errorf("no iio accelerometer found")
+}
+
+func (s *iioAccelSensor) Name() string { return s.device }
+
+func (s *iioAccelSensor) Orientations() <-chan string { return s.orientations }
+
+func (s *iioAccelSensor) read() ([3]float64, error) {
+	var raw [3]float64
+	for i, path := range s.axes {
+		v, err := readFloatFile(path)
+		switch {
+		case err == nil:
+			raw[i] = v * s.scale
+		case i == 2:
+			// Some two-axis parts have no z channel.
+		default:
+			return raw, err
+		}
+	}
+
+	var accel [3]float64
+	for row := range 3 {
+		for col := range 3 {
+			accel[row] += s.mount[row][col] * raw[col]
+		}
+	}
+	return accel, nil
+}
+
+func (s *iioAccelSensor) run() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(iioPollInterval)
+	defer ticker.Stop()
+
+	last := OrientationUndefined
+	for {
+		accel, err := s.read()
+		switch {
+		case err != nil:
+			log.Debugf("Failed to read %s: %v", s.Name(), err)
+		default:
+			if orientation := orientationFromAccel(accel, last); orientation != last {
+				last = orientation
+				select {
+				case <-s.orientations:
+				default:
+				}
+				s.orientations <- orientation
+			}
+		}
+
+		select {
+		case <-s.stopChan:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *iioAccelSensor) Close() {
+	s.once.Do(func() {
+		close(s.stopChan)
+		s.wg.Wait()
+	})
+}
+
+// orientationFromAccel mirrors iio-sensor-proxy: whichever in-plane axis
+// is tilted past the threshold wins, and a device lying flat keeps its
+// previous orientation.
+func orientationFromAccel(accel [3]float64, previous string) string {
+	x, y, z := accel[0], accel[1], accel[2]
+	portrait := math.Atan2(x, math.Hypot(y, z)) * 180 / math.Pi
+	landscape := math.Atan2(y, math.Hypot(x, z)) * 180 / math.Pi
+
+	switch {
+	case math.Abs(portrait) > tiltThreshold:
+		if portrait > 0 {
+			return OrientationLeftUp
+		}
+		return OrientationRightUp
+	case math.Abs(landscape) > tiltThreshold:
+		if landscape > 0 {
+			return OrientationBottomUp
+		}
+		return OrientationNormal
+	}
+	return previous
+}
+
+// parseMountMatrix reads the iio "x1, y1, z1; x2, y2, z2; x3, y3, z3"
+// format.
+func parseMountMatrix(s string) ([3][3]float64, bool) {
+	var m [3][3]float64
+	rows := strings.Split(strings.TrimSpace(s), ";")
+	if len(rows) != 3 {
+		return m, false
+	}
+	for i, row := range rows {
+		cols := strings.Split(row, ",")
+		if len(cols) != 3 {
+			return m, false
+		}
+		for j, col := range cols {
+			v, err := strconv.ParseFloat(strings.TrimSpace(col), 64)
+			if err != nil {
+				return m, false
+			}
+			m[i][j] = v
+		}
+	}
+	return m, true
+}
+
+func identityMatrix() [3][3]float64 {
+	return [3][3]float64{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}}
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func readFloatFile(path string) (float64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseFloat(strings.TrimSpace(string(data)), 64)
+}
@@ -0,0 +1,65 @@
+package orientation
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrientationFromAccel(t *testing.T) {
+	tests := []struct {
+		name     string
+		accel    [3]float64
+		previous string
+		want     string
+	}{
+		{"upright", [3]float64{0, -9.8, 0}, OrientationUndefined, OrientationNormal},
+		{"upside down", [3]float64{0, 9.8, 0}, OrientationNormal, OrientationBottomUp},
+		{"left edge up", [3]float64{9.8, 0, 0}, OrientationNormal, OrientationLeftUp},
+		{"right edge up", [3]float64{-9.8, 0, 0}, OrientationNormal, OrientationRightUp},
+		{"flat keeps previous", [3]float64{0.5, -0.5, 9.8}, OrientationLeftUp, OrientationLeftUp},
+		{"shallow tilt keeps previous", [3]float64{0, -4, 9}, OrientationRightUp, OrientationRightUp},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, orientationFromAccel(tt.accel, tt.previous))
+		})
+	}
+}
+
+func TestParseMountMatrix(t *testing.T) {
+	m, ok := parseMountMatrix("0, 1, 0; -1, 0, 0; 0, 0, 1\n")
+	require.True(t, ok)
+	assert.Equal(t, [3][3]float64{{0, 1, 0}, {-1, 0, 0}, {0, 0, 1}}, m)
+
+	_, ok = parseMountMatrix("1, 0; 0, 1")
+	assert.False(t, ok)
+}
+
+func TestIIOAccelSensor(t *testing.T) {
+	root := t.TempDir()
+	dev := filepath.Join(root, "iio:device0")
+	require.NoError(t, os.MkdirAll(dev, 0o755))
+	write := func(name, value string) {
+		require.NoError(t, os.WriteFile(filepath.Join(dev, name), []byte(value+"\n"), 0o644))
+	}
+	write("in_accel_x_raw", "0")
+	write("in_accel_y_raw", "1000")
+	write("in_accel_z_raw", "0")
+	write("in_accel_scale", "0.0098")
+	write("in_accel_mount_matrix", "1, 0, 0; 0, -1, 0; 0, 0, 1")
+
+	_, err := newIIOAccelSensor(t.TempDir())
+	assert.Error(t, err)
+
+	s, err := newIIOAccelSensor(root)
+	require.NoError(t, err)
+	defer s.Close()
+
+	assert.Equal(t, "iio:device0", s.Name())
+	assert.Equal(t, OrientationNormal, <-s.Orientations(), "mount matrix flips y")
+}
@@ -0,0 +1,48 @@
+package server
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/AvengeMedia/DankMaterialShell/core/internal/colorpicker"
+	"github.com/AvengeMedia/DankMaterialShell/core/internal/screenshot"
+)
+
+// portalCaptureEngine backs the Screenshot and PickColor portals with DMS's
+// own screenshot and color picker overlays.
+type portalCaptureEngine struct{}
+
+func (portalCaptureEngine) Screenshot(interactive bool) (string, error) {
+	config := screenshot.DefaultConfig()
+	config.Mode = screenshot.ModeAllScreens
+	if interactive {
+		config.Mode = screenshot.ModeRegion
+	}
+
+	result, err := screenshot.New(config).Run()
+	if err != nil {
+		return "", err
+	}
+	if result == nil {
+		return "", nil
+	}
+	defer result.Buffer.Close()
+
+	if result.YInverted {
+		result.Buffer.FlipVertical()
+	}
+
+	path := filepath.Join(screenshot.GetOutputDir(), screenshot.GenerateFilename(screenshot.FormatPNG))
+	if err := screenshot.WriteToFileWithFormat(result.Buffer, path, screenshot.FormatPNG, config.Quality, result.Format, result.CICP); err != nil {
+		return "", fmt.Errorf("failed to save screenshot: %w", err)
+	}
+	return path, nil
+}
+
+func (portalCaptureEngine) PickColor() (*[3]float64, error) {
+	color, err := colorpicker.New(colorpicker.Config{Format: colorpicker.FormatHex}).Run()
+	if err != nil || color == nil {
+		return nil, err
+	}
+	return &[3]float64{float64(color.R) / 255, float64(color.G) / 255, float64(color.B) / 255}, nil
+}
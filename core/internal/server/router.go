@@ -13,12 +13,15 @@ import (
 	serverDgop "github.com/AvengeMedia/DankMaterialShell/core/internal/server/dgop"
 	"github.com/AvengeMedia/DankMaterialShell/core/internal/server/evdev"
 	"github.com/AvengeMedia/DankMaterialShell/core/internal/server/freedesktop"
+	"github.com/AvengeMedia/DankMaterialShell/core/internal/server/idle"
 	"github.com/AvengeMedia/DankMaterialShell/core/internal/server/location"
 	"github.com/AvengeMedia/DankMaterialShell/core/internal/server/loginctl"
 	"github.com/AvengeMedia/DankMaterialShell/core/internal/server/mime"
 	"github.com/AvengeMedia/DankMaterialShell/core/internal/server/models"
 	"github.com/AvengeMedia/DankMaterialShell/core/internal/server/network"
 	"github.com/AvengeMedia/DankMaterialShell/core/internal/server/notifyactions"
+	"github.com/AvengeMedia/DankMaterialShell/core/internal/server/notifyrules"
+	"github.com/AvengeMedia/DankMaterialShell/core/internal/server/orientation"
 	serverPlugins "github.com/AvengeMedia/DankMaterialShell/core/internal/server/plugins"
 	serverRegistries "github.com/AvengeMedia/DankMaterialShell/core/internal/server/registries"
 	"github.com/AvengeMedia/DankMaterialShell/core/internal/server/sysupdate"
@@ -174,6 +177,24 @@ func RouteRequest(conn *models.Conn, req models.Request) {
 		return
 	}
 
+	if strings.HasPrefix(req.Method, "orientation.") {
+		if orientationManager == nil {
+			models.RespondError(conn, req.ID, "orientation manager not initialized")
+			return
+		}
+		orientation.HandleRequest(conn, req, orientationManager)
+		return
+	}
+
+	if strings.HasPrefix(req.Method, "idle.") {
+		if idleManager == nil {
+			models.RespondError(conn, req.ID, "idle manager not initialized")
+			return
+		}
+		idle.HandleRequest(conn, req, idleManager)
+		return
+	}
+
 	if strings.HasPrefix(req.Method, "dbus.") {
 		if dbusManager == nil {
 			models.RespondError(conn, req.ID, "dbus manager not initialized")
@@ -210,6 +231,15 @@ func RouteRequest(conn *models.Conn, req models.Request) {
 		return
 	}
 
+	if strings.HasPrefix(req.Method, "notify.rules.") || strings.HasPrefix(req.Method, "notify.dnd.") {
+		if notifyRulesManager == nil {
+			models.RespondError(conn, req.ID, "notification rules manager not initialized")
+			return
+		}
+		notifyrules.HandleRequest(conn, req, notifyRulesManager)
+		return
+	}
+
 	if strings.HasPrefix(req.Method, "notify.") {
 		if notifyActionsManager == nil {
 			models.RespondError(conn, req.ID, "notification action manager not initialized")
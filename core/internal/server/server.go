@@ -20,11 +20,14 @@ import (
 	serverDbus "github.com/AvengeMedia/DankMaterialShell/core/internal/server/dbus"
 	"github.com/AvengeMedia/DankMaterialShell/core/internal/server/evdev"
 	"github.com/AvengeMedia/DankMaterialShell/core/internal/server/freedesktop"
+	"github.com/AvengeMedia/DankMaterialShell/core/internal/server/idle"
 	"github.com/AvengeMedia/DankMaterialShell/core/internal/server/location"
 	"github.com/AvengeMedia/DankMaterialShell/core/internal/server/loginctl"
 	"github.com/AvengeMedia/DankMaterialShell/core/internal/server/models"
 	"github.com/AvengeMedia/DankMaterialShell/core/internal/server/network"
 	"github.com/AvengeMedia/DankMaterialShell/core/internal/server/notifyactions"
+	"github.com/AvengeMedia/DankMaterialShell/core/internal/server/notifyrules"
+	"github.com/AvengeMedia/DankMaterialShell/core/internal/server/orientation"
 	"github.com/AvengeMedia/DankMaterialShell/core/internal/server/sysupdate"
 	"github.com/AvengeMedia/DankMaterialShell/core/internal/server/tailscale"
 	"github.com/AvengeMedia/DankMaterialShell/core/internal/server/thememode"
@@ -68,6 +71,8 @@ var tailscaleManager *tailscale.Manager
 var brightnessManager *brightness.Manager
 var wlrOutputManager *wlroutput.Manager
 var evdevManager *evdev.Manager
+var orientationManager *orientation.Manager
+var idleManager *idle.Manager
 var clipboardManager *clipboard.Manager
 var dbusManager *serverDbus.Manager
 var wlContext *wlcontext.SharedContext
@@ -77,6 +82,7 @@ var trayRecoveryManager *trayrecovery.Manager
 var locationManager *location.Manager
 var sysUpdateManager *sysupdate.Manager
 var notifyActionsManager *notifyactions.Manager
+var notifyRulesManager *notifyrules.Manager
 var geoClientInstance geolocation.Client
 
 const dbusClientID = "dms-dbus-client"
@@ -126,6 +132,10 @@ func InitializeFreedeskManager() error {
 
 	freedesktopManager = manager
 	matugen.SetColorSchemeEchoHook(manager.ExpectColorSchemeEcho)
+	matugen.SetThemeAppliedHook(func(theme matugen.AppliedTheme) {
+		manager.SetTheme(theme.Mode == matugen.ColorModeLight, theme.Primary)
+	})
+	manager.SetCaptureEngine(portalCaptureEngine{})
 
 	log.Info("Freedesktop manager initialized")
 	return nil
@@ -277,6 +287,44 @@ func InitializeEvdevManager() error {
 	return nil
 }
 
+func InitializeOrientationManager() error {
+	if wlrOutputManager == nil {
+		return fmt.Errorf("wlroutput manager not initialized")
+	}
+
+	manager, err := orientation.NewManager(wlrOutputManager)
+	if err != nil {
+		return err
+	}
+
+	orientationManager = manager
+
+	log.Info("Orientation manager initialized")
+	return nil
+}
+
+// InitializeIdleManager must run before the shared Wayland context starts
+// dispatching.
+func InitializeIdleManager() error {
+	if wlContext == nil {
+		ctx, err := wlcontext.New()
+		if err != nil {
+			return err
+		}
+		wlContext = ctx
+	}
+
+	manager, err := idle.NewManager(wlContext)
+	if err != nil {
+		return err
+	}
+
+	idleManager = manager
+
+	log.Info("Idle manager initialized")
+	return nil
+}
+
 func InitializeClipboardManager() error {
 	log.Info("Attempting to initialize clipboard manager...")
 
@@ -365,6 +413,12 @@ func InitializeNotifyActionsManager() error {
 	return nil
 }
 
+func InitializeNotifyRulesManager() error {
+	notifyRulesManager = notifyrules.NewManager()
+	log.Info("Notification rules manager initialized")
+	return nil
+}
+
 func InitializeSysUpdateManager() error {
 	manager, err := sysupdate.NewManager()
 	if err != nil {
@@ -423,6 +477,9 @@ func getCapabilities() Capabilities {
 
 	if bluezManager != nil {
 		caps = append(caps, "bluetooth")
+		if bluezManager.Obex() != nil {
+			caps = append(caps, "bluetooth.obex")
+		}
 	}
 
 	if appPickerManager != nil {
@@ -449,6 +506,14 @@ func getCapabilities() Capabilities {
 		caps = append(caps, "evdev")
 	}
 
+	if orientationManager != nil {
+		caps = append(caps, "orientation")
+	}
+
+	if idleManager != nil {
+		caps = append(caps, "idle")
+	}
+
 	if clipboardManager != nil {
 		caps = append(caps, "clipboard")
 	}
@@ -473,6 +538,14 @@ func getCapabilities() Capabilities {
 		caps = append(caps, "sysupdate")
 	}
 
+	if notifyActionsManager != nil && notifyActionsManager.History() != nil {
+		caps = append(caps, "notify.history")
+	}
+
+	if notifyRulesManager != nil {
+		caps = append(caps, "notify.rules")
+	}
+
 	return Capabilities{Capabilities: caps}
 }
 
@@ -858,6 +931,57 @@ func handleSubscribe(conn *models.Conn, req models.Request) {
 		}()
 	}
 
+	if shouldSubscribe("bluetooth.battery") && bluezManager != nil {
+		wg.Add(1)
+		batteryChan := bluezManager.SubscribeBattery(clientID + "-battery")
+		go func() {
+			defer wg.Done()
+			defer bluezManager.UnsubscribeBattery(clientID + "-battery")
+
+			for {
+				select {
+				case event, ok := <-batteryChan:
+					if !ok {
+						return
+					}
+					select {
+					case eventChan <- ServiceEvent{Service: "bluetooth.battery", Data: event}:
+					case <-stopChan:
+						return
+					}
+				case <-stopChan:
+					return
+				}
+			}
+		}()
+	}
+
+	if shouldSubscribe("bluetooth.obex") && bluezManager != nil && bluezManager.Obex() != nil {
+		wg.Add(1)
+		obex := bluezManager.Obex()
+		transferChan := obex.Subscribe(clientID + "-obex")
+		go func() {
+			defer wg.Done()
+			defer obex.Unsubscribe(clientID + "-obex")
+
+			for {
+				select {
+				case transfer, ok := <-transferChan:
+					if !ok {
+						return
+					}
+					select {
+					case eventChan <- ServiceEvent{Service: "bluetooth.obex", Data: transfer}:
+					case <-stopChan:
+						return
+					}
+				case <-stopChan:
+					return
+				}
+			}
+		}()
+	}
+
 	if shouldSubscribe("browser") && appPickerManager != nil {
 		wg.Add(1)
 		appPickerChan := appPickerManager.Subscribe(clientID + "-browser")
@@ -1054,8 +1178,11 @@ func handleSubscribe(conn *models.Conn, req models.Request) {
 	}
 
 	if shouldSubscribe("evdev") && evdevManager != nil {
-		wg.Add(1)
+		wg.Add(3)
 		evdevChan := evdevManager.Subscribe(clientID + "-evdev")
+		evdevDeviceChan := evdevManager.SubscribeDevices(clientID + "-evdev-devices")
+		evdevGestureChan := evdevManager.SubscribeGestures(clientID + "-evdev-gestures")
+
 		go func() {
 			defer wg.Done()
 			defer evdevManager.Unsubscribe(clientID + "-evdev")
@@ -1083,6 +1210,166 @@ func handleSubscribe(conn *models.Conn, req models.Request) {
 				}
 			}
 		}()
+
+		go func() {
+			defer wg.Done()
+			defer evdevManager.UnsubscribeDevices(clientID + "-evdev-devices")
+
+			for {
+				select {
+				case event, ok := <-evdevDeviceChan:
+					if !ok {
+						return
+					}
+					select {
+					case eventChan <- ServiceEvent{Service: "evdev.device", Data: event}:
+					case <-stopChan:
+						return
+					}
+				case <-stopChan:
+					return
+				}
+			}
+		}()
+
+		go func() {
+			defer wg.Done()
+			defer evdevManager.UnsubscribeGestures(clientID + "-evdev-gestures")
+
+			for {
+				select {
+				case gesture, ok := <-evdevGestureChan:
+					if !ok {
+						return
+					}
+					select {
+					case eventChan <- ServiceEvent{Service: "evdev.gesture", Data: gesture}:
+					case <-stopChan:
+						return
+					}
+				case <-stopChan:
+					return
+				}
+			}
+		}()
+	}
+
+	if shouldSubscribe("orientation") && orientationManager != nil {
+		wg.Add(1)
+		orientationChan := orientationManager.Subscribe(clientID + "-orientation")
+		go func() {
+			defer wg.Done()
+			defer orientationManager.Unsubscribe(clientID + "-orientation")
+
+			initialState := orientationManager.GetState()
+			select {
+			case eventChan <- ServiceEvent{Service: "orientation", Data: initialState}:
+			case <-stopChan:
+				return
+			}
+
+			for {
+				select {
+				case state, ok := <-orientationChan:
+					if !ok {
+						return
+					}
+					select {
+					case eventChan <- ServiceEvent{Service: "orientation", Data: state}:
+					case <-stopChan:
+						return
+					}
+				case <-stopChan:
+					return
+				}
+			}
+		}()
+	}
+
+	if shouldSubscribe("idle") && idleManager != nil {
+		wg.Add(1)
+		idleChan := idleManager.Subscribe(clientID + "-idle")
+		go func() {
+			defer wg.Done()
+			defer idleManager.Unsubscribe(clientID + "-idle")
+
+			initialState := idleManager.GetState()
+			select {
+			case eventChan <- ServiceEvent{Service: "idle", Data: initialState}:
+			case <-stopChan:
+				return
+			}
+
+			for {
+				select {
+				case state, ok := <-idleChan:
+					if !ok {
+						return
+					}
+					select {
+					case eventChan <- ServiceEvent{Service: "idle", Data: state}:
+					case <-stopChan:
+						return
+					}
+				case <-stopChan:
+					return
+				}
+			}
+		}()
+	}
+
+	if shouldSubscribe("notify.rules") && notifyRulesManager != nil {
+		wg.Add(2)
+		notifyRulesChan := notifyRulesManager.Subscribe(clientID + "-notify-rules")
+		notifyDecisionChan := notifyRulesManager.SubscribeDecisions(clientID + "-notify-decisions")
+		go func() {
+			defer wg.Done()
+			defer notifyRulesManager.Unsubscribe(clientID + "-notify-rules")
+
+			initialState := notifyRulesManager.GetState()
+			select {
+			case eventChan <- ServiceEvent{Service: "notify.rules", Data: initialState}:
+			case <-stopChan:
+				return
+			}
+
+			for {
+				select {
+				case state, ok := <-notifyRulesChan:
+					if !ok {
+						return
+					}
+					select {
+					case eventChan <- ServiceEvent{Service: "notify.rules", Data: state}:
+					case <-stopChan:
+						return
+					}
+				case <-stopChan:
+					return
+				}
+			}
+		}()
+
+		go func() {
+			defer wg.Done()
+			defer notifyRulesManager.UnsubscribeDecisions(clientID + "-notify-decisions")
+
+			for {
+				select {
+				case decided, ok := <-notifyDecisionChan:
+					if !ok {
+						return
+					}
+					select {
+					case eventChan <- ServiceEvent{Service: "notify.rules.decision", Data: decided}:
+					case <-stopChan:
+						return
+					}
+				case <-stopChan:
+					return
+				}
+			}
+		}()
 	}
 
 	if shouldSubscribe("clipboard") && clipboardManager != nil {
@@ -1259,12 +1546,19 @@ func cleanupManagers() {
 	if brightnessManager != nil {
 		brightnessManager.Close()
 	}
+	if orientationManager != nil {
+		orientationManager.Close()
+	}
 	if wlrOutputManager != nil {
 		wlrOutputManager.Close()
 	}
 	if evdevManager != nil {
 		evdevManager.Close()
 	}
+	if idleManager != nil {
+		idleManager.Close()
+	}
+
 	if clipboardManager != nil {
 		clipboardManager.Close()
 	}
@@ -1274,6 +1568,9 @@ func cleanupManagers() {
 	if notifyActionsManager != nil {
 		notifyActionsManager.Close()
 	}
+	if notifyRulesManager != nil {
+		notifyRulesManager.Close()
+	}
 	if themeModeManager != nil {
 		themeModeManager.Close()
 	}
@@ -1403,9 +1700,22 @@ func (s *Server) Serve(printDocs bool) error {
 		log.Info(" loginctl.setSleepInhibitorEnabled - Enable/disable sleep inhibitor (params: enabled)")
 		log.Info(" loginctl.lockerReady        - Signal locker UI is ready (releases sleep inhibitor)")
 		log.Info(" loginctl.terminate          - Terminate session")
+		log.Info(" loginctl.getCapabilities    - Probe suspend/hibernate/suspend-then-hibernate/hybrid-sleep support (yes, challenge, no, na) and RTC wake alarm")
+		log.Info(" loginctl.suspend            - Suspend (params: wakeAt? (RFC 3339), wakeIn? (seconds))")
+		log.Info(" loginctl.hibernate          - Hibernate (params: wakeAt?, wakeIn?)")
+		log.Info(" loginctl.suspendThenHibernate - Suspend, then hibernate after the configured delay (params: wakeAt?, wakeIn?)")
+		log.Info(" loginctl.hybridSleep        - Suspend with a hibernation image (params: wakeAt?, wakeIn?)")
+		log.Info(" loginctl.getWakeAlarm       - Get the RTC wake alarm")
+		log.Info(" loginctl.setWakeAlarm       - Program the RTC wake alarm (params: at? (RFC 3339), delay? (seconds))")
+		log.Info(" loginctl.clearWakeAlarm     - Clear the RTC wake alarm")
+		log.Info(" loginctl.listInhibitors     - List logind inhibitors (what, who, why, mode, uid, pid, process)")
+		log.Info(" loginctl.killInhibitor      - Terminate a process holding an inhibitor (params: pid, force?)")
+		log.Info(" loginctl.schedule.set       - Schedule poweroff/reboot/suspend/hibernate/logout/lock (params: action, at? (RFC 3339), delay? (seconds), warnings? (seconds before))")
+		log.Info(" loginctl.schedule.get       - Get the pending power schedule")
+		log.Info(" loginctl.schedule.cancel    - Cancel the pending power schedule")
 		log.Info(" loginctl.subscribe          - Subscribe to session state changes (streaming)")
 		log.Info("Freedesktop:")
-		log.Info(" freedesktop.getState                  - Get accounts & settings state")
+		log.Info(" freedesktop.getState                  - Get accounts, settings & portal state")
 		log.Info(" freedesktop.accounts.setIconFile      - Set profile icon (params: path)")
 		log.Info(" freedesktop.accounts.setRealName      - Set real name (params: name)")
 		log.Info(" freedesktop.accounts.setEmail         - Set email (params: email)")
@@ -1413,6 +1723,7 @@ func (s *Server) Serve(printDocs bool) error {
 		log.Info(" freedesktop.accounts.setLocation      - Set location (params: location)")
 		log.Info(" freedesktop.accounts.getUserIconFile  - Get user icon (params: username)")
 		log.Info(" freedesktop.settings.getColorScheme   - Get color scheme")
+		log.Info(" freedesktop.settings.getAppearance    - Get appearance served by the DMS portal backend")
 		log.Info(" freedesktop.settings.setIconTheme     - Set icon theme (params: iconTheme)")
 		log.Info("Wayland:")
 		log.Info(" wayland.gamma.getState                - Get current gamma control state")
@@ -1435,7 +1746,12 @@ func (s *Server) Serve(printDocs bool) error {
 		log.Info(" bluetooth.getState                    - Get current bluetooth state")
 		log.Info(" bluetooth.startDiscovery              - Start device discovery (params: adapter?)")
 		log.Info(" bluetooth.stopDiscovery               - Stop device discovery (params: adapter?)")
-		log.Info(" bluetooth.setPowered                  - Set adapter power state (params: powered, adapter?)")
+		log.Info(" bluetooth.setPowered                  - Set adapter power state, remembered across restarts (params: powered, adapter?)")
+		log.Info(" bluetooth.setAlias                    - Rename an adapter, empty resets (params: alias, adapter?)")
+		log.Info(" bluetooth.setDiscoverable             - Toggle discoverability (params: discoverable, timeout? seconds, adapter?)")
+		log.Info(" bluetooth.setPairable                 - Toggle pairability (params: pairable, timeout? seconds, adapter?)")
+		log.Info(" bluetooth.setDefaultAdapter           - Prefer an adapter by path or address, empty clears (params: adapter?)")
+		log.Info(" bluetooth.setDiscoveryFilter          - Filter discovery results, no params clears (params: transport? auto|bredr|le, rssi?, uuids?, pattern?)")
 		log.Info(" bluetooth.pair                        - Pair with device (params: device)")
 		log.Info(" bluetooth.connect                     - Connect to device (params: device)")
 		log.Info(" bluetooth.disconnect                  - Disconnect from device (params: device)")
@@ -1445,6 +1761,18 @@ func (s *Server) Serve(printDocs bool) error {
 		log.Info(" bluetooth.pairing.submit              - Submit pairing response (params: token, secrets?, accept?)")
 		log.Info(" bluetooth.pairing.cancel              - Cancel pairing prompt (params: token)")
 		log.Info(" bluetooth.subscribe                   - Subscribe to bluetooth state changes (streaming)")
+		log.Info("   (service \"bluetooth.battery\" streams low/critical battery events for connected devices)")
+		log.Info(" bluetooth.obex.getState               - Get OBEX availability, receive directory and recent transfers")
+		log.Info(" bluetooth.obex.send                   - Send a file over OBEX push (params: device, file, follow?)")
+		log.Info(" bluetooth.obex.cancel                 - Cancel a transfer (params: id)")
+		log.Info(" bluetooth.obex.setReceiveDir          - Set where received files are saved (params: dir, empty = Downloads)")
+		log.Info(" bluetooth.obex.subscribe              - Subscribe to OBEX state changes (streaming)")
+		log.Info("   (incoming pushes arrive as \"authorize-push\" pairing prompts; service \"bluetooth.obex\" streams transfer updates)")
+		log.Info(" bluetooth.policy.getState             - Get auto-connect policies, priority groups and reconnect progress")
+		log.Info(" bluetooth.policy.set                  - Replace the whole policy (params: policy {devices, groups, retry})")
+		log.Info(" bluetooth.policy.setDevice            - Set device flags (params: device, autoConnect?, disconnectOnLock?, disconnectOnSuspend?)")
+		log.Info(" bluetooth.policy.setGroups            - Set priority groups, first reachable device wins (params: groups [{id, name?, devices}])")
+		log.Info(" bluetooth.policy.reconnect            - Run auto-connect now with retry and backoff")
 		log.Info("CUPS:")
 		log.Info(" cups.getPrinters                      - Get printers list")
 		log.Info(" cups.getJobs                          - Get non-completed jobs list (params: printerName)")
@@ -1459,6 +1787,27 @@ func (s *Server) Serve(printDocs bool) error {
 		log.Info(" brightness.decrement                  - Decrement device brightness (params: device, step?)")
 		log.Info(" brightness.rescan                     - Rescan for brightness devices (e.g., after plugging in monitor)")
 		log.Info(" brightness.subscribe                  - Subscribe to brightness state changes (streaming)")
+		log.Info(" brightness.auto.getState              - Get auto-brightness state, current lux and learned curve")
+		log.Info(" brightness.auto.enable                - Enable ambient light auto-brightness (params: device?)")
+		log.Info(" brightness.auto.disable               - Disable auto-brightness")
+		log.Info(" brightness.auto.pause                 - Pause auto-brightness (params: duration? seconds, 0 = until resumed)")
+		log.Info(" brightness.auto.resume                - Resume auto-brightness")
+		log.Info(" brightness.auto.resetCurve            - Forget learned adjustments (params: device?)")
+		log.Info(" brightness.groups.list                - List brightness link groups")
+		log.Info(" brightness.groups.set                 - Create or replace a link group (params: name, members)")
+		log.Info(" brightness.groups.delete              - Delete a link group (params: name)")
+		log.Info("   Link groups appear as device \"group:<name>\" for setBrightness/increment/decrement")
+		log.Info("     - members[].device : Member device ID")
+		log.Info("     - members[].offset : Added to the group percentage (optional)")
+		log.Info("     - members[].scale  : Multiplies the group percentage (optional, default 1)")
+		log.Info("     - members[].points : [{in, out}] mapping curve, overrides offset/scale (optional)")
+		log.Info(" brightness.kbd.getState               - Get keyboard backlight policy state")
+		log.Info(" brightness.kbd.configure              - Configure keyboard backlight policy (params: enabled?, device?, timeout?, batteryMaxPercent?, offOnLid?, offOnLock?)")
+		log.Info(" brightness.ddc.capabilities           - Read a monitor's DDC/CI capability string (params: device)")
+		log.Info(" brightness.ddc.getState               - Read supported DDC/CI features (params: device)")
+		log.Info(" brightness.ddc.get                    - Read a DDC/CI feature (params: device, feature)")
+		log.Info(" brightness.ddc.set                    - Write a DDC/CI feature (params: device, feature, value)")
+		log.Info(" brightness.ddc.setInput               - Switch monitor input source (params: device, input)")
 		log.Info("   Subscription events:")
 		log.Info("     - brightness       : Full device list (on rescan, DDC discovery, device changes)")
 		log.Info("     - brightness.update: Single device update (on brightness change for efficiency)")
@@ -1467,6 +1816,11 @@ func (s *Server) Serve(printDocs bool) error {
 		log.Info(" wlroutput.applyConfiguration          - Apply output configuration (params: heads)")
 		log.Info(" wlroutput.testConfiguration           - Test output configuration without applying (params: heads)")
 		log.Info(" wlroutput.subscribe                   - Subscribe to output state changes (streaming)")
+		log.Info(" wlroutput.profiles.list               - List saved display profiles")
+		log.Info(" wlroutput.profiles.save               - Save current layout as a profile (params: name)")
+		log.Info(" wlroutput.profiles.delete             - Delete a display profile (params: name)")
+		log.Info(" wlroutput.profiles.apply              - Apply a display profile (params: name)")
+		log.Info(" wlroutput.persist                     - Write layout to the compositor outputs config (params: compositor?, heads?)")
 		log.Info("   Head configuration params:")
 		log.Info("     - name         : Output name (required)")
 		log.Info("     - enabled      : Enable/disable output (required)")
@@ -1477,8 +1831,34 @@ func (s *Server) Serve(printDocs bool) error {
 		log.Info("     - scale        : Scale value (optional)")
 		log.Info("     - adaptiveSync : Adaptive sync state (optional)")
 		log.Info("Evdev:")
-		log.Info(" evdev.getState                        - Get current evdev state (caps lock)")
+		log.Info(" evdev.getState                        - Get current evdev state (lock keys, switches, input devices)")
+		log.Info(" evdev.listDevices                     - List input devices with kinds and vendor/product IDs")
 		log.Info(" evdev.subscribe                       - Subscribe to evdev state changes (streaming)")
+		log.Info(" evdev.remap.getState                  - Get key remapper state (config, grabbed devices, active layers)")
+		log.Info(" evdev.remap.enable                    - Enable the key remapper")
+		log.Info(" evdev.remap.disable                   - Disable the key remapper and release grabbed keyboards")
+		log.Info(" evdev.remap.toggle                    - Toggle the key remapper")
+		log.Info(" evdev.remap.setEnabled                - Enable or disable the key remapper (params: enabled)")
+		log.Info(" evdev.remap.setConfig                 - Replace the remap config (params: config {enabled, tapTimeout, rules})")
+		log.Info(" evdev.remap.reload                    - Reload remap.json from disk")
+		log.Info(" evdev.gestures.getState               - Get touchpad gesture config and watched touchpads")
+		log.Info(" evdev.gestures.setEnabled             - Enable or disable touchpad gestures (params: enabled)")
+		log.Info(" evdev.gestures.setConfig              - Replace the gesture config (params: config {enabled, minFingers, swipeThreshold, pinchThreshold, holdTime, bindings})")
+		log.Info("   Subscription events:")
+		log.Info("     - evdev       : Lock keys, switches (lid, tabletMode, headphoneInsert, microphoneInsert, dock) and device inventory")
+		log.Info("     - evdev.device: Input device added/removed ({type, device})")
+		log.Info("     - evdev.gesture: Recognized touchpad gesture ({type, fingers, direction, device, action})")
+		log.Info("Orientation:")
+		log.Info(" orientation.getState                  - Get accelerometer orientation, panel transform and rotation lock")
+		log.Info(" orientation.setLocked                 - Lock or unlock auto-rotation (params: locked)")
+		log.Info(" orientation.toggleLock                - Toggle the rotation lock")
+		log.Info(" orientation.configure                 - Configure auto-rotation (params: locked?, tabletOnly?, output?)")
+		log.Info(" orientation.subscribe                 - Subscribe to orientation changes (streaming)")
+		log.Info("Idle:")
+		log.Info(" idle.getState                         - Get idle stages, active profile, current stage and inhibitors")
+		log.Info(" idle.setEnabled                       - Enable or disable staged idle handling (params: enabled)")
+		log.Info(" idle.setConfig                        - Update idle config (params: config{enabled?, ac?, battery?, dimPercent?, fadeTime?, respectInhibitors?})")
+		log.Info(" idle.subscribe                        - Subscribe to idle stage transitions (streaming)")
 		log.Info("Clipboard:")
 		log.Info(" clipboard.getState                    - Get clipboard state (enabled, history, current)")
 		log.Info(" clipboard.getHistory                  - Get clipboard history with previews")
@@ -1493,6 +1873,26 @@ func (s *Server) Serve(printDocs bool) error {
 		log.Info(" clipboard.subscribe                   - Subscribe to clipboard state changes (streaming)")
 		log.Info("Notify:")
 		log.Info(" notify.watchAction                    - Open a file when a notification action fires (params: id, path)")
+		log.Info(" notify.history.list                   - List recorded notifications, newest first (params: appName?, unread?, limit?, offset?)")
+		log.Info(" notify.history.search                 - Full-text search of app, summary and body (params: query, appName?, unread?, limit?, offset?, before?, after?)")
+		log.Info(" notify.history.delete                 - Delete a history entry (params: id)")
+		log.Info(" notify.history.clearApp               - Delete all history from an app (params: appName)")
+		log.Info(" notify.history.clear                  - Delete all notification history")
+		log.Info(" notify.history.markRead               - Mark entries read (params: ids? - all when omitted)")
+		log.Info(" notify.rules.getState                 - Get rules, DND profiles and DND state")
+		log.Info(" notify.rules.set                      - Replace all rules (params: rules [{id, name?, enabled, match{app?, summary?, body?, urgency?, category?}, action{suppress?, urgency?, timeout?, sound?, bypassDnd?}}])")
+		log.Info(" notify.rules.add                      - Add or replace a rule by id (params: rule)")
+		log.Info(" notify.rules.remove                   - Remove a rule (params: id)")
+		log.Info(" notify.rules.evaluate                 - Decide how to present a notification; the shell must call this before showing it (params: appName, summary, body, desktopEntry?, urgency?, category?, timeout?)")
+		log.Info(" notify.rules.subscribe                - Subscribe to rule and DND changes (streaming)")
+		log.Info(" notify.dnd.getState                   - Same as notify.rules.getState")
+		log.Info(" notify.dnd.set                        - Set DND by hand (params: enabled, profile?, duration? seconds)")
+		log.Info(" notify.dnd.clear                      - Drop manual DND and follow schedules again")
+		log.Info(" notify.dnd.setProfiles                - Replace DND profiles (params: profiles [{id, name?, enabled, schedules?[{days?, start, end}], whenFullscreen?, whenScreenSharing?, allowCritical, allowApps?}])")
+		log.Info(" notify.dnd.setActivity                - Report fullscreen or screen sharing (params: fullscreen?, screenSharing?)")
+		log.Info("   Subscription events:")
+		log.Info("     - notify.rules         : Rules, DND profiles and DND state")
+		log.Info("     - notify.rules.decision: Advisory rule decision for each accepted notification, sent after the shell already has it; use notify.rules.evaluate to act on rules ({id, notification, decision})")
 		log.Info("Location:")
 		log.Info(" location.getState                      - Get current location state")
 		log.Info(" location.subscribe                     - Subscribe to location changes (streaming)")
@@ -1646,6 +2046,10 @@ func (s *Server) Serve(printDocs bool) error {
 			err := InitializeBluezManager()
 			if err == nil {
 				notifyCapabilityChange()
+				<-loginctlReady
+				if loginctlManager != nil {
+					bluezManager.WatchLoginctl(loginctlManager)
+				}
 				return
 			}
 			log.Warnf("Bluez manager unavailable: %v", err)
@@ -1682,7 +2086,11 @@ func (s *Server) Serve(printDocs bool) error {
 		}()
 	}
 
+	brightnessReady := make(chan struct{})
+	evdevReady := make(chan struct{})
+
 	go func() {
+		defer close(brightnessReady)
 		if err := InitializeBrightnessManager(); err != nil {
 			log.Warnf("Brightness manager unavailable: %v", err)
 		} else {
@@ -1691,6 +2099,7 @@ func (s *Server) Serve(printDocs bool) error {
 	}()
 
 	go func() {
+		defer close(evdevReady)
 		if err := InitializeEvdevManager(); err != nil {
 			log.Debugf("Evdev manager unavailable: %v", err)
 		} else {
@@ -1698,16 +2107,78 @@ func (s *Server) Serve(printDocs bool) error {
 		}
 	}()
 
+	// Keyboard backlight policy: key presses and session lock/idle state.
+	go func() {
+		<-brightnessReady
+		<-evdevReady
+		if brightnessManager == nil || evdevManager == nil {
+			return
+		}
+		brightnessManager.WatchEvdev(evdevManager)
+	}()
+
+	// Auto-rotation only rotates in tablet mode on convertibles.
+	go func() {
+		if err := InitializeOrientationManager(); err != nil {
+			log.Debugf("Orientation manager unavailable: %v", err)
+			return
+		}
+		notifyCapabilityChange()
+
+		<-evdevReady
+		if evdevManager == nil {
+			return
+		}
+		orientationManager.WatchEvdev(evdevManager)
+	}()
+
+	go func() {
+		<-brightnessReady
+		<-loginctlReady
+		if brightnessManager == nil || loginctlManager == nil {
+			return
+		}
+		brightnessManager.WatchLoginctl(loginctlManager)
+	}()
+
+	idleReady := make(chan struct{})
+
 	go func() {
 		if err := InitializeClipboardManager(); err != nil {
 			log.Warnf("Clipboard manager unavailable: %v", err)
 		}
+		if err := InitializeIdleManager(); err != nil {
+			log.Warnf("Idle manager unavailable: %v", err)
+		} else {
+			notifyCapabilityChange()
+		}
+		close(idleReady)
 		if wlContext != nil {
 			wlContext.Start()
 			log.Info("Wayland event dispatcher started")
 		}
 	}()
 
+	// Idle stages dim, lock and suspend through the other managers.
+	go func() {
+		<-idleReady
+		if idleManager == nil {
+			return
+		}
+		<-loginctlReady
+		if loginctlManager != nil {
+			idleManager.WatchLoginctl(loginctlManager)
+		}
+		<-brightnessReady
+		if brightnessManager != nil {
+			idleManager.WatchBrightness(brightnessManager)
+		}
+		<-freedesktopReady
+		if freedesktopManager != nil {
+			idleManager.WatchFreedesktop(freedesktopManager)
+		}
+	}()
+
 	go func() {
 		if err := InitializeDbusManager(); err != nil {
 			log.Warnf("DBus manager unavailable: %v", err)
@@ -1716,9 +2187,18 @@ func (s *Server) Serve(printDocs bool) error {
 		}
 	}()
 
+	if err := InitializeNotifyRulesManager(); err != nil {
+		log.Warnf("Notification rules manager unavailable: %v", err)
+	}
+
 	go func() {
 		if err := InitializeNotifyActionsManager(); err != nil {
 			log.Warnf("Notification action manager unavailable: %v", err)
+			return
+		}
+		notifyCapabilityChange()
+		if notifyRulesManager != nil {
+			notifyRulesManager.WatchNotifications(notifyActionsManager)
 		}
 	}()
 
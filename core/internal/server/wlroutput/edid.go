@@ -0,0 +1,464 @@
+package wlroutput
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	edidBlockSize = 128
+
+	edidDescriptorSerial = 0xFF
+	edidDescriptorRange  = 0xFD
+	edidDescriptorName   = 0xFC
+
+	ctaExtensionTag        = 0x02
+	ctaTagVendor           = 0x03
+	ctaTagExtended         = 0x07
+	ctaExtTagColorimetry   = 0x05
+	ctaExtTagHDRStaticMeta = 0x06
+
+	ouiAMD          = 0x00001A
+	ouiHDMIForum    = 0xC45DD8
+	featureContFreq = 0x01
+)
+
+var drmSysfsRoot = "/sys/class/drm"
+
+var edidHeader = []byte{0x00, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0x00}
+
+type EDIDTiming struct {
+	Width      int     `json:"width"`
+	Height     int     `json:"height"`
+	Refresh    float64 `json:"refresh"`
+	Interlaced bool    `json:"interlaced,omitempty"`
+	Source     string  `json:"source"`
+}
+
+type EDIDHDR struct {
+	EOTFs                []string `json:"eotfs"`
+	MaxLuminance         float64  `json:"maxLuminance,omitempty"`
+	MaxFrameAvgLuminance float64  `json:"maxFrameAvgLuminance,omitempty"`
+	MinLuminance         float64  `json:"minLuminance,omitempty"`
+}
+
+type EDIDRange struct {
+	MinHz int `json:"minHz"`
+	MaxHz int `json:"maxHz"`
+}
+
+type EDID struct {
+	Manufacturer     string       `json:"manufacturer"`
+	ProductCode      uint16       `json:"productCode"`
+	SerialNumber     uint32       `json:"serialNumber,omitempty"`
+	SerialString     string       `json:"serialString,omitempty"`
+	Name             string       `json:"name,omitempty"`
+	Week             int          `json:"week,omitempty"`
+	Year             int          `json:"year"`
+	ModelYear        bool         `json:"modelYear,omitempty"`
+	Version          string       `json:"version"`
+	PhysicalWidthMM  int          `json:"physicalWidthMm"`
+	PhysicalHeightMM int          `json:"physicalHeightMm"`
+	NativeMode       *EDIDTiming  `json:"nativeMode,omitempty"`
+	Timings          []EDIDTiming `json:"timings"`
+	Colorimetry      []string     `json:"colorimetry,omitempty"`
+	HDR              *EDIDHDR     `json:"hdr,omitempty"`
+	// RefreshRange is the monitor's range limits descriptor, which nearly
+	// every display has. VRR is only set when the display also claims
+	// variable refresh.
+	RefreshRange  *EDIDRange `json:"refreshRange,omitempty"`
+	VRR           *EDIDRange `json:"vrr,omitempty"`
+	ChecksumValid bool       `json:"checksumValid"`
+}
+
+// establishedTimings is indexed by bit position in the 24-bit field formed
+// by bytes 35-37, starting at bit 7 of byte 37.
+var establishedTimings = [17]EDIDTiming{
+	{Width: 1152, Height: 870, Refresh: 75},
+	{Width: 1280, Height: 1024, Refresh: 75},
+	{Width: 1024, Height: 768, Refresh: 75},
+	{Width: 1024, Height: 768, Refresh: 70},
+	{Width: 1024, Height: 768, Refresh: 60},
+	{Width: 1024, Height: 768, Refresh: 87, Interlaced: true},
+	{Width: 832, Height: 624, Refresh: 75},
+	{Width: 800, Height: 600, Refresh: 75},
+	{Width: 800, Height: 600, Refresh: 72},
+	{Width: 800, Height: 600, Refresh: 60},
+	{Width: 800, Height: 600, Refresh: 56},
+	{Width: 640, Height: 480, Refresh: 75},
+	{Width: 640, Height: 480, Refresh: 72},
+	{Width: 640, Height: 480, Refresh: 67},
+	{Width: 640, Height: 480, Refresh: 60},
+	{Width: 720, Height: 400, Refresh: 88},
+	{Width: 720, Height: 400, Refresh: 70},
+}
+
+var colorimetryNames = [9]string{
+	"xvYCC601", "xvYCC709", "sYCC601", "opYCC601", "opRGB",
+	"BT2020cYCC", "BT2020YCC", "BT2020RGB", "DCI-P3",
+}
+
+var eotfNames = [4]string{"sdr", "hdr", "pq", "hlg"}
+
+// ParseEDID decodes the base block and any CTA-861 extensions of an EDID
+// blob. A bad checksum is reported through ChecksumValid rather than an
+// error since plenty of panels ship with one.
+func ParseEDID(data []byte) (*EDID, error) {
+	if len(data) < edidBlockSize {
+		return nil, fmt.Errorf("edid too short: %d bytes", len(data))
+	}
+	if !bytes.Equal(data[:8], edidHeader) {
+		return nil, fmt.Errorf("invalid edid header")
+	}
+
+	e := &EDID{
+		Manufacturer:  decodeManufacturer(binary.BigEndian.Uint16(data[8:10])),
+		ProductCode:   binary.LittleEndian.Uint16(data[10:12]),
+		SerialNumber:  binary.LittleEndian.Uint32(data[12:16]),
+		Version:       fmt.Sprintf("%d.%d", data[18], data[19]),
+		ChecksumValid: edidChecksum(data[:edidBlockSize]),
+		Timings:       []EDIDTiming{},
+	}
+
+	switch week := data[16]; week {
+	case 0xFF:
+		e.ModelYear = true
+	default:
+		e.Week = int(week)
+	}
+	e.Year = int(data[17]) + 1990
+
+	e.PhysicalWidthMM = int(data[21]) * 10
+	e.PhysicalHeightMM = int(data[22]) * 10
+
+	established := uint32(data[35])<<16 | uint32(data[36])<<8 | uint32(data[37])
+	for bit := range establishedTimings {
+		if established&(1<<(bit+7)) == 0 {
+			continue
+		}
+		t := establishedTimings[bit]
+		t.Source = "established"
+		e.Timings = append(e.Timings, t)
+	}
+
+	for i := 38; i < 54; i += 2 {
+		if t, ok := parseStandardTiming(data[i], data[i+1], data[19]); ok {
+			e.Timings = append(e.Timings, t)
+		}
+	}
+
+	for i := 54; i < 126; i += 18 {
+		e.parseDescriptor(data[i : i+18])
+	}
+
+	// EDID 1.4 repurposed the GTF bit as "continuous frequency", which is
+	// how eDP panels with adaptive sync advertise it.
+	if data[18] == 1 && data[19] >= 4 && data[24]&featureContFreq != 0 && e.RefreshRange != nil {
+		vrr := *e.RefreshRange
+		e.VRR = &vrr
+	}
+
+	extensions := int(data[126])
+	for n := 1; n <= extensions; n++ {
+		start := n * edidBlockSize
+		if start+edidBlockSize > len(data) {
+			break
+		}
+		block := data[start : start+edidBlockSize]
+		if block[0] == ctaExtensionTag {
+			e.parseCTA(block)
+		}
+	}
+
+	return e, nil
+}
+
+func edidChecksum(block []byte) bool {
+	var sum byte
+	for _, b := range block {
+		sum += b
+	}
+	return sum == 0
+}
+
+func decodeManufacturer(v uint16) string {
+	letters := []byte{
+		byte((v>>10)&0x1F) + 'A' - 1,
+		byte((v>>5)&0x1F) + 'A' - 1,
+		byte(v&0x1F) + 'A' - 1,
+	}
+	return string(letters)
+}
+
+func parseStandardTiming(b0, b1, revision byte) (EDIDTiming, bool) {
+	if (b0 == 0x01 && b1 == 0x01) || b0 == 0x00 {
+		return EDIDTiming{}, false
+	}
+
+	width := (int(b0) + 31) * 8
+	var height int
+	switch b1 >> 6 {
+	case 0:
+		if revision < 3 {
+			height = width
+		} else {
+			height = width * 10 / 16
+		}
+	case 1:
+		height = width * 3 / 4
+	case 2:
+		height = width * 4 / 5
+	case 3:
+		height = width * 9 / 16
+	}
+
+	return EDIDTiming{
+		Width:   width,
+		Height:  height,
+		Refresh: float64(int(b1&0x3F) + 60),
+		Source:  "standard",
+	}, true
+}
+
+func parseDetailedTiming(d []byte) (EDIDTiming, int, int, bool) {
+	pixelClock := int(binary.LittleEndian.Uint16(d[0:2])) * 10000
+	if pixelClock == 0 {
+		return EDIDTiming{}, 0, 0, false
+	}
+
+	hActive := int(d[2]) | int(d[4]&0xF0)<<4
+	hBlank := int(d[3]) | int(d[4]&0x0F)<<8
+	vActive := int(d[5]) | int(d[7]&0xF0)<<4
+	vBlank := int(d[6]) | int(d[7]&0x0F)<<8
+	widthMM := int(d[12]) | int(d[14]&0xF0)<<4
+	heightMM := int(d[13]) | int(d[14]&0x0F)<<8
+	interlaced := d[17]&0x80 != 0
+
+	total := (hActive + hBlank) * (vActive + vBlank)
+	if total == 0 {
+		return EDIDTiming{}, 0, 0, false
+	}
+	refresh := float64(pixelClock) / float64(total)
+	if interlaced {
+		vActive *= 2
+	}
+
+	return EDIDTiming{
+		Width:      hActive,
+		Height:     vActive,
+		Refresh:    math.Round(refresh*1000) / 1000,
+		Interlaced: interlaced,
+		Source:     "detailed",
+	}, widthMM, heightMM, true
+}
+
+func (e *EDID) addDetailedTiming(d []byte) {
+	t, widthMM, heightMM, ok := parseDetailedTiming(d)
+	if !ok {
+		return
+	}
+	e.Timings = append(e.Timings, t)
+	if e.NativeMode != nil {
+		return
+	}
+	native := t
+	e.NativeMode = &native
+	if widthMM > 0 && heightMM > 0 {
+		e.PhysicalWidthMM = widthMM
+		e.PhysicalHeightMM = heightMM
+	}
+}
+
+func (e *EDID) parseDescriptor(d []byte) {
+	if d[0] != 0 || d[1] != 0 {
+		e.addDetailedTiming(d)
+		return
+	}
+
+	switch d[3] {
+	case edidDescriptorName:
+		e.Name = descriptorString(d[5:18])
+	case edidDescriptorSerial:
+		e.SerialString = descriptorString(d[5:18])
+	case edidDescriptorRange:
+		minV, maxV := int(d[5]), int(d[6])
+		if d[4]&0x01 != 0 {
+			minV += 255
+		}
+		if d[4]&0x02 != 0 {
+			maxV += 255
+		}
+		if minV > 0 && maxV > minV {
+			e.RefreshRange = &EDIDRange{MinHz: minV, MaxHz: maxV}
+		}
+	}
+}
+
+func descriptorString(b []byte) string {
+	if idx := bytes.IndexByte(b, 0x0A); idx >= 0 {
+		b = b[:idx]
+	}
+	return strings.TrimSpace(string(b))
+}
+
+func (e *EDID) parseCTA(block []byte) {
+	dtdOffset := int(block[2])
+	if dtdOffset < 4 || dtdOffset > 127 {
+		dtdOffset = 127
+	}
+
+	for i := 4; i < dtdOffset; {
+		tag := block[i] >> 5
+		length := int(block[i] & 0x1F)
+		if i+1+length > dtdOffset {
+			break
+		}
+		payload := block[i+1 : i+1+length]
+		switch {
+		case tag == ctaTagVendor && length >= 3:
+			e.parseCTAVendor(payload)
+		case tag == ctaTagExtended && length > 0:
+			e.parseCTAExtended(payload)
+		}
+		i += 1 + length
+	}
+
+	for i := dtdOffset; i+18 <= 127; i += 18 {
+		e.addDetailedTiming(block[i : i+18])
+	}
+}
+
+// parseCTAVendor picks the VRR range out of the AMD FreeSync and HDMI Forum
+// vendor blocks.
+func (e *EDID) parseCTAVendor(payload []byte) {
+	oui := int(payload[0]) | int(payload[1])<<8 | int(payload[2])<<16
+	var vrr EDIDRange
+	switch {
+	case oui == ouiAMD && len(payload) >= 7:
+		vrr = EDIDRange{MinHz: int(payload[5]), MaxHz: int(payload[6])}
+	case oui == ouiHDMIForum && len(payload) >= 10:
+		vrr.MinHz = int(payload[9] & 0x3F)
+		if len(payload) >= 11 {
+			vrr.MaxHz = int(payload[9]&0xC0)<<2 | int(payload[10])
+		}
+		if vrr.MaxHz == 0 && e.RefreshRange != nil {
+			vrr.MaxHz = e.RefreshRange.MaxHz
+		}
+	default:
+		return
+	}
+	if vrr.MinHz > 0 && vrr.MaxHz > vrr.MinHz {
+		e.VRR = &vrr
+	}
+}
+
+func (e *EDID) parseCTAExtended(payload []byte) {
+	switch payload[0] {
+	case ctaExtTagColorimetry:
+		if len(payload) < 3 {
+			return
+		}
+		flags := uint16(payload[1]) | uint16(payload[2]&0x80)<<1
+		for bit, name := range colorimetryNames {
+			if flags&(1<<bit) != 0 {
+				e.Colorimetry = append(e.Colorimetry, name)
+			}
+		}
+	case ctaExtTagHDRStaticMeta:
+		if len(payload) < 3 {
+			return
+		}
+		hdr := &EDIDHDR{EOTFs: []string{}}
+		for bit, name := range eotfNames {
+			if payload[1]&(1<<bit) != 0 {
+				hdr.EOTFs = append(hdr.EOTFs, name)
+			}
+		}
+		if len(payload) > 3 && payload[3] != 0 {
+			hdr.MaxLuminance = roundLuminance(50 * math.Pow(2, float64(payload[3])/32))
+		}
+		if len(payload) > 4 && payload[4] != 0 {
+			hdr.MaxFrameAvgLuminance = roundLuminance(50 * math.Pow(2, float64(payload[4])/32))
+		}
+		if len(payload) > 5 && hdr.MaxLuminance > 0 {
+			cv := float64(payload[5]) / 255
+			hdr.MinLuminance = math.Round(hdr.MaxLuminance*cv*cv/100*10000) / 10000
+		}
+		e.HDR = hdr
+	}
+}
+
+func roundLuminance(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// SuggestedScale picks a scale that brings the panel close to 96 logical
+// DPI, rounded to quarter steps the way most compositors expose them.
+func (e *EDID) SuggestedScale() float64 {
+	if e == nil || e.NativeMode == nil || e.PhysicalWidthMM <= 0 {
+		return 0
+	}
+	dpi := float64(e.NativeMode.Width) / (float64(e.PhysicalWidthMM) / 25.4)
+	scale := math.Round(dpi/96*4) / 4
+	return max(1, min(scale, 3))
+}
+
+type drmConnector struct {
+	name string
+	edid *EDID
+}
+
+func readDRMConnectors() []drmConnector {
+	entries, err := os.ReadDir(drmSysfsRoot)
+	if err != nil {
+		return nil
+	}
+
+	var connectors []drmConnector
+	for _, entry := range entries {
+		card, name, ok := strings.Cut(entry.Name(), "-")
+		if !ok || !strings.HasPrefix(card, "card") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(drmSysfsRoot, entry.Name(), "edid"))
+		if err != nil || len(data) == 0 {
+			continue
+		}
+		edid, err := ParseEDID(data)
+		if err != nil {
+			continue
+		}
+		connectors = append(connectors, drmConnector{name: name, edid: edid})
+	}
+	return connectors
+}
+
+// LookupEDID finds the DRM connector backing a head. Compositors name heads
+// after their connector, so the name is tried first; the EDID monitor name
+// and serial are the fallback for nested or renamed outputs.
+func LookupEDID(name, model, serial string) (string, *EDID) {
+	connectors := readDRMConnectors()
+	for _, c := range connectors {
+		if c.name == name {
+			return c.name, c.edid
+		}
+	}
+	if model == "" {
+		return "", nil
+	}
+	for _, c := range connectors {
+		if c.edid.Name != model {
+			continue
+		}
+		if serial != "" && c.edid.SerialString != serial {
+			continue
+		}
+		return c.name, c.edid
+	}
+	return "", nil
+}
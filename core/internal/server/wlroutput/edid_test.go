@@ -0,0 +1,202 @@
+package wlroutput
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func loadEDIDFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	require.NoError(t, err)
+	return data
+}
+
+func TestParseEDID_LaptopPanel(t *testing.T) {
+	e, err := ParseEDID(loadEDIDFixture(t, "boe_ne135fbm.bin"))
+	require.NoError(t, err)
+
+	assert.True(t, e.ChecksumValid)
+	assert.Equal(t, "BOE", e.Manufacturer)
+	assert.Equal(t, uint16(0x0BCA), e.ProductCode)
+	assert.Equal(t, "NE135FBM-N41", e.Name)
+	assert.Equal(t, 12, e.Week)
+	assert.Equal(t, 2021, e.Year)
+	assert.Equal(t, "1.4", e.Version)
+	assert.Equal(t, 285, e.PhysicalWidthMM)
+	assert.Equal(t, 190, e.PhysicalHeightMM)
+
+	require.NotNil(t, e.NativeMode)
+	assert.Equal(t, 2256, e.NativeMode.Width)
+	assert.Equal(t, 1504, e.NativeMode.Height)
+	assert.InDelta(t, 60.0, e.NativeMode.Refresh, 0.01)
+	assert.Len(t, e.Timings, 1)
+
+	require.NotNil(t, e.RefreshRange)
+	assert.Equal(t, EDIDRange{MinHz: 48, MaxHz: 60}, *e.RefreshRange)
+	assert.Nil(t, e.VRR, "range limits alone don't mean VRR")
+	assert.Nil(t, e.HDR)
+	assert.Equal(t, 2.0, e.SuggestedScale())
+}
+
+func TestParseEDID_MonitorWithCTA(t *testing.T) {
+	e, err := ParseEDID(loadEDIDFixture(t, "dell_u2720q.bin"))
+	require.NoError(t, err)
+
+	assert.True(t, e.ChecksumValid)
+	assert.Equal(t, "DEL", e.Manufacturer)
+	assert.Equal(t, "DELL U2720Q", e.Name)
+	assert.Equal(t, "ABC123", e.SerialString)
+	assert.Equal(t, uint32(0x4C4F4E41), e.SerialNumber)
+	assert.Equal(t, 2020, e.Year)
+	assert.Equal(t, 597, e.PhysicalWidthMM)
+	assert.Equal(t, 336, e.PhysicalHeightMM)
+
+	require.NotNil(t, e.NativeMode)
+	assert.Equal(t, 3840, e.NativeMode.Width)
+	assert.Equal(t, 2160, e.NativeMode.Height)
+	assert.InDelta(t, 60.0, e.NativeMode.Refresh, 0.05)
+
+	bySource := map[string][]EDIDTiming{}
+	for _, timing := range e.Timings {
+		bySource[timing.Source] = append(bySource[timing.Source], timing)
+	}
+	assert.ElementsMatch(t, []EDIDTiming{
+		{Width: 640, Height: 480, Refresh: 60, Source: "established"},
+		{Width: 800, Height: 600, Refresh: 60, Source: "established"},
+		{Width: 1024, Height: 768, Refresh: 60, Source: "established"},
+	}, bySource["established"])
+	assert.ElementsMatch(t, []EDIDTiming{
+		{Width: 1920, Height: 1080, Refresh: 60, Source: "standard"},
+		{Width: 1280, Height: 1024, Refresh: 60, Source: "standard"},
+	}, bySource["standard"])
+	require.Len(t, bySource["detailed"], 2, "base block and CTA detailed timings")
+	assert.Equal(t, 1920, bySource["detailed"][1].Width)
+
+	assert.Equal(t, []string{"BT2020YCC", "BT2020RGB", "DCI-P3"}, e.Colorimetry)
+	require.NotNil(t, e.HDR)
+	assert.Equal(t, []string{"sdr", "pq"}, e.HDR.EOTFs)
+	assert.Equal(t, 400.0, e.HDR.MaxLuminance)
+	assert.InDelta(t, 282.84, e.HDR.MaxFrameAvgLuminance, 0.01)
+	assert.InDelta(t, 0.0157, e.HDR.MinLuminance, 0.0001)
+
+	require.NotNil(t, e.RefreshRange)
+	assert.Equal(t, EDIDRange{MinHz: 24, MaxHz: 75}, *e.RefreshRange)
+	assert.Nil(t, e.VRR)
+	assert.Equal(t, 1.75, e.SuggestedScale())
+}
+
+func TestParseEDID_VRR(t *testing.T) {
+	base := loadEDIDFixture(t, "boe_ne135fbm.bin")[:edidBlockSize]
+
+	contFreq := append([]byte(nil), base...)
+	contFreq[24] |= featureContFreq
+	e, err := ParseEDID(contFreq)
+	require.NoError(t, err)
+	require.NotNil(t, e.VRR, "EDID 1.4 continuous frequency bit")
+	assert.Equal(t, EDIDRange{MinHz: 48, MaxHz: 60}, *e.VRR)
+
+	withCTA := func(vsdb ...byte) []byte {
+		data := append(append([]byte(nil), base...), make([]byte, edidBlockSize)...)
+		data[126] = 1
+		cta := data[edidBlockSize:]
+		cta[0], cta[1], cta[2] = ctaExtensionTag, 3, byte(4+len(vsdb)+1)
+		cta[4] = ctaTagVendor<<5 | byte(len(vsdb))
+		copy(cta[5:], vsdb)
+		return data
+	}
+
+	e, err = ParseEDID(withCTA(0x1A, 0x00, 0x00, 0x01, 0x01, 48, 144))
+	require.NoError(t, err)
+	require.NotNil(t, e.VRR, "AMD FreeSync VSDB")
+	assert.Equal(t, EDIDRange{MinHz: 48, MaxHz: 144}, *e.VRR)
+
+	e, err = ParseEDID(withCTA(0xD8, 0x5D, 0xC4, 0x01, 0x78, 0x80, 0x00, 0x00, 0x00, 0x40|0x28, 0x20))
+	require.NoError(t, err)
+	require.NotNil(t, e.VRR, "HDMI Forum VSDB")
+	assert.Equal(t, EDIDRange{MinHz: 40, MaxHz: 288}, *e.VRR)
+
+	e, err = ParseEDID(withCTA(0x03, 0x0C, 0x00, 0x10, 0x00))
+	require.NoError(t, err)
+	assert.Nil(t, e.VRR, "HDMI 1.4 VSDB carries no VRR")
+}
+
+func TestParseEDID_BadChecksumStillParses(t *testing.T) {
+	e, err := ParseEDID(loadEDIDFixture(t, "bad_checksum.bin"))
+	require.NoError(t, err)
+	assert.False(t, e.ChecksumValid)
+	assert.Equal(t, "NE135FBM-N41", e.Name)
+}
+
+func TestParseEDID_Invalid(t *testing.T) {
+	_, err := ParseEDID([]byte{0x00, 0xFF})
+	assert.Error(t, err)
+
+	data := loadEDIDFixture(t, "boe_ne135fbm.bin")
+	data[1] = 0x00
+	_, err = ParseEDID(data)
+	assert.Error(t, err)
+}
+
+func TestSuggestedScale_Unknown(t *testing.T) {
+	var e *EDID
+	assert.Equal(t, 0.0, e.SuggestedScale())
+	assert.Equal(t, 0.0, (&EDID{}).SuggestedScale())
+}
+
+func TestLookupEDID(t *testing.T) {
+	root := t.TempDir()
+	orig := drmSysfsRoot
+	drmSysfsRoot = root
+	t.Cleanup(func() { drmSysfsRoot = orig })
+
+	for dir, fixture := range map[string]string{
+		"card1-eDP-1": "boe_ne135fbm.bin",
+		"card1-DP-3":  "dell_u2720q.bin",
+	} {
+		require.NoError(t, os.MkdirAll(filepath.Join(root, dir), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(root, dir, "edid"), loadEDIDFixture(t, fixture), 0o644))
+	}
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "card1-HDMI-A-1"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "card1-HDMI-A-1", "edid"), nil, 0o644))
+
+	connector, e := LookupEDID("eDP-1", "", "")
+	assert.Equal(t, "eDP-1", connector)
+	require.NotNil(t, e)
+	assert.Equal(t, "BOE", e.Manufacturer)
+
+	connector, e = LookupEDID("WL-1", "DELL U2720Q", "ABC123")
+	assert.Equal(t, "DP-3", connector)
+	require.NotNil(t, e)
+
+	connector, e = LookupEDID("HDMI-A-1", "", "")
+	assert.Empty(t, connector)
+	assert.Nil(t, e)
+}
+
+func TestHeadMatch_EDIDSerialFallback(t *testing.T) {
+	out := &Output{Name: "DP-3", Make: "Dell Inc.", Model: "DELL U2720Q", EDID: &EDID{SerialString: "ABC123"}}
+	assert.Equal(t, HeadMatch{Make: "Dell Inc.", Model: "DELL U2720Q", Serial: "ABC123"}, matchFor(out))
+	assert.True(t, HeadMatch{Serial: "ABC123"}.matches(out))
+}
+
+func TestLoadEDID_ModellessHeadStopsAfterDone(t *testing.T) {
+	orig := drmSysfsRoot
+	drmSysfsRoot = t.TempDir()
+	t.Cleanup(func() { drmSysfsRoot = orig })
+
+	m := &Manager{}
+	head := &headState{name: "HEADLESS-1"}
+
+	m.loadEDID(head)
+	assert.False(t, head.edidLoaded, "the model may still be on its way")
+
+	head.described = true
+	m.loadEDID(head)
+	assert.True(t, head.edidLoaded, "no model after done means none is coming")
+	assert.Nil(t, head.edid)
+}
@@ -45,6 +45,16 @@ func HandleRequest(conn *models.Conn, req models.Request, manager *Manager) {
 		handleApplyConfiguration(conn, req, manager, true)
 	case "wlroutput.subscribe":
 		handleSubscribe(conn, req, manager)
+	case "wlroutput.profiles.list":
+		models.Respond(conn, req.ID, manager.ListProfiles())
+	case "wlroutput.profiles.save":
+		handleSaveProfile(conn, req, manager)
+	case "wlroutput.profiles.delete":
+		handleDeleteProfile(conn, req, manager)
+	case "wlroutput.profiles.apply":
+		handleApplyProfile(conn, req, manager)
+	case "wlroutput.persist":
+		handlePersist(conn, req, manager)
 	default:
 		models.RespondError(conn, req.ID, fmt.Sprintf("unknown method: %s", req.Method))
 	}
@@ -61,15 +71,9 @@ func handleApplyConfiguration(conn *models.Conn, req models.Request, manager *Ma
 		return
 	}
 
-	headsJSON, err := json.Marshal(headsParam)
+	heads, err := decodeHeads(headsParam)
 	if err != nil {
-		models.RespondError(conn, req.ID, "invalid 'heads' parameter format")
-		return
-	}
-
-	var heads []HeadConfig
-	if err := json.Unmarshal(headsJSON, &heads); err != nil {
-		models.RespondError(conn, req.ID, fmt.Sprintf("invalid heads configuration: %v", err))
+		models.RespondError(conn, req.ID, err.Error())
 		return
 	}
 
@@ -85,6 +89,82 @@ func handleApplyConfiguration(conn *models.Conn, req models.Request, manager *Ma
 	models.Respond(conn, req.ID, models.SuccessResult{Success: true, Message: msg})
 }
 
+func decodeHeads(param any) ([]HeadConfig, error) {
+	headsJSON, err := json.Marshal(param)
+	if err != nil {
+		return nil, fmt.Errorf("invalid 'heads' parameter format")
+	}
+
+	var heads []HeadConfig
+	if err := json.Unmarshal(headsJSON, &heads); err != nil {
+		return nil, fmt.Errorf("invalid heads configuration: %v", err)
+	}
+	return heads, nil
+}
+
+func handlePersist(conn *models.Conn, req models.Request, manager *Manager) {
+	compositor, _ := models.Get[string](req, "compositor")
+
+	var heads []HeadConfig
+	if headsParam, ok := models.Get[any](req, "heads"); ok {
+		var err error
+		if heads, err = decodeHeads(headsParam); err != nil {
+			models.RespondError(conn, req.ID, err.Error())
+			return
+		}
+	}
+
+	path, err := manager.Persist(compositor, heads)
+	if err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+	models.Respond(conn, req.ID, models.SuccessResult{Success: true, Message: path})
+}
+
+func handleSaveProfile(conn *models.Conn, req models.Request, manager *Manager) {
+	name, ok := models.Get[string](req, "name")
+	if !ok || name == "" {
+		models.RespondError(conn, req.ID, "missing 'name' parameter")
+		return
+	}
+
+	profile, err := manager.SaveCurrentProfile(name)
+	if err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+	models.Respond(conn, req.ID, profile)
+}
+
+func handleDeleteProfile(conn *models.Conn, req models.Request, manager *Manager) {
+	name, ok := models.Get[string](req, "name")
+	if !ok || name == "" {
+		models.RespondError(conn, req.ID, "missing 'name' parameter")
+		return
+	}
+
+	if err := manager.DeleteProfile(name); err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+	models.Respond(conn, req.ID, models.SuccessResult{Success: true, Message: "profile deleted"})
+}
+
+func handleApplyProfile(conn *models.Conn, req models.Request, manager *Manager) {
+	name, ok := models.Get[string](req, "name")
+	if !ok || name == "" {
+		models.RespondError(conn, req.ID, "missing 'name' parameter")
+		return
+	}
+
+	if err := manager.ApplyProfile(name); err != nil {
+		models.RespondError(conn, req.ID, err.Error())
+		return
+	}
+	models.Respond(conn, req.ID, models.SuccessResult{Success: true, Message: "profile applied"})
+}
+
 func handleSubscribe(conn *models.Conn, req models.Request, manager *Manager) {
 	clientID := fmt.Sprintf("client-%p", conn)
 	stateChan := manager.Subscribe(clientID)
@@ -17,6 +17,7 @@ func NewManager(display wlclient.WaylandDisplay) (*Manager, error) {
 		stopChan:   make(chan struct{}),
 		dirty:      make(chan struct{}, 1),
 		fatalError: make(chan error, 1),
+		profiles:   LoadProfiles(),
 	}
 
 	m.wg.Add(1)
@@ -104,6 +105,10 @@ func (m *Manager) setupRegistry() error {
 				log.Debugf("WlrOutput: done event received (serial=%d)", e.Serial)
 				m.serial = e.Serial
 				m.post(func() {
+					m.heads.Range(func(_ uint32, head *headState) bool {
+						head.described = true
+						return true
+					})
 					m.updateState()
 				})
 			})
@@ -331,6 +336,10 @@ func (m *Manager) updateState() {
 			return true
 		}
 
+		if !head.edidLoaded {
+			m.loadEDID(head)
+		}
+
 		modes := make([]OutputMode, 0)
 		var currentMode *OutputMode
 
@@ -375,21 +384,42 @@ func (m *Manager) updateState() {
 			AdaptiveSync:          head.adaptiveSync,
 			AdaptiveSyncSupported: head.adaptiveSyncSupported,
 			ID:                    head.id,
+			DRMConnector:          head.drmConnector,
+			EDID:                  head.edid,
+			SuggestedScale:        head.edid.SuggestedScale(),
 		}
 		outputs = append(outputs, output)
 		return true
 	})
 
+	m.stateMutex.Lock()
 	newState := State{
-		Outputs: outputs,
-		Serial:  m.serial,
+		Outputs:       outputs,
+		Serial:        m.serial,
+		ActiveProfile: m.activeProfile,
 	}
-
-	m.stateMutex.Lock()
 	m.state = &newState
 	m.stateMutex.Unlock()
 
 	m.notifySubscribers()
+	m.scheduleProfileMatch(outputs)
+}
+
+// loadEDID maps a head to its DRM connector once. Heads that cannot be
+// matched by name are retried until their model is known, or until a done
+// event shows the compositor has none to send (headless and virtual
+// outputs).
+func (m *Manager) loadEDID(head *headState) {
+	connector, edid := LookupEDID(head.name, head.model, head.serialNumber)
+	if edid == nil && head.model == "" && !head.described {
+		return
+	}
+	head.edidLoaded = true
+	head.drmConnector = connector
+	head.edid = edid
+	if edid != nil {
+		log.Debugf("WlrOutput: Head %d mapped to DRM connector %s (%s %s)", head.id, connector, edid.Manufacturer, edid.Name)
+	}
 }
 
 func (m *Manager) notifier() {
@@ -457,6 +487,12 @@ func (m *Manager) notifier() {
 }
 
 func (m *Manager) Close() {
+	m.profilesMutex.Lock()
+	if m.profileTimer != nil {
+		m.profileTimer.Stop()
+	}
+	m.profilesMutex.Unlock()
+
 	close(m.stopChan)
 	m.wg.Wait()
 	m.notifierWg.Wait()
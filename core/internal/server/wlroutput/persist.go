@@ -0,0 +1,125 @@
+package wlroutput
+
+import (
+	"fmt"
+
+	"github.com/AvengeMedia/DankMaterialShell/core/internal/config"
+)
+
+// LayoutsFromState converts outputs into compositor-agnostic layouts. When
+// heads is non-empty it is overlaid on the outputs, so a configuration can be
+// persisted without first being applied.
+func LayoutsFromState(outputs []Output, heads []HeadConfig) ([]config.OutputLayout, error) {
+	byName := make(map[string]*Output, len(outputs))
+	for i := range outputs {
+		byName[outputs[i].Name] = &outputs[i]
+	}
+
+	layouts := make([]config.OutputLayout, 0, len(outputs))
+	index := make(map[string]int, len(outputs))
+	for i := range outputs {
+		index[outputs[i].Name] = len(layouts)
+		layouts = append(layouts, layoutFromOutput(&outputs[i]))
+	}
+
+	for _, head := range heads {
+		out, ok := byName[head.Name]
+		if !ok {
+			return nil, fmt.Errorf("head not found: %s", head.Name)
+		}
+		if err := overlayHeadConfig(&layouts[index[head.Name]], out, head); err != nil {
+			return nil, err
+		}
+	}
+	return layouts, nil
+}
+
+func layoutFromOutput(out *Output) config.OutputLayout {
+	layout := config.OutputLayout{
+		Name:      out.Name,
+		Enabled:   out.Enabled,
+		X:         out.X,
+		Y:         out.Y,
+		Scale:     out.Scale,
+		Transform: out.Transform,
+		VRR:       out.AdaptiveSyncSupported && out.AdaptiveSync == 1,
+	}
+	mode := out.CurrentMode
+	if mode == nil {
+		mode = preferredMode(out.Modes)
+	}
+	if mode != nil {
+		layout.Width, layout.Height, layout.Refresh = mode.Width, mode.Height, mode.Refresh
+	}
+	return layout
+}
+
+func preferredMode(modes []OutputMode) *OutputMode {
+	for i := range modes {
+		if modes[i].Preferred {
+			return &modes[i]
+		}
+	}
+	return nil
+}
+
+func overlayHeadConfig(layout *config.OutputLayout, out *Output, head HeadConfig) error {
+	layout.Enabled = head.Enabled
+	if !head.Enabled {
+		return nil
+	}
+
+	switch {
+	case head.ModeID != nil:
+		idx := -1
+		for i := range out.Modes {
+			if out.Modes[i].ID == *head.ModeID {
+				idx = i
+				break
+			}
+		}
+		if idx == -1 {
+			return fmt.Errorf("mode not found: %d", *head.ModeID)
+		}
+		mode := out.Modes[idx]
+		layout.Width, layout.Height, layout.Refresh = mode.Width, mode.Height, mode.Refresh
+	case head.CustomMode != nil:
+		layout.Width, layout.Height, layout.Refresh = head.CustomMode.Width, head.CustomMode.Height, head.CustomMode.Refresh
+	}
+
+	if head.Position != nil {
+		layout.X, layout.Y = head.Position.X, head.Position.Y
+	}
+	if head.Transform != nil {
+		layout.Transform = *head.Transform
+	}
+	if head.Scale != nil {
+		layout.Scale = *head.Scale
+	}
+	if head.AdaptiveSync != nil {
+		layout.VRR = *head.AdaptiveSync == 1
+	}
+	return nil
+}
+
+// Persist writes the current layout, optionally overlaid with heads, into the
+// compositor's dms/outputs fragment. An empty compositor is auto-detected.
+func (m *Manager) Persist(compositor string, heads []HeadConfig) (string, error) {
+	if compositor == "" {
+		compositor = config.DetectOutputsCompositor()
+	}
+	if compositor == "" {
+		return "", fmt.Errorf("no supported compositor detected")
+	}
+
+	state := m.GetState()
+	if len(state.Outputs) == 0 {
+		return "", fmt.Errorf("no outputs connected")
+	}
+
+	layouts, err := LayoutsFromState(state.Outputs, heads)
+	if err != nil {
+		return "", err
+	}
+	return config.WriteOutputsConfig(compositor, layouts)
+}
@@ -0,0 +1,65 @@
+package wlroutput
+
+import (
+	"testing"
+
+	"github.com/AvengeMedia/DankMaterialShell/core/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLayoutsFromState(t *testing.T) {
+	layouts, err := LayoutsFromState(dockedOutputs(), nil)
+	require.NoError(t, err)
+	require.Len(t, layouts, 2)
+
+	assert.Equal(t, config.OutputLayout{
+		Name: "eDP-1", Enabled: true, Width: 2256, Height: 1504, Refresh: 59999, Scale: 1.5,
+	}, layouts[0])
+	assert.Equal(t, config.OutputLayout{
+		Name: "DP-3", Enabled: true, Width: 3840, Height: 2160, Refresh: 59997, X: 1504, Scale: 1, VRR: true,
+	}, layouts[1])
+}
+
+func TestLayoutsFromState_OverlayHeads(t *testing.T) {
+	modeID := uint32(12)
+	scale := 2.0
+	asOff := uint32(0)
+	heads := []HeadConfig{
+		{Name: "eDP-1", Enabled: false},
+		{Name: "DP-3", Enabled: true, ModeID: &modeID, Scale: &scale, AdaptiveSync: &asOff, Position: &struct{ X, Y int32 }{0, 0}},
+	}
+
+	layouts, err := LayoutsFromState(dockedOutputs(), heads)
+	require.NoError(t, err)
+
+	assert.False(t, layouts[0].Enabled)
+	assert.Equal(t, int32(1920), layouts[1].Width)
+	assert.Equal(t, int32(60000), layouts[1].Refresh)
+	assert.Equal(t, int32(0), layouts[1].X)
+	assert.Equal(t, 2.0, layouts[1].Scale)
+	assert.False(t, layouts[1].VRR)
+}
+
+func TestLayoutsFromState_UnknownHeadOrMode(t *testing.T) {
+	_, err := LayoutsFromState(dockedOutputs(), []HeadConfig{{Name: "HDMI-A-1", Enabled: true}})
+	assert.Error(t, err)
+
+	bad := uint32(99)
+	_, err = LayoutsFromState(dockedOutputs(), []HeadConfig{{Name: "DP-3", Enabled: true, ModeID: &bad}})
+	assert.Error(t, err)
+}
+
+func TestLayoutsFromState_PreferredModeFallback(t *testing.T) {
+	outputs := []Output{{
+		Name:    "HDMI-A-1",
+		Enabled: false,
+		Modes: []OutputMode{
+			{Width: 1280, Height: 720, Refresh: 60000},
+			{Width: 1920, Height: 1080, Refresh: 60000, Preferred: true},
+		},
+	}}
+	layouts, err := LayoutsFromState(outputs, nil)
+	require.NoError(t, err)
+	assert.Equal(t, int32(1920), layouts[0].Width)
+}
@@ -0,0 +1,437 @@
+package wlroutput
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/AvengeMedia/DankMaterialShell/core/internal/log"
+)
+
+const profileApplyDelay = 750 * time.Millisecond
+
+// HeadMatch identifies a connected head. Name accepts shell glob patterns
+// (e.g. "DP-*"); make, model and serial must match exactly when set.
+type HeadMatch struct {
+	Name   string `json:"name,omitempty"`
+	Make   string `json:"make,omitempty"`
+	Model  string `json:"model,omitempty"`
+	Serial string `json:"serial,omitempty"`
+}
+
+type ProfileMode struct {
+	Width   int32 `json:"width"`
+	Height  int32 `json:"height"`
+	Refresh int32 `json:"refresh"`
+}
+
+type ProfileHead struct {
+	Match        HeadMatch    `json:"match"`
+	Enabled      bool         `json:"enabled"`
+	Mode         *ProfileMode `json:"mode,omitempty"`
+	X            int32        `json:"x"`
+	Y            int32        `json:"y"`
+	Scale        float64      `json:"scale,omitempty"`
+	Transform    int32        `json:"transform"`
+	AdaptiveSync *uint32      `json:"adaptiveSync,omitempty"`
+}
+
+type Profile struct {
+	Name  string        `json:"name"`
+	Heads []ProfileHead `json:"heads"`
+}
+
+type profileFile struct {
+	Profiles []Profile `json:"profiles"`
+}
+
+func profilesPath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "DankMaterialShell", "display-profiles.json"), nil
+}
+
+func LoadProfiles() []Profile {
+	path, err := profilesPath()
+	if err != nil {
+		return []Profile{}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return []Profile{}
+	}
+
+	var file profileFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		log.Warnf("WlrOutput: invalid profiles file %s: %v", path, err)
+		return []Profile{}
+	}
+	if file.Profiles == nil {
+		return []Profile{}
+	}
+	return file.Profiles
+}
+
+func SaveProfiles(profiles []Profile) error {
+	path, err := profilesPath()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(profileFile{Profiles: profiles}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0o644)
+}
+
+func (hm HeadMatch) matches(out *Output) bool {
+	if hm.Name != "" {
+		ok, err := filepath.Match(hm.Name, out.Name)
+		if err != nil || !ok {
+			return false
+		}
+	}
+	if hm.Make != "" && hm.Make != out.Make {
+		return false
+	}
+	if hm.Model != "" && hm.Model != out.Model {
+		return false
+	}
+	if hm.Serial != "" && hm.Serial != outputSerial(out) {
+		return false
+	}
+	return hm.Name != "" || hm.Make != "" || hm.Model != "" || hm.Serial != ""
+}
+
+// outputSerial falls back to the EDID serial descriptor for compositors
+// that don't forward it through wlr-output-management.
+func outputSerial(out *Output) string {
+	if out.SerialNumber == "" && out.EDID != nil {
+		return out.EDID.SerialString
+	}
+	return out.SerialNumber
+}
+
+// matchFor builds a HeadMatch for out, preferring the EDID-derived identity
+// over the connector name so profiles survive docks that renumber ports.
+func matchFor(out *Output) HeadMatch {
+	serial := outputSerial(out)
+	if out.Make == "" && out.Model == "" && serial == "" {
+		return HeadMatch{Name: out.Name}
+	}
+	return HeadMatch{Make: out.Make, Model: out.Model, Serial: serial}
+}
+
+// ProfileFromState captures the current layout of every connected head.
+// Identical monitors without a serial also keep their connector name, so
+// applying the profile can't swap them.
+func ProfileFromState(name string, state State) Profile {
+	identities := make(map[HeadMatch]int, len(state.Outputs))
+	for i := range state.Outputs {
+		identities[matchFor(&state.Outputs[i])]++
+	}
+
+	heads := make([]ProfileHead, 0, len(state.Outputs))
+	for i := range state.Outputs {
+		out := &state.Outputs[i]
+		match := matchFor(out)
+		if identities[match] > 1 {
+			match.Name = out.Name
+		}
+		head := ProfileHead{
+			Match:     match,
+			Enabled:   out.Enabled,
+			X:         out.X,
+			Y:         out.Y,
+			Scale:     out.Scale,
+			Transform: out.Transform,
+		}
+		if out.CurrentMode != nil {
+			head.Mode = &ProfileMode{
+				Width:   out.CurrentMode.Width,
+				Height:  out.CurrentMode.Height,
+				Refresh: out.CurrentMode.Refresh,
+			}
+		}
+		if out.AdaptiveSyncSupported {
+			as := out.AdaptiveSync
+			head.AdaptiveSync = &as
+		}
+		heads = append(heads, head)
+	}
+	sort.Slice(heads, func(i, j int) bool {
+		return heads[i].X < heads[j].X || (heads[i].X == heads[j].X && heads[i].Y < heads[j].Y)
+	})
+	return Profile{Name: name, Heads: heads}
+}
+
+// assign maps every profile head onto a distinct output. A profile only
+// matches when it covers exactly the set of connected outputs.
+func (p *Profile) assign(outputs []Output) ([]int, bool) {
+	if len(p.Heads) == 0 || len(p.Heads) != len(outputs) {
+		return nil, false
+	}
+
+	assignment := make([]int, len(p.Heads))
+	used := make([]bool, len(outputs))
+
+	var solve func(i int) bool
+	solve = func(i int) bool {
+		if i == len(p.Heads) {
+			return true
+		}
+		for j := range outputs {
+			if used[j] || !p.Heads[i].Match.matches(&outputs[j]) {
+				continue
+			}
+			used[j] = true
+			assignment[i] = j
+			if solve(i + 1) {
+				return true
+			}
+			used[j] = false
+		}
+		return false
+	}
+
+	if !solve(0) {
+		return nil, false
+	}
+	return assignment, true
+}
+
+func findMatchingProfile(profiles []Profile, outputs []Output) (*Profile, []int) {
+	for i := range profiles {
+		if assignment, ok := profiles[i].assign(outputs); ok {
+			return &profiles[i], assignment
+		}
+	}
+	return nil, nil
+}
+
+func closestMode(modes []OutputMode, want *ProfileMode) *OutputMode {
+	var best *OutputMode
+	bestDelta := int32(math.MaxInt32)
+	for i := range modes {
+		mode := &modes[i]
+		if mode.Width != want.Width || mode.Height != want.Height {
+			continue
+		}
+		delta := mode.Refresh - want.Refresh
+		if delta < 0 {
+			delta = -delta
+		}
+		if delta < bestDelta {
+			best = mode
+			bestDelta = delta
+		}
+	}
+	return best
+}
+
+func (p *Profile) headConfigs(outputs []Output, assignment []int) []HeadConfig {
+	configs := make([]HeadConfig, 0, len(p.Heads))
+	for i, ph := range p.Heads {
+		out := &outputs[assignment[i]]
+		cfg := HeadConfig{Name: out.Name, Enabled: ph.Enabled}
+		if !ph.Enabled {
+			configs = append(configs, cfg)
+			continue
+		}
+
+		if ph.Mode != nil {
+			if mode := closestMode(out.Modes, ph.Mode); mode != nil {
+				id := mode.ID
+				cfg.ModeID = &id
+			} else {
+				cfg.CustomMode = &struct {
+					Width   int32 `json:"width"`
+					Height  int32 `json:"height"`
+					Refresh int32 `json:"refresh"`
+				}{ph.Mode.Width, ph.Mode.Height, ph.Mode.Refresh}
+			}
+		}
+
+		cfg.Position = &struct{ X, Y int32 }{ph.X, ph.Y}
+		transform := ph.Transform
+		cfg.Transform = &transform
+		if ph.Scale > 0 {
+			scale := ph.Scale
+			cfg.Scale = &scale
+		}
+		if ph.AdaptiveSync != nil && out.AdaptiveSyncSupported {
+			as := *ph.AdaptiveSync
+			cfg.AdaptiveSync = &as
+		}
+		configs = append(configs, cfg)
+	}
+	return configs
+}
+
+func connectedKey(outputs []Output) string {
+	names := make([]string, 0, len(outputs))
+	for _, out := range outputs {
+		names = append(names, out.Name+"|"+out.Make+"|"+out.Model+"|"+out.SerialNumber)
+	}
+	slices.Sort(names)
+	return strings.Join(names, ",")
+}
+
+func (m *Manager) ListProfiles() []Profile {
+	m.profilesMutex.Lock()
+	defer m.profilesMutex.Unlock()
+	return slices.Clone(m.profiles)
+}
+
+func (m *Manager) SaveCurrentProfile(name string) (Profile, error) {
+	if name == "" {
+		return Profile{}, fmt.Errorf("profile name is required")
+	}
+
+	state := m.GetState()
+	if len(state.Outputs) == 0 {
+		return Profile{}, fmt.Errorf("no outputs connected")
+	}
+	profile := ProfileFromState(name, state)
+
+	m.profilesMutex.Lock()
+	profiles := slices.Clone(m.profiles)
+	idx := slices.IndexFunc(profiles, func(p Profile) bool { return p.Name == name })
+	switch idx {
+	case -1:
+		profiles = append(profiles, profile)
+	default:
+		profiles[idx] = profile
+	}
+	if err := SaveProfiles(profiles); err != nil {
+		m.profilesMutex.Unlock()
+		return Profile{}, fmt.Errorf("failed to save profiles: %w", err)
+	}
+	m.profiles = profiles
+	m.profilesMutex.Unlock()
+
+	m.setActiveProfile(name)
+	return profile, nil
+}
+
+func (m *Manager) DeleteProfile(name string) error {
+	m.profilesMutex.Lock()
+	idx := slices.IndexFunc(m.profiles, func(p Profile) bool { return p.Name == name })
+	if idx == -1 {
+		m.profilesMutex.Unlock()
+		return fmt.Errorf("profile not found: %s", name)
+	}
+	profiles := slices.Delete(slices.Clone(m.profiles), idx, idx+1)
+	if err := SaveProfiles(profiles); err != nil {
+		m.profilesMutex.Unlock()
+		return fmt.Errorf("failed to save profiles: %w", err)
+	}
+	m.profiles = profiles
+	m.profilesMutex.Unlock()
+
+	if m.GetState().ActiveProfile == name {
+		m.setActiveProfile("")
+	}
+	return nil
+}
+
+func (m *Manager) ApplyProfile(name string) error {
+	m.profilesMutex.Lock()
+	idx := slices.IndexFunc(m.profiles, func(p Profile) bool { return p.Name == name })
+	if idx == -1 {
+		m.profilesMutex.Unlock()
+		return fmt.Errorf("profile not found: %s", name)
+	}
+	profile := m.profiles[idx]
+	m.profilesMutex.Unlock()
+
+	outputs := m.GetState().Outputs
+	assignment, ok := profile.assign(outputs)
+	if !ok {
+		return fmt.Errorf("profile %s does not match the connected outputs", name)
+	}
+
+	if err := m.ApplyConfiguration(profile.headConfigs(outputs, assignment), false); err != nil {
+		return err
+	}
+	m.setActiveProfile(name)
+	return nil
+}
+
+func (m *Manager) setActiveProfile(name string) {
+	m.stateMutex.Lock()
+	m.activeProfile = name
+	if m.state != nil {
+		m.state.ActiveProfile = name
+	}
+	m.stateMutex.Unlock()
+	m.notifySubscribers()
+}
+
+// scheduleProfileMatch debounces head add/remove bursts; heads are announced
+// one property at a time so the layout is only final once events settle.
+func (m *Manager) scheduleProfileMatch(outputs []Output) {
+	key := connectedKey(outputs)
+
+	m.profilesMutex.Lock()
+	defer m.profilesMutex.Unlock()
+
+	if key == m.lastConnectedKey {
+		return
+	}
+	m.lastConnectedKey = key
+
+	if m.profileTimer != nil {
+		m.profileTimer.Stop()
+	}
+	m.profileTimer = time.AfterFunc(profileApplyDelay, m.applyMatchingProfile)
+}
+
+func (m *Manager) applyMatchingProfile() {
+	select {
+	case <-m.stopChan:
+		return
+	default:
+	}
+
+	outputs := m.GetState().Outputs
+	if len(outputs) == 0 {
+		return
+	}
+
+	m.profilesMutex.Lock()
+	profile, assignment := findMatchingProfile(m.profiles, outputs)
+	var matched Profile
+	if profile != nil {
+		matched = *profile
+	}
+	m.profilesMutex.Unlock()
+
+	if profile == nil {
+		log.Debugf("WlrOutput: no profile matches connected outputs")
+		m.setActiveProfile("")
+		return
+	}
+
+	log.Infof("WlrOutput: applying profile %q", matched.Name)
+	if err := m.ApplyConfiguration(matched.headConfigs(outputs, assignment), false); err != nil {
+		log.Warnf("WlrOutput: failed to apply profile %q: %v", matched.Name, err)
+		return
+	}
+	m.setActiveProfile(matched.Name)
+}
@@ -0,0 +1,193 @@
+package wlroutput
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func dockedOutputs() []Output {
+	return []Output{
+		{
+			Name:    "eDP-1",
+			Make:    "BOE",
+			Model:   "0x0BCA",
+			Enabled: true,
+			Scale:   1.5,
+			Modes: []OutputMode{
+				{Width: 2256, Height: 1504, Refresh: 59999, ID: 1},
+			},
+			CurrentMode: &OutputMode{Width: 2256, Height: 1504, Refresh: 59999, ID: 1},
+		},
+		{
+			Name:         "DP-3",
+			Make:         "Dell Inc.",
+			Model:        "DELL U2720Q",
+			SerialNumber: "ABC123",
+			Enabled:      true,
+			X:            1504,
+			Scale:        1.0,
+			Modes: []OutputMode{
+				{Width: 3840, Height: 2160, Refresh: 59997, ID: 10},
+				{Width: 3840, Height: 2160, Refresh: 29981, ID: 11},
+				{Width: 1920, Height: 1080, Refresh: 60000, ID: 12},
+			},
+			CurrentMode:           &OutputMode{Width: 3840, Height: 2160, Refresh: 59997, ID: 10},
+			AdaptiveSyncSupported: true,
+			AdaptiveSync:          1,
+		},
+	}
+}
+
+func TestHeadMatch_Matches(t *testing.T) {
+	out := &Output{Name: "DP-3", Make: "Dell Inc.", Model: "DELL U2720Q", SerialNumber: "ABC123"}
+
+	assert.True(t, HeadMatch{Name: "DP-3"}.matches(out))
+	assert.True(t, HeadMatch{Name: "DP-*"}.matches(out))
+	assert.True(t, HeadMatch{Make: "Dell Inc.", Model: "DELL U2720Q"}.matches(out))
+	assert.True(t, HeadMatch{Name: "DP-*", Serial: "ABC123"}.matches(out))
+	assert.False(t, HeadMatch{Name: "HDMI-*"}.matches(out))
+	assert.False(t, HeadMatch{Make: "Dell Inc.", Serial: "XYZ"}.matches(out))
+	assert.False(t, HeadMatch{}.matches(out), "empty match must not match anything")
+}
+
+func TestProfileFromState(t *testing.T) {
+	p := ProfileFromState("docked", State{Outputs: dockedOutputs()})
+
+	assert.Equal(t, "docked", p.Name)
+	require.Len(t, p.Heads, 2)
+
+	assert.Equal(t, HeadMatch{Make: "BOE", Model: "0x0BCA"}, p.Heads[0].Match)
+	assert.Equal(t, 1.5, p.Heads[0].Scale)
+	assert.Nil(t, p.Heads[0].AdaptiveSync)
+
+	assert.Equal(t, HeadMatch{Make: "Dell Inc.", Model: "DELL U2720Q", Serial: "ABC123"}, p.Heads[1].Match)
+	assert.Equal(t, int32(1504), p.Heads[1].X)
+	require.NotNil(t, p.Heads[1].Mode)
+	assert.Equal(t, int32(59997), p.Heads[1].Mode.Refresh)
+	require.NotNil(t, p.Heads[1].AdaptiveSync)
+	assert.Equal(t, uint32(1), *p.Heads[1].AdaptiveSync)
+}
+
+func TestProfileFromState_NoEDIDFallsBackToName(t *testing.T) {
+	p := ProfileFromState("virt", State{Outputs: []Output{{Name: "Virtual-1", Enabled: true}}})
+	require.Len(t, p.Heads, 1)
+	assert.Equal(t, HeadMatch{Name: "Virtual-1"}, p.Heads[0].Match)
+}
+
+func TestProfileFromState_IdenticalHeadsKeepConnector(t *testing.T) {
+	outputs := []Output{
+		{Name: "DP-1", Make: "Dell Inc.", Model: "DELL P2419H", Enabled: true},
+		{Name: "DP-2", Make: "Dell Inc.", Model: "DELL P2419H", Enabled: true, X: 1920},
+	}
+	p := ProfileFromState("twins", State{Outputs: outputs})
+	require.Len(t, p.Heads, 2)
+	assert.Equal(t, HeadMatch{Name: "DP-1", Make: "Dell Inc.", Model: "DELL P2419H"}, p.Heads[0].Match)
+	assert.Equal(t, HeadMatch{Name: "DP-2", Make: "Dell Inc.", Model: "DELL P2419H"}, p.Heads[1].Match)
+
+	swapped := []Output{outputs[1], outputs[0]}
+	assignment, ok := p.assign(swapped)
+	require.True(t, ok)
+	configs := p.headConfigs(swapped, assignment)
+	require.Len(t, configs, 2)
+	assert.Equal(t, "DP-1", configs[0].Name)
+	assert.Equal(t, int32(0), configs[0].Position.X)
+	assert.Equal(t, "DP-2", configs[1].Name)
+	assert.Equal(t, int32(1920), configs[1].Position.X)
+}
+
+func TestProfile_AssignRequiresExactSet(t *testing.T) {
+	outputs := dockedOutputs()
+	p := ProfileFromState("docked", State{Outputs: outputs})
+
+	_, ok := p.assign(outputs)
+	assert.True(t, ok)
+
+	_, ok = p.assign(outputs[:1])
+	assert.False(t, ok, "profile with two heads must not match one output")
+
+	laptop := Profile{Name: "laptop", Heads: []ProfileHead{{Match: HeadMatch{Name: "eDP-1"}, Enabled: true}}}
+	_, ok = laptop.assign(outputs)
+	assert.False(t, ok, "profile must cover every connected output")
+}
+
+func TestProfile_AssignBacktracks(t *testing.T) {
+	outputs := []Output{{Name: "DP-1"}, {Name: "DP-2"}}
+	p := Profile{Heads: []ProfileHead{
+		{Match: HeadMatch{Name: "DP-*"}},
+		{Match: HeadMatch{Name: "DP-1"}},
+	}}
+
+	assignment, ok := p.assign(outputs)
+	require.True(t, ok)
+	assert.Equal(t, []int{1, 0}, assignment)
+}
+
+func TestFindMatchingProfile_FirstMatchWins(t *testing.T) {
+	outputs := dockedOutputs()
+	profiles := []Profile{
+		{Name: "laptop", Heads: []ProfileHead{{Match: HeadMatch{Name: "eDP-1"}}}},
+		{Name: "any-two", Heads: []ProfileHead{{Match: HeadMatch{Name: "*"}}, {Match: HeadMatch{Name: "*"}}}},
+		ProfileFromState("docked", State{Outputs: outputs}),
+	}
+
+	p, _ := findMatchingProfile(profiles, outputs)
+	require.NotNil(t, p)
+	assert.Equal(t, "any-two", p.Name)
+
+	p, _ = findMatchingProfile(profiles[:1], outputs)
+	assert.Nil(t, p)
+}
+
+func TestProfile_HeadConfigs(t *testing.T) {
+	outputs := dockedOutputs()
+	p := ProfileFromState("docked", State{Outputs: outputs})
+	p.Heads[1].Mode.Refresh = 30000
+
+	assignment, ok := p.assign(outputs)
+	require.True(t, ok)
+
+	configs := p.headConfigs(outputs, assignment)
+	require.Len(t, configs, 2)
+
+	assert.Equal(t, "DP-3", configs[1].Name)
+	require.NotNil(t, configs[1].ModeID)
+	assert.Equal(t, uint32(11), *configs[1].ModeID, "closest refresh should be chosen")
+	require.NotNil(t, configs[1].Position)
+	assert.Equal(t, int32(1504), configs[1].Position.X)
+	require.NotNil(t, configs[1].AdaptiveSync)
+}
+
+func TestProfile_HeadConfigsCustomModeAndDisabled(t *testing.T) {
+	outputs := dockedOutputs()
+	p := Profile{Heads: []ProfileHead{
+		{Match: HeadMatch{Name: "eDP-1"}, Enabled: false},
+		{Match: HeadMatch{Name: "DP-3"}, Enabled: true, Mode: &ProfileMode{Width: 2560, Height: 1440, Refresh: 60000}},
+	}}
+
+	assignment, ok := p.assign(outputs)
+	require.True(t, ok)
+	configs := p.headConfigs(outputs, assignment)
+
+	assert.False(t, configs[0].Enabled)
+	assert.Nil(t, configs[0].Position)
+
+	assert.Nil(t, configs[1].ModeID)
+	require.NotNil(t, configs[1].CustomMode)
+	assert.Equal(t, int32(2560), configs[1].CustomMode.Width)
+	assert.Nil(t, configs[1].Scale)
+}
+
+func TestConnectedKey_OrderIndependent(t *testing.T) {
+	outputs := dockedOutputs()
+	reversed := []Output{outputs[1], outputs[0]}
+	assert.Equal(t, connectedKey(outputs), connectedKey(reversed))
+	assert.NotEqual(t, connectedKey(outputs), connectedKey(outputs[:1]))
+}
+
+func TestStateChanged_ActiveProfileDiffers(t *testing.T) {
+	a := &State{Serial: 1, Outputs: []Output{}}
+	b := &State{Serial: 1, Outputs: []Output{}, ActiveProfile: "docked"}
+	assert.True(t, stateChanged(a, b))
+}
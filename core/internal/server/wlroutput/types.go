@@ -2,6 +2,7 @@ package wlroutput
 
 import (
 	"sync"
+	"time"
 
 	"github.com/AvengeMedia/DankMaterialShell/core/internal/proto/wlr_output_management"
 	"github.com/AvengeMedia/dankgo/syncmap"
@@ -34,11 +35,15 @@ type Output struct {
 	AdaptiveSync          uint32       `json:"adaptiveSync"`
 	AdaptiveSyncSupported bool         `json:"adaptiveSyncSupported"`
 	ID                    uint32       `json:"id"`
+	DRMConnector          string       `json:"drmConnector,omitempty"`
+	EDID                  *EDID        `json:"edid,omitempty"`
+	SuggestedScale        float64      `json:"suggestedScale,omitempty"`
 }
 
 type State struct {
-	Outputs []Output `json:"outputs"`
-	Serial  uint32   `json:"serial"`
+	Outputs       []Output `json:"outputs"`
+	Serial        uint32   `json:"serial"`
+	ActiveProfile string   `json:"activeProfile,omitempty"`
 }
 
 type cmd struct {
@@ -66,8 +71,14 @@ type Manager struct {
 	notifierWg   sync.WaitGroup
 	lastNotified *State
 
-	stateMutex sync.RWMutex
-	state      *State
+	stateMutex    sync.RWMutex
+	state         *State
+	activeProfile string
+
+	profilesMutex    sync.Mutex
+	profiles         []Profile
+	profileTimer     *time.Timer
+	lastConnectedKey string
 
 	fatalError chan error
 }
@@ -93,6 +104,10 @@ type headState struct {
 	adaptiveSyncSupported bool
 	finished              bool
 	ready                 bool
+	described             bool // a done event followed the initial properties
+	edidLoaded            bool
+	drmConnector          string
+	edid                  *EDID
 }
 
 type modeState struct {
@@ -150,7 +165,7 @@ func stateChanged(old, new *State) bool {
 	if old == nil || new == nil {
 		return true
 	}
-	if old.Serial != new.Serial {
+	if old.Serial != new.Serial || old.ActiveProfile != new.ActiveProfile {
 		return true
 	}
 	if len(old.Outputs) != len(new.Outputs) {
@@ -187,6 +202,9 @@ func stateChanged(old, new *State) bool {
 		if len(oldOut.Modes) != len(newOut.Modes) {
 			return true
 		}
+		if (oldOut.EDID == nil) != (newOut.EDID == nil) {
+			return true
+		}
 	}
 	return false
 }
@@ -18,8 +18,14 @@ func XDGConfigHome() string { return paths.XDGConfigHome() }
 
 func ExpandPath(path string) (string, error) { return paths.ExpandPath(path) }
 
-func XDGPicturesDir() string {
-	if dir := os.Getenv("XDG_PICTURES_DIR"); dir != "" {
+func XDGPicturesDir() string { return xdgUserDir("PICTURES") }
+
+func XDGDownloadDir() string { return xdgUserDir("DOWNLOAD") }
+
+// xdgUserDir resolves XDG_<name>_DIR from the environment or user-dirs.dirs.
+func xdgUserDir(name string) string {
+	key := "XDG_" + name + "_DIR"
+	if dir := os.Getenv(key); dir != "" {
 		if expanded, err := ExpandPath(dir); err == nil {
 			return expanded
 		}
@@ -30,7 +36,7 @@ func XDGPicturesDir() string {
 		return ""
 	}
 
-	const prefix = "XDG_PICTURES_DIR="
+	prefix := key + "="
 	for line := range strings.SplitSeq(string(data), "\n") {
 		if len(line) == 0 || line[0] == '#' {
 			continue